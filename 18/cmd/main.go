@@ -1,57 +1,384 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"flag"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
+	"time"
 
+	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"l2.18/internal/config"
+	"l2.18/internal/grpcapi"
 	"l2.18/internal/handler"
+	"l2.18/internal/idempotency"
+	"l2.18/internal/metrics"
+	"l2.18/internal/openapi"
+	"l2.18/internal/pubsub"
+	"l2.18/internal/ratelimit"
+	"l2.18/internal/reminder"
+	"l2.18/internal/repository/file"
 	"l2.18/internal/repository/memory"
+	"l2.18/internal/repository/sqlite"
 	"l2.18/internal/service/events"
+	"l2.18/internal/tenant"
+	"l2.18/pkg/models"
 	"l2.18/pkg/server"
 )
 
+// historyPruneInterval - как часто удаляются записи истории старше -history-retention.
+const historyPruneInterval = time.Hour
+
+// idempotencyPruneInterval - как часто удаляются ответы старше -idempotency-ttl.
+const idempotencyPruneInterval = 10 * time.Minute
+
+// authFailureRate и authFailureBurst ограничивают неудачные попытки аутентификации
+// с одного IP: 10 подряд, дальше в среднем одна в 10 секунд.
+const (
+	authFailureRate  = 0.1
+	authFailureBurst = 10
+)
+
 func main() {
-	port := flag.String("port", "8000", "Port to run the server on")
-	flag.Parse()
+	cfg, err := config.Load(os.Args[1:], os.Getenv)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		fmt.Printf("invalid config: %v\n", err)
+		os.Exit(1)
+	}
+
+	reminderOffsets, err := parseOffsets(cfg.Reminders)
+	if err != nil {
+		fmt.Printf("invalid -reminders: %v\n", err)
+		os.Exit(1)
+	}
+
+	ctx, cancel := signal.NotifyContext(
+		context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	g, gCtx := errgroup.WithContext(ctx)
+
+	hub := pubsub.NewHub(64)
+
+	var repo metrics.Repository
+	switch cfg.Storage {
+	case "memory":
+		repo = memory.NewEventsRepository()
+	case "file":
+		fileRepo, err := file.NewEventsRepository(cfg.DataDir)
+		if err != nil {
+			fmt.Printf("failed to open storage: %v\n", err)
+			os.Exit(1)
+		}
+		defer fileRepo.Close()
+
+		g.Go(func() error { return fileRepo.Run(gCtx, cfg.CompactInterval) })
+		repo = fileRepo
+	case "sqlite":
+		sqliteRepo, err := sqlite.NewEventsRepository(cfg.DBPath)
+		if err != nil {
+			fmt.Printf("failed to open storage: %v\n", err)
+			os.Exit(1)
+		}
+		defer sqliteRepo.Close()
+
+		repo = sqliteRepo
+	default:
+		fmt.Printf("unknown storage %q\n", cfg.Storage)
+		os.Exit(1)
+	}
+
+	serverMetrics := metrics.New(repo)
+	instrumented := serverMetrics.Repository(repo)
+
+	tenants, err := tenant.NewRegistry(instrumented, models.TenantQuota{
+		MaxEvents: cfg.TenantMaxEvents,
+		RateLimit: cfg.TenantRateLimit,
+		RateBurst: cfg.TenantRateBurst,
+	}, tenantQuotas(cfg.Tenants))
+	if err != nil {
+		fmt.Printf("invalid tenants: %v\n", err)
+		os.Exit(1)
+	}
+
+	// tenantService возвращает сервис событий арендатора: его хранилище,
+	// история и уведомления не пересекаются с другими арендаторами.
+	tenantService := func(id models.TenantID) *events.Service {
+		tenantRepo := tenants.Repository(id)
+		opts := []events.Option{
+			events.WithPublisher(tenant.NewHub(hub, id)),
+			events.WithHistory(tenantRepo, cfg.HistoryRetention),
+		}
+		if cfg.RejectConflicts {
+			opts = append(opts, events.WithConflictCheck())
+		}
+		return events.New(tenantRepo, opts...)
+	}
+
+	// service работает с событиями всех арендаторов сразу и нужен только
+	// фоновым задачам: напоминаниям и очистке истории.
+	service := events.New(instrumented, events.WithHistory(instrumented, cfg.HistoryRetention))
 
-	repo := memory.NewEventsRepository()
-	service := events.New(repo)
-	eventsHandler := handler.NewEventsHandler(service)
+	reminderLogger := slog.New(slog.NewTextHandler(
+		os.Stdout, &slog.HandlerOptions{}).WithGroup("reminder"))
+
+	var notifier reminder.Notifier = reminder.NewLogNotifier(reminderLogger)
+	if cfg.ReminderWebhook != "" {
+		notifier = reminder.NewWebhookNotifier(cfg.ReminderWebhook, &http.Client{Timeout: 10 * time.Second})
+	}
+
+	scheduler := reminder.New(service, instrumented, notifier, reminderLogger, reminder.Config{
+		Offsets: reminderOffsets,
+		Grace:   cfg.ReminderGrace,
+	})
+	g.Go(func() error { return scheduler.Run(gCtx, cfg.ReminderInterval) })
+	g.Go(func() error { return service.RunHistoryPruning(gCtx, historyPruneInterval) })
+
+	authConfig, err := loadAuthConfig(cfg.JWTSecretFile, cfg.JWTPublicKey, cfg.APIKeysFile)
+	if err != nil {
+		fmt.Printf("failed to load auth config: %v\n", err)
+		os.Exit(1)
+	}
+	authConfig.Issuer = cfg.JWTIssuer
+	authConfig.Audience = cfg.JWTAudience
+
+	auth := handler.NewAuth(authConfig)
+	if !auth.Enabled() {
+		fmt.Println("warning: authentication is disabled, users are identified by user_id")
+	}
+
+	tenantHandlers := handler.NewTenants(
+		func(id models.TenantID) *handler.EventsHandler {
+			return handler.NewEventsHandler(tenantService(id))
+		},
+		func(id models.TenantID) *handler.StreamHandler {
+			return handler.NewStreamHandler(tenant.NewHub(hub, id), cfg.CORSOrigins)
+		},
+	)
+	eh, sh := tenantHandlers.Events, tenantHandlers.Streams
 
 	handlerLogger := slog.New(slog.NewTextHandler(
 		os.Stdout, &slog.HandlerOptions{}).WithGroup("handler"))
 	middleware := handler.NewMiddleware(handlerLogger)
 
+	validator, err := openapi.New()
+	if err != nil {
+		fmt.Printf("failed to load openapi document: %v\n", err)
+		os.Exit(1)
+	}
+	validation := handler.NewValidation(validator)
+
+	// limit ограничивает частоту запросов пользователя, если это включено.
+	// Тот же limiter ограничивает вызовы gRPC.
+	var limiter *ratelimit.Limiter
+	limit := func(h handler.ErrHandlerFunc) handler.ErrHandlerFunc { return h }
+	if cfg.RateLimit > 0 {
+		limiter = ratelimit.New(cfg.RateLimit, cfg.RateBurst)
+		limit = handler.NewRateLimit(limiter).Limit
+	}
+
+	// idempotent повторяет сохраненные ответы на запросы с Idempotency-Key, если это включено.
+	idempotent := func(h handler.ErrHandlerFunc) handler.ErrHandlerFunc { return h }
+	if cfg.IdempotencyTTL > 0 {
+		keys := idempotency.New(instrumented, cfg.IdempotencyTTL)
+		g.Go(func() error { return keys.Run(gCtx, idempotencyPruneInterval) })
+		idempotent = handler.NewIdempotency(keys, handlerLogger).Wrap
+	}
+
+	// authFailures ограничивает неудачные попытки аутентификации, если она включена.
+	// Тот же limiter ограничивает попытки через gRPC.
+	var failureLimiter *ratelimit.Limiter
+	authFailures := func(h handler.ErrHandlerFunc) handler.ErrHandlerFunc { return h }
+	if auth.Enabled() {
+		failureLimiter = ratelimit.New(authFailureRate, authFailureBurst)
+		authFailures = handler.NewAuthFailureLimit(failureLimiter).Limit
+	}
+
+	// tenantLimit учитывает запросы арендатора и ограничивает их частоту по его квоте.
+	tenantLimit := handler.NewTenantRateLimit(tenants).Limit
+
+	// protected - обработчик с логированием, аутентификацией (и ограничением
+	// неудачных попыток), ограничением частоты запросов и проверкой запроса
+	// по спецификации.
+	protected := func(h handler.ErrHandlerFunc) http.HandlerFunc {
+		return middleware.Logging(authFailures(auth.Authenticate(limit(tenantLimit(validation.Validate(h))))))
+	}
+
+	health := handler.NewHealth(repo)
+
 	mux := http.NewServeMux()
-	mux.HandleFunc("/create_event", middleware.Logging(eventsHandler.CreateEvent))
-	mux.HandleFunc("/update_event", middleware.Logging(eventsHandler.UpdateEvent))
-	mux.HandleFunc("/delete_event", middleware.Logging(eventsHandler.DeleteEvent))
-	mux.HandleFunc("/events_for_day", middleware.Logging(eventsHandler.EventsForDay))
-	mux.HandleFunc("/events_for_week", middleware.Logging(eventsHandler.EventsForWeek))
-	mux.HandleFunc("/events_for_month", middleware.Logging(eventsHandler.EventsForMonth))
 
-	ctx, cancel := signal.NotifyContext(
-		context.Background(), os.Interrupt, syscall.SIGTERM)
-	defer cancel()
+	// handle регистрирует обработчик с метриками запросов по шаблону маршрута.
+	handle := func(pattern string, h http.HandlerFunc) {
+		mux.Handle(pattern, serverMetrics.InstrumentHandler(pattern, h))
+	}
 
-	srv := server.New(*port, mux)
+	handle("/create_event", protected(idempotent(eh((*handler.EventsHandler).CreateEvent))))
+	handle("/update_event", protected(eh((*handler.EventsHandler).UpdateEvent)))
+	handle("/delete_event", protected(eh((*handler.EventsHandler).DeleteEvent)))
+	handle("/events_for_day", protected(eh((*handler.EventsHandler).EventsForDay)))
+	handle("/events_for_week", protected(eh((*handler.EventsHandler).EventsForWeek)))
+	handle("/events_for_month", protected(eh((*handler.EventsHandler).EventsForMonth)))
+	handle("/events_for_range", protected(eh((*handler.EventsHandler).EventsForRange)))
+	handle("/export_ics", protected(eh((*handler.EventsHandler).ExportICS)))
+	handle("/import_ics", protected(eh((*handler.EventsHandler).ImportICS)))
+
+	// protectedV2 - то же, что protected, но с моделью ошибок API v2.
+	protectedV2 := func(h handler.ErrHandlerFunc) http.HandlerFunc {
+		return middleware.LoggingV2(authFailures(auth.Authenticate(limit(tenantLimit(validation.Validate(h))))))
+	}
+
+	handle("GET /v2/users/{user}/events", protectedV2(eh((*handler.EventsHandler).ListEventsV2)))
+	handle("POST /v2/users/{user}/events", protectedV2(idempotent(eh((*handler.EventsHandler).CreateEventV2))))
+	handle("POST /v2/users/{user}/events/batch", protectedV2(idempotent(eh((*handler.EventsHandler).BatchV2))))
+	handle("GET /v2/users/{user}/events/{id}", protectedV2(eh((*handler.EventsHandler).GetEventV2)))
+	handle("PUT /v2/users/{user}/events/{id}", protectedV2(eh((*handler.EventsHandler).ReplaceEventV2)))
+	handle("PATCH /v2/users/{user}/events/{id}", protectedV2(eh((*handler.EventsHandler).PatchEventV2)))
+	handle("DELETE /v2/users/{user}/events/{id}", protectedV2(eh((*handler.EventsHandler).DeleteEventV2)))
+	handle("GET /v2/users/{user}/events/{id}/conflicts", protectedV2(eh((*handler.EventsHandler).EventConflictsV2)))
+	handle("GET /v2/users/{user}/events/{id}/attendees", protectedV2(eh((*handler.EventsHandler).AttendeesV2)))
+	handle("GET /v2/users/{user}/events/{id}/history", protectedV2(eh((*handler.EventsHandler).HistoryV2)))
+	handle("POST /v2/users/{user}/events/{id}/restore", protectedV2(eh((*handler.EventsHandler).RestoreV2)))
+	handle("GET /v2/users/{user}/search", protectedV2(eh((*handler.EventsHandler).SearchV2)))
+	handle("GET /v2/users/{user}/invitations", protectedV2(eh((*handler.EventsHandler).InvitationsV2)))
+	handle("POST /v2/users/{user}/invitations/{owner}/{id}/rsvp", protectedV2(eh((*handler.EventsHandler).RespondV2)))
+	handle("GET /v2/freebusy", protectedV2(eh((*handler.EventsHandler).FreeBusyV2)))
+
+	handle("GET /events/stream", protected(sh((*handler.StreamHandler).SSE)))
+	handle("GET /events/ws", protected(sh((*handler.StreamHandler).WebSocket)))
+
+	adminUsers := make([]models.UserID, len(cfg.AdminUsers))
+	for i, userID := range cfg.AdminUsers {
+		adminUsers[i] = models.UserID(userID)
+	}
+	admin := handler.NewAdmin(tenants, adminUsers)
+
+	// adminOnly - обработчик API администратора: доступен только -admin-users.
+	adminOnly := func(h handler.ErrHandlerFunc) http.HandlerFunc {
+		return middleware.LoggingV2(authFailures(auth.Authenticate(limit(admin.Authorize(validation.Validate(h))))))
+	}
+
+	handle("GET /admin/tenants", adminOnly(admin.Tenants))
+	handle("GET /admin/tenants/{tenant}", adminOnly(admin.Tenant))
+
+	handle("GET /openapi.json", middleware.Logging(handler.OpenAPI))
+
+	handle("/.well-known/caldav", middleware.Logging(eh((*handler.EventsHandler).CalDAVWellKnown)))
+	handle("OPTIONS /caldav/", middleware.Logging(eh((*handler.EventsHandler).CalDAVOptions)))
+	handle("PROPFIND /caldav/{$}", protected(eh((*handler.EventsHandler).PropfindRoot)))
+	handle("PROPFIND /caldav/{user}/{$}", protected(eh((*handler.EventsHandler).PropfindPrincipal)))
+	handle("PROPFIND /caldav/{user}/events/{$}", protected(eh((*handler.EventsHandler).PropfindCalendar)))
+	handle("REPORT /caldav/{user}/events/{$}", protected(eh((*handler.EventsHandler).ReportCalendar)))
+	handle("PROPFIND /caldav/{user}/events/{file}", protected(eh((*handler.EventsHandler).PropfindEvent)))
+	handle("GET /caldav/{user}/events/{file}", protected(eh((*handler.EventsHandler).GetEventICS)))
+
+	// Проверки и метрики не логируются и не попадают в метрики запросов,
+	// чтобы частые опросы не зашумляли их.
+	mux.Handle("GET /metrics", serverMetrics.Handler())
+	mux.HandleFunc("GET /healthz", health.Healthz)
+	mux.HandleFunc("GET /readyz", health.Readyz)
+
+	cors := handler.NewCORS(handler.CORSConfig{
+		AllowedOrigins: cfg.CORSOrigins,
+		AllowedMethods: cfg.CORSMethods,
+		AllowedHeaders: cfg.CORSHeaders,
+		MaxAge:         cfg.CORSMaxAge,
+	})
+
+	srv, err := server.New(server.Config{
+		Port:              cfg.Port,
+		ReadTimeout:       cfg.ReadTimeout,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		TLS: server.TLSConfig{
+			CertFile:     cfg.TLSCert,
+			KeyFile:      cfg.TLSKey,
+			ClientCAFile: cfg.TLSClientCA,
+		},
+		Logger: slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{}).WithGroup("server")),
+	}, cors.Handler(handler.MaxBodySize(cfg.MaxBodySize, mux)))
+	if err != nil {
+		fmt.Printf("failed to load tls certificates: %v\n", err)
+		os.Exit(1)
+	}
 
-	g, gCtx := errgroup.WithContext(ctx)
 	g.Go(func() error { return srv.Run() })
+
+	if cfg.TLSCert != "" {
+		// SIGHUP перечитывает сертификаты TLS без остановки сервера.
+		hup := make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
+		defer signal.Stop(hup)
+
+		g.Go(func() error { return srv.WatchTLS(gCtx, cfg.TLSReloadInterval, hup) })
+	}
+
+	var grpcServer *grpc.Server
+	if cfg.GRPCPort != "" {
+		grpcLogger := slog.New(slog.NewTextHandler(
+			os.Stdout, &slog.HandlerOptions{}).WithGroup("grpc"))
+
+		interceptorOpts := []grpcapi.Option{grpcapi.WithTenantRateLimit(tenants)}
+		if limiter != nil {
+			interceptorOpts = append(interceptorOpts, grpcapi.WithRateLimit(limiter))
+		}
+		if failureLimiter != nil {
+			interceptorOpts = append(interceptorOpts, grpcapi.WithAuthFailureLimit(failureLimiter))
+		}
+
+		grpcOpts := grpcapi.NewInterceptors(grpcLogger, auth, interceptorOpts...).ServerOptions()
+		if tlsConfig := srv.TLSConfig(); tlsConfig != nil {
+			grpcOpts = append(grpcOpts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+		}
+		grpcServer = grpc.NewServer(grpcOpts...)
+		grpcapi.NewTenants(func(id models.TenantID) *grpcapi.Server {
+			return grpcapi.NewServer(tenantService(id), tenant.NewHub(hub, id))
+		}).Register(grpcServer)
+
+		lis, err := net.Listen("tcp", ":"+cfg.GRPCPort)
+		if err != nil {
+			fmt.Printf("failed to listen for grpc: %v\n", err)
+			os.Exit(1)
+		}
+		g.Go(func() error { return grpcServer.Serve(lis) })
+	}
+
 	g.Go(func() error {
 		<-gCtx.Done()
+		health.SetShuttingDown()
+		time.Sleep(cfg.ShutdownDelay)
+		// Закрываем потоки изменений, иначе Shutdown и GracefulStop
+		// будут ждать их бесконечно.
+		hub.Close()
+		if grpcServer != nil {
+			grpcServer.GracefulStop()
+		}
 		return srv.Shutdown(context.Background())
 	})
 
-	fmt.Printf("server started on port %s\n", *port)
+	scheme := "http"
+	if cfg.TLSCert != "" {
+		scheme = "https"
+	}
+	fmt.Printf("server started on port %s (%s)\n", cfg.Port, scheme)
+	if cfg.GRPCPort != "" {
+		fmt.Printf("grpc server started on port %s\n", cfg.GRPCPort)
+	}
 
 	if err := g.Wait(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		fmt.Printf("exit with %v\n", err)
@@ -59,3 +386,98 @@ func main() {
 
 	fmt.Println("server was shut down")
 }
+
+// parseOffsets разбирает список длительностей через запятую.
+func parseOffsets(s string) ([]time.Duration, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	var offsets []time.Duration
+	for _, part := range strings.Split(s, ",") {
+		d, err := time.ParseDuration(strings.TrimSpace(part))
+		if err != nil {
+			return nil, err
+		}
+		if d < 0 {
+			return nil, fmt.Errorf("negative offset %s", d)
+		}
+		offsets = append(offsets, d)
+	}
+
+	return offsets, nil
+}
+
+// loadAuthConfig читает ключи аутентификации из файлов. Пустой путь пропускается.
+func loadAuthConfig(secretFile, publicKeyFile, apiKeysFile string) (handler.AuthConfig, error) {
+	var cfg handler.AuthConfig
+
+	if secretFile != "" {
+		secret, err := os.ReadFile(secretFile)
+		if err != nil {
+			return cfg, err
+		}
+		cfg.HMACSecret = bytes.TrimSpace(secret)
+		if len(cfg.HMACSecret) == 0 {
+			return cfg, fmt.Errorf("empty secret in %s", secretFile)
+		}
+	}
+
+	if publicKeyFile != "" {
+		data, err := os.ReadFile(publicKeyFile)
+		if err != nil {
+			return cfg, err
+		}
+		if cfg.RSAPublicKey, err = jwt.ParseRSAPublicKeyFromPEM(data); err != nil {
+			return cfg, fmt.Errorf("%s: %w", publicKeyFile, err)
+		}
+	}
+
+	if apiKeysFile != "" {
+		data, err := os.ReadFile(apiKeysFile)
+		if err != nil {
+			return cfg, err
+		}
+
+		cfg.APIKeys = make(map[string]models.UserID)
+		for i, line := range strings.Split(string(data), "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+
+			fields := strings.Fields(line)
+			if len(fields) != 2 && len(fields) != 3 {
+				return cfg, fmt.Errorf("%s:%d: expected \"user_id key [tenant]\"", apiKeysFile, i+1)
+			}
+			cfg.APIKeys[fields[1]] = models.UserID(fields[0])
+
+			if len(fields) == 3 {
+				id := models.TenantID(fields[2])
+				if !tenant.Valid(id) {
+					return cfg, fmt.Errorf("%s:%d: invalid tenant %q", apiKeysFile, i+1, id)
+				}
+				if cfg.APIKeyTenants == nil {
+					cfg.APIKeyTenants = make(map[string]models.TenantID)
+				}
+				cfg.APIKeyTenants[fields[1]] = id
+			}
+		}
+	}
+
+	return cfg, nil
+}
+
+// tenantQuotas переводит квоты арендаторов из настроек в models.TenantQuota.
+func tenantQuotas(quotas map[string]config.TenantQuota) map[models.TenantID]models.TenantQuota {
+	result := make(map[models.TenantID]models.TenantQuota, len(quotas))
+	for id, q := range quotas {
+		result[models.TenantID(id)] = models.TenantQuota{
+			MaxEvents: q.MaxEvents,
+			RateLimit: q.RateLimit,
+			RateBurst: q.RateBurst,
+		}
+	}
+
+	return result
+}
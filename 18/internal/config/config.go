@@ -0,0 +1,329 @@
+// Package config собирает настройки сервера календаря из файла YAML,
+// переменных окружения и флагов командной строки.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix - префикс переменных окружения: флаг -db-path задается
+// переменной CALENDAR_DB_PATH.
+const EnvPrefix = "CALENDAR_"
+
+// Config - настройки сервера. Ключи YAML совпадают с именами флагов.
+type Config struct {
+	Port    string `yaml:"port"`
+	Storage string `yaml:"storage"`
+	DataDir string `yaml:"data-dir"`
+	DBPath  string `yaml:"db-path"`
+
+	// GRPCPort - порт gRPC API, пустой отключает его.
+	GRPCPort string `yaml:"grpc-port"`
+
+	CompactInterval time.Duration `yaml:"compact-interval"`
+
+	// Reminders - смещения до начала события через запятую, например 1h,15m.
+	Reminders        string        `yaml:"reminders"`
+	ReminderInterval time.Duration `yaml:"reminder-interval"`
+	ReminderGrace    time.Duration `yaml:"reminder-grace"`
+	ReminderWebhook  string        `yaml:"reminder-webhook"`
+
+	JWTSecretFile string `yaml:"jwt-secret-file"`
+	JWTPublicKey  string `yaml:"jwt-public-key"`
+	JWTIssuer     string `yaml:"jwt-issuer"`
+	JWTAudience   string `yaml:"jwt-audience"`
+	APIKeysFile   string `yaml:"api-keys-file"`
+
+	RejectConflicts  bool          `yaml:"reject-conflicts"`
+	HistoryRetention time.Duration `yaml:"history-retention"`
+	ShutdownDelay    time.Duration `yaml:"shutdown-delay"`
+
+	// IdempotencyTTL - сколько хранятся ответы на запросы с Idempotency-Key, 0 - ключи не поддерживаются.
+	IdempotencyTTL time.Duration `yaml:"idempotency-ttl"`
+
+	ReadTimeout       time.Duration `yaml:"read-timeout"`
+	ReadHeaderTimeout time.Duration `yaml:"read-header-timeout"`
+	WriteTimeout      time.Duration `yaml:"write-timeout"`
+	IdleTimeout       time.Duration `yaml:"idle-timeout"`
+	MaxBodySize       int64         `yaml:"max-body-size"`
+
+	// RateLimit - сколько запросов в секунду в среднем разрешено одному
+	// пользователю (или IP, если пользователь неизвестен), 0 - без ограничения.
+	RateLimit float64 `yaml:"rate-limit"`
+	RateBurst int     `yaml:"rate-burst"`
+
+	// TenantMaxEvents, TenantRateLimit и TenantRateBurst - квоты арендатора
+	// по умолчанию на всех его пользователей вместе, 0 - без ограничения.
+	TenantMaxEvents int     `yaml:"tenant-max-events"`
+	TenantRateLimit float64 `yaml:"tenant-rate-limit"`
+	TenantRateBurst int     `yaml:"tenant-rate-burst"`
+	// Tenants - квоты отдельных арендаторов, заменяющие квоты по умолчанию.
+	// Задаются только в файле.
+	Tenants map[string]TenantQuota `yaml:"tenants"`
+	// AdminUsers - пользователи арендатора по умолчанию, которым доступен /admin.
+	AdminUsers []string `yaml:"admin-users"`
+
+	CORSOrigins []string      `yaml:"cors-origins"`
+	CORSMethods []string      `yaml:"cors-methods"`
+	CORSHeaders []string      `yaml:"cors-headers"`
+	CORSMaxAge  time.Duration `yaml:"cors-max-age"`
+
+	// TLSCert и TLSKey включают HTTPS, TLSClientCA - проверку сертификатов клиентов.
+	TLSCert           string        `yaml:"tls-cert"`
+	TLSKey            string        `yaml:"tls-key"`
+	TLSClientCA       string        `yaml:"tls-client-ca"`
+	TLSReloadInterval time.Duration `yaml:"tls-reload-interval"`
+}
+
+// TenantQuota - квоты арендатора. Незаданное поле - без ограничения.
+type TenantQuota struct {
+	MaxEvents int     `yaml:"max-events"`
+	RateLimit float64 `yaml:"rate-limit"`
+	RateBurst int     `yaml:"rate-burst"`
+}
+
+func (q TenantQuota) validate() error {
+	switch {
+	case q.MaxEvents < 0:
+		return errors.New("max-events must not be negative")
+	case q.RateLimit < 0:
+		return errors.New("rate-limit must not be negative")
+	case q.RateLimit > 0 && q.RateBurst < 1:
+		return errors.New("rate-burst must be positive")
+	}
+
+	return nil
+}
+
+// Default возвращает настройки по умолчанию.
+func Default() Config {
+	return Config{
+		Port:              "8000",
+		GRPCPort:          "50051",
+		Storage:           "memory",
+		DataDir:           "data",
+		DBPath:            "calendar.db",
+		CompactInterval:   10 * time.Minute,
+		ReminderInterval:  30 * time.Second,
+		ReminderGrace:     15 * time.Minute,
+		HistoryRetention:  30 * 24 * time.Hour,
+		IdempotencyTTL:    24 * time.Hour,
+		ReadTimeout:       30 * time.Second,
+		ReadHeaderTimeout: 10 * time.Second,
+		WriteTimeout:      60 * time.Second,
+		IdleTimeout:       2 * time.Minute,
+		MaxBodySize:       10 << 20,
+		RateBurst:         20,
+		TenantRateBurst:   100,
+		CORSMethods:       []string{"GET", "POST", "PUT", "PATCH", "DELETE"},
+		CORSHeaders:       []string{"Authorization", "X-API-Key", "X-Tenant-ID", "Content-Type", "If-Match", "If-None-Match", "Idempotency-Key"},
+		CORSMaxAge:        10 * time.Minute,
+		TLSReloadInterval: time.Minute,
+	}
+}
+
+// Load собирает настройки по аргументам командной строки args (без имени программы).
+// Каждый следующий источник переопределяет предыдущий: значения по умолчанию,
+// файл YAML из -config (или CALENDAR_CONFIG), переменные окружения, флаги.
+func Load(args []string, getenv func(string) string) (Config, error) {
+	// Сначала флаги разбираются только ради пути к файлу: остальные
+	// значения из них должны применяться последними.
+	probe := Default()
+	var path string
+	fs := newFlagSet(&probe, &path)
+	if err := fs.Parse(args); err != nil {
+		return Config{}, err
+	}
+	if path == "" {
+		path = getenv(EnvPrefix + "CONFIG")
+	}
+
+	cfg := Default()
+	if path != "" {
+		if err := readFile(path, &cfg); err != nil {
+			return Config{}, err
+		}
+	}
+
+	fs = newFlagSet(&cfg, &path)
+
+	var errs []error
+	fs.VisitAll(func(f *flag.Flag) {
+		name := envName(f.Name)
+		if v := getenv(name); v != "" && f.Name != "config" {
+			if err := f.Value.Set(v); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			}
+		}
+	})
+	if err := errors.Join(errs...); err != nil {
+		return Config{}, err
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return Config{}, err
+	}
+
+	return cfg, cfg.validate()
+}
+
+// newFlagSet создает флаги, записывающие значения в cfg. Значение флага
+// по умолчанию - текущее значение поля.
+func newFlagSet(cfg *Config, path *string) *flag.FlagSet {
+	fs := flag.NewFlagSet("calendar", flag.ContinueOnError)
+
+	fs.StringVar(path, "config", *path, "YAML file with settings; keys are the flag names")
+
+	fs.StringVar(&cfg.Port, "port", cfg.Port, "Port to run the server on")
+	fs.StringVar(&cfg.GRPCPort, "grpc-port", cfg.GRPCPort, "Port to run the gRPC API on (empty disables gRPC)")
+	fs.StringVar(&cfg.Storage, "storage", cfg.Storage, "Events storage: memory, file or sqlite")
+	fs.StringVar(&cfg.DataDir, "data-dir", cfg.DataDir, "Directory for the file storage")
+	fs.StringVar(&cfg.DBPath, "db-path", cfg.DBPath, "Database file for the sqlite storage")
+	fs.DurationVar(&cfg.CompactInterval, "compact-interval", cfg.CompactInterval,
+		"How often the file storage compacts its log into a snapshot")
+
+	fs.StringVar(&cfg.Reminders, "reminders", cfg.Reminders,
+		"Comma-separated offsets before an event to send reminders at, e.g. 1h,15m (empty disables reminders)")
+	fs.DurationVar(&cfg.ReminderInterval, "reminder-interval", cfg.ReminderInterval,
+		"How often upcoming events are scanned for reminders")
+	fs.DurationVar(&cfg.ReminderGrace, "reminder-grace", cfg.ReminderGrace, "How late a reminder may still be sent")
+	fs.StringVar(&cfg.ReminderWebhook, "reminder-webhook", cfg.ReminderWebhook,
+		"URL to POST reminders to (reminders are logged if empty)")
+
+	fs.StringVar(&cfg.JWTSecretFile, "jwt-secret-file", cfg.JWTSecretFile, "File with the HS256 secret for bearer tokens")
+	fs.StringVar(&cfg.JWTPublicKey, "jwt-public-key", cfg.JWTPublicKey, "PEM file with the RS256 public key for bearer tokens")
+	fs.StringVar(&cfg.JWTIssuer, "jwt-issuer", cfg.JWTIssuer, "Required iss claim of bearer tokens")
+	fs.StringVar(&cfg.JWTAudience, "jwt-audience", cfg.JWTAudience, "Required aud claim of bearer tokens")
+	fs.StringVar(&cfg.APIKeysFile, "api-keys-file", cfg.APIKeysFile,
+		"File with static API keys, one \"user_id key\" pair per line")
+
+	fs.BoolVar(&cfg.RejectConflicts, "reject-conflicts", cfg.RejectConflicts,
+		"Reject events that overlap other events of the user with 409")
+	fs.DurationVar(&cfg.HistoryRetention, "history-retention", cfg.HistoryRetention,
+		"How long event change history is kept and events can be restored (0 keeps it forever)")
+	fs.DurationVar(&cfg.IdempotencyTTL, "idempotency-ttl", cfg.IdempotencyTTL,
+		"How long responses to requests with an Idempotency-Key are replayed (0 disables idempotency keys)")
+	fs.DurationVar(&cfg.ShutdownDelay, "shutdown-delay", cfg.ShutdownDelay,
+		"How long /readyz reports shutdown before the server stops accepting connections")
+
+	fs.DurationVar(&cfg.ReadTimeout, "read-timeout", cfg.ReadTimeout,
+		"Maximum time to read a whole request including the body (0 disables)")
+	fs.DurationVar(&cfg.ReadHeaderTimeout, "read-header-timeout", cfg.ReadHeaderTimeout,
+		"Maximum time to read request headers (0 disables)")
+	fs.DurationVar(&cfg.WriteTimeout, "write-timeout", cfg.WriteTimeout,
+		"Maximum time to write a response; event streams extend it on every write (0 disables)")
+	fs.DurationVar(&cfg.IdleTimeout, "idle-timeout", cfg.IdleTimeout,
+		"How long an idle keep-alive connection stays open (0 disables)")
+	fs.Int64Var(&cfg.MaxBodySize, "max-body-size", cfg.MaxBodySize, "Maximum request body size in bytes (0 disables)")
+
+	fs.Float64Var(&cfg.RateLimit, "rate-limit", cfg.RateLimit,
+		"Average requests per second allowed for a user or IP address (0 disables rate limiting)")
+	fs.IntVar(&cfg.RateBurst, "rate-burst", cfg.RateBurst, "Requests a user or IP address may make at once")
+
+	fs.IntVar(&cfg.TenantMaxEvents, "tenant-max-events", cfg.TenantMaxEvents,
+		"Events all users of a tenant may store together (0 disables the quota)")
+	fs.Float64Var(&cfg.TenantRateLimit, "tenant-rate-limit", cfg.TenantRateLimit,
+		"Average requests per second allowed for all users of a tenant together (0 disables the quota)")
+	fs.IntVar(&cfg.TenantRateBurst, "tenant-rate-burst", cfg.TenantRateBurst,
+		"Requests all users of a tenant may make at once")
+	fs.Var((*listValue)(&cfg.AdminUsers), "admin-users",
+		"Comma-separated users of the default tenant allowed to call the admin API")
+
+	fs.Var((*listValue)(&cfg.CORSOrigins), "cors-origins",
+		"Comma-separated origins allowed to call the API from a browser, * for any (empty disables CORS)")
+	fs.Var((*listValue)(&cfg.CORSMethods), "cors-methods", "Comma-separated methods allowed in CORS requests")
+	fs.Var((*listValue)(&cfg.CORSHeaders), "cors-headers", "Comma-separated request headers allowed in CORS requests")
+	fs.DurationVar(&cfg.CORSMaxAge, "cors-max-age", cfg.CORSMaxAge, "How long browsers may cache CORS preflight responses")
+
+	fs.StringVar(&cfg.TLSCert, "tls-cert", cfg.TLSCert, "PEM file with the server certificate chain (enables HTTPS and HTTP/2)")
+	fs.StringVar(&cfg.TLSKey, "tls-key", cfg.TLSKey, "PEM file with the server private key")
+	fs.StringVar(&cfg.TLSClientCA, "tls-client-ca", cfg.TLSClientCA,
+		"PEM file with CA certificates; clients must present a certificate signed by one of them")
+	fs.DurationVar(&cfg.TLSReloadInterval, "tls-reload-interval", cfg.TLSReloadInterval,
+		"How often certificate files are checked for changes (0 reloads only on SIGHUP)")
+
+	return fs
+}
+
+func (c Config) validate() error {
+	for id, q := range c.Tenants {
+		if err := q.validate(); err != nil {
+			return fmt.Errorf("tenants: %s: %w", id, err)
+		}
+	}
+
+	switch {
+	case c.MaxBodySize < 0:
+		return errors.New("max-body-size must not be negative")
+	case c.IdempotencyTTL < 0:
+		return errors.New("idempotency-ttl must not be negative")
+	case c.RateLimit < 0:
+		return errors.New("rate-limit must not be negative")
+	case c.RateLimit > 0 && c.RateBurst < 1:
+		return errors.New("rate-burst must be positive")
+	case c.TenantMaxEvents < 0:
+		return errors.New("tenant-max-events must not be negative")
+	case c.TenantRateLimit < 0:
+		return errors.New("tenant-rate-limit must not be negative")
+	case c.TenantRateLimit > 0 && c.TenantRateBurst < 1:
+		return errors.New("tenant-rate-burst must be positive")
+	case (c.TLSCert == "") != (c.TLSKey == ""):
+		return errors.New("tls-cert and tls-key must be set together")
+	case c.TLSClientCA != "" && c.TLSCert == "":
+		return errors.New("tls-client-ca requires tls-cert")
+	case c.GRPCPort != "" && c.GRPCPort == c.Port:
+		return errors.New("grpc-port must differ from port")
+	}
+
+	return nil
+}
+
+// readFile читает настройки из файла YAML поверх cfg. Неизвестные ключи - ошибка.
+func readFile(path string, cfg *Config) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	return nil
+}
+
+// envName возвращает имя переменной окружения для флага name.
+func envName(name string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// listValue - флаг со списком значений через запятую.
+type listValue []string
+
+func (l *listValue) String() string {
+	if l == nil {
+		return ""
+	}
+	return strings.Join(*l, ",")
+}
+
+func (l *listValue) Set(s string) error {
+	*l = nil
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			*l = append(*l, part)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func env(vars map[string]string) func(string) string {
+	return func(name string) string { return vars[name] }
+}
+
+func writeConfig(t *testing.T, data string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "calendar.yaml")
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadDefaults(t *testing.T) {
+	cfg, err := Load(nil, env(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(cfg, Default()) {
+		t.Errorf("got %+v, want defaults", cfg)
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeConfig(t, `
+port: "9000"
+storage: sqlite
+db-path: file.db
+write-timeout: 5s
+rate-limit: 2.5
+cors-origins: [https://a.example, https://b.example]
+`)
+
+	cfg, err := Load([]string{"-config", path, "-port", "9002"}, env(map[string]string{
+		"CALENDAR_PORT":         "9001",
+		"CALENDAR_DB_PATH":      "env.db",
+		"CALENDAR_CORS_METHODS": "GET, POST",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Port != "9002" {
+		t.Errorf("port: got %q, want flag value", cfg.Port)
+	}
+	if cfg.DBPath != "env.db" {
+		t.Errorf("db-path: got %q, want env value", cfg.DBPath)
+	}
+	if cfg.Storage != "sqlite" || cfg.WriteTimeout != 5*time.Second || cfg.RateLimit != 2.5 {
+		t.Errorf("file values not applied: %+v", cfg)
+	}
+	if want := []string{"https://a.example", "https://b.example"}; !reflect.DeepEqual(cfg.CORSOrigins, want) {
+		t.Errorf("cors-origins: got %q, want %q", cfg.CORSOrigins, want)
+	}
+	if want := []string{"GET", "POST"}; !reflect.DeepEqual(cfg.CORSMethods, want) {
+		t.Errorf("cors-methods: got %q, want %q", cfg.CORSMethods, want)
+	}
+	if cfg.ReadTimeout != Default().ReadTimeout {
+		t.Errorf("read-timeout: got %s, want default", cfg.ReadTimeout)
+	}
+}
+
+func TestLoadTenants(t *testing.T) {
+	path := writeConfig(t, `
+tenant-max-events: 1000
+admin-users: [root]
+tenants:
+  acme:
+    max-events: 50000
+    rate-limit: 100
+    rate-burst: 200
+`)
+
+	cfg, err := Load([]string{"-config", path, "-admin-users", "root,ops"}, env(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.TenantMaxEvents != 1000 || cfg.TenantRateBurst != Default().TenantRateBurst {
+		t.Errorf("tenant quota not applied: %+v", cfg)
+	}
+	if want := (TenantQuota{MaxEvents: 50000, RateLimit: 100, RateBurst: 200}); cfg.Tenants["acme"] != want {
+		t.Errorf("tenants: got %+v, want %+v", cfg.Tenants["acme"], want)
+	}
+	if want := []string{"root", "ops"}; !reflect.DeepEqual(cfg.AdminUsers, want) {
+		t.Errorf("admin-users: got %q, want %q", cfg.AdminUsers, want)
+	}
+}
+
+func TestLoadConfigFromEnv(t *testing.T) {
+	path := writeConfig(t, "storage: file\n")
+
+	cfg, err := Load(nil, env(map[string]string{"CALENDAR_CONFIG": path}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Storage != "file" {
+		t.Errorf("got storage %q, want file", cfg.Storage)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		env  map[string]string
+	}{
+		{name: "unknown key", args: []string{"-config", writeConfig(t, "prot: 1\n")}},
+		{name: "missing file", args: []string{"-config", filepath.Join(t.TempDir(), "none.yaml")}},
+		{name: "invalid env", env: map[string]string{"CALENDAR_READ_TIMEOUT": "soon"}},
+		{name: "invalid flag", args: []string{"-rate-burst", "many"}},
+		{name: "negative body size", args: []string{"-max-body-size", "-1"}},
+		{name: "negative idempotency ttl", args: []string{"-idempotency-ttl", "-1h"}},
+		{name: "zero burst", args: []string{"-rate-limit", "1", "-rate-burst", "0"}},
+		{name: "negative tenant max events", args: []string{"-tenant-max-events", "-1"}},
+		{name: "zero tenant burst", args: []string{"-tenant-rate-limit", "1", "-tenant-rate-burst", "0"}},
+		{name: "invalid tenant quota", args: []string{"-config", writeConfig(t, "tenants:\n  acme:\n    rate-limit: 5\n")}},
+		{name: "cert without key", args: []string{"-tls-cert", "cert.pem"}},
+		{name: "client ca without cert", args: []string{"-tls-client-ca", "ca.pem"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Load(tt.args, env(tt.env)); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
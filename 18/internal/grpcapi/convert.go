@@ -0,0 +1,159 @@
+package grpcapi
+
+import (
+	"fmt"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"l2.18/pkg/calendarpb"
+	"l2.18/pkg/models"
+)
+
+var changeTypes = map[models.ChangeType]calendarpb.EventChange_Type{
+	models.ChangeCreated: calendarpb.EventChange_TYPE_CREATED,
+	models.ChangeUpdated: calendarpb.EventChange_TYPE_UPDATED,
+	models.ChangeDeleted: calendarpb.EventChange_TYPE_DELETED,
+}
+
+func timestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+// toProto переводит событие в сообщение API.
+func toProto(event models.Event) *calendarpb.Event {
+	pb := &calendarpb.Event{
+		Id:          string(event.ID),
+		Start:       timestamp(event.Date),
+		End:         timestamp(event.End),
+		TimeZone:    event.TimeZone,
+		Title:       event.Event,
+		Location:    event.Place,
+		Description: event.Description,
+		Category:    event.Category,
+		Color:       event.Color,
+		Tags:        event.Tags,
+		Attributes:  event.Attributes,
+		Rrule:       event.RRule,
+		Owner:       string(event.Owner),
+		Version:     event.Version,
+	}
+	for _, d := range event.ExDates {
+		pb.Exdates = append(pb.Exdates, timestamppb.New(d))
+	}
+	for _, o := range event.Overrides {
+		pb.Overrides = append(pb.Overrides, toProto(o))
+	}
+	if event.RecurrenceID != nil {
+		pb.RecurrenceId = timestamppb.New(*event.RecurrenceID)
+	}
+	for _, a := range event.Attendees {
+		pb.Attendees = append(pb.Attendees, &calendarpb.Attendee{UserId: string(a.UserID), Status: string(a.Status)})
+	}
+
+	return pb
+}
+
+// fromProto собирает событие из сообщения API. Время переводится
+// в часовой пояс события, как в HTTP API.
+func fromProto(pb *calendarpb.Event) (models.Event, error) {
+	if pb == nil {
+		return models.Event{}, fmt.Errorf("%w: event required", errInvalidArgument)
+	}
+	if pb.GetStart() == nil {
+		return models.Event{}, fmt.Errorf("%w: start required", errInvalidArgument)
+	}
+
+	loc := time.UTC
+	if pb.GetTimeZone() != "" {
+		var err error
+		if loc, err = time.LoadLocation(pb.GetTimeZone()); err != nil {
+			return models.Event{}, fmt.Errorf("%w: %v", errInvalidArgument, err)
+		}
+	}
+
+	event, err := fromProtoIn(pb, loc)
+	if err != nil {
+		return models.Event{}, err
+	}
+	for _, o := range pb.GetOverrides() {
+		override, err := fromProtoIn(o, loc)
+		if err != nil {
+			return models.Event{}, err
+		}
+		event.Overrides = append(event.Overrides, override)
+	}
+
+	return event, nil
+}
+
+func fromProtoIn(pb *calendarpb.Event, loc *time.Location) (models.Event, error) {
+	event := models.Event{
+		ID:          models.EventID(pb.GetId()),
+		TimeZone:    pb.GetTimeZone(),
+		Event:       pb.GetTitle(),
+		Place:       pb.GetLocation(),
+		Description: pb.GetDescription(),
+		Category:    pb.GetCategory(),
+		Color:       pb.GetColor(),
+		Tags:        pb.GetTags(),
+		Attributes:  pb.GetAttributes(),
+		RRule:       pb.GetRrule(),
+		Version:     pb.GetVersion(),
+	}
+
+	var err error
+	if event.Date, err = timeIn(pb.GetStart(), loc); err != nil {
+		return models.Event{}, err
+	}
+	if event.End, err = timeIn(pb.GetEnd(), loc); err != nil {
+		return models.Event{}, err
+	}
+	for _, d := range pb.GetExdates() {
+		t, err := timeIn(d, loc)
+		if err != nil {
+			return models.Event{}, err
+		}
+		event.ExDates = append(event.ExDates, t)
+	}
+	if pb.GetRecurrenceId() != nil {
+		t, err := timeIn(pb.GetRecurrenceId(), loc)
+		if err != nil {
+			return models.Event{}, err
+		}
+		event.RecurrenceID = &t
+	}
+	for _, a := range pb.GetAttendees() {
+		event.Attendees = append(event.Attendees, models.Attendee{
+			UserID: models.UserID(a.GetUserId()),
+			Status: models.RSVPStatus(a.GetStatus()),
+		})
+	}
+
+	return event, nil
+}
+
+// timeIn переводит ts в loc. Незаданное время - нулевое.
+func timeIn(ts *timestamppb.Timestamp, loc *time.Location) (time.Time, error) {
+	if ts == nil {
+		return time.Time{}, nil
+	}
+	if err := ts.CheckValid(); err != nil {
+		return time.Time{}, fmt.Errorf("%w: %v", errInvalidArgument, err)
+	}
+	return ts.AsTime().In(loc), nil
+}
+
+func changeToProto(change models.Change) *calendarpb.EventChange {
+	pb := &calendarpb.EventChange{
+		Type:    changeTypes[change.Type],
+		EventId: string(change.EventID),
+		At:      timestamp(change.At),
+	}
+	if change.Event != nil {
+		pb.Event = toProto(*change.Event)
+	}
+	return pb
+}
@@ -0,0 +1,49 @@
+package grpcapi
+
+import (
+	"errors"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"l2.18/internal/pubsub"
+	"l2.18/internal/service"
+)
+
+// errInvalidArgument возвращается, если запрос не прошел проверку.
+var errInvalidArgument = errors.New("invalid argument")
+
+// errPermissionDenied возвращается, если пользователь обращается к чужим событиям.
+var errPermissionDenied = errors.New("permission denied")
+
+// toStatus переводит ошибку сервиса в статус gRPC. Как и в HTTP API,
+// текст внутренних ошибок клиенту не передается.
+func toStatus(err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	var code codes.Code
+
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		code = codes.NotFound
+	case errors.Is(err, service.ErrAlreadyExist):
+		code = codes.AlreadyExists
+	case errors.Is(err, service.ErrConflict):
+		code = codes.FailedPrecondition
+	case errors.Is(err, service.ErrVersionMismatch):
+		code = codes.Aborted
+	case errors.Is(err, service.ErrInvalidEvent), errors.Is(err, errInvalidArgument):
+		code = codes.InvalidArgument
+	case errors.Is(err, errPermissionDenied):
+		code = codes.PermissionDenied
+	case errors.Is(err, service.ErrQuotaExceeded):
+		code = codes.ResourceExhausted
+	case errors.Is(err, pubsub.ErrSlowConsumer), errors.Is(err, pubsub.ErrClosed):
+		code = codes.Unavailable
+	default:
+		return status.Error(codes.Internal, "internal error")
+	}
+
+	return status.Error(code, err.Error())
+}
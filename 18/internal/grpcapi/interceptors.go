@@ -0,0 +1,275 @@
+package grpcapi
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"l2.18/internal/tenant"
+	"l2.18/pkg/models"
+)
+
+type logger interface {
+	Info(msg string, args ...any)
+	Error(msg string, args ...any)
+}
+
+type authenticator interface {
+	Enabled() bool
+	Identify(apiKey, authorization string) (models.UserID, models.TenantID, error)
+}
+
+type rateLimiter interface {
+	Allow(key string) (bool, time.Duration)
+}
+
+type failureLimiter interface {
+	rateLimiter
+	Peek(key string) (bool, time.Duration)
+}
+
+// Interceptors логирует вызовы, проверяет учетные данные, ограничивает частоту
+// вызовов и переводит ошибки в статусы gRPC.
+type Interceptors struct {
+	log  logger
+	auth authenticator
+	// users и tenants ограничивают частоту вызовов пользователя и арендатора
+	// (WithRateLimit, WithTenantRateLimit). nil - без ограничения.
+	users   rateLimiter
+	tenants rateLimiter
+	// failures ограничивает неудачные попытки аутентификации (WithAuthFailureLimit).
+	failures failureLimiter
+}
+
+// Option настраивает Interceptors.
+type Option func(*Interceptors)
+
+// WithRateLimit ограничивает частоту вызовов каждого пользователя limiter.
+// Ключи те же, что у RateLimit в HTTP API, поэтому с общим limiter вызовы
+// gRPC и запросы HTTP расходуют одну квоту.
+func WithRateLimit(limiter rateLimiter) Option {
+	return func(i *Interceptors) {
+		i.users = limiter
+	}
+}
+
+// WithTenantRateLimit ограничивает частоту вызовов всех пользователей
+// арендатора вместе. Ключ limiter - ID арендатора, как у TenantRateLimit в HTTP API.
+func WithTenantRateLimit(limiter rateLimiter) Option {
+	return func(i *Interceptors) {
+		i.tenants = limiter
+	}
+}
+
+// WithAuthFailureLimit ограничивает неудачные попытки аутентификации с каждого IP,
+// как AuthFailureLimit в HTTP API: каждая расходует токен limiter, а после
+// исчерпания токенов вызовы с этого IP получают ResourceExhausted.
+func WithAuthFailureLimit(limiter failureLimiter) Option {
+	return func(i *Interceptors) {
+		i.failures = limiter
+	}
+}
+
+// NewInterceptors создает новый Interceptors.
+func NewInterceptors(log logger, auth authenticator, opts ...Option) *Interceptors {
+	i := &Interceptors{log: log, auth: auth}
+	for _, opt := range opts {
+		opt(i)
+	}
+
+	return i
+}
+
+// ServerOptions возвращает опции grpc.Server с перехватчиками.
+func (i *Interceptors) ServerOptions() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.UnaryInterceptor(i.unary),
+		grpc.StreamInterceptor(i.stream),
+	}
+}
+
+func (i *Interceptors) unary(ctx context.Context, req any, info *grpc.UnaryServerInfo, h grpc.UnaryHandler) (any, error) {
+	i.log.Info("new request", "method", info.FullMethod)
+
+	ctx, err := i.authenticate(ctx)
+	if err != nil {
+		return nil, i.fail(info.FullMethod, err)
+	}
+	if err := i.limit(ctx); err != nil {
+		return nil, i.fail(info.FullMethod, err)
+	}
+
+	resp, err := h(ctx, req)
+	if err != nil {
+		return nil, i.fail(info.FullMethod, err)
+	}
+	return resp, nil
+}
+
+func (i *Interceptors) stream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, h grpc.StreamHandler) error {
+	i.log.Info("new request", "method", info.FullMethod)
+
+	ctx, err := i.authenticate(ss.Context())
+	if err != nil {
+		return i.fail(info.FullMethod, err)
+	}
+	if err := i.limit(ctx); err != nil {
+		return i.fail(info.FullMethod, err)
+	}
+
+	if err := h(srv, &serverStream{ServerStream: ss, ctx: ctx}); err != nil {
+		return i.fail(info.FullMethod, err)
+	}
+	return nil
+}
+
+// fail логирует ошибку вызова и возвращает ее статус.
+func (i *Interceptors) fail(method string, err error) error {
+	st := toStatus(err)
+
+	i.log.Error("request failed",
+		"method", method,
+		"error", err.Error(),
+		"code", status.Code(st).String())
+
+	return st
+}
+
+// authenticate сохраняет в контексте пользователя из метаданных authorization
+// или x-api-key и его арендатора. Без включенной аутентификации арендатора
+// задают метаданные x-tenant-id, а с ней они должны совпадать с учетными данными.
+func (i *Interceptors) authenticate(ctx context.Context) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	first := func(key string) string {
+		if v := md.Get(key); len(v) > 0 {
+			return v[0]
+		}
+		return ""
+	}
+
+	claimed := models.TenantID(first("x-tenant-id"))
+	if !tenant.Valid(claimed) {
+		return nil, status.Error(codes.InvalidArgument, "invalid x-tenant-id")
+	}
+
+	if !i.auth.Enabled() {
+		return withTenant(ctx, claimed), nil
+	}
+
+	key := peerKey(ctx)
+	if i.failures != nil {
+		if ok, retryAfter := i.failures.Peek(key); !ok {
+			return nil, tooManyRequests(retryAfter)
+		}
+	}
+
+	userID, tenantID, err := i.auth.Identify(first("x-api-key"), first("authorization"))
+	if err != nil {
+		if i.failures != nil {
+			i.failures.Allow(key)
+		}
+		return nil, status.Error(codes.Unauthenticated, fmt.Sprintf("unauthorized: %v", err))
+	}
+	if claimed != "" && claimed != tenantID {
+		return nil, status.Error(codes.PermissionDenied, "x-tenant-id does not match credentials")
+	}
+
+	return withTenant(withUserID(ctx, userID), tenantID), nil
+}
+
+// limit учитывает вызов в ограничениях частоты пользователя и арендатора,
+// как RateLimit и TenantRateLimit в HTTP API. Вызов сверх ограничения
+// получает ResourceExhausted. Должен вызываться после authenticate.
+func (i *Interceptors) limit(ctx context.Context) error {
+	if i.users != nil {
+		if ok, retryAfter := i.users.Allow(clientKey(ctx)); !ok {
+			return tooManyRequests(retryAfter)
+		}
+	}
+	if i.tenants != nil {
+		if ok, retryAfter := i.tenants.Allow(string(tenantFromContext(ctx))); !ok {
+			return tooManyRequests(retryAfter)
+		}
+	}
+
+	return nil
+}
+
+func tooManyRequests(retryAfter time.Duration) error {
+	seconds := int64(math.Ceil(retryAfter.Seconds()))
+	return status.Errorf(codes.ResourceExhausted, "too many requests, retry after %ds", seconds)
+}
+
+// clientKey возвращает ключ ограничения частоты вызовов: пользователя, если он
+// аутентифицирован, иначе IP клиента - так же, как в HTTP API.
+func clientKey(ctx context.Context) string {
+	if userID, ok := ctx.Value(userIDKey{}).(models.UserID); ok {
+		return "user:" + string(tenant.UserKey(tenantFromContext(ctx), userID))
+	}
+	return peerKey(ctx)
+}
+
+// peerKey возвращает ключ ограничения частоты вызовов по IP клиента.
+func peerKey(ctx context.Context) string {
+	var host string
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		host = p.Addr.String()
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+	}
+	return "ip:" + host
+}
+
+// serverStream подменяет контекст потока.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+type userIDKey struct{}
+
+type tenantKey struct{}
+
+func withTenant(ctx context.Context, id models.TenantID) context.Context {
+	return context.WithValue(ctx, tenantKey{}, id)
+}
+
+// tenantFromContext возвращает арендатора вызова, по умолчанию - tenant.Default.
+func tenantFromContext(ctx context.Context) models.TenantID {
+	id, _ := ctx.Value(tenantKey{}).(models.TenantID)
+	return id
+}
+
+func withUserID(ctx context.Context, userID models.UserID) context.Context {
+	return context.WithValue(ctx, userIDKey{}, userID)
+}
+
+// resolveUserID возвращает пользователя, от имени которого выполняется вызов.
+// claimed - user_id из запроса, правила те же, что в HTTP API.
+func resolveUserID(ctx context.Context, claimed string) (models.UserID, error) {
+	userID, ok := ctx.Value(userIDKey{}).(models.UserID)
+	if !ok {
+		if claimed == "" {
+			return "", fmt.Errorf("%w: user_id required", errInvalidArgument)
+		}
+		return models.UserID(claimed), nil
+	}
+
+	if claimed != "" && models.UserID(claimed) != userID {
+		return "", fmt.Errorf("%w: user_id does not match credentials", errPermissionDenied)
+	}
+
+	return userID, nil
+}
@@ -0,0 +1,197 @@
+// Package grpcapi реализует gRPC API календаря поверх того же сервиса событий, что и HTTP.
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc"
+	"l2.18/internal/pubsub"
+	"l2.18/pkg/calendarpb"
+	"l2.18/pkg/models"
+)
+
+type eventsService interface {
+	AddEvent(userID models.UserID, event models.Event) (models.EventID, error)
+	ReplaceEvent(userID models.UserID, event models.Event) error
+	RemoveEvent(userID models.UserID, eventID models.EventID, version int64) error
+	GetEvent(userID models.UserID, eventID models.EventID) (*models.Event, error)
+	GetEvents(userID models.UserID, start, end time.Time, filter models.EventFilter) ([]models.Event, error)
+	ListEvents(userID models.UserID, start, end time.Time, filter models.EventFilter) ([]models.Event, error)
+}
+
+type changesHub interface {
+	Subscribe(userID models.UserID) *pubsub.Subscription
+}
+
+// Server реализует calendarpb.CalendarServer.
+type Server struct {
+	calendarpb.UnimplementedCalendarServer
+
+	service eventsService
+	hub     changesHub
+}
+
+// NewServer создает новый Server.
+func NewServer(service eventsService, hub changesHub) *Server {
+	return &Server{service: service, hub: hub}
+}
+
+// Register регистрирует Server в gs.
+func (s *Server) Register(gs *grpc.Server) {
+	calendarpb.RegisterCalendarServer(gs, s)
+}
+
+// CreateEvent создает событие.
+func (s *Server) CreateEvent(ctx context.Context, req *calendarpb.CreateEventRequest) (*calendarpb.Event, error) {
+	userID, err := resolveUserID(ctx, req.GetUserId())
+	if err != nil {
+		return nil, err
+	}
+
+	event, err := fromProto(req.GetEvent())
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := s.service.AddEvent(userID, event)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.get(userID, id)
+}
+
+// GetEvent возвращает событие.
+func (s *Server) GetEvent(ctx context.Context, req *calendarpb.GetEventRequest) (*calendarpb.Event, error) {
+	userID, err := resolveUserID(ctx, req.GetUserId())
+	if err != nil {
+		return nil, err
+	}
+	if req.GetId() == "" {
+		return nil, fmt.Errorf("%w: id required", errInvalidArgument)
+	}
+
+	return s.get(userID, models.EventID(req.GetId()))
+}
+
+// UpdateEvent заменяет событие.
+func (s *Server) UpdateEvent(ctx context.Context, req *calendarpb.UpdateEventRequest) (*calendarpb.Event, error) {
+	userID, err := resolveUserID(ctx, req.GetUserId())
+	if err != nil {
+		return nil, err
+	}
+
+	event, err := fromProto(req.GetEvent())
+	if err != nil {
+		return nil, err
+	}
+	if event.ID == "" {
+		return nil, fmt.Errorf("%w: id required", errInvalidArgument)
+	}
+
+	if err := s.service.ReplaceEvent(userID, event); err != nil {
+		return nil, err
+	}
+
+	return s.get(userID, event.ID)
+}
+
+// DeleteEvent удаляет событие.
+func (s *Server) DeleteEvent(ctx context.Context, req *calendarpb.DeleteEventRequest) (*calendarpb.DeleteEventResponse, error) {
+	userID, err := resolveUserID(ctx, req.GetUserId())
+	if err != nil {
+		return nil, err
+	}
+	if req.GetId() == "" {
+		return nil, fmt.Errorf("%w: id required", errInvalidArgument)
+	}
+
+	if err := s.service.RemoveEvent(userID, models.EventID(req.GetId()), req.GetVersion()); err != nil {
+		return nil, err
+	}
+
+	return &calendarpb.DeleteEventResponse{}, nil
+}
+
+// ListEvents возвращает события из диапазона, подходящие фильтру запроса.
+func (s *Server) ListEvents(ctx context.Context, req *calendarpb.ListEventsRequest) (*calendarpb.ListEventsResponse, error) {
+	userID, err := resolveUserID(ctx, req.GetUserId())
+	if err != nil {
+		return nil, err
+	}
+
+	if req.GetStart() == nil || req.GetEnd() == nil {
+		return nil, fmt.Errorf("%w: start and end required", errInvalidArgument)
+	}
+	start, err := timeIn(req.GetStart(), time.UTC)
+	if err != nil {
+		return nil, err
+	}
+	end, err := timeIn(req.GetEnd(), time.UTC)
+	if err != nil {
+		return nil, err
+	}
+	if !start.Before(end) {
+		return nil, fmt.Errorf("%w: start must be before end", errInvalidArgument)
+	}
+
+	list := s.service.ListEvents
+	if req.GetExpand() {
+		list = s.service.GetEvents
+	}
+	filter := models.EventFilter{Tags: req.GetTags(), Category: req.GetCategory()}
+	events, err := list(userID, start, end, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &calendarpb.ListEventsResponse{Events: make([]*calendarpb.Event, len(events))}
+	for i, event := range events {
+		resp.Events[i] = toProto(event)
+	}
+	return resp, nil
+}
+
+// WatchEvents отправляет изменения событий пользователя. Вызов завершается
+// с UNAVAILABLE, если клиент не успевает читать изменения или сервер
+// останавливается, - клиенту стоит переподключиться.
+func (s *Server) WatchEvents(req *calendarpb.WatchEventsRequest, stream grpc.ServerStreamingServer[calendarpb.EventChange]) error {
+	userID, err := resolveUserID(stream.Context(), req.GetUserId())
+	if err != nil {
+		return err
+	}
+
+	sub := s.hub.Subscribe(userID)
+	defer sub.Close()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+
+		case change, ok := <-sub.Changes():
+			if !ok {
+				if err := sub.Err(); err != nil {
+					return err
+				}
+				return errors.New("subscription closed")
+			}
+
+			if err := stream.Send(changeToProto(change)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (s *Server) get(userID models.UserID, eventID models.EventID) (*calendarpb.Event, error) {
+	event, err := s.service.GetEvent(userID, eventID)
+	if err != nil {
+		return nil, err
+	}
+
+	return toProto(*event), nil
+}
@@ -0,0 +1,414 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"l2.18/internal/pubsub"
+	"l2.18/internal/ratelimit"
+	"l2.18/internal/repository/memory"
+	"l2.18/internal/service/events"
+	"l2.18/internal/tenant"
+	"l2.18/pkg/calendarpb"
+	"l2.18/pkg/models"
+)
+
+// keyAuth принимает единственный ключ API.
+type keyAuth struct {
+	enabled bool
+}
+
+func (a keyAuth) Enabled() bool { return a.enabled }
+
+func (a keyAuth) Identify(apiKey, authorization string) (models.UserID, models.TenantID, error) {
+	switch apiKey {
+	case "secret":
+		return "user1", "", nil
+	case "acme-secret":
+		return "user1", "acme", nil
+	case "acme-bot":
+		return "bot", "acme", nil
+	case "acme-bot2":
+		return "bot2", "acme", nil
+	}
+	return "", "", errors.New("unknown api key")
+}
+
+func newClient(t *testing.T, auth authenticator) (calendarpb.CalendarClient, *pubsub.Hub) {
+	t.Helper()
+
+	hub := pubsub.NewHub(16)
+	service := events.New(memory.NewEventsRepository(), events.WithPublisher(hub))
+
+	return dial(t, auth, hub, NewServer(service, hub).Register), hub
+}
+
+// newTenantsClient возвращает клиента сервера, у каждого арендатора которого
+// свое хранилище поверх общего.
+func newTenantsClient(t *testing.T, auth authenticator) calendarpb.CalendarClient {
+	t.Helper()
+
+	hub := pubsub.NewHub(16)
+	store := memory.NewEventsRepository()
+	tenants := NewTenants(func(id models.TenantID) *Server {
+		tenantHub := tenant.NewHub(hub, id)
+		service := events.New(tenant.NewRepository(store, id, models.TenantQuota{MaxEvents: 1}),
+			events.WithPublisher(tenantHub))
+		return NewServer(service, tenantHub)
+	})
+
+	return dial(t, auth, hub, tenants.Register)
+}
+
+// dial запускает gRPC-сервер, в котором register регистрирует сервис, и подключается к нему.
+func dial(
+	t *testing.T, auth authenticator, hub *pubsub.Hub, register func(gs *grpc.Server), opts ...Option,
+) calendarpb.CalendarClient {
+	t.Helper()
+
+	gs := grpc.NewServer(NewInterceptors(slog.New(slog.DiscardHandler), auth, opts...).ServerOptions()...)
+	register(gs)
+
+	lis := bufconn.Listen(1 << 20)
+	go gs.Serve(lis)
+	t.Cleanup(func() {
+		hub.Close()
+		gs.Stop()
+	})
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return calendarpb.NewCalendarClient(conn)
+}
+
+func wantCode(t *testing.T, err error, code codes.Code) {
+	t.Helper()
+
+	if got := status.Code(err); got != code {
+		t.Errorf("got code %s (%v), want %s", got, err, code)
+	}
+}
+
+func TestCRUD(t *testing.T) {
+	client, _ := newClient(t, keyAuth{})
+	ctx := context.Background()
+
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	created, err := client.CreateEvent(ctx, &calendarpb.CreateEventRequest{
+		UserId: "user1",
+		Event: &calendarpb.Event{
+			Start:    timestamppb.New(start),
+			End:      timestamppb.New(start.Add(time.Hour)),
+			TimeZone: "Europe/Moscow",
+			Title:    "standup",
+			Rrule:    "FREQ=DAILY;COUNT=3",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.GetId() == "" || created.GetVersion() == 0 || !created.GetStart().AsTime().Equal(start) {
+		t.Fatalf("unexpected created event %v", created)
+	}
+
+	list, err := client.ListEvents(ctx, &calendarpb.ListEventsRequest{
+		UserId: "user1",
+		Start:  timestamppb.New(start),
+		End:    timestamppb.New(start.AddDate(0, 0, 7)),
+		Expand: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.GetEvents()) != 3 {
+		t.Fatalf("got %d occurrences, want 3", len(list.GetEvents()))
+	}
+
+	created.Title = "daily"
+	updated, err := client.UpdateEvent(ctx, &calendarpb.UpdateEventRequest{UserId: "user1", Event: created})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.GetTitle() != "daily" || updated.GetVersion() <= created.GetVersion() {
+		t.Fatalf("unexpected updated event %v", updated)
+	}
+
+	// Версия created уже устарела.
+	_, err = client.UpdateEvent(ctx, &calendarpb.UpdateEventRequest{UserId: "user1", Event: created})
+	wantCode(t, err, codes.Aborted)
+
+	_, err = client.DeleteEvent(ctx, &calendarpb.DeleteEventRequest{UserId: "user1", Id: created.GetId()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = client.GetEvent(ctx, &calendarpb.GetEventRequest{UserId: "user1", Id: created.GetId()})
+	wantCode(t, err, codes.NotFound)
+
+	_, err = client.CreateEvent(ctx, &calendarpb.CreateEventRequest{UserId: "user1", Event: &calendarpb.Event{Title: "no start"}})
+	wantCode(t, err, codes.InvalidArgument)
+
+	_, err = client.GetEvent(ctx, &calendarpb.GetEventRequest{Id: "1"})
+	wantCode(t, err, codes.InvalidArgument)
+}
+
+func TestAuthentication(t *testing.T) {
+	client, _ := newClient(t, keyAuth{enabled: true})
+
+	_, err := client.GetEvent(context.Background(), &calendarpb.GetEventRequest{UserId: "user1", Id: "1"})
+	wantCode(t, err, codes.Unauthenticated)
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", "secret")
+
+	_, err = client.GetEvent(ctx, &calendarpb.GetEventRequest{UserId: "user2", Id: "1"})
+	wantCode(t, err, codes.PermissionDenied)
+
+	_, err = client.GetEvent(ctx, &calendarpb.GetEventRequest{Id: "1"})
+	wantCode(t, err, codes.NotFound)
+}
+
+func TestWatchEvents(t *testing.T) {
+	client, hub := newClient(t, keyAuth{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := client.WatchEvents(ctx, &calendarpb.WatchEventsRequest{UserId: "user1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Подписка создается на сервере асинхронно, поэтому событие
+	// создается повторно, пока изменение не придет.
+	changes := make(chan *calendarpb.EventChange)
+	var recvErr error
+	go func() {
+		defer close(changes)
+		for {
+			change, err := stream.Recv()
+			if err != nil {
+				recvErr = err
+				return
+			}
+			changes <- change
+		}
+	}()
+
+	create := func() {
+		_, err := client.CreateEvent(ctx, &calendarpb.CreateEventRequest{
+			UserId: "user1",
+			Event:  &calendarpb.Event{Start: timestamppb.Now(), Title: "watched"},
+		})
+		if err != nil {
+			t.Error(err)
+		}
+	}
+
+	create()
+	timeout := time.After(2 * time.Second)
+	var change *calendarpb.EventChange
+	for change == nil {
+		select {
+		case change = <-changes:
+		case <-time.After(20 * time.Millisecond):
+			create()
+		case <-timeout:
+			t.Fatal("no change received")
+		}
+	}
+
+	if change.GetType() != calendarpb.EventChange_TYPE_CREATED || change.GetEvent().GetTitle() != "watched" {
+		t.Errorf("unexpected change %v", change)
+	}
+
+	// Остановка Hub завершает поток с UNAVAILABLE.
+	hub.Close()
+	for range changes {
+	}
+	if errors.Is(recvErr, io.EOF) {
+		t.Fatal("stream ended without error")
+	}
+	wantCode(t, recvErr, codes.Unavailable)
+}
+
+func TestListEventsFilter(t *testing.T) {
+	client, _ := newClient(t, keyAuth{})
+	ctx := context.Background()
+
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	for i, tags := range [][]string{{"Team", "urgent"}, {"team"}, nil} {
+		_, err := client.CreateEvent(ctx, &calendarpb.CreateEventRequest{
+			UserId: "user1",
+			Event: &calendarpb.Event{
+				Start:      timestamppb.New(start.Add(time.Duration(i) * time.Hour)),
+				Title:      "review",
+				Location:   "room 1",
+				Category:   "work",
+				Tags:       tags,
+				Attributes: map[string]string{"jira": "CAL-1"},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	list, err := client.ListEvents(ctx, &calendarpb.ListEventsRequest{
+		UserId:   "user1",
+		Start:    timestamppb.New(start),
+		End:      timestamppb.New(start.AddDate(0, 0, 1)),
+		Tags:     []string{"team"},
+		Category: "work",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.GetEvents()) != 2 {
+		t.Fatalf("got %d events, want 2", len(list.GetEvents()))
+	}
+
+	first := list.GetEvents()[0]
+	if first.GetLocation() != "room 1" || first.GetAttributes()["jira"] != "CAL-1" ||
+		len(first.GetTags()) != 2 || first.GetTags()[0] != "team" {
+		t.Errorf("unexpected event metadata %v", first)
+	}
+}
+
+func TestTenants(t *testing.T) {
+	client := newTenantsClient(t, keyAuth{})
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+
+	acme := metadata.AppendToOutgoingContext(context.Background(), "x-tenant-id", "acme")
+	created, err := client.CreateEvent(acme, &calendarpb.CreateEventRequest{
+		UserId: "user1",
+		Event:  &calendarpb.Event{Start: timestamppb.New(start), Title: "standup"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = client.GetEvent(context.Background(), &calendarpb.GetEventRequest{UserId: "user1", Id: created.GetId()})
+	wantCode(t, err, codes.NotFound)
+
+	_, err = client.CreateEvent(acme, &calendarpb.CreateEventRequest{
+		UserId: "user2",
+		Event:  &calendarpb.Event{Start: timestamppb.New(start), Title: "review"},
+	})
+	wantCode(t, err, codes.ResourceExhausted)
+
+	invalid := metadata.AppendToOutgoingContext(context.Background(), "x-tenant-id", "ACME/1")
+	_, err = client.GetEvent(invalid, &calendarpb.GetEventRequest{UserId: "user1", Id: created.GetId()})
+	wantCode(t, err, codes.InvalidArgument)
+}
+
+func TestTenantFromCredentials(t *testing.T) {
+	client := newTenantsClient(t, keyAuth{enabled: true})
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+
+	acme := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", "acme-secret")
+	created, err := client.CreateEvent(acme, &calendarpb.CreateEventRequest{
+		Event: &calendarpb.Event{Start: timestamppb.New(start), Title: "standup"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", "secret")
+	_, err = client.GetEvent(other, &calendarpb.GetEventRequest{Id: created.GetId()})
+	wantCode(t, err, codes.NotFound)
+
+	// Арендатора из учетных данных нельзя подменить метаданными.
+	spoofed := metadata.AppendToOutgoingContext(other, "x-tenant-id", "acme")
+	_, err = client.GetEvent(spoofed, &calendarpb.GetEventRequest{Id: created.GetId()})
+	wantCode(t, err, codes.PermissionDenied)
+}
+
+// countingLimiter пропускает allowed вызовов с каждым ключом и запоминает ключи.
+type countingLimiter struct {
+	allowed int
+	calls   map[string]int
+}
+
+func (l *countingLimiter) Allow(key string) (bool, time.Duration) {
+	l.calls[key]++
+	return l.calls[key] <= l.allowed, 1500 * time.Millisecond
+}
+
+func TestRateLimit(t *testing.T) {
+	users := &countingLimiter{allowed: 2, calls: map[string]int{}}
+	tenants := &countingLimiter{allowed: 2, calls: map[string]int{}}
+
+	hub := pubsub.NewHub(16)
+	service := events.New(memory.NewEventsRepository(), events.WithPublisher(hub))
+	client := dial(t, keyAuth{enabled: true}, hub, NewServer(service, hub).Register,
+		WithRateLimit(users), WithTenantRateLimit(tenants))
+
+	user1 := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", "secret")
+	bot := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", "acme-bot")
+	bot2 := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", "acme-bot2")
+	get := func(ctx context.Context) error {
+		_, err := client.GetEvent(ctx, &calendarpb.GetEventRequest{Id: "1"})
+		return err
+	}
+
+	wantCode(t, get(user1), codes.NotFound)
+	wantCode(t, get(user1), codes.NotFound)
+	err := get(user1)
+	wantCode(t, err, codes.ResourceExhausted)
+	if msg := status.Convert(err).Message(); msg != "too many requests, retry after 2s" {
+		t.Errorf("unexpected message %q", msg)
+	}
+
+	// Вызовы разных пользователей арендатора acme ограничиваются вместе.
+	wantCode(t, get(bot), codes.NotFound)
+	wantCode(t, get(bot), codes.NotFound)
+	wantCode(t, get(bot2), codes.ResourceExhausted)
+
+	// Поток ограничивается так же.
+	stream, err := client.WatchEvents(user1, &calendarpb.WatchEventsRequest{})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	wantCode(t, err, codes.ResourceExhausted)
+
+	if users.calls["user:user1"] != 4 || users.calls["user:acme/bot2"] != 1 || tenants.calls[""] != 2 || tenants.calls["acme"] != 3 {
+		t.Errorf("unexpected limiter calls: users %v, tenants %v", users.calls, tenants.calls)
+	}
+}
+
+func TestAuthFailureLimit(t *testing.T) {
+	hub := pubsub.NewHub(16)
+	service := events.New(memory.NewEventsRepository(), events.WithPublisher(hub))
+	client := dial(t, keyAuth{enabled: true}, hub, NewServer(service, hub).Register,
+		WithAuthFailureLimit(ratelimit.New(0.001, 2)))
+
+	get := func(key string) error {
+		ctx := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", key)
+		_, err := client.GetEvent(ctx, &calendarpb.GetEventRequest{Id: "1"})
+		return err
+	}
+
+	wantCode(t, get("secret"), codes.NotFound)
+	wantCode(t, get("guess"), codes.Unauthenticated)
+	wantCode(t, get("guess"), codes.Unauthenticated)
+	wantCode(t, get("guess"), codes.ResourceExhausted)
+	wantCode(t, get("secret"), codes.ResourceExhausted)
+}
@@ -0,0 +1,61 @@
+package grpcapi
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"l2.18/pkg/calendarpb"
+	"l2.18/pkg/models"
+)
+
+// Tenants реализует calendarpb.CalendarServer, направляя вызовы в Server
+// арендатора, которого определили перехватчики.
+type Tenants struct {
+	calendarpb.UnimplementedCalendarServer
+
+	servers func(id models.TenantID) *Server
+}
+
+// NewTenants создает новый Tenants. servers возвращает Server арендатора.
+func NewTenants(servers func(id models.TenantID) *Server) *Tenants {
+	return &Tenants{servers: servers}
+}
+
+// Register регистрирует Tenants в gs.
+func (t *Tenants) Register(gs *grpc.Server) {
+	calendarpb.RegisterCalendarServer(gs, t)
+}
+
+func (t *Tenants) server(ctx context.Context) *Server {
+	return t.servers(tenantFromContext(ctx))
+}
+
+// CreateEvent создает событие.
+func (t *Tenants) CreateEvent(ctx context.Context, req *calendarpb.CreateEventRequest) (*calendarpb.Event, error) {
+	return t.server(ctx).CreateEvent(ctx, req)
+}
+
+// GetEvent возвращает событие.
+func (t *Tenants) GetEvent(ctx context.Context, req *calendarpb.GetEventRequest) (*calendarpb.Event, error) {
+	return t.server(ctx).GetEvent(ctx, req)
+}
+
+// UpdateEvent заменяет событие.
+func (t *Tenants) UpdateEvent(ctx context.Context, req *calendarpb.UpdateEventRequest) (*calendarpb.Event, error) {
+	return t.server(ctx).UpdateEvent(ctx, req)
+}
+
+// DeleteEvent удаляет событие.
+func (t *Tenants) DeleteEvent(ctx context.Context, req *calendarpb.DeleteEventRequest) (*calendarpb.DeleteEventResponse, error) {
+	return t.server(ctx).DeleteEvent(ctx, req)
+}
+
+// ListEvents возвращает события из диапазона.
+func (t *Tenants) ListEvents(ctx context.Context, req *calendarpb.ListEventsRequest) (*calendarpb.ListEventsResponse, error) {
+	return t.server(ctx).ListEvents(ctx, req)
+}
+
+// WatchEvents отправляет изменения событий пользователя.
+func (t *Tenants) WatchEvents(req *calendarpb.WatchEventsRequest, stream grpc.ServerStreamingServer[calendarpb.EventChange]) error {
+	return t.server(stream.Context()).WatchEvents(req, stream)
+}
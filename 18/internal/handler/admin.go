@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"l2.18/internal/tenant"
+	"l2.18/pkg/models"
+)
+
+type tenantUsage interface {
+	Usage() ([]models.TenantUsage, error)
+	TenantUsage(id models.TenantID) (models.TenantUsage, error)
+}
+
+type tenantsResponse struct {
+	Result []models.TenantUsage `json:"result"`
+}
+
+// Admin обрабатывает запросы администраторов сервера.
+type Admin struct {
+	tenants tenantUsage
+	users   map[models.UserID]struct{}
+}
+
+// NewAdmin создает новый Admin. Администраторы - пользователи users
+// арендатора по умолчанию.
+func NewAdmin(tenants tenantUsage, users []models.UserID) *Admin {
+	a := &Admin{tenants: tenants, users: make(map[models.UserID]struct{}, len(users))}
+	for _, userID := range users {
+		a.users[userID] = struct{}{}
+	}
+
+	return a
+}
+
+// Authorize пропускает к h только аутентифицированных администраторов.
+// Должен вызываться после Authenticate: без аутентификации администратора
+// определить нельзя, и запросы отклоняются.
+func (a *Admin) Authorize(h ErrHandlerFunc) ErrHandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		userID, ok := userIDFromContext(r.Context())
+		if !ok || tenantFromContext(r.Context()) != tenant.Default {
+			return fmt.Errorf("%w: admin access required", errForbidden)
+		}
+		if _, ok := a.users[userID]; !ok {
+			return fmt.Errorf("%w: admin access required", errForbidden)
+		}
+
+		return h(w, r)
+	}
+}
+
+// Tenants обрабатывает GET /admin/tenants - использование квот арендаторами.
+func (a *Admin) Tenants(w http.ResponseWriter, r *http.Request) error {
+	usage, err := a.tenants.Usage()
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(w, http.StatusOK, tenantsResponse{Result: usage})
+}
+
+// Tenant обрабатывает GET /admin/tenants/{tenant} - использование квот арендатором.
+// Арендатор по умолчанию есть только в списке Tenants: у него пустой ID.
+func (a *Admin) Tenant(w http.ResponseWriter, r *http.Request) error {
+	id := models.TenantID(r.PathValue("tenant"))
+	if !tenant.Valid(id) {
+		return fmt.Errorf("%w: invalid tenant", errInvalidData)
+	}
+
+	usage, err := a.tenants.TenantUsage(id)
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(w, http.StatusOK, usage)
+}
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"net/http"
+
+	"l2.18/pkg/models"
+)
+
+// rsvpRequest - тело ответа на приглашение.
+type rsvpRequest struct {
+	Status models.RSVPStatus `json:"status"`
+}
+
+type attendeesResponse struct {
+	Result []models.Attendee `json:"result"`
+}
+
+// InvitationsV2 обрабатывает GET /v2/users/{user}/invitations - события других
+// пользователей, в которые приглашен user. У каждого события заполнен owner.
+func (eh *EventsHandler) InvitationsV2(w http.ResponseWriter, r *http.Request) error {
+	userID, err := resolveUserID(r, models.UserID(r.PathValue("user")))
+	if err != nil {
+		return err
+	}
+
+	res, err := eh.service.GetInvitations(userID)
+	if err != nil {
+		return err
+	}
+	if res == nil {
+		res = []models.Event{}
+	}
+
+	return writeJSON(w, http.StatusOK, eventResponse{Result: res})
+}
+
+// RespondV2 обрабатывает POST /v2/users/{user}/invitations/{owner}/{id}/rsvp -
+// ответ user на приглашение в событие id пользователя owner.
+func (eh *EventsHandler) RespondV2(w http.ResponseWriter, r *http.Request) error {
+	userID, err := resolveUserID(r, models.UserID(r.PathValue("user")))
+	if err != nil {
+		return err
+	}
+	owner := models.UserID(r.PathValue("owner"))
+	eventID := models.EventID(r.PathValue("id"))
+
+	var req rsvpRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		return err
+	}
+
+	if err := eh.service.RespondToInvitation(userID, owner, eventID, req.Status); err != nil {
+		return err
+	}
+
+	// Событие возвращается от имени владельца, но с заполненным owner,
+	// как в списке приглашений.
+	event, err := eh.service.GetEvent(owner, eventID)
+	if err != nil {
+		return err
+	}
+	event.Owner = owner
+
+	return writeJSON(w, http.StatusOK, event)
+}
+
+// AttendeesV2 обрабатывает GET /v2/users/{user}/events/{id}/attendees -
+// участников события и их ответы. Доступно только владельцу события.
+func (eh *EventsHandler) AttendeesV2(w http.ResponseWriter, r *http.Request) error {
+	userID, err := resolveUserID(r, models.UserID(r.PathValue("user")))
+	if err != nil {
+		return err
+	}
+
+	event, err := eh.service.GetEvent(userID, models.EventID(r.PathValue("id")))
+	if err != nil {
+		return err
+	}
+
+	res := event.Attendees
+	if res == nil {
+		res = []models.Attendee{}
+	}
+
+	return writeJSON(w, http.StatusOK, attendeesResponse{Result: res})
+}
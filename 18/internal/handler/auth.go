@@ -0,0 +1,241 @@
+package handler
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"l2.18/internal/tenant"
+	"l2.18/pkg/models"
+)
+
+// jwtLeeway - допустимое расхождение часов сервера и издателя JWT при проверке exp, nbf и iat.
+const jwtLeeway = time.Minute
+
+// AuthConfig задает способы аутентификации. Если не задан ни один ключ,
+// аутентификация отключена и пользователь определяется параметром user_id.
+type AuthConfig struct {
+	// HMACSecret - секрет для JWT с алгоритмом HS256.
+	HMACSecret []byte
+	// RSAPublicKey - открытый ключ для JWT с алгоритмом RS256.
+	RSAPublicKey *rsa.PublicKey
+	// Issuer и Audience, если заданы, проверяются в claims iss и aud.
+	Issuer   string
+	Audience string
+	// APIKeys - статические ключи API и пользователи, которым они выданы.
+	APIKeys map[string]models.UserID
+	// APIKeyTenants - арендаторы пользователей ключей API. Ключ без арендатора
+	// относится к арендатору по умолчанию.
+	APIKeyTenants map[string]models.TenantID
+}
+
+// Auth проверяет учетные данные запроса и сохраняет пользователя и его
+// арендатора в контексте.
+//
+// Поддерживаются:
+//   - Authorization: Bearer <JWT>, пользователь - claim sub, арендатор - claim tenant;
+//   - X-API-Key: <ключ> или Authorization: Basic с ключом в качестве пароля (для клиентов CalDAV);
+//   - параметр access_token с JWT - для EventSource и WebSocket в браузере, которые не умеют задавать заголовки.
+type Auth struct {
+	enabled bool
+	keyFunc jwt.Keyfunc
+	methods []string
+	options []jwt.ParserOption
+	apiKeys map[[sha256.Size]byte]identity
+}
+
+// identity - пользователь и его арендатор.
+type identity struct {
+	userID models.UserID
+	tenant models.TenantID
+}
+
+// NewAuth создает новый Auth.
+func NewAuth(cfg AuthConfig) *Auth {
+	a := &Auth{apiKeys: make(map[[sha256.Size]byte]identity, len(cfg.APIKeys))}
+
+	// Ключи храним по хешу: поиск по хешу не раскрывает ключ через время сравнения.
+	for key, userID := range cfg.APIKeys {
+		a.apiKeys[sha256.Sum256([]byte(key))] = identity{userID: userID, tenant: cfg.APIKeyTenants[key]}
+	}
+
+	if cfg.HMACSecret != nil {
+		a.methods = append(a.methods, jwt.SigningMethodHS256.Alg())
+	}
+	if cfg.RSAPublicKey != nil {
+		a.methods = append(a.methods, jwt.SigningMethodRS256.Alg())
+	}
+
+	a.keyFunc = func(token *jwt.Token) (any, error) {
+		switch token.Method.Alg() {
+		case jwt.SigningMethodHS256.Alg():
+			return cfg.HMACSecret, nil
+		case jwt.SigningMethodRS256.Alg():
+			return cfg.RSAPublicKey, nil
+		default:
+			return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+		}
+	}
+
+	// Токен без exp действовал бы бессрочно, поэтому exp обязателен.
+	a.options = []jwt.ParserOption{
+		jwt.WithValidMethods(a.methods),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(jwtLeeway),
+	}
+	if cfg.Issuer != "" {
+		a.options = append(a.options, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		a.options = append(a.options, jwt.WithAudience(cfg.Audience))
+	}
+
+	a.enabled = len(a.methods) > 0 || len(a.apiKeys) > 0
+
+	return a
+}
+
+// Enabled сообщает, включена ли аутентификация.
+func (a *Auth) Enabled() bool {
+	return a.enabled
+}
+
+// Authenticate пропускает к h только запросы с корректными учетными данными.
+//
+// Арендатор запроса берется из учетных данных. Без аутентификации его задает
+// заголовок X-Tenant-ID, а с ней заголовок, если передан, должен совпадать
+// с арендатором из учетных данных.
+func (a *Auth) Authenticate(h ErrHandlerFunc) ErrHandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		claimed := models.TenantID(r.Header.Get(tenantHeader))
+		if !tenant.Valid(claimed) {
+			return fmt.Errorf("%w: invalid %s", errInvalidData, tenantHeader)
+		}
+
+		if !a.enabled {
+			return h(w, r.WithContext(withTenant(r.Context(), claimed)))
+		}
+
+		id, err := a.identify(r)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="calendar", Basic realm="calendar"`)
+			return fmt.Errorf("%w: %v", errUnauthorized, err)
+		}
+		if claimed != "" && claimed != id.tenant {
+			return fmt.Errorf("%w: %s does not match credentials", errForbidden, tenantHeader)
+		}
+
+		ctx := withTenant(withUserID(r.Context(), id.userID), id.tenant)
+		return h(w, r.WithContext(ctx))
+	}
+}
+
+// identify определяет пользователя по учетным данным запроса.
+func (a *Auth) identify(r *http.Request) (identity, error) {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return a.apiKey(key)
+	}
+
+	if _, password, ok := r.BasicAuth(); ok {
+		return a.apiKey(password)
+	}
+
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return a.jwt(strings.TrimSpace(token))
+	}
+
+	if token := r.URL.Query().Get("access_token"); token != "" {
+		return a.jwt(token)
+	}
+
+	return identity{}, fmt.Errorf("credentials required")
+}
+
+// Identify определяет пользователя и его арендатора по ключу API или значению
+// Authorization с Bearer JWT. Нужен транспортам без http.Request, например gRPC.
+func (a *Auth) Identify(apiKey, authorization string) (models.UserID, models.TenantID, error) {
+	var id identity
+	var err error
+
+	switch token, ok := strings.CutPrefix(authorization, "Bearer "); {
+	case apiKey != "":
+		id, err = a.apiKey(apiKey)
+	case ok:
+		id, err = a.jwt(strings.TrimSpace(token))
+	default:
+		err = fmt.Errorf("credentials required")
+	}
+
+	return id.userID, id.tenant, err
+}
+
+func (a *Auth) apiKey(key string) (identity, error) {
+	id, ok := a.apiKeys[sha256.Sum256([]byte(key))]
+	if !ok {
+		return identity{}, fmt.Errorf("unknown api key")
+	}
+
+	return id, nil
+}
+
+// claims - claims JWT с арендатором пользователя.
+type claims struct {
+	jwt.RegisteredClaims
+	Tenant models.TenantID `json:"tenant,omitempty"`
+}
+
+func (a *Auth) jwt(raw string) (identity, error) {
+	if len(a.methods) == 0 {
+		return identity{}, fmt.Errorf("tokens are not accepted")
+	}
+
+	var c claims
+	if _, err := jwt.ParseWithClaims(raw, &c, a.keyFunc, a.options...); err != nil {
+		return identity{}, err
+	}
+	if c.Subject == "" {
+		return identity{}, fmt.Errorf("token has no subject")
+	}
+	if !tenant.Valid(c.Tenant) {
+		return identity{}, fmt.Errorf("token has invalid tenant")
+	}
+
+	return identity{userID: models.UserID(c.Subject), tenant: c.Tenant}, nil
+}
+
+type userIDKey struct{}
+
+func withUserID(ctx context.Context, userID models.UserID) context.Context {
+	return context.WithValue(ctx, userIDKey{}, userID)
+}
+
+// userIDFromContext возвращает аутентифицированного пользователя, если он есть.
+func userIDFromContext(ctx context.Context) (models.UserID, bool) {
+	userID, ok := ctx.Value(userIDKey{}).(models.UserID)
+	return userID, ok
+}
+
+// resolveUserID возвращает пользователя, от имени которого выполняется запрос.
+// claimed - user_id из параметров или тела запроса. Для аутентифицированного
+// запроса его можно не передавать, но если он передан, то должен совпадать
+// с пользователем из учетных данных.
+func resolveUserID(r *http.Request, claimed models.UserID) (models.UserID, error) {
+	userID, ok := userIDFromContext(r.Context())
+	if !ok {
+		if claimed == "" {
+			return "", errInvalidData
+		}
+		return claimed, nil
+	}
+
+	if claimed != "" && claimed != userID {
+		return "", fmt.Errorf("%w: user_id does not match credentials", errForbidden)
+	}
+
+	return userID, nil
+}
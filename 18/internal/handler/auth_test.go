@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestAuthenticateJWTExpiration(t *testing.T) {
+	secret := []byte("secret")
+	auth := NewAuth(AuthConfig{HMACSecret: secret})
+
+	testCases := []struct {
+		name    string
+		claims  jwt.MapClaims
+		wantErr bool
+	}{
+		{name: "valid", claims: jwt.MapClaims{"sub": "alice", "exp": time.Now().Add(time.Hour).Unix()}},
+		{name: "within leeway", claims: jwt.MapClaims{"sub": "alice", "exp": time.Now().Add(-jwtLeeway / 2).Unix()}},
+		{name: "expired", claims: jwt.MapClaims{"sub": "alice", "exp": time.Now().Add(-time.Hour).Unix()}, wantErr: true},
+		{name: "without exp", claims: jwt.MapClaims{"sub": "alice"}, wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, tc.claims).SignedString(secret)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			r := httptest.NewRequest("GET", "/events_for_day", nil)
+			r.Header.Set("Authorization", "Bearer "+token)
+
+			var userID string
+			err = auth.Authenticate(func(w http.ResponseWriter, r *http.Request) error {
+				id, _ := userIDFromContext(r.Context())
+				userID = string(id)
+				return nil
+			})(httptest.NewRecorder(), r)
+
+			if tc.wantErr {
+				if !errors.Is(err, errUnauthorized) {
+					t.Errorf("expected %v, got %v", errUnauthorized, err)
+				}
+				return
+			}
+			if err != nil || userID != "alice" {
+				t.Errorf("expected alice, got %q and %v", userID, err)
+			}
+		})
+	}
+}
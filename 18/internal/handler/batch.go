@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"l2.18/pkg/models"
+)
+
+const (
+	// maxBatchOperations - максимальное количество операций в пакете.
+	maxBatchOperations = 1000
+	// maxBatchSize - максимальный размер тела запроса с пакетом.
+	maxBatchSize = 8 << 20
+)
+
+// batchRequest - тело запроса POST /v2/users/{user}/events/batch.
+type batchRequest struct {
+	// Atomic - выполнить операции все или ни одной.
+	Atomic     bool                    `json:"atomic"`
+	Operations []models.BatchOperation `json:"operations"`
+}
+
+// batchResponse - ответ на пакет: результаты операций в порядке запроса.
+type batchResponse struct {
+	Result []batchResult `json:"result"`
+}
+
+// batchResult - результат операции пакета в ответе.
+type batchResult struct {
+	// Status - HTTP-статус, которым ответил бы отдельный запрос с этой операцией.
+	Status int            `json:"status"`
+	ID     models.EventID `json:"id,omitempty"`
+	// Event - событие после выполнения пакета для create и update.
+	Event *models.Event `json:"event,omitempty"`
+	Error *apiErrorBody `json:"error,omitempty"`
+}
+
+// BatchV2 обрабатывает POST /v2/users/{user}/events/batch - пакет операций
+// create, update и delete над событиями пользователя. С atomic=true применяются
+// либо все операции, либо ни одной; иначе каждая выполняется независимо.
+// Отвечает 200, если все операции выполнены, и 207 с результатами по операциям
+// в том же порядке, если хотя бы одна не выполнена.
+func (eh *EventsHandler) BatchV2(w http.ResponseWriter, r *http.Request) error {
+	userID, err := resolveUserID(r, models.UserID(r.PathValue("user")))
+	if err != nil {
+		return err
+	}
+
+	var req batchRequest
+	if err := decodeJSONLimit(w, r, &req, maxBatchSize); err != nil {
+		return err
+	}
+	if len(req.Operations) > maxBatchOperations {
+		return fmt.Errorf("%w: at most %d operations allowed", errInvalidData, maxBatchOperations)
+	}
+	for i, op := range req.Operations {
+		if op.Event == nil {
+			continue
+		}
+		if err := localizeEvent(op.Event); err != nil {
+			return fmt.Errorf("operations[%d]: %w", i, err)
+		}
+	}
+
+	results, err := eh.service.Batch(userID, req.Operations, req.Atomic)
+	if err != nil {
+		return err
+	}
+
+	status := http.StatusOK
+	res := make([]batchResult, len(results))
+	for i, result := range results {
+		if result.Err != nil {
+			code, body := errorV2(result.Err)
+			res[i] = batchResult{Status: code, ID: result.EventID, Error: &body}
+			status = http.StatusMultiStatus
+			continue
+		}
+
+		res[i] = batchResult{Status: http.StatusOK, ID: result.EventID}
+		switch req.Operations[i].Op {
+		case models.BatchCreate:
+			res[i].Status = http.StatusCreated
+		case models.BatchDelete:
+			res[i].Status = http.StatusNoContent
+			continue
+		}
+		if event, err := eh.service.GetEvent(userID, result.EventID); err == nil {
+			res[i].Event = event
+		}
+	}
+
+	return writeJSON(w, status, batchResponse{Result: res})
+}
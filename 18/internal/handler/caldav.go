@@ -0,0 +1,413 @@
+package handler
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"l2.18/internal/service"
+	"l2.18/pkg/ical"
+	"l2.18/pkg/models"
+)
+
+// Календарь пользователя доступен только на чтение по адресам:
+//
+//	/caldav/{user}/                - принципал и домашняя коллекция календарей
+//	/caldav/{user}/events/         - календарь событий
+//	/caldav/{user}/events/{id}.ics - отдельное событие или серия
+const (
+	caldavRoot     = "/caldav/"
+	calendarName   = "events"
+	icsExtension   = ".ics"
+	icsContentType = "text/calendar; charset=utf-8"
+)
+
+// caldavTimeLayout - формат атрибутов CALDAV:time-range.
+const caldavTimeLayout = "20060102T150405Z"
+
+// CalDAVOptions обрабатывает OPTIONS /caldav/ и сообщает о поддержке CalDAV.
+func (eh *EventsHandler) CalDAVOptions(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("DAV", "1, calendar-access")
+	w.Header().Set("Allow", "OPTIONS, GET, HEAD, PROPFIND, REPORT")
+	w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+// CalDAVWellKnown обрабатывает /.well-known/caldav (RFC 6764).
+func (eh *EventsHandler) CalDAVWellKnown(w http.ResponseWriter, r *http.Request) error {
+	http.Redirect(w, r, caldavRoot, http.StatusMovedPermanently)
+	return nil
+}
+
+// PropfindRoot обрабатывает PROPFIND /caldav/. Без аутентификации принципал
+// определяется только адресом, поэтому корень сообщает лишь, что является коллекцией.
+func (eh *EventsHandler) PropfindRoot(w http.ResponseWriter, r *http.Request) error {
+	req, err := parsePropfind(r)
+	if err != nil {
+		return err
+	}
+
+	root := resource{
+		href: caldavRoot,
+		props: []davProp{
+			{XMLName: xml.Name{Space: nsDAV, Local: "resourcetype"}, InnerXML: `<collection xmlns="DAV:"/>`},
+		},
+	}
+
+	// Для аутентифицированного клиента сообщаем его принципала - так работает
+	// автоматическое обнаружение календаря через /.well-known/caldav.
+	if userID, ok := userIDFromContext(r.Context()); ok {
+		root.props = append(root.props,
+			hrefProp(xml.Name{Space: nsDAV, Local: "current-user-principal"}, principalHref(userID)))
+	}
+
+	return writeMultistatus(w, multistatus{Responses: []davResponse{
+		root.response(req.Prop, req.PropName != nil),
+	}})
+}
+
+// PropfindPrincipal обрабатывает PROPFIND /caldav/{user}/.
+func (eh *EventsHandler) PropfindPrincipal(w http.ResponseWriter, r *http.Request) error {
+	userID, err := resolveUserID(r, models.UserID(r.PathValue("user")))
+	if err != nil {
+		return err
+	}
+
+	req, err := parsePropfind(r)
+	if err != nil {
+		return err
+	}
+	d, err := depth(r)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errInvalidData, err)
+	}
+
+	responses := []davResponse{
+		principalResource(userID).response(req.Prop, req.PropName != nil),
+	}
+
+	if d > 0 {
+		calendar, err := eh.calendarResource(userID)
+		if err != nil {
+			return err
+		}
+		responses = append(responses, calendar.response(req.Prop, req.PropName != nil))
+	}
+
+	return writeMultistatus(w, multistatus{Responses: responses})
+}
+
+// PropfindCalendar обрабатывает PROPFIND /caldav/{user}/events/. С Depth: 1
+// в ответ также попадают все события календаря.
+func (eh *EventsHandler) PropfindCalendar(w http.ResponseWriter, r *http.Request) error {
+	userID, err := resolveUserID(r, models.UserID(r.PathValue("user")))
+	if err != nil {
+		return err
+	}
+
+	req, err := parsePropfind(r)
+	if err != nil {
+		return err
+	}
+	d, err := depth(r)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errInvalidData, err)
+	}
+
+	calendar, err := eh.calendarResource(userID)
+	if err != nil {
+		return err
+	}
+	responses := []davResponse{calendar.response(req.Prop, req.PropName != nil)}
+
+	if d > 0 {
+		res, err := eh.service.ListEvents(userID, exportFrom, exportTo, models.EventFilter{})
+		if err != nil {
+			return err
+		}
+
+		for _, event := range res {
+			resource, err := eventResource(userID, event)
+			if err != nil {
+				return err
+			}
+			responses = append(responses, resource.response(req.Prop, req.PropName != nil))
+		}
+	}
+
+	return writeMultistatus(w, multistatus{Responses: responses})
+}
+
+// PropfindEvent обрабатывает PROPFIND /caldav/{user}/events/{id}.ics.
+func (eh *EventsHandler) PropfindEvent(w http.ResponseWriter, r *http.Request) error {
+	userID, err := resolveUserID(r, models.UserID(r.PathValue("user")))
+	if err != nil {
+		return err
+	}
+
+	event, err := eh.getEventResource(userID, r.PathValue("file"))
+	if err != nil {
+		return err
+	}
+
+	req, err := parsePropfind(r)
+	if err != nil {
+		return err
+	}
+
+	resource, err := eventResource(userID, *event)
+	if err != nil {
+		return err
+	}
+
+	return writeMultistatus(w, multistatus{Responses: []davResponse{
+		resource.response(req.Prop, req.PropName != nil),
+	}})
+}
+
+// GetEventICS обрабатывает GET /caldav/{user}/events/{id}.ics.
+func (eh *EventsHandler) GetEventICS(w http.ResponseWriter, r *http.Request) error {
+	userID, err := resolveUserID(r, models.UserID(r.PathValue("user")))
+	if err != nil {
+		return err
+	}
+
+	event, err := eh.getEventResource(userID, r.PathValue("file"))
+	if err != nil {
+		return err
+	}
+
+	etag := eventETag(*event)
+	if notModified(w, r, etag) {
+		return nil
+	}
+
+	w.Header().Set("Content-Type", icsContentType)
+	w.Header().Set("ETag", etag)
+
+	return ical.Encode(w, []models.Event{*event})
+}
+
+// ReportCalendar обрабатывает REPORT /caldav/{user}/events/: calendar-query
+// с необязательным фильтром time-range и calendar-multiget.
+func (eh *EventsHandler) ReportCalendar(w http.ResponseWriter, r *http.Request) error {
+	userID, err := resolveUserID(r, models.UserID(r.PathValue("user")))
+	if err != nil {
+		return err
+	}
+
+	var req reportRequest
+	ok, err := decodeXML(r.Body, &req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errInvalidData, err)
+	}
+	if !ok {
+		return fmt.Errorf("%w: empty report", errInvalidData)
+	}
+
+	names := req.Prop
+	if req.AllProp != nil {
+		names = nil
+	}
+
+	var responses []davResponse
+
+	switch req.XMLName {
+	case xml.Name{Space: nsCalDAV, Local: "calendar-query"}:
+		start, end, err := queryRange(req.Filter)
+		if err != nil {
+			return fmt.Errorf("%w: %v", errInvalidData, err)
+		}
+
+		res, err := eh.service.ListEvents(userID, start, end, models.EventFilter{})
+		if err != nil {
+			return err
+		}
+
+		for _, event := range res {
+			resource, err := eventResource(userID, event)
+			if err != nil {
+				return err
+			}
+			responses = append(responses, resource.response(names, false))
+		}
+
+	case xml.Name{Space: nsCalDAV, Local: "calendar-multiget"}:
+		for _, href := range req.Hrefs {
+			file, err := url.PathUnescape(path.Base(strings.TrimSpace(href)))
+			if err != nil {
+				return fmt.Errorf("%w: %v", errInvalidData, err)
+			}
+
+			event, err := eh.getEventResource(userID, file)
+			if errors.Is(err, errNotFound) {
+				responses = append(responses, davResponse{Href: href, Status: statusLine(http.StatusNotFound)})
+				continue
+			} else if err != nil {
+				return err
+			}
+
+			resource, err := eventResource(userID, *event)
+			if err != nil {
+				return err
+			}
+			responses = append(responses, resource.response(names, false))
+		}
+
+	default:
+		return fmt.Errorf("%w: unsupported report %s", errInvalidData, req.XMLName.Local)
+	}
+
+	return writeMultistatus(w, multistatus{Responses: responses})
+}
+
+// getEventResource возвращает событие по имени ресурса {id}.ics.
+func (eh *EventsHandler) getEventResource(userID models.UserID, file string) (*models.Event, error) {
+	eventID, ok := strings.CutSuffix(file, icsExtension)
+	if !ok || eventID == "" {
+		return nil, fmt.Errorf("%w: %s", errNotFound, file)
+	}
+
+	event, err := eh.service.GetEvent(userID, models.EventID(eventID))
+	if errors.Is(err, service.ErrNotFound) {
+		return nil, fmt.Errorf("%w: %s", errNotFound, file)
+	} else if err != nil {
+		return nil, err
+	}
+
+	return event, nil
+}
+
+// calendarResource описывает календарь пользователя. CTag меняется при любом
+// изменении событий, по нему клиенты решают, нужна ли синхронизация.
+func (eh *EventsHandler) calendarResource(userID models.UserID) (resource, error) {
+	res, err := eh.service.ListEvents(userID, exportFrom, exportTo, models.EventFilter{})
+	if err != nil {
+		return resource{}, err
+	}
+
+	h := sha256.New()
+	for _, event := range res {
+		fmt.Fprintf(h, "%s %s\n", event.ID, eventETag(event))
+	}
+	ctag := `"` + hex.EncodeToString(h.Sum(nil))[:32] + `"`
+
+	return resource{
+		href: calendarHref(userID),
+		props: []davProp{
+			{
+				XMLName:  xml.Name{Space: nsDAV, Local: "resourcetype"},
+				InnerXML: `<collection xmlns="DAV:"/><calendar xmlns="urn:ietf:params:xml:ns:caldav"/>`,
+			},
+			textProp(xml.Name{Space: nsDAV, Local: "displayname"}, string(userID)),
+			{
+				XMLName:  xml.Name{Space: nsCalDAV, Local: "supported-calendar-component-set"},
+				InnerXML: `<comp xmlns="urn:ietf:params:xml:ns:caldav" name="VEVENT"/>`,
+			},
+			{
+				XMLName: xml.Name{Space: nsDAV, Local: "current-user-privilege-set"},
+				InnerXML: `<privilege xmlns="DAV:"><read/></privilege>` +
+					`<privilege xmlns="DAV:"><read-current-user-privilege-set/></privilege>`,
+			},
+			hrefProp(xml.Name{Space: nsDAV, Local: "current-user-principal"}, principalHref(userID)),
+			textProp(xml.Name{Space: nsCS, Local: "getctag"}, ctag),
+			textProp(xml.Name{Space: nsDAV, Local: "getetag"}, ctag),
+		},
+	}, nil
+}
+
+// principalResource описывает принципала пользователя, он же домашняя коллекция календарей.
+func principalResource(userID models.UserID) resource {
+	href := principalHref(userID)
+
+	return resource{
+		href: href,
+		props: []davProp{
+			{
+				XMLName:  xml.Name{Space: nsDAV, Local: "resourcetype"},
+				InnerXML: `<collection xmlns="DAV:"/><principal xmlns="DAV:"/>`,
+			},
+			textProp(xml.Name{Space: nsDAV, Local: "displayname"}, string(userID)),
+			hrefProp(xml.Name{Space: nsDAV, Local: "current-user-principal"}, href),
+			hrefProp(xml.Name{Space: nsDAV, Local: "principal-URL"}, href),
+			hrefProp(xml.Name{Space: nsCalDAV, Local: "calendar-home-set"}, href),
+		},
+	}
+}
+
+// eventResource описывает событие календаря вместе с его данными iCalendar.
+func eventResource(userID models.UserID, event models.Event) (resource, error) {
+	etag := eventETag(event)
+
+	var data bytes.Buffer
+	if err := ical.Encode(&data, []models.Event{event}); err != nil {
+		return resource{}, err
+	}
+
+	return resource{
+		href: calendarHref(userID) + url.PathEscape(string(event.ID)) + icsExtension,
+		props: []davProp{
+			{XMLName: xml.Name{Space: nsDAV, Local: "resourcetype"}},
+			textProp(xml.Name{Space: nsDAV, Local: "getcontenttype"}, icsContentType),
+			textProp(xml.Name{Space: nsDAV, Local: "getetag"}, etag),
+			textProp(xml.Name{Space: nsCalDAV, Local: "calendar-data"}, data.String()),
+		},
+	}, nil
+}
+
+// queryRange возвращает диапазон фильтра time-range для VEVENT.
+// Без фильтра возвращается диапазон экспорта по умолчанию.
+func queryRange(filter *davFilter) (time.Time, time.Time, error) {
+	start, end := exportFrom, exportTo
+	if filter == nil {
+		return start, end, nil
+	}
+
+	comp := filter.Comp.find("VEVENT")
+	if comp == nil || comp.TimeRange == nil {
+		return start, end, nil
+	}
+
+	var err error
+	if v := comp.TimeRange.Start; v != "" {
+		if start, err = time.Parse(caldavTimeLayout, v); err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+	}
+	if v := comp.TimeRange.End; v != "" {
+		if end, err = time.Parse(caldavTimeLayout, v); err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+	}
+
+	return start, end, nil
+}
+
+// parsePropfind разбирает тело PROPFIND. Пустое тело равносильно allprop.
+func parsePropfind(r *http.Request) (propfindRequest, error) {
+	var req propfindRequest
+	if _, err := decodeXML(r.Body, &req); err != nil {
+		return req, fmt.Errorf("%w: %v", errInvalidData, err)
+	}
+	if req.AllProp != nil {
+		req.Prop = nil
+	}
+
+	return req, nil
+}
+
+func principalHref(userID models.UserID) string {
+	return caldavRoot + url.PathEscape(string(userID)) + "/"
+}
+
+func calendarHref(userID models.UserID) string {
+	return principalHref(userID) + calendarName + "/"
+}
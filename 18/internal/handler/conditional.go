@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"l2.18/internal/service"
+	"l2.18/pkg/models"
+)
+
+// eventETag возвращает ETag события - его версию.
+func eventETag(event models.Event) string {
+	return `"` + strconv.FormatInt(event.Version, 10) + `"`
+}
+
+// ifMatch возвращает ожидаемую версию события из заголовка If-Match.
+// Без заголовка и для "*" вернет 0 - изменение без проверки версии.
+// ETag, который не может совпасть ни с одной версией (в том числе слабый),
+// сразу дает service.ErrVersionMismatch.
+func ifMatch(r *http.Request) (int64, error) {
+	v := strings.TrimSpace(r.Header.Get("If-Match"))
+	if v == "" || v == "*" {
+		return 0, nil
+	}
+	if strings.Contains(v, ",") {
+		return 0, fmt.Errorf("%w: If-Match must contain a single ETag", errInvalidData)
+	}
+
+	version, err := strconv.ParseInt(strings.Trim(v, `"`), 10, 64)
+	if err != nil || version < 1 || !strings.HasPrefix(v, `"`) {
+		return 0, service.ErrVersionMismatch
+	}
+
+	return version, nil
+}
+
+// notModified отвечает 304 Not Modified, если ETag события совпадает
+// с одним из ETag в заголовке If-None-Match.
+func notModified(w http.ResponseWriter, r *http.Request, etag string) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+
+	for _, tag := range strings.Split(header, ",") {
+		// Для If-None-Match используется слабое сравнение.
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == etag || tag == "*" {
+			w.Header().Set("ETag", etag)
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSConfig - политика CORS для браузерных клиентов.
+type CORSConfig struct {
+	// AllowedOrigins - разрешенные источники, * - любой. Пустой список отключает CORS.
+	AllowedOrigins []string
+	AllowedMethods []string
+	AllowedHeaders []string
+	// MaxAge - сколько браузер может кешировать ответ на предварительный запрос.
+	MaxAge time.Duration
+}
+
+// corsExposedHeaders - заголовки ответа, которые браузер показывает клиенту.
+var corsExposedHeaders = strings.Join([]string{"ETag", "Location", "Retry-After", "Idempotent-Replayed"}, ", ")
+
+// CORS добавляет к ответам заголовки CORS для разрешенных источников
+// и отвечает на предварительные запросы (OPTIONS с Access-Control-Request-Method).
+type CORS struct {
+	cfg     CORSConfig
+	methods string
+	headers string
+}
+
+// NewCORS создает новый CORS.
+func NewCORS(cfg CORSConfig) *CORS {
+	return &CORS{
+		cfg:     cfg,
+		methods: strings.Join(cfg.AllowedMethods, ", "),
+		headers: strings.Join(cfg.AllowedHeaders, ", "),
+	}
+}
+
+// Handler оборачивает h. Запросы без Origin и с неразрешенным Origin передаются
+// в h без заголовков CORS: браузер сам не отдаст ответ странице.
+func (c *CORS) Handler(h http.Handler) http.Handler {
+	if len(c.cfg.AllowedOrigins) == 0 {
+		return h
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		w.Header().Add("Vary", "Origin")
+
+		if origin == "" || !c.allowed(origin) {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.Header().Set("Access-Control-Allow-Methods", c.methods)
+			w.Header().Set("Access-Control-Allow-Headers", c.headers)
+			if c.cfg.MaxAge > 0 {
+				w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(c.cfg.MaxAge.Seconds())))
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		w.Header().Set("Access-Control-Expose-Headers", corsExposedHeaders)
+		h.ServeHTTP(w, r)
+	})
+}
+
+func (c *CORS) allowed(origin string) bool {
+	return slices.Contains(c.cfg.AllowedOrigins, "*") || slices.Contains(c.cfg.AllowedOrigins, origin)
+}
@@ -0,0 +1,242 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Пространства имен XML, используемые WebDAV и CalDAV.
+const (
+	nsDAV    = "DAV:"
+	nsCalDAV = "urn:ietf:params:xml:ns:caldav"
+	nsCS     = "http://calendarserver.org/ns/"
+)
+
+// propNames - имена свойств, перечисленных в элементе DAV:prop запроса.
+type propNames []xml.Name
+
+func (p *propNames) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			*p = append(*p, t.Name)
+			if err := d.Skip(); err != nil {
+				return err
+			}
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// propfindRequest - тело запроса PROPFIND (RFC 4918, 14.20).
+type propfindRequest struct {
+	XMLName  xml.Name  `xml:"DAV: propfind"`
+	AllProp  *struct{} `xml:"DAV: allprop"`
+	PropName *struct{} `xml:"DAV: propname"`
+	Prop     propNames `xml:"DAV: prop"`
+}
+
+// reportRequest - тело запроса REPORT: calendar-query или calendar-multiget (RFC 4791, 9.5 и 9.10).
+type reportRequest struct {
+	XMLName xml.Name
+	AllProp *struct{}  `xml:"DAV: allprop"`
+	Prop    propNames  `xml:"DAV: prop"`
+	Hrefs   []string   `xml:"DAV: href"`
+	Filter  *davFilter `xml:"urn:ietf:params:xml:ns:caldav filter"`
+}
+
+type davFilter struct {
+	Comp compFilter `xml:"urn:ietf:params:xml:ns:caldav comp-filter"`
+}
+
+type compFilter struct {
+	Name      string       `xml:"name,attr"`
+	TimeRange *timeRange   `xml:"urn:ietf:params:xml:ns:caldav time-range"`
+	Comps     []compFilter `xml:"urn:ietf:params:xml:ns:caldav comp-filter"`
+}
+
+type timeRange struct {
+	Start string `xml:"start,attr"`
+	End   string `xml:"end,attr"`
+}
+
+// find возвращает фильтр компонента name, если он есть в дереве фильтров.
+func (f *compFilter) find(name string) *compFilter {
+	if strings.EqualFold(f.Name, name) {
+		return f
+	}
+
+	for i := range f.Comps {
+		if found := f.Comps[i].find(name); found != nil {
+			return found
+		}
+	}
+
+	return nil
+}
+
+// multistatus - ответ 207 Multi-Status (RFC 4918, 14.16).
+type multistatus struct {
+	XMLName   xml.Name      `xml:"DAV: multistatus"`
+	Responses []davResponse `xml:"response"`
+}
+
+type davResponse struct {
+	Href      string     `xml:"href"`
+	Status    string     `xml:"status,omitempty"`
+	Propstats []propstat `xml:"propstat,omitempty"`
+}
+
+type propstat struct {
+	Prop   davProps `xml:"prop"`
+	Status string   `xml:"status"`
+}
+
+type davProps struct {
+	Props []davProp `xml:",any"`
+}
+
+// davProp - свойство ресурса. InnerXML уже экранирован.
+type davProp struct {
+	XMLName  xml.Name
+	InnerXML string `xml:",innerxml"`
+}
+
+// textProp создает свойство с текстовым значением.
+func textProp(name xml.Name, value string) davProp {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(value))
+
+	return davProp{XMLName: name, InnerXML: b.String()}
+}
+
+// hrefProp создает свойство, содержащее DAV:href.
+func hrefProp(name xml.Name, href string) davProp {
+	var b strings.Builder
+	b.WriteString(`<href xmlns="DAV:">`)
+	_ = xml.EscapeText(&b, []byte(href))
+	b.WriteString(`</href>`)
+
+	return davProp{XMLName: name, InnerXML: b.String()}
+}
+
+// resource описывает свойства одного ресурса DAV.
+type resource struct {
+	href  string
+	props []davProp
+}
+
+// response отбирает запрошенные свойства ресурса. Для names == nil
+// возвращаются все свойства. Неизвестные свойства попадают в propstat 404.
+func (res resource) response(names propNames, nameOnly bool) davResponse {
+	var found, missing []davProp
+
+	if names == nil {
+		found = res.props
+	} else {
+		for _, name := range names {
+			prop, ok := res.prop(name)
+			if ok {
+				found = append(found, prop)
+			} else {
+				missing = append(missing, davProp{XMLName: name})
+			}
+		}
+	}
+
+	if nameOnly {
+		names := make([]davProp, len(found))
+		for i, prop := range found {
+			names[i] = davProp{XMLName: prop.XMLName}
+		}
+		found = names
+	}
+
+	resp := davResponse{Href: res.href}
+	if len(found) > 0 {
+		resp.Propstats = append(resp.Propstats, propstat{
+			Prop:   davProps{Props: found},
+			Status: statusLine(http.StatusOK),
+		})
+	}
+	if len(missing) > 0 {
+		resp.Propstats = append(resp.Propstats, propstat{
+			Prop:   davProps{Props: missing},
+			Status: statusLine(http.StatusNotFound),
+		})
+	}
+
+	return resp
+}
+
+func (res resource) prop(name xml.Name) (davProp, bool) {
+	for _, prop := range res.props {
+		if prop.XMLName == name {
+			return prop, true
+		}
+	}
+
+	return davProp{}, false
+}
+
+func statusLine(code int) string {
+	return fmt.Sprintf("HTTP/1.1 %d %s", code, http.StatusText(code))
+}
+
+// decodeXML разбирает тело запроса в v. Пустое тело не считается ошибкой.
+func decodeXML(r io.Reader, v any) (bool, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return false, err
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return false, nil
+	}
+
+	if err := xml.Unmarshal(data, v); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// writeMultistatus записывает ответ 207 Multi-Status.
+func writeMultistatus(w http.ResponseWriter, ms multistatus) error {
+	data, err := xml.Marshal(ms)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusMultiStatus)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// depth возвращает значение заголовка Depth. Для infinity и пустого заголовка
+// вернет 1: коллекции календаря не содержат вложенных коллекций.
+func depth(r *http.Request) (int, error) {
+	switch r.Header.Get("Depth") {
+	case "0":
+		return 0, nil
+	case "1", "infinity", "":
+		return 1, nil
+	default:
+		return 0, errors.New("invalid depth header")
+	}
+}
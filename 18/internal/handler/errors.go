@@ -3,3 +3,16 @@ package handler
 import "errors"
 
 var errInvalidData = errors.New("indalid input")
+
+// errNotFound возвращается, если ресурс CalDAV не найден.
+// В отличие от service.ErrNotFound отвечает 404.
+var errNotFound = errors.New("resource not found")
+
+// errUnauthorized возвращается, если запрос не содержит корректных учетных данных.
+var errUnauthorized = errors.New("unauthorized")
+
+// errForbidden возвращается, если пользователь обращается к чужим событиям.
+var errForbidden = errors.New("forbidden")
+
+// errTooManyRequests возвращается, если пользователь превысил ограничение частоты запросов.
+var errTooManyRequests = errors.New("too many requests")
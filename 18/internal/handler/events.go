@@ -9,15 +9,30 @@ import (
 	"time"
 
 	"l2.18/pkg/models"
+	"l2.18/pkg/search"
 )
 
 type eventsService interface {
-	AddEvent(userID models.UserID, event models.Event) error
+	AddEvent(userID models.UserID, event models.Event) (models.EventID, error)
 	UpdateEvent(userID models.UserID, event models.Event) error
-	RemoveEvent(userID models.UserID, eventID models.EventID) error
-	GetEventsForDay(userID models.UserID, day time.Time) ([]models.Event, error)
-	GetEventsForWeek(userID models.UserID, weekStart time.Time) ([]models.Event, error)
-	GetEventsForMonth(userID models.UserID, month time.Time) ([]models.Event, error)
+	ReplaceEvent(userID models.UserID, event models.Event) error
+	UpdateOccurrence(userID models.UserID, event models.Event, occurrence time.Time) error
+	RemoveEvent(userID models.UserID, eventID models.EventID, version int64) error
+	RemoveOccurrence(userID models.UserID, eventID models.EventID, occurrence time.Time, version int64) error
+	GetEvent(userID models.UserID, eventID models.EventID) (*models.Event, error)
+	GetEventsForDay(userID models.UserID, day time.Time, filter models.EventFilter) ([]models.Event, error)
+	GetEventsForWeek(userID models.UserID, weekStart time.Time, filter models.EventFilter) ([]models.Event, error)
+	GetEventsForMonth(userID models.UserID, month time.Time, filter models.EventFilter) ([]models.Event, error)
+	GetEvents(userID models.UserID, start, end time.Time, filter models.EventFilter) ([]models.Event, error)
+	ListEvents(userID models.UserID, start, end time.Time, filter models.EventFilter) ([]models.Event, error)
+	Conflicts(userID models.UserID, event models.Event) ([]models.Event, error)
+	FreeBusy(userIDs []models.UserID, start, end time.Time, duration time.Duration, limit int) (*models.FreeBusy, error)
+	RespondToInvitation(userID, owner models.UserID, eventID models.EventID, status models.RSVPStatus) error
+	GetInvitations(userID models.UserID) ([]models.Event, error)
+	Search(userID models.UserID, query search.Query, start, end time.Time) ([]models.Event, error)
+	History(userID models.UserID, eventID models.EventID) ([]models.HistoryEntry, error)
+	RestoreEvent(userID models.UserID, eventID models.EventID, revision, version int64) error
+	Batch(userID models.UserID, ops []models.BatchOperation, atomic bool) ([]models.BatchResult, error)
 }
 
 // EventsHandler обрабатывает CRUD событий.
@@ -33,10 +48,77 @@ func NewEventsHandler(service eventsService) *EventsHandler {
 type eventRequest struct {
 	UserID models.UserID `json:"user_id"`
 	Event  struct {
-		ID    string `json:"id"`
-		Date  string `json:"date"`
-		Event string `json:"event"`
+		ID string `json:"id"`
+		// Date - день события YYYY-MM-DD (событие без времени).
+		Date string `json:"date"`
+		// Start и End - начало и конец события в RFC 3339. Start имеет приоритет над Date.
+		Start    string   `json:"start"`
+		End      string   `json:"end"`
+		TimeZone string   `json:"timezone"`
+		Event    string   `json:"event"`
+		RRule    string   `json:"rrule"`
+		ExDates  []string `json:"exdates"`
+		// Attendees - идентификаторы приглашенных пользователей.
+		Attendees []models.UserID `json:"attendees"`
+
+		Location    string            `json:"location"`
+		Description string            `json:"description"`
+		Category    string            `json:"category"`
+		Color       string            `json:"color"`
+		Tags        []string          `json:"tags"`
+		Attributes  map[string]string `json:"attributes"`
 	}
+	// Occurrence - начало вхождения серии. Если задано, обновление
+	// касается только этого вхождения, иначе всей серии.
+	Occurrence string `json:"occurrence"`
+}
+
+// event собирает модель события из запроса. Время переводится в часовой пояс события.
+func (req *eventRequest) event() (models.Event, error) {
+	loc, err := parseLocation(req.Event.TimeZone)
+	if err != nil {
+		return models.Event{}, err
+	}
+
+	start := req.Event.Start
+	if start == "" {
+		start = req.Event.Date
+	}
+
+	event := models.Event{
+		ID:          models.EventID(req.Event.ID),
+		TimeZone:    req.Event.TimeZone,
+		Event:       req.Event.Event,
+		Place:       req.Event.Location,
+		Description: req.Event.Description,
+		Category:    req.Event.Category,
+		Color:       req.Event.Color,
+		Tags:        req.Event.Tags,
+		Attributes:  req.Event.Attributes,
+		RRule:       req.Event.RRule,
+	}
+
+	if start != "" {
+		if event.Date, err = parseTime(start, loc); err != nil {
+			return models.Event{}, err
+		}
+	}
+	if req.Event.End != "" {
+		if event.End, err = parseTime(req.Event.End, loc); err != nil {
+			return models.Event{}, err
+		}
+	}
+	if event.ExDates, err = parseDates(req.Event.ExDates, loc); err != nil {
+		return models.Event{}, err
+	}
+	if req.Event.Attendees != nil {
+		event.Attendees = make([]models.Attendee, len(req.Event.Attendees))
+		for i, id := range req.Event.Attendees {
+			event.Attendees[i] = models.Attendee{UserID: id}
+		}
+	}
+
+	return event, nil
 }
 
 type eventResponse struct {
@@ -47,7 +129,7 @@ type eventResponse struct {
 func (eh *EventsHandler) CreateEvent(w http.ResponseWriter, r *http.Request) error {
 	data, err := io.ReadAll(r.Body)
 	if err != nil || len(data) == 0 {
-		return fmt.Errorf("%w: %v", errInvalidData, err)
+		return fmt.Errorf("%w: %w", errInvalidData, err)
 	}
 	defer func() {
 		err := r.Body.Close()
@@ -63,14 +145,21 @@ func (eh *EventsHandler) CreateEvent(w http.ResponseWriter, r *http.Request) err
 		return fmt.Errorf("%w: %v", errInvalidData, err)
 	}
 
-	parsedDate, err := time.Parse("2006-01-02", req.Event.Date)
+	req.UserID, err = resolveUserID(r, req.UserID)
 	if err != nil {
-		return fmt.Errorf("%w: %v", errInvalidData, err)
+		return err
 	}
 
-	event := models.Event{Date: parsedDate, Event: req.Event.Event}
+	event, err := req.event()
+	if err != nil {
+		return fmt.Errorf("%w: %v", errInvalidData, err)
+	}
+	if event.Date.IsZero() {
+		return fmt.Errorf("%w: start or date required", errInvalidData)
+	}
+	event.ID = ""
 
-	err = eh.service.AddEvent(req.UserID, event)
+	_, err = eh.service.AddEvent(req.UserID, event)
 	if err != nil {
 		return err
 	}
@@ -85,7 +174,7 @@ func (eh *EventsHandler) CreateEvent(w http.ResponseWriter, r *http.Request) err
 func (eh *EventsHandler) UpdateEvent(w http.ResponseWriter, r *http.Request) error {
 	data, err := io.ReadAll(r.Body)
 	if err != nil || len(data) == 0 {
-		return fmt.Errorf("%w: %v", errInvalidData, err)
+		return fmt.Errorf("%w: %w", errInvalidData, err)
 	}
 	defer func() {
 		err := r.Body.Close()
@@ -101,12 +190,35 @@ func (eh *EventsHandler) UpdateEvent(w http.ResponseWriter, r *http.Request) err
 		return fmt.Errorf("%w: %v", errInvalidData, err)
 	}
 
-	parsedDate, err := time.Parse("2006-01-02", req.Event.Date)
+	req.UserID, err = resolveUserID(r, req.UserID)
+	if err != nil {
+		return err
+	}
+
+	event, err := req.event()
 	if err != nil {
 		return fmt.Errorf("%w: %v", errInvalidData, err)
 	}
+	if event.Version, err = ifMatch(r); err != nil {
+		return err
+	}
+
+	if req.Occurrence != "" {
+		loc, _ := parseLocation(req.Event.TimeZone)
+
+		occurrence, err := parseTime(req.Occurrence, loc)
+		if err != nil {
+			return fmt.Errorf("%w: %v", errInvalidData, err)
+		}
 
-	event := models.Event{ID: models.EventID(req.Event.ID), Date: parsedDate, Event: req.Event.Event}
+		err = eh.service.UpdateOccurrence(req.UserID, event, occurrence)
+		if err != nil {
+			return err
+		}
+
+		w.WriteHeader(http.StatusOK)
+		return nil
+	}
 
 	err = eh.service.UpdateEvent(req.UserID, event)
 	if err != nil {
@@ -121,9 +233,9 @@ func (eh *EventsHandler) UpdateEvent(w http.ResponseWriter, r *http.Request) err
 //
 // ! В тз описано POST, желательно заменить на DELETE.
 func (eh *EventsHandler) DeleteEvent(w http.ResponseWriter, r *http.Request) error {
-	userID := r.FormValue("user_id")
-	if userID == "" {
-		return errInvalidData
+	userID, err := resolveUserID(r, models.UserID(r.FormValue("user_id")))
+	if err != nil {
+		return err
 	}
 
 	eventID := r.FormValue("id")
@@ -131,7 +243,32 @@ func (eh *EventsHandler) DeleteEvent(w http.ResponseWriter, r *http.Request) err
 		return errInvalidData
 	}
 
-	err := eh.service.RemoveEvent(models.UserID(userID), models.EventID(eventID))
+	version, err := ifMatch(r)
+	if err != nil {
+		return err
+	}
+
+	if occurrence := r.FormValue("occurrence"); occurrence != "" {
+		loc, err := parseLocation(r.FormValue("timezone"))
+		if err != nil {
+			return fmt.Errorf("%w: %v", errInvalidData, err)
+		}
+
+		t, err := parseTime(occurrence, loc)
+		if err != nil {
+			return fmt.Errorf("%w: %v", errInvalidData, err)
+		}
+
+		err = eh.service.RemoveOccurrence(userID, models.EventID(eventID), t, version)
+		if err != nil {
+			return err
+		}
+
+		w.WriteHeader(http.StatusOK)
+		return nil
+	}
+
+	err = eh.service.RemoveEvent(userID, models.EventID(eventID), version)
 	if err != nil {
 		return err
 	}
@@ -142,9 +279,14 @@ func (eh *EventsHandler) DeleteEvent(w http.ResponseWriter, r *http.Request) err
 
 // EventsForDay обрабатывает GET /events_for_day.
 func (eh *EventsHandler) EventsForDay(w http.ResponseWriter, r *http.Request) error {
-	userID := r.FormValue("user_id")
-	if userID == "" {
-		return errInvalidData
+	userID, err := resolveUserID(r, models.UserID(r.FormValue("user_id")))
+	if err != nil {
+		return err
+	}
+
+	p, err := parsePage(r, 0)
+	if err != nil {
+		return err
 	}
 
 	day := r.FormValue("date")
@@ -152,28 +294,34 @@ func (eh *EventsHandler) EventsForDay(w http.ResponseWriter, r *http.Request) er
 		return errInvalidData
 	}
 
-	t, err := time.Parse("2006-01-02", day)
+	loc, err := parseLocation(r.FormValue("timezone"))
 	if err != nil {
 		return fmt.Errorf("%w: %v", errInvalidData, err)
 	}
 
-	res, err := eh.service.GetEventsForDay(models.UserID(userID), t)
+	t, err := time.ParseInLocation("2006-01-02", day, loc)
 	if err != nil {
-		return err
+		return fmt.Errorf("%w: %v", errInvalidData, err)
 	}
 
-	if err := json.NewEncoder(w).Encode(eventResponse{Result: res}); err != nil {
+	res, err := eh.service.GetEventsForDay(userID, t, parseFilter(r))
+	if err != nil {
 		return err
 	}
 
-	return nil
+	return p.write(w, res)
 }
 
 // EventsForWeek обрабатывает GET /events_for_week.
 func (eh *EventsHandler) EventsForWeek(w http.ResponseWriter, r *http.Request) error {
-	userID := r.FormValue("user_id")
-	if userID == "" {
-		return errInvalidData
+	userID, err := resolveUserID(r, models.UserID(r.FormValue("user_id")))
+	if err != nil {
+		return err
+	}
+
+	p, err := parsePage(r, 0)
+	if err != nil {
+		return err
 	}
 
 	weekStart := r.FormValue("date")
@@ -181,28 +329,34 @@ func (eh *EventsHandler) EventsForWeek(w http.ResponseWriter, r *http.Request) e
 		return errInvalidData
 	}
 
-	t, err := time.Parse("2006-01-02", weekStart)
+	loc, err := parseLocation(r.FormValue("timezone"))
 	if err != nil {
 		return fmt.Errorf("%w: %v", errInvalidData, err)
 	}
 
-	res, err := eh.service.GetEventsForWeek(models.UserID(userID), t)
+	t, err := time.ParseInLocation("2006-01-02", weekStart, loc)
 	if err != nil {
-		return err
+		return fmt.Errorf("%w: %v", errInvalidData, err)
 	}
 
-	if err := json.NewEncoder(w).Encode(eventResponse{Result: res}); err != nil {
+	res, err := eh.service.GetEventsForWeek(userID, t, parseFilter(r))
+	if err != nil {
 		return err
 	}
 
-	return nil
+	return p.write(w, res)
 }
 
 // EventsForMonth обрабатывает GET /events_for_month.
 func (eh *EventsHandler) EventsForMonth(w http.ResponseWriter, r *http.Request) error {
-	userID := r.FormValue("user_id")
-	if userID == "" {
-		return errInvalidData
+	userID, err := resolveUserID(r, models.UserID(r.FormValue("user_id")))
+	if err != nil {
+		return err
+	}
+
+	p, err := parsePage(r, 0)
+	if err != nil {
+		return err
 	}
 
 	month := r.FormValue("date")
@@ -210,19 +364,52 @@ func (eh *EventsHandler) EventsForMonth(w http.ResponseWriter, r *http.Request)
 		return errInvalidData
 	}
 
-	t, err := time.Parse("2006-01-02", month)
+	loc, err := parseLocation(r.FormValue("timezone"))
 	if err != nil {
 		return fmt.Errorf("%w: %v", errInvalidData, err)
 	}
 
-	res, err := eh.service.GetEventsForMonth(models.UserID(userID), t)
+	t, err := time.ParseInLocation("2006-01-02", month, loc)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		return fmt.Errorf("%w: %v", errInvalidData, err)
 	}
 
-	if err := json.NewEncoder(w).Encode(eventResponse{Result: res}); err != nil {
+	res, err := eh.service.GetEventsForMonth(userID, t, parseFilter(r))
+	if err != nil {
 		return err
 	}
 
-	return nil
+	return p.write(w, res)
+}
+
+// EventsForRange обрабатывает GET /events_for_range - события, пересекающиеся
+// с произвольным диапазоном [from, to). Серии развернуты во вхождения.
+func (eh *EventsHandler) EventsForRange(w http.ResponseWriter, r *http.Request) error {
+	userID, err := resolveUserID(r, models.UserID(r.FormValue("user_id")))
+	if err != nil {
+		return err
+	}
+
+	p, err := parsePage(r, 0)
+	if err != nil {
+		return err
+	}
+
+	if r.FormValue("from") == "" || r.FormValue("to") == "" {
+		return fmt.Errorf("%w: from and to required", errInvalidData)
+	}
+	from, to, err := parseRange(r)
+	if err != nil {
+		return err
+	}
+	if !from.Before(to) {
+		return fmt.Errorf("%w: from must be before to", errInvalidData)
+	}
+
+	res, err := eh.service.GetEvents(userID, from, to, parseFilter(r))
+	if err != nil {
+		return err
+	}
+
+	return p.write(w, res)
 }
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"l2.18/pkg/models"
+)
+
+const (
+	// maxFreeBusyWindow - наибольшее окно запроса занятости.
+	maxFreeBusyWindow = 366 * 24 * time.Hour
+	// maxFreeBusyUsers - наибольшее число пользователей в запросе занятости.
+	maxFreeBusyUsers = 50
+	// defaultFreeSlots и maxFreeSlots - сколько свободных слотов возвращается по умолчанию и максимум.
+	defaultFreeSlots = 10
+	maxFreeSlots     = 100
+)
+
+// FreeBusyV2 обрабатывает GET /v2/freebusy - общую занятость пользователей users
+// в окне [from, to) и свободные для всех слоты длительностью duration.
+//
+// Занятость намеренно доступна любому пользователю арендатора, чтобы подбирать время
+// встречи до приглашения: ответ содержит только промежутки, без событий и их деталей.
+// Пользователи других арендаторов в users - другие пользователи, их занятость не видна.
+func (eh *EventsHandler) FreeBusyV2(w http.ResponseWriter, r *http.Request) error {
+	var userIDs []models.UserID
+	for _, u := range strings.Split(r.FormValue("users"), ",") {
+		if u = strings.TrimSpace(u); u != "" {
+			userIDs = append(userIDs, models.UserID(u))
+		}
+	}
+	if len(userIDs) == 0 {
+		return fmt.Errorf("%w: users required", errInvalidData)
+	}
+	if len(userIDs) > maxFreeBusyUsers {
+		return fmt.Errorf("%w: at most %d users allowed", errInvalidData, maxFreeBusyUsers)
+	}
+
+	loc, err := parseLocation(r.FormValue("timezone"))
+	if err != nil {
+		return fmt.Errorf("%w: %v", errInvalidData, err)
+	}
+
+	from, err := parseTime(r.FormValue("from"), loc)
+	if err != nil {
+		return fmt.Errorf("%w: from: %v", errInvalidData, err)
+	}
+	to, err := parseTime(r.FormValue("to"), loc)
+	if err != nil {
+		return fmt.Errorf("%w: to: %v", errInvalidData, err)
+	}
+	if !from.Before(to) || to.Sub(from) > maxFreeBusyWindow {
+		return fmt.Errorf("%w: window must be positive and at most %s", errInvalidData, maxFreeBusyWindow)
+	}
+
+	duration, err := time.ParseDuration(r.FormValue("duration"))
+	if err != nil || duration <= 0 {
+		return fmt.Errorf("%w: duration must be a positive duration like 30m", errInvalidData)
+	}
+
+	limit := defaultFreeSlots
+	if v := r.FormValue("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit < 1 || limit > maxFreeSlots {
+			return fmt.Errorf("%w: limit must be between 1 and %d", errInvalidData, maxFreeSlots)
+		}
+	}
+
+	res, err := eh.service.FreeBusy(userIDs, from, to, duration, limit)
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(w, http.StatusOK, res)
+}
+
+// EventConflictsV2 обрабатывает GET /v2/users/{user}/events/{id}/conflicts -
+// события (вхождения серий), пересекающиеся с событием id.
+func (eh *EventsHandler) EventConflictsV2(w http.ResponseWriter, r *http.Request) error {
+	userID, err := resolveUserID(r, models.UserID(r.PathValue("user")))
+	if err != nil {
+		return err
+	}
+
+	event, err := eh.service.GetEvent(userID, models.EventID(r.PathValue("id")))
+	if err != nil {
+		return err
+	}
+
+	res, err := eh.service.Conflicts(userID, *event)
+	if err != nil {
+		return err
+	}
+	if res == nil {
+		res = []models.Event{}
+	}
+
+	return writeJSON(w, http.StatusOK, eventResponse{Result: res})
+}
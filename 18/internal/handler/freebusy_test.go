@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"l2.18/internal/repository/memory"
+	"l2.18/internal/service/events"
+	"l2.18/internal/tenant"
+	"l2.18/pkg/models"
+)
+
+// Занятость видна всем пользователям арендатора, но не другим арендаторам,
+// и ответ не раскрывает детали событий.
+func TestFreeBusyTenantVisible(t *testing.T) {
+	day := time.Date(2025, time.February, 17, 0, 0, 0, 0, time.UTC)
+	store := memory.NewEventsRepository()
+
+	services := map[models.TenantID]*events.Service{}
+	service := func(id models.TenantID) *events.Service {
+		if services[id] == nil {
+			services[id] = events.New(tenant.NewRepository(store, id, models.TenantQuota{}))
+		}
+		return services[id]
+	}
+	tenants := NewTenants(func(id models.TenantID) *EventsHandler {
+		return NewEventsHandler(service(id))
+	}, nil)
+
+	_, err := service("acme").AddEvent("bob", models.Event{
+		Date: day.Add(10 * time.Hour), End: day.Add(11 * time.Hour), Event: "secret interview",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	freeBusy := func(id models.TenantID) (int, string) {
+		r := httptest.NewRequest(http.MethodGet,
+			"/v2/freebusy?users=bob&from=2025-02-17T09:00:00Z&to=2025-02-17T12:00:00Z&duration=30m", nil)
+		r = r.WithContext(withTenant(withUserID(r.Context(), "alice"), id))
+		w := httptest.NewRecorder()
+		if err := tenants.Events((*EventsHandler).FreeBusyV2)(w, r); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return w.Code, w.Body.String()
+	}
+
+	code, body := freeBusy("acme")
+	if code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", code, body)
+	}
+	var res map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(body), &res); err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != 2 || !strings.Contains(string(res["busy"]), "2025-02-17T10:00:00Z") {
+		t.Errorf("expected only busy and free with bob's interval, got %s", body)
+	}
+	if strings.Contains(body, "secret") {
+		t.Errorf("expected event details to be hidden, got %s", body)
+	}
+
+	// bob арендатора по умолчанию - другой пользователь, и он свободен.
+	_, body = freeBusy(tenant.Default)
+	if err := json.Unmarshal([]byte(body), &res); err != nil {
+		t.Fatal(err)
+	}
+	if string(res["busy"]) != "[]" {
+		t.Errorf("expected bob of another tenant to be free, got %s", body)
+	}
+}
+
+func TestFreeBusyTooManyUsers(t *testing.T) {
+	eh := NewEventsHandler(events.New(memory.NewEventsRepository()))
+
+	users := make([]string, maxFreeBusyUsers+1)
+	for i := range users {
+		users[i] = fmt.Sprintf("user%d", i)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/v2/freebusy?users="+strings.Join(users, ",")+
+		"&from=2025-02-17T09:00:00Z&to=2025-02-17T12:00:00Z&duration=30m", nil)
+	err := eh.FreeBusyV2(httptest.NewRecorder(), r)
+	if !errors.Is(err, errInvalidData) {
+		t.Errorf("expected %v, got %v", errInvalidData, err)
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "/v2/freebusy?users="+strings.Join(users[:maxFreeBusyUsers], ",")+
+		"&from=2025-02-17T09:00:00Z&to=2025-02-17T12:00:00Z&duration=30m", nil)
+	if err := eh.FreeBusyV2(httptest.NewRecorder(), r); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// healthTimeout - сколько ждать ответа хранилища при проверке здоровья.
+const healthTimeout = 2 * time.Second
+
+type pinger interface {
+	Ping(ctx context.Context) error
+}
+
+type healthResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Health отвечает на проверки живости и готовности сервера.
+type Health struct {
+	repo         pinger
+	shuttingDown atomic.Bool
+}
+
+// NewHealth создает новый Health, проверяющий хранилище repo.
+func NewHealth(repo pinger) *Health {
+	return &Health{repo: repo}
+}
+
+// SetShuttingDown отмечает, что сервер останавливается: с этого момента
+// Readyz отвечает 503, чтобы балансировщик перестал присылать запросы.
+func (h *Health) SetShuttingDown() {
+	h.shuttingDown.Store(true)
+}
+
+// Healthz обрабатывает GET /healthz - 200, если хранилище отвечает, иначе 503.
+// Обработчик не логирует запросы: его часто опрашивают.
+func (h *Health) Healthz(w http.ResponseWriter, r *http.Request) {
+	h.check(w, r, false)
+}
+
+// Readyz обрабатывает GET /readyz - то же, что Healthz, но во время остановки
+// сервера всегда 503.
+func (h *Health) Readyz(w http.ResponseWriter, r *http.Request) {
+	h.check(w, r, true)
+}
+
+func (h *Health) check(w http.ResponseWriter, r *http.Request, ready bool) {
+	if ready && h.shuttingDown.Load() {
+		_ = writeJSON(w, http.StatusServiceUnavailable, healthResponse{Status: "shutting down"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), healthTimeout)
+	defer cancel()
+
+	if err := h.repo.Ping(ctx); err != nil {
+		_ = writeJSON(w, http.StatusServiceUnavailable, healthResponse{Status: "unavailable", Error: err.Error()})
+		return
+	}
+
+	_ = writeJSON(w, http.StatusOK, healthResponse{Status: "ok"})
+}
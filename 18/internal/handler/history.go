@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"l2.18/pkg/models"
+)
+
+type historyResponse struct {
+	Result []models.HistoryEntry `json:"result"`
+}
+
+// HistoryV2 обрабатывает GET /v2/users/{user}/events/{id}/history - историю
+// изменений события, в том числе удаленного, в пределах срока хранения.
+func (eh *EventsHandler) HistoryV2(w http.ResponseWriter, r *http.Request) error {
+	userID, err := resolveUserID(r, models.UserID(r.PathValue("user")))
+	if err != nil {
+		return err
+	}
+
+	res, err := eh.service.History(userID, models.EventID(r.PathValue("id")))
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(w, http.StatusOK, historyResponse{Result: res})
+}
+
+// RestoreV2 обрабатывает POST /v2/users/{user}/events/{id}/restore - возврат события
+// к записи истории revision. Без revision восстанавливает удаленное событие.
+// If-Match проверяется так же, как в ReplaceEventV2.
+func (eh *EventsHandler) RestoreV2(w http.ResponseWriter, r *http.Request) error {
+	userID, err := resolveUserID(r, models.UserID(r.PathValue("user")))
+	if err != nil {
+		return err
+	}
+	eventID := models.EventID(r.PathValue("id"))
+
+	var revision int64
+	if s := r.URL.Query().Get("revision"); s != "" {
+		if revision, err = strconv.ParseInt(s, 10, 64); err != nil || revision < 1 {
+			return fmt.Errorf("%w: invalid revision", errInvalidData)
+		}
+	}
+
+	version, err := ifMatch(r)
+	if err != nil {
+		return err
+	}
+
+	if err := eh.service.RestoreEvent(userID, eventID, revision, version); err != nil {
+		return err
+	}
+
+	return eh.writeEvent(w, userID, eventID)
+}
@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"time"
+
+	"l2.18/pkg/ical"
+	"l2.18/pkg/models"
+)
+
+// maxICSSize - максимальный размер импортируемого файла.
+const maxICSSize = 10 << 20
+
+// Диапазон экспорта по умолчанию, если from или to не заданы.
+var (
+	exportFrom = time.Date(1900, time.January, 1, 0, 0, 0, 0, time.UTC)
+	exportTo   = time.Date(2200, time.January, 1, 0, 0, 0, 0, time.UTC)
+)
+
+type importResult struct {
+	UID    string         `json:"uid,omitempty"`
+	ID     models.EventID `json:"id,omitempty"`
+	Status string         `json:"status"`
+	Error  string         `json:"error,omitempty"`
+}
+
+type importResponse struct {
+	Imported int            `json:"imported"`
+	Failed   int            `json:"failed"`
+	Result   []importResult `json:"result"`
+}
+
+// ExportICS обрабатывает GET /export_ics. Параметры from и to (YYYY-MM-DD или RFC 3339)
+// ограничивают выгрузку событиями, пересекающимися с [from, to), а tag и category -
+// событиями с этими метками и категорией.
+func (eh *EventsHandler) ExportICS(w http.ResponseWriter, r *http.Request) error {
+	userID, err := resolveUserID(r, models.UserID(r.FormValue("user_id")))
+	if err != nil {
+		return err
+	}
+
+	loc, err := parseLocation(r.FormValue("timezone"))
+	if err != nil {
+		return fmt.Errorf("%w: %v", errInvalidData, err)
+	}
+
+	from, to := exportFrom, exportTo
+	if v := r.FormValue("from"); v != "" {
+		if from, err = parseTime(v, loc); err != nil {
+			return fmt.Errorf("%w: %v", errInvalidData, err)
+		}
+	}
+	if v := r.FormValue("to"); v != "" {
+		if to, err = parseTime(v, loc); err != nil {
+			return fmt.Errorf("%w: %v", errInvalidData, err)
+		}
+	}
+
+	res, err := eh.service.ListEvents(userID, from, to, parseFilter(r))
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="calendar.ics"`)
+
+	return ical.Encode(w, res)
+}
+
+// ImportICS обрабатывает POST /import_ics. Файл передается телом запроса
+// или полем file формы multipart/form-data. Каждый VEVENT добавляется отдельно,
+// в ответе перечислены результаты по каждому из них.
+func (eh *EventsHandler) ImportICS(w http.ResponseWriter, r *http.Request) error {
+	r.Body = http.MaxBytesReader(w, r.Body, maxICSSize)
+	defer func() {
+		err := r.Body.Close()
+		if err != nil {
+			log.Println("body was not closed: ", err)
+		}
+	}()
+
+	userID, err := resolveUserID(r, models.UserID(r.FormValue("user_id")))
+	if err != nil {
+		return err
+	}
+
+	var body io.Reader = r.Body
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "multipart/form-data" {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			return fmt.Errorf("%w: %v", errInvalidData, err)
+		}
+		defer file.Close()
+
+		body = file
+	}
+
+	items, err := ical.Decode(body)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errInvalidData, err)
+	}
+
+	resp := importResponse{Result: make([]importResult, 0, len(items))}
+	for _, item := range items {
+		result := importResult{UID: item.UID}
+
+		err := item.Err
+		if err == nil {
+			result.ID, err = eh.service.AddEvent(userID, item.Event)
+		}
+
+		if err != nil {
+			result.Status = "failed"
+			result.Error = err.Error()
+			resp.Failed++
+		} else {
+			result.Status = "created"
+			resp.Imported++
+		}
+
+		resp.Result = append(resp.Result, result)
+	}
+
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,154 @@
+package handler
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"l2.18/pkg/models"
+)
+
+// maxIdempotencyKeyLength - максимальная длина ключа идемпотентности.
+const maxIdempotencyKeyLength = 255
+
+// idempotentHeaders - заголовки ответа, которые сохраняются и повторяются вместе с ним.
+var idempotentHeaders = []string{"Content-Type", "Location", "ETag"}
+
+type idempotencyKeys interface {
+	Begin(userID models.UserID, key, fingerprint string) (*models.IdempotencyRecord, error)
+	Finish(record models.IdempotencyRecord) error
+	Abort(userID models.UserID, key string)
+}
+
+// Idempotency повторяет сохраненный ответ на запрос с заголовком Idempotency-Key
+// вместо его повторного выполнения.
+type Idempotency struct {
+	keys idempotencyKeys
+	log  logger
+}
+
+// NewIdempotency создает новый Idempotency.
+func NewIdempotency(keys idempotencyKeys, log logger) *Idempotency {
+	return &Idempotency{keys: keys, log: log}
+}
+
+// Wrap выполняет h для запроса с новым ключом и сохраняет успешный (2xx) ответ.
+// Повтор запроса с тем же ключом получает сохраненный ответ с заголовком
+// Idempotent-Replayed: true. Ошибки не сохраняются: повтор выполнит запрос снова.
+// Запросы без заголовка передаются в h как есть.
+func (i *Idempotency) Wrap(h ErrHandlerFunc) ErrHandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		key := r.Header.Get("Idempotency-Key")
+		if key == "" {
+			return h(w, r)
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			return fmt.Errorf("%w: idempotency key longer than %d characters", errInvalidData, maxIdempotencyKeyLength)
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			return fmt.Errorf("%w: %w", errInvalidData, err)
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		// Пользователи разных арендаторов с одинаковым ID ключи не делят.
+		userID := idempotencyUser(r, body)
+		if userID == "" {
+			return fmt.Errorf("%w: idempotency key requires user_id", errInvalidData)
+		}
+		userID = tenantUserKey(r, userID)
+
+		fp := fingerprint(r, body)
+		stored, err := i.keys.Begin(userID, key, fp)
+		if err != nil {
+			return err
+		}
+		if stored != nil {
+			for name, value := range stored.Header {
+				w.Header().Set(name, value)
+			}
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(stored.Status)
+			_, err := w.Write(stored.Body)
+			return err
+		}
+
+		rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		if err := h(rec, r); err != nil {
+			i.keys.Abort(userID, key)
+			return err
+		}
+		if rec.status < 200 || rec.status > 299 {
+			i.keys.Abort(userID, key)
+			return nil
+		}
+
+		record := models.IdempotencyRecord{
+			UserID:      userID,
+			Key:         key,
+			Fingerprint: fp,
+			Status:      rec.status,
+			Header:      make(map[string]string),
+			Body:        rec.body.Bytes(),
+		}
+		for _, name := range idempotentHeaders {
+			if value := w.Header().Get(name); value != "" {
+				record.Header[name] = value
+			}
+		}
+
+		// Ответ уже отправлен, поэтому ошибка сохранения только логируется.
+		if err := i.keys.Finish(record); err != nil {
+			i.log.Error("failed to save idempotent response", "key", key, "error", err.Error())
+		}
+		return nil
+	}
+}
+
+// idempotencyUser возвращает пользователя, ключи которого использует запрос.
+// Без аутентификации это пользователь из пути (v2) или поле user_id тела (v1).
+// Если пользователя нет, вернет пустой ID.
+func idempotencyUser(r *http.Request, body []byte) models.UserID {
+	if userID, ok := userIDFromContext(r.Context()); ok {
+		return userID
+	}
+	if userID := r.PathValue("user"); userID != "" {
+		return models.UserID(userID)
+	}
+
+	var req struct {
+		UserID models.UserID `json:"user_id"`
+	}
+	_ = json.Unmarshal(body, &req)
+	return req.UserID
+}
+
+// fingerprint возвращает хеш метода, адреса и тела запроса.
+func fingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s\n", r.Method, r.URL.RequestURI())
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// responseRecorder передает ответ клиенту, запоминая его статус и тело.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rr *responseRecorder) WriteHeader(status int) {
+	rr.status = status
+	rr.ResponseWriter.WriteHeader(status)
+}
+
+func (rr *responseRecorder) Write(p []byte) (int, error) {
+	rr.body.Write(p)
+	return rr.ResponseWriter.Write(p)
+}
@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"l2.18/internal/idempotency"
+	"l2.18/internal/repository/memory"
+)
+
+type nopLogger struct{}
+
+func (nopLogger) Info(msg string, args ...any)  {}
+func (nopLogger) Error(msg string, args ...any) {}
+
+func TestIdempotencyV1Users(t *testing.T) {
+	keys := idempotency.New(memory.NewEventsRepository(), time.Hour)
+
+	var calls int
+	h := NewIdempotency(keys, nopLogger{}).Wrap(func(w http.ResponseWriter, r *http.Request) error {
+		calls++
+		return writeJSON(w, http.StatusOK, map[string]string{"id": fmt.Sprint(calls)})
+	})
+
+	post := func(body string) (*httptest.ResponseRecorder, error) {
+		r := httptest.NewRequest(http.MethodPost, "/create_event", strings.NewReader(body))
+		r.Header.Set("Idempotency-Key", "abc")
+		w := httptest.NewRecorder()
+		return w, h(w, r)
+	}
+
+	alice := `{"user_id": "alice", "event": {"date": "2025-02-17", "event": "standup"}}`
+	bob := `{"user_id": "bob", "event": {"date": "2025-02-17", "event": "standup"}}`
+
+	first, _ := post(alice)
+	replayed, _ := post(alice)
+	if replayed.Header().Get("Idempotent-Replayed") != "true" || replayed.Body.String() != first.Body.String() {
+		t.Errorf("expected replayed response %q, got %q", first.Body, replayed.Body)
+	}
+
+	// Пользователь с тем же ключом не получает чужой ответ.
+	other, err := post(bob)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if other.Header().Get("Idempotent-Replayed") != "" || other.Body.String() == first.Body.String() {
+		t.Errorf("expected a new response for another user, got %q", other.Body)
+	}
+
+	if _, err := post(`{"event": {"date": "2025-02-17"}}`); !errors.Is(err, errInvalidData) {
+		t.Errorf("expected %v without user, got %v", errInvalidData, err)
+	}
+	if calls != 2 {
+		t.Errorf("expected 2 calls, got %d", calls)
+	}
+}
@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"errors"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type rateLimiter interface {
+	Allow(key string) (bool, time.Duration)
+}
+
+// RateLimit ограничивает частоту запросов каждого пользователя или арендатора.
+type RateLimit struct {
+	limiter rateLimiter
+	key     func(r *http.Request) string
+}
+
+// NewRateLimit создает RateLimit, ограничивающий запросы каждого пользователя.
+func NewRateLimit(limiter rateLimiter) *RateLimit {
+	return &RateLimit{limiter: limiter, key: clientKey}
+}
+
+// NewTenantRateLimit создает RateLimit, ограничивающий запросы всех
+// пользователей арендатора вместе. Ключ limiter - ID арендатора.
+func NewTenantRateLimit(limiter rateLimiter) *RateLimit {
+	return &RateLimit{limiter: limiter, key: func(r *http.Request) string {
+		return string(tenantFromContext(r.Context()))
+	}}
+}
+
+// Limit пропускает запрос к h, только если пользователь (арендатор) не превысил
+// ограничение, иначе отвечает 429 с заголовком Retry-After. Должен вызываться
+// после Authenticate: запросы аутентифицированного пользователя считаются
+// вместе, остальные - по IP.
+func (rl *RateLimit) Limit(h ErrHandlerFunc) ErrHandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		if ok, retryAfter := rl.limiter.Allow(rl.key(r)); !ok {
+			setRetryAfter(w, retryAfter)
+			return errTooManyRequests
+		}
+
+		return h(w, r)
+	}
+}
+
+type failureLimiter interface {
+	rateLimiter
+	Peek(key string) (bool, time.Duration)
+}
+
+// AuthFailureLimit ограничивает неудачные попытки аутентификации с каждого IP,
+// чтобы ключи API, пароли Basic и JWT нельзя было подбирать.
+type AuthFailureLimit struct {
+	limiter failureLimiter
+}
+
+// NewAuthFailureLimit создает AuthFailureLimit. Каждая неудачная попытка
+// расходует токен limiter; ключ - IP клиента.
+func NewAuthFailureLimit(limiter failureLimiter) *AuthFailureLimit {
+	return &AuthFailureLimit{limiter: limiter}
+}
+
+// Limit отвечает 429 с заголовком Retry-After на запросы с IP, исчерпавшего
+// неудачные попытки, и учитывает неудачную аутентификацию остальных.
+// Должен вызываться перед Authenticate: запросы с неверными учетными данными
+// до ограничения частоты пользователя не доходят.
+func (l *AuthFailureLimit) Limit(h ErrHandlerFunc) ErrHandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		key := ipKey(r)
+		if ok, retryAfter := l.limiter.Peek(key); !ok {
+			setRetryAfter(w, retryAfter)
+			return errTooManyRequests
+		}
+
+		err := h(w, r)
+		if errors.Is(err, errUnauthorized) {
+			l.limiter.Allow(key)
+		}
+		return err
+	}
+}
+
+// setRetryAfter задает заголовок Retry-After в целых секундах.
+func setRetryAfter(w http.ResponseWriter, retryAfter time.Duration) {
+	seconds := int64(math.Ceil(retryAfter.Seconds()))
+	w.Header().Set("Retry-After", strconv.FormatInt(seconds, 10))
+}
+
+// clientKey возвращает ключ ограничения частоты запросов: пользователя (с его
+// арендатором), если он аутентифицирован, иначе IP клиента. user_id без
+// аутентификации не учитывается: его может подставить кто угодно.
+func clientKey(r *http.Request) string {
+	if userID, ok := userIDFromContext(r.Context()); ok {
+		return "user:" + string(tenantUserKey(r, userID))
+	}
+	return ipKey(r)
+}
+
+// ipKey возвращает ключ ограничения частоты запросов по IP клиента.
+func ipKey(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// MaxBodySize ограничивает тело запроса n байтами. Чтение сверх лимита
+// возвращает *http.MaxBytesError, на который сервер отвечает 413.
+func MaxBodySize(n int64, h http.Handler) http.Handler {
+	if n <= 0 {
+		return h
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, n)
+		h.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"l2.18/internal/ratelimit"
+	"l2.18/internal/tenant"
+	"l2.18/pkg/models"
+)
+
+func TestClientKey(t *testing.T) {
+	testCases := []struct {
+		name     string
+		userID   models.UserID
+		tenant   models.TenantID
+		expected string
+	}{
+		{name: "anonymous", expected: "ip:192.0.2.1"},
+		{name: "default tenant", userID: "alice", tenant: tenant.Default, expected: "user:alice"},
+		{name: "other tenant", userID: "alice", tenant: "acme", expected: "user:acme/alice"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/events_for_day", nil)
+			if tc.userID != "" {
+				r = r.WithContext(withTenant(withUserID(r.Context(), tc.userID), tc.tenant))
+			}
+
+			if got := clientKey(r); got != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestAuthFailureLimit(t *testing.T) {
+	auth := NewAuth(AuthConfig{APIKeys: map[string]models.UserID{"secret": "alice"}})
+	h := NewAuthFailureLimit(ratelimit.New(0.001, 2)).Limit(auth.Authenticate(
+		func(w http.ResponseWriter, r *http.Request) error { return nil }))
+
+	request := func(key, addr string) (*httptest.ResponseRecorder, error) {
+		r := httptest.NewRequest("GET", "/events_for_day", nil)
+		r.RemoteAddr = addr
+		r.Header.Set("X-API-Key", key)
+		w := httptest.NewRecorder()
+		return w, h(w, r)
+	}
+
+	// Удачные запросы попыток не расходуют.
+	for range 3 {
+		if _, err := request("secret", "192.0.2.1:1234"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	for range 2 {
+		if _, err := request("guess", "192.0.2.1:1234"); !errors.Is(err, errUnauthorized) {
+			t.Fatalf("expected %v, got %v", errUnauthorized, err)
+		}
+	}
+
+	// После исчерпания попыток отклоняются и верные учетные данные с того же IP.
+	w, err := request("secret", "192.0.2.1:1234")
+	if !errors.Is(err, errTooManyRequests) || w.Header().Get("Retry-After") == "" {
+		t.Errorf("expected %v with Retry-After, got %v", errTooManyRequests, err)
+	}
+	if _, err := request("secret", "192.0.2.2:1234"); err != nil {
+		t.Errorf("expected another IP to be allowed, got %v", err)
+	}
+}
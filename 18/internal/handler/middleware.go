@@ -5,6 +5,8 @@ import (
 	"errors"
 	"net/http"
 
+	"l2.18/internal/idempotency"
+	"l2.18/internal/openapi"
 	"l2.18/internal/service"
 )
 
@@ -25,32 +27,143 @@ func NewMiddleware(log logger) *Middleware {
 
 // Logging выполняет логирование запроса и обработку ошибок.
 func (m *Middleware) Logging(h ErrHandlerFunc) http.HandlerFunc {
+	return m.handle(h, writeError)
+}
+
+// LoggingV2 выполняет логирование запроса и обработку ошибок API v2:
+// статус-коды соответствуют семантике HTTP, ошибка описывается apiError.
+func (m *Middleware) LoggingV2(h ErrHandlerFunc) http.HandlerFunc {
+	return m.handle(h, writeErrorV2)
+}
+
+// handle вызывает h и, если он вернул ошибку, логирует ее и отвечает через writeErr.
+func (m *Middleware) handle(h ErrHandlerFunc, writeErr func(w http.ResponseWriter, err error) int) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		m.log.Info("new request", "method", r.Method, "path", r.URL.Path)
 
-		var statusCode int
-
 		if err := h(w, r); err != nil {
-			switch {
-			case errors.Is(err, service.ErrAlreadyExist):
-				statusCode = http.StatusServiceUnavailable
-			case errors.Is(err, service.ErrNotFound):
-				statusCode = http.StatusServiceUnavailable
-			case errors.Is(err, errInvalidData):
-				statusCode = http.StatusBadRequest
-			default:
-				statusCode = http.StatusInternalServerError
-			}
+			statusCode := writeErr(w, err)
 
 			m.log.Error("request failed",
 				"method", r.Method,
 				"path", r.URL.Path,
 				"error", err.Error(),
 				"status", statusCode)
-
-			w.WriteHeader(statusCode)
-			w.Header().Set("Content-Type", "application/json")
-			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 		}
 	})
 }
+
+// writeError отвечает ошибкой API v1 и возвращает статус ответа.
+func writeError(w http.ResponseWriter, err error) int {
+	var statusCode int
+	var maxBytesErr *http.MaxBytesError
+
+	switch {
+	case errors.Is(err, service.ErrAlreadyExist):
+		statusCode = http.StatusServiceUnavailable
+	case errors.Is(err, service.ErrConflict):
+		statusCode = http.StatusConflict
+	case errors.Is(err, service.ErrVersionMismatch):
+		statusCode = http.StatusPreconditionFailed
+	case errors.Is(err, service.ErrNotFound):
+		statusCode = http.StatusServiceUnavailable
+	case errors.Is(err, errUnauthorized):
+		statusCode = http.StatusUnauthorized
+	case errors.Is(err, errForbidden), errors.Is(err, service.ErrQuotaExceeded):
+		statusCode = http.StatusForbidden
+	case errors.Is(err, errNotFound):
+		statusCode = http.StatusNotFound
+	case errors.Is(err, errTooManyRequests):
+		statusCode = http.StatusTooManyRequests
+	case errors.Is(err, idempotency.ErrInProgress):
+		statusCode = http.StatusConflict
+	case errors.Is(err, idempotency.ErrKeyReused):
+		statusCode = http.StatusUnprocessableEntity
+	case errors.As(err, &maxBytesErr):
+		statusCode = http.StatusRequestEntityTooLarge
+	case errors.Is(err, errInvalidData), errors.Is(err, service.ErrInvalidEvent):
+		statusCode = http.StatusBadRequest
+	default:
+		statusCode = http.StatusInternalServerError
+	}
+
+	w.WriteHeader(statusCode)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(errorResponse{Error: err.Error(), Fields: fieldErrors(err)})
+
+	return statusCode
+}
+
+// errorResponse - тело ответа с ошибкой API v1.
+type errorResponse struct {
+	Error string `json:"error"`
+	// Fields - ошибки по полям запроса, если он не прошел проверку по спецификации.
+	Fields []openapi.FieldError `json:"fields,omitempty"`
+}
+
+// apiError - тело ответа с ошибкой API v2.
+type apiError struct {
+	Error apiErrorBody `json:"error"`
+}
+
+type apiErrorBody struct {
+	// Code - машиночитаемый код ошибки, например not_found.
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// Details - ошибки по полям запроса, если он не прошел проверку по спецификации.
+	Details []openapi.FieldError `json:"details,omitempty"`
+}
+
+// writeErrorV2 отвечает ошибкой API v2 и возвращает статус ответа.
+func writeErrorV2(w http.ResponseWriter, err error) int {
+	statusCode, body := errorV2(err)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(apiError{Error: body})
+
+	return statusCode
+}
+
+// errorV2 возвращает статус и описание ошибки API v2.
+// Текст внутренних ошибок клиенту не раскрывается.
+func errorV2(err error) (int, apiErrorBody) {
+	statusCode, code, message := http.StatusInternalServerError, "internal", "internal error"
+	var maxBytesErr *http.MaxBytesError
+
+	switch {
+	case errors.Is(err, service.ErrAlreadyExist), errors.Is(err, service.ErrConflict):
+		statusCode, code = http.StatusConflict, "conflict"
+	case errors.Is(err, service.ErrVersionMismatch):
+		statusCode, code = http.StatusPreconditionFailed, "precondition_failed"
+	case errors.Is(err, service.ErrNotFound), errors.Is(err, errNotFound):
+		statusCode, code = http.StatusNotFound, "not_found"
+	case errors.Is(err, service.ErrBatchAborted):
+		statusCode, code = http.StatusFailedDependency, "aborted"
+	case errors.Is(err, errUnauthorized):
+		statusCode, code = http.StatusUnauthorized, "unauthorized"
+	case errors.Is(err, errForbidden):
+		statusCode, code = http.StatusForbidden, "forbidden"
+	case errors.Is(err, service.ErrQuotaExceeded):
+		statusCode, code = http.StatusForbidden, "quota_exceeded"
+	case errors.Is(err, errTooManyRequests):
+		statusCode, code = http.StatusTooManyRequests, "rate_limited"
+	case errors.Is(err, idempotency.ErrInProgress):
+		statusCode, code = http.StatusConflict, "request_in_progress"
+	case errors.Is(err, idempotency.ErrKeyReused):
+		statusCode, code = http.StatusUnprocessableEntity, "idempotency_key_reused"
+	case errors.As(err, &maxBytesErr):
+		statusCode, code = http.StatusRequestEntityTooLarge, "payload_too_large"
+	case errors.Is(err, errInvalidData), errors.Is(err, service.ErrInvalidEvent):
+		statusCode, code = http.StatusBadRequest, "invalid_input"
+	}
+	if statusCode != http.StatusInternalServerError {
+		message = err.Error()
+	}
+
+	return statusCode, apiErrorBody{
+		Code:    code,
+		Message: message,
+		Details: fieldErrors(err),
+	}
+}
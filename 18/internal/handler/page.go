@@ -0,0 +1,185 @@
+package handler
+
+import (
+	"cmp"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"l2.18/pkg/models"
+)
+
+// maxPageLimit - наибольший размер страницы списка событий. Он же используется
+// в API v2, если limit не задан.
+const maxPageLimit = 1000
+
+// eventFields - поля события, которые можно выбрать параметром fields.
+var eventFields = map[string]bool{
+	"id": true, "date": true, "end": true, "timezone": true, "event": true, "rrule": true,
+	"exdates": true, "overrides": true, "recurrence_id": true, "attendees": true, "owner": true,
+	"location": true, "description": true, "category": true, "color": true, "tags": true, "attributes": true,
+}
+
+// cursor - позиция в списке событий, упорядоченном по началу и айди.
+// Начало и айди однозначно определяют и вхождение серии.
+type cursor struct {
+	Date time.Time      `json:"d"`
+	ID   models.EventID `json:"i"`
+}
+
+// encode возвращает непрозрачное для клиента представление курсора.
+func (c cursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(s string) (*cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// compare сравнивает позицию курсора с событием в порядке по возрастанию.
+func (c cursor) compare(e models.Event) int {
+	return cmp.Or(c.Date.Compare(e.Date), cmp.Compare(c.ID, e.ID))
+}
+
+// page - параметры страницы списка событий: limit, cursor, order и fields.
+// Нулевой limit - без ограничения.
+type page struct {
+	limit  int
+	after  *cursor
+	desc   bool
+	fields []string
+}
+
+type pageResponse struct {
+	Result any `json:"result"`
+	// NextCursor - курсор следующей страницы. Пуст на последней странице.
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// parsePage разбирает параметры страницы из запроса. defaultLimit - размер
+// страницы, если limit не задан: v1 передает 0, чтобы клиенты без пагинации
+// получали списки целиком, как раньше.
+func parsePage(r *http.Request, defaultLimit int) (page, error) {
+	p := page{limit: defaultLimit}
+
+	var err error
+	if v := r.FormValue("limit"); v != "" {
+		if p.limit, err = strconv.Atoi(v); err != nil || p.limit < 1 || p.limit > maxPageLimit {
+			return page{}, fmt.Errorf("%w: limit must be between 1 and %d", errInvalidData, maxPageLimit)
+		}
+	}
+
+	if v := r.FormValue("cursor"); v != "" {
+		if p.after, err = decodeCursor(v); err != nil {
+			return page{}, fmt.Errorf("%w: invalid cursor", errInvalidData)
+		}
+	}
+
+	switch r.FormValue("order") {
+	case "", "asc":
+	case "desc":
+		p.desc = true
+	default:
+		return page{}, fmt.Errorf("%w: order must be asc or desc", errInvalidData)
+	}
+
+	if v := r.FormValue("fields"); v != "" {
+		for _, f := range strings.Split(v, ",") {
+			f = strings.TrimSpace(f)
+			if !eventFields[f] {
+				return page{}, fmt.Errorf("%w: unknown field %q", errInvalidData, f)
+			}
+			p.fields = append(p.fields, f)
+		}
+	}
+
+	return p, nil
+}
+
+// apply упорядочивает события и выбирает из них страницу. Вернет курсор
+// следующей страницы или пустую строку, если страница последняя.
+func (p page) apply(events []models.Event) ([]models.Event, string) {
+	sorted := slices.Clone(events)
+	slices.SortStableFunc(sorted, func(a, b models.Event) int {
+		c := cmp.Or(a.Date.Compare(b.Date), cmp.Compare(a.ID, b.ID))
+		if p.desc {
+			return -c
+		}
+		return c
+	})
+
+	if p.after != nil {
+		i := slices.IndexFunc(sorted, func(e models.Event) bool {
+			c := p.after.compare(e)
+			return p.desc && c > 0 || !p.desc && c < 0
+		})
+		if i == -1 {
+			i = len(sorted)
+		}
+		sorted = sorted[i:]
+	}
+
+	if p.limit == 0 || len(sorted) <= p.limit {
+		return sorted, ""
+	}
+
+	sorted = sorted[:p.limit]
+	last := sorted[len(sorted)-1]
+	return sorted, cursor{Date: last.Date, ID: last.ID}.encode()
+}
+
+// write отвечает страницей событий. Если задан fields, у событий остаются только эти поля.
+func (p page) write(w http.ResponseWriter, events []models.Event) error {
+	events, next := p.apply(events)
+
+	res := pageResponse{Result: events, NextCursor: next}
+	if p.fields != nil {
+		selected, err := selectFields(events, p.fields)
+		if err != nil {
+			return err
+		}
+		res.Result = selected
+	}
+
+	return writeJSON(w, http.StatusOK, res)
+}
+
+// selectFields оставляет у событий только поля fields, как они называются в JSON.
+func selectFields(events []models.Event, fields []string) ([]map[string]json.RawMessage, error) {
+	result := make([]map[string]json.RawMessage, len(events))
+	for i, e := range events {
+		data, err := json.Marshal(e)
+		if err != nil {
+			return nil, err
+		}
+
+		var all map[string]json.RawMessage
+		if err := json.Unmarshal(data, &all); err != nil {
+			return nil, err
+		}
+
+		result[i] = make(map[string]json.RawMessage, len(fields))
+		for _, f := range fields {
+			if v, ok := all[f]; ok {
+				result[i][f] = v
+			}
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"l2.18/pkg/models"
+)
+
+func TestParsePageDefaultLimit(t *testing.T) {
+	day := time.Date(2025, time.February, 17, 0, 0, 0, 0, time.UTC)
+	events := make([]models.Event, maxPageLimit+5)
+	for i := range events {
+		events[i] = models.Event{ID: models.EventID(fmt.Sprintf("%04d", i)), Date: day}
+	}
+
+	testCases := []struct {
+		name         string
+		query        string
+		defaultLimit int
+		expected     int
+		next         bool
+	}{
+		{name: "v1 without limit", defaultLimit: 0, expected: len(events)},
+		{name: "v2 without limit", defaultLimit: maxPageLimit, expected: maxPageLimit, next: true},
+		{name: "v1 with limit", query: "?limit=10", defaultLimit: 0, expected: 10, next: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := parsePage(httptest.NewRequest("GET", "/events"+tc.query, nil), tc.defaultLimit)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			got, next := p.apply(events)
+			if len(got) != tc.expected || (next != "") != tc.next {
+				t.Errorf("expected %d events and next %v, got %d and %q", tc.expected, tc.next, len(got), next)
+			}
+		})
+	}
+}
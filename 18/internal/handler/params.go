@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"l2.18/pkg/models"
+)
+
+// parseLocation возвращает часовой пояс IANA по имени. Пустое имя - UTC.
+func parseLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+
+	return time.LoadLocation(name)
+}
+
+// parseTime разбирает момент времени в RFC 3339 или день YYYY-MM-DD.
+// День интерпретируется как полночь в часовом поясе loc.
+// Результат переводится в loc.
+func parseTime(value string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.In(loc), nil
+	}
+
+	return time.ParseInLocation("2006-01-02", value, loc)
+}
+
+// parseDates разбирает список значений через parseTime. Для пустого списка вернет nil.
+func parseDates(dates []string, loc *time.Location) ([]time.Time, error) {
+	if len(dates) == 0 {
+		return nil, nil
+	}
+
+	result := make([]time.Time, len(dates))
+	for i, d := range dates {
+		t, err := parseTime(d, loc)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = t
+	}
+
+	return result, nil
+}
+
+// parseFilter разбирает фильтр событий из параметров tag (можно повторять) и category.
+func parseFilter(r *http.Request) models.EventFilter {
+	category := r.FormValue("category")
+	return models.EventFilter{Tags: r.Form["tag"], Category: category}
+}
@@ -0,0 +1,406 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"l2.18/internal/service"
+	"l2.18/pkg/models"
+)
+
+// maxEventSize - максимальный размер тела запроса с событием.
+const maxEventSize = 1 << 20
+
+// eventPatch - частичное изменение события для PATCH. Отсутствующие
+// и null-поля не изменяются.
+type eventPatch struct {
+	Date     *time.Time   `json:"date"`
+	End      *time.Time   `json:"end"`
+	TimeZone *string      `json:"timezone"`
+	Event    *string      `json:"event"`
+	RRule    *string      `json:"rrule"`
+	ExDates  *[]time.Time `json:"exdates"`
+
+	Attendees *[]models.Attendee `json:"attendees"`
+
+	Location    *string            `json:"location"`
+	Description *string            `json:"description"`
+	Category    *string            `json:"category"`
+	Color       *string            `json:"color"`
+	Tags        *[]string          `json:"tags"`
+	Attributes  *map[string]string `json:"attributes"`
+}
+
+// apply применяет изменение к событию.
+func (p *eventPatch) apply(event *models.Event) {
+	if p.Date != nil {
+		event.Date = *p.Date
+	}
+	if p.End != nil {
+		event.End = *p.End
+	}
+	if p.TimeZone != nil {
+		event.TimeZone = *p.TimeZone
+	}
+	if p.Event != nil {
+		event.Event = *p.Event
+	}
+	if p.RRule != nil {
+		event.RRule = *p.RRule
+	}
+	if p.ExDates != nil {
+		event.ExDates = *p.ExDates
+	}
+	if p.Attendees != nil {
+		event.Attendees = *p.Attendees
+	}
+	if p.Location != nil {
+		event.Place = *p.Location
+	}
+	if p.Description != nil {
+		event.Description = *p.Description
+	}
+	if p.Category != nil {
+		event.Category = *p.Category
+	}
+	if p.Color != nil {
+		event.Color = *p.Color
+	}
+	if p.Tags != nil {
+		event.Tags = *p.Tags
+	}
+	if p.Attributes != nil {
+		event.Attributes = *p.Attributes
+	}
+}
+
+// metadata сообщает, изменяет ли patch метаданные события.
+func (p *eventPatch) metadata() bool {
+	return p.Location != nil || p.Description != nil || p.Category != nil ||
+		p.Color != nil || p.Tags != nil || p.Attributes != nil
+}
+
+// ListEventsV2 обрабатывает GET /v2/users/{user}/events. Параметры from и to
+// (YYYY-MM-DD или RFC 3339) ограничивают список событиями, пересекающимися с [from, to).
+// С expand=true серии разворачиваются во вхождения. Параметры tag (можно повторять)
+// и category оставляют только события со всеми этими метками и этой категорией.
+// Список разбивается на страницы параметрами limit, cursor, order и fields.
+func (eh *EventsHandler) ListEventsV2(w http.ResponseWriter, r *http.Request) error {
+	userID, err := resolveUserID(r, models.UserID(r.PathValue("user")))
+	if err != nil {
+		return err
+	}
+
+	from, to, err := parseRange(r)
+	if err != nil {
+		return err
+	}
+
+	p, err := parsePage(r, maxPageLimit)
+	if err != nil {
+		return err
+	}
+
+	expand := false
+	if v := r.FormValue("expand"); v != "" {
+		if expand, err = strconv.ParseBool(v); err != nil {
+			return fmt.Errorf("%w: expand: %v", errInvalidData, err)
+		}
+	}
+
+	filter := parseFilter(r)
+
+	var res []models.Event
+	if expand {
+		res, err = eh.service.GetEvents(userID, from, to, filter)
+	} else {
+		res, err = eh.service.ListEvents(userID, from, to, filter)
+	}
+	if err != nil {
+		return err
+	}
+	if res == nil {
+		res = []models.Event{}
+	}
+
+	return p.write(w, res)
+}
+
+// GetEventV2 обрабатывает GET /v2/users/{user}/events/{id}. Если событие не изменилось
+// с версии из If-None-Match, отвечает 304.
+func (eh *EventsHandler) GetEventV2(w http.ResponseWriter, r *http.Request) error {
+	userID, err := resolveUserID(r, models.UserID(r.PathValue("user")))
+	if err != nil {
+		return err
+	}
+
+	event, err := eh.service.GetEvent(userID, models.EventID(r.PathValue("id")))
+	if err != nil {
+		return err
+	}
+
+	if notModified(w, r, eventETag(*event)) {
+		return nil
+	}
+
+	w.Header().Set("ETag", eventETag(*event))
+	return writeJSON(w, http.StatusOK, event)
+}
+
+// CreateEventV2 обрабатывает POST /v2/users/{user}/events. Если в теле задан id,
+// событие создается с ним, а если такое событие уже есть - сервер отвечает 409.
+func (eh *EventsHandler) CreateEventV2(w http.ResponseWriter, r *http.Request) error {
+	userID, err := resolveUserID(r, models.UserID(r.PathValue("user")))
+	if err != nil {
+		return err
+	}
+
+	var event models.Event
+	if err := decodeJSON(w, r, &event); err != nil {
+		return err
+	}
+	if err := localizeEvent(&event); err != nil {
+		return err
+	}
+
+	id, err := eh.service.AddEvent(userID, event)
+	if err != nil {
+		return err
+	}
+
+	created, err := eh.service.GetEvent(userID, id)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Location", eventPath(userID, id))
+	w.Header().Set("ETag", eventETag(*created))
+	return writeJSON(w, http.StatusCreated, created)
+}
+
+// ReplaceEventV2 обрабатывает PUT /v2/users/{user}/events/{id} - полную замену события.
+// С If-Match событие заменяется, только если его версия не изменилась, иначе 412.
+func (eh *EventsHandler) ReplaceEventV2(w http.ResponseWriter, r *http.Request) error {
+	userID, err := resolveUserID(r, models.UserID(r.PathValue("user")))
+	if err != nil {
+		return err
+	}
+	eventID := models.EventID(r.PathValue("id"))
+
+	var event models.Event
+	if err := decodeJSON(w, r, &event); err != nil {
+		return err
+	}
+	if event.ID != "" && event.ID != eventID {
+		return fmt.Errorf("%w: id does not match path", errInvalidData)
+	}
+	event.ID = eventID
+
+	// Версия из тела игнорируется: ожидаемую версию задает только If-Match.
+	if event.Version, err = ifMatch(r); err != nil {
+		return err
+	}
+
+	if err := localizeEvent(&event); err != nil {
+		return err
+	}
+
+	if err := eh.service.ReplaceEvent(userID, event); err != nil {
+		return err
+	}
+
+	return eh.writeEvent(w, userID, eventID)
+}
+
+// PatchEventV2 обрабатывает PATCH /v2/users/{user}/events/{id} - частичное изменение события.
+// С параметром occurrence изменяется только это вхождение серии (допустимы date, end и event).
+// If-Match проверяется так же, как в ReplaceEventV2.
+func (eh *EventsHandler) PatchEventV2(w http.ResponseWriter, r *http.Request) error {
+	userID, err := resolveUserID(r, models.UserID(r.PathValue("user")))
+	if err != nil {
+		return err
+	}
+	eventID := models.EventID(r.PathValue("id"))
+
+	var patch eventPatch
+	if err := decodeJSON(w, r, &patch); err != nil {
+		return err
+	}
+
+	version, err := ifMatch(r)
+	if err != nil {
+		return err
+	}
+
+	occurrence, ok, err := parseOccurrence(r)
+	if err != nil {
+		return err
+	}
+
+	if ok {
+		if patch.TimeZone != nil || patch.RRule != nil || patch.ExDates != nil || patch.Attendees != nil || patch.metadata() {
+			return fmt.Errorf("%w: only date, end and event can be changed for an occurrence", errInvalidData)
+		}
+
+		event := models.Event{ID: eventID, Version: version}
+		patch.apply(&event)
+		if err := eh.service.UpdateOccurrence(userID, event, occurrence); err != nil {
+			return err
+		}
+
+		return eh.writeEvent(w, userID, eventID)
+	}
+
+	event, err := eh.service.GetEvent(userID, eventID)
+	if err != nil {
+		return err
+	}
+	// Событие заменяется в прочитанной версии, поэтому изменение,
+	// сделанное между чтением и заменой, не будет потеряно.
+	if !event.MatchesVersion(version) {
+		return service.ErrVersionMismatch
+	}
+
+	patch.apply(event)
+	if err := localizeEvent(event); err != nil {
+		return err
+	}
+
+	if err := eh.service.ReplaceEvent(userID, *event); err != nil {
+		return err
+	}
+
+	return eh.writeEvent(w, userID, eventID)
+}
+
+// DeleteEventV2 обрабатывает DELETE /v2/users/{user}/events/{id}.
+// С параметром occurrence удаляется только это вхождение серии.
+// С If-Match событие удаляется, только если его версия не изменилась, иначе 412.
+func (eh *EventsHandler) DeleteEventV2(w http.ResponseWriter, r *http.Request) error {
+	userID, err := resolveUserID(r, models.UserID(r.PathValue("user")))
+	if err != nil {
+		return err
+	}
+	eventID := models.EventID(r.PathValue("id"))
+
+	version, err := ifMatch(r)
+	if err != nil {
+		return err
+	}
+
+	occurrence, ok, err := parseOccurrence(r)
+	if err != nil {
+		return err
+	}
+
+	if ok {
+		err = eh.service.RemoveOccurrence(userID, eventID, occurrence, version)
+	} else {
+		err = eh.service.RemoveEvent(userID, eventID, version)
+	}
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// writeEvent отвечает текущим состоянием события и его ETag.
+func (eh *EventsHandler) writeEvent(w http.ResponseWriter, userID models.UserID, eventID models.EventID) error {
+	event, err := eh.service.GetEvent(userID, eventID)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("ETag", eventETag(*event))
+	return writeJSON(w, http.StatusOK, event)
+}
+
+// parseOccurrence разбирает параметр occurrence в часовом поясе из параметра timezone.
+// ok сообщает, задан ли параметр.
+func parseOccurrence(r *http.Request) (occurrence time.Time, ok bool, err error) {
+	v := r.FormValue("occurrence")
+	if v == "" {
+		return time.Time{}, false, nil
+	}
+
+	loc, err := parseLocation(r.FormValue("timezone"))
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("%w: %v", errInvalidData, err)
+	}
+
+	occurrence, err = parseTime(v, loc)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("%w: %v", errInvalidData, err)
+	}
+
+	return occurrence, true, nil
+}
+
+// localizeEvent проверяет событие из тела запроса и переводит его время
+// в часовой пояс события, как это делает API v1.
+func localizeEvent(event *models.Event) error {
+	if event.Date.IsZero() {
+		return fmt.Errorf("%w: date required", errInvalidData)
+	}
+
+	loc, err := parseLocation(event.TimeZone)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errInvalidData, err)
+	}
+
+	event.Date = event.Date.In(loc)
+	if !event.End.IsZero() {
+		event.End = event.End.In(loc)
+	}
+	for i := range event.ExDates {
+		event.ExDates[i] = event.ExDates[i].In(loc)
+	}
+	for i := range event.Overrides {
+		o := &event.Overrides[i]
+		o.Date = o.Date.In(loc)
+		if !o.End.IsZero() {
+			o.End = o.End.In(loc)
+		}
+		if o.RecurrenceID != nil {
+			t := o.RecurrenceID.In(loc)
+			o.RecurrenceID = &t
+		}
+	}
+
+	return nil
+}
+
+// decodeJSON разбирает тело запроса в v. Неизвестные поля считаются ошибкой.
+func decodeJSON(w http.ResponseWriter, r *http.Request, v any) error {
+	return decodeJSONLimit(w, r, v, maxEventSize)
+}
+
+// decodeJSONLimit - то же, что decodeJSON, но с ограничением размера тела limit.
+func decodeJSONLimit(w http.ResponseWriter, r *http.Request, v any, limit int64) error {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, limit))
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("%w: %w", errInvalidData, err)
+	}
+
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	return json.NewEncoder(w).Encode(v)
+}
+
+// eventPath возвращает путь события в API v2.
+func eventPath(userID models.UserID, eventID models.EventID) string {
+	return "/v2/users/" + url.PathEscape(string(userID)) + "/events/" + url.PathEscape(string(eventID))
+}
@@ -0,0 +1,260 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"l2.18/internal/idempotency"
+	"l2.18/internal/openapi"
+	"l2.18/internal/repository/memory"
+	"l2.18/internal/service/events"
+	"l2.18/internal/tenant"
+	"l2.18/pkg/models"
+)
+
+// testMaxBodySize - ограничение тела запроса тестового сервера.
+const testMaxBodySize = 4 << 10
+
+// testServer - маршруты API v2, собранные так же, как в cmd/main.go,
+// поверх хранилища в памяти без аутентификации.
+type testServer struct {
+	handler http.Handler
+	keys    *idempotency.Keys
+}
+
+func newTestServer(t *testing.T, quotas map[models.TenantID]models.TenantQuota) *testServer {
+	t.Helper()
+
+	store := memory.NewEventsRepository()
+	registry, err := tenant.NewRegistry(store, models.TenantQuota{}, quotas)
+	if err != nil {
+		t.Fatal(err)
+	}
+	validator, err := openapi.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	services := map[models.TenantID]*EventsHandler{}
+	tenants := NewTenants(func(id models.TenantID) *EventsHandler {
+		if services[id] == nil {
+			services[id] = NewEventsHandler(events.New(registry.Repository(id)))
+		}
+		return services[id]
+	}, nil)
+	eh := tenants.Events
+
+	keys := idempotency.New(store, time.Hour)
+	idempotent := NewIdempotency(keys, nopLogger{}).Wrap
+	middleware := NewMiddleware(nopLogger{})
+	auth := NewAuth(AuthConfig{})
+	tenantLimit := NewTenantRateLimit(registry).Limit
+	validation := NewValidation(validator)
+
+	protectedV2 := func(h ErrHandlerFunc) http.HandlerFunc {
+		return middleware.LoggingV2(auth.Authenticate(tenantLimit(validation.Validate(h))))
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("POST /v2/users/{user}/events", protectedV2(idempotent(eh((*EventsHandler).CreateEventV2))))
+	mux.Handle("POST /v2/users/{user}/events/batch", protectedV2(idempotent(eh((*EventsHandler).BatchV2))))
+	mux.Handle("GET /v2/users/{user}/events/{id}", protectedV2(eh((*EventsHandler).GetEventV2)))
+	mux.Handle("PUT /v2/users/{user}/events/{id}", protectedV2(eh((*EventsHandler).ReplaceEventV2)))
+	mux.Handle("PATCH /v2/users/{user}/events/{id}", protectedV2(eh((*EventsHandler).PatchEventV2)))
+
+	return &testServer{handler: MaxBodySize(testMaxBodySize, mux), keys: keys}
+}
+
+// do выполняет запрос к серверу. header - пары имя, значение.
+func (s *testServer) do(method, path, body string, header ...string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		r.Header.Set("Content-Type", "application/json")
+	}
+	for i := 0; i+1 < len(header); i += 2 {
+		r.Header.Set(header[i], header[i+1])
+	}
+
+	w := httptest.NewRecorder()
+	s.handler.ServeHTTP(w, r)
+	return w
+}
+
+// expectError проверяет статус и код ошибки API v2 в ответе.
+func expectError(t *testing.T, w *httptest.ResponseRecorder, status int, code string) {
+	t.Helper()
+
+	var res apiError
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+		t.Fatalf("expected error body, got %q: %v", w.Body, err)
+	}
+	if w.Code != status || res.Error.Code != code || res.Error.Message == "" {
+		t.Errorf("expected %d %s, got %d: %s", status, code, w.Code, w.Body)
+	}
+}
+
+const testEvent = `{"id": "standup", "date": "2025-02-17T10:00:00Z", "event": "standup"}`
+
+func TestEventsV2Conditional(t *testing.T) {
+	s := newTestServer(t, nil)
+
+	w := s.do("POST", "/v2/users/alice/events", testEvent)
+	if w.Code != http.StatusCreated || w.Header().Get("ETag") != `"1"` {
+		t.Fatalf("expected 201 with ETag \"1\", got %d %q: %s", w.Code, w.Header().Get("ETag"), w.Body)
+	}
+
+	w = s.do("GET", "/v2/users/alice/events/standup", "", "If-None-Match", `"1"`)
+	if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Errorf("expected 304 without body, got %d: %s", w.Code, w.Body)
+	}
+
+	replace := `{"date": "2025-02-17T11:00:00Z", "event": "standup"}`
+	w = s.do("PUT", "/v2/users/alice/events/standup", replace, "If-Match", `"2"`)
+	expectError(t, w, http.StatusPreconditionFailed, "precondition_failed")
+
+	w = s.do("PATCH", "/v2/users/alice/events/standup", `{"event": "retro"}`, "If-Match", `W/"1"`)
+	expectError(t, w, http.StatusPreconditionFailed, "precondition_failed")
+
+	w = s.do("PUT", "/v2/users/alice/events/standup", replace, "If-Match", `"1"`)
+	if w.Code != http.StatusOK || w.Header().Get("ETag") != `"2"` {
+		t.Errorf("expected 200 with ETag \"2\", got %d %q: %s", w.Code, w.Header().Get("ETag"), w.Body)
+	}
+
+	w = s.do("GET", "/v2/users/alice/events/missing", "")
+	expectError(t, w, http.StatusNotFound, "not_found")
+}
+
+func TestEventsV2Conflict(t *testing.T) {
+	s := newTestServer(t, nil)
+
+	if w := s.do("POST", "/v2/users/alice/events", testEvent); w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body)
+	}
+
+	w := s.do("POST", "/v2/users/alice/events", testEvent)
+	expectError(t, w, http.StatusConflict, "conflict")
+}
+
+func TestEventsV2Idempotency(t *testing.T) {
+	s := newTestServer(t, nil)
+
+	first := s.do("POST", "/v2/users/alice/events", testEvent, "Idempotency-Key", "abc")
+	if first.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", first.Code, first.Body)
+	}
+
+	// Без ключа тот же запрос создал бы дубликат и получил 409.
+	replayed := s.do("POST", "/v2/users/alice/events", testEvent, "Idempotency-Key", "abc")
+	if replayed.Code != http.StatusCreated || replayed.Header().Get("Idempotent-Replayed") != "true" ||
+		replayed.Header().Get("Location") != first.Header().Get("Location") ||
+		replayed.Body.String() != first.Body.String() {
+		t.Errorf("expected replayed %d %s, got %d: %s", first.Code, first.Body, replayed.Code, replayed.Body)
+	}
+
+	w := s.do("POST", "/v2/users/alice/events", `{"date": "2025-02-18T10:00:00Z"}`, "Idempotency-Key", "abc")
+	expectError(t, w, http.StatusUnprocessableEntity, "idempotency_key_reused")
+
+	// Ключ, запрос с которым еще выполняется.
+	if _, err := s.keys.Begin("alice", "pending", ""); err != nil {
+		t.Fatal(err)
+	}
+	w = s.do("POST", "/v2/users/alice/events", `{"date": "2025-02-18T10:00:00Z"}`, "Idempotency-Key", "pending")
+	expectError(t, w, http.StatusConflict, "request_in_progress")
+}
+
+func TestEventsV2Batch(t *testing.T) {
+	operations := `"operations": [
+		{"op": "create", "event": {"id": "a", "date": "2025-02-17T10:00:00Z", "event": "standup"}},
+		{"op": "delete", "id": "missing"}
+	]`
+
+	testCases := []struct {
+		name     string
+		atomic   bool
+		statuses []int
+		created  bool
+	}{
+		{name: "atomic", atomic: true, statuses: []int{http.StatusFailedDependency, http.StatusNotFound}},
+		{name: "best effort", statuses: []int{http.StatusCreated, http.StatusNotFound}, created: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newTestServer(t, nil)
+
+			w := s.do("POST", "/v2/users/alice/events/batch", fmt.Sprintf(`{"atomic": %t, %s}`, tc.atomic, operations))
+			if w.Code != http.StatusMultiStatus {
+				t.Fatalf("expected 207, got %d: %s", w.Code, w.Body)
+			}
+
+			var res batchResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+				t.Fatal(err)
+			}
+			statuses := make([]int, len(res.Result))
+			for i, result := range res.Result {
+				statuses[i] = result.Status
+			}
+			if !slices.Equal(statuses, tc.statuses) {
+				t.Errorf("expected statuses %v, got %s", tc.statuses, w.Body)
+			}
+
+			w = s.do("GET", "/v2/users/alice/events/a", "")
+			if created := w.Code == http.StatusOK; created != tc.created {
+				t.Errorf("expected created %v, got %d: %s", tc.created, w.Code, w.Body)
+			}
+		})
+	}
+}
+
+func TestEventsV2Tenants(t *testing.T) {
+	s := newTestServer(t, map[models.TenantID]models.TenantQuota{
+		"limited": {RateLimit: 1, RateBurst: 1},
+		"small":   {MaxEvents: 1},
+	})
+
+	if w := s.do("POST", "/v2/users/alice/events", testEvent, tenantHeader, "acme"); w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body)
+	}
+
+	// alice арендатора по умолчанию - другой пользователь.
+	w := s.do("GET", "/v2/users/alice/events/standup", "")
+	expectError(t, w, http.StatusNotFound, "not_found")
+	if w := s.do("GET", "/v2/users/alice/events/standup", "", tenantHeader, "acme"); w.Code != http.StatusOK {
+		t.Errorf("expected 200, got %d: %s", w.Code, w.Body)
+	}
+
+	w = s.do("GET", "/v2/users/alice/events/standup", "", tenantHeader, "bad tenant")
+	expectError(t, w, http.StatusBadRequest, "invalid_input")
+
+	s.do("GET", "/v2/users/alice/events/standup", "", tenantHeader, "limited")
+	w = s.do("GET", "/v2/users/alice/events/standup", "", tenantHeader, "limited")
+	expectError(t, w, http.StatusTooManyRequests, "rate_limited")
+	if w.Header().Get("Retry-After") != "1" {
+		t.Errorf("expected Retry-After 1, got %q", w.Header().Get("Retry-After"))
+	}
+
+	s.do("POST", "/v2/users/alice/events", testEvent, tenantHeader, "small")
+	w = s.do("POST", "/v2/users/bob/events", testEvent, tenantHeader, "small")
+	expectError(t, w, http.StatusForbidden, "quota_exceeded")
+}
+
+func TestEventsV2BodyTooLarge(t *testing.T) {
+	s := newTestServer(t, nil)
+
+	body := `{"date": "2025-02-17T10:00:00Z", "event": "` + strings.Repeat("a", testMaxBodySize) + `"}`
+	w := s.do("POST", "/v2/users/alice/events", body)
+	expectError(t, w, http.StatusRequestEntityTooLarge, "payload_too_large")
+
+	w = s.do("POST", "/v2/users/alice/events/batch", `{"operations": []`+strings.Repeat(" ", testMaxBodySize)+`}`)
+	expectError(t, w, http.StatusRequestEntityTooLarge, "payload_too_large")
+
+	w = s.do("GET", "/v2/users/alice/events/standup", "")
+	expectError(t, w, http.StatusNotFound, "not_found")
+}
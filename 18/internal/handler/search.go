@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"l2.18/pkg/models"
+	"l2.18/pkg/search"
+)
+
+const (
+	// defaultSearchLimit и maxSearchLimit - сколько найденных событий возвращается по умолчанию и максимум.
+	defaultSearchLimit = 50
+	maxSearchLimit     = 100
+)
+
+type searchResponse struct {
+	Result []models.Event `json:"result"`
+	// Total - число найденных событий без учета limit и offset.
+	Total int `json:"total"`
+}
+
+// SearchV2 обрабатывает GET /v2/users/{user}/search - поиск событий по тексту q.
+// Параметры from и to ограничивают поиск событиями, пересекающимися с [from, to),
+// limit и offset выбирают страницу результата.
+func (eh *EventsHandler) SearchV2(w http.ResponseWriter, r *http.Request) error {
+	userID, err := resolveUserID(r, models.UserID(r.PathValue("user")))
+	if err != nil {
+		return err
+	}
+
+	query, err := search.Parse(r.FormValue("q"))
+	if errors.Is(err, search.ErrEmptyQuery) {
+		return fmt.Errorf("%w: q must contain at least one word", errInvalidData)
+	} else if err != nil {
+		return fmt.Errorf("%w: q: %v", errInvalidData, err)
+	}
+
+	from, to, err := parseRange(r)
+	if err != nil {
+		return err
+	}
+
+	limit := defaultSearchLimit
+	if v := r.FormValue("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit < 1 || limit > maxSearchLimit {
+			return fmt.Errorf("%w: limit must be between 1 and %d", errInvalidData, maxSearchLimit)
+		}
+	}
+
+	offset := 0
+	if v := r.FormValue("offset"); v != "" {
+		if offset, err = strconv.Atoi(v); err != nil || offset < 0 {
+			return fmt.Errorf("%w: offset must not be negative", errInvalidData)
+		}
+	}
+
+	found, err := eh.service.Search(userID, query, from, to)
+	if err != nil {
+		return err
+	}
+
+	res := searchResponse{Result: []models.Event{}, Total: len(found)}
+	if offset < len(found) {
+		res.Result = found[offset:min(offset+limit, len(found))]
+	}
+
+	return writeJSON(w, http.StatusOK, res)
+}
+
+// parseRange разбирает необязательные параметры from и to (YYYY-MM-DD или RFC 3339)
+// в часовом поясе из параметра timezone. Без них диапазон не ограничен.
+func parseRange(r *http.Request) (from, to time.Time, err error) {
+	loc, err := parseLocation(r.FormValue("timezone"))
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: %v", errInvalidData, err)
+	}
+
+	from, to = exportFrom, exportTo
+	if v := r.FormValue("from"); v != "" {
+		if from, err = parseTime(v, loc); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("%w: from: %v", errInvalidData, err)
+		}
+	}
+	if v := r.FormValue("to"); v != "" {
+		if to, err = parseTime(v, loc); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("%w: to: %v", errInvalidData, err)
+		}
+	}
+
+	return from, to, nil
+}
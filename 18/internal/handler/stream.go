@@ -0,0 +1,191 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/coder/websocket"
+	"l2.18/internal/pubsub"
+	"l2.18/pkg/models"
+)
+
+const (
+	// heartbeatInterval - как часто в открытый поток отправляется heartbeat,
+	// чтобы прокси не закрывали простаивающее соединение.
+	heartbeatInterval = 15 * time.Second
+	// streamWriteTimeout - сколько можно ждать записи в поток, прежде чем
+	// посчитать клиента отключившимся.
+	streamWriteTimeout = 10 * time.Second
+)
+
+type changesHub interface {
+	Subscribe(userID models.UserID) *pubsub.Subscription
+}
+
+// StreamHandler отдает изменения событий пользователя в реальном времени.
+type StreamHandler struct {
+	hub      changesHub
+	wsAccept *websocket.AcceptOptions
+}
+
+// NewStreamHandler создает новый StreamHandler. origins - источники, разрешенные
+// CORS (* - любой): браузер на них может открыть WebSocket с другого источника.
+func NewStreamHandler(hub changesHub, origins []string) *StreamHandler {
+	return &StreamHandler{
+		hub:      hub,
+		wsAccept: &websocket.AcceptOptions{OriginPatterns: originPatterns(origins)},
+	}
+}
+
+// originPatterns переводит источники CORS в шаблоны websocket.AcceptOptions.
+// Источник со схемой сравнивается с "схема://хост" запроса, поэтому шаблоны
+// совпадают только с ним самим: спецсимволы path.Match экранируются.
+func originPatterns(origins []string) []string {
+	patterns := make([]string, 0, len(origins))
+	for _, origin := range origins {
+		if origin != "*" {
+			origin = originEscaper.Replace(origin)
+		}
+		patterns = append(patterns, origin)
+	}
+	return patterns
+}
+
+var originEscaper = strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`)
+
+// SSE обрабатывает GET /events/stream - поток изменений в формате Server-Sent Events.
+// Каждое изменение отправляется событием с именем его типа (created, updated, deleted).
+func (sh *StreamHandler) SSE(w http.ResponseWriter, r *http.Request) error {
+	userID, err := resolveUserID(r, models.UserID(r.FormValue("user_id")))
+	if err != nil {
+		return err
+	}
+
+	rc := http.NewResponseController(w)
+
+	sub := sh.hub.Subscribe(userID)
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	write := func(format string, args ...any) error {
+		_ = rc.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+		if _, err := fmt.Fprintf(w, format, args...); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+
+	if err := write(": connected\n\n"); err != nil {
+		return nil
+	}
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for id := 1; ; {
+		select {
+		case <-r.Context().Done():
+			return nil
+
+		case <-heartbeat.C:
+			if err := write(": heartbeat\n\n"); err != nil {
+				return nil
+			}
+
+		case change, ok := <-sub.Changes():
+			if !ok {
+				// Сообщаем клиенту причину, чтобы он мог переподключиться.
+				_ = write("event: closed\ndata: %s\n\n", closeReason(sub.Err()))
+				return nil
+			}
+
+			data, err := json.Marshal(change)
+			if err != nil {
+				return err
+			}
+			if err := write("id: %d\nevent: %s\ndata: %s\n\n", id, change.Type, data); err != nil {
+				return nil
+			}
+			id++
+		}
+	}
+}
+
+// WebSocket обрабатывает GET /events/ws - поток изменений через WebSocket.
+// Каждое изменение отправляется текстовым сообщением с JSON.
+func (sh *StreamHandler) WebSocket(w http.ResponseWriter, r *http.Request) error {
+	userID, err := resolveUserID(r, models.UserID(r.FormValue("user_id")))
+	if err != nil {
+		return err
+	}
+
+	conn, err := websocket.Accept(w, r, sh.wsAccept)
+	if err != nil {
+		// Accept уже записал ответ с ошибкой.
+		return nil
+	}
+	defer conn.CloseNow()
+
+	sub := sh.hub.Subscribe(userID)
+	defer sub.Close()
+
+	// Сообщения клиента не ожидаются, CloseRead обрабатывает управляющие
+	// кадры и отменяет ctx, когда клиент закрывает соединение.
+	ctx := conn.CloseRead(r.Context())
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case <-heartbeat.C:
+			pingCtx, cancel := context.WithTimeout(ctx, streamWriteTimeout)
+			err := conn.Ping(pingCtx)
+			cancel()
+			if err != nil {
+				return nil
+			}
+
+		case change, ok := <-sub.Changes():
+			if !ok {
+				status := websocket.StatusGoingAway
+				if errors.Is(sub.Err(), pubsub.ErrSlowConsumer) {
+					status = websocket.StatusPolicyViolation
+				}
+				_ = conn.Close(status, closeReason(sub.Err()))
+				return nil
+			}
+
+			data, err := json.Marshal(change)
+			if err != nil {
+				return err
+			}
+
+			writeCtx, cancel := context.WithTimeout(ctx, streamWriteTimeout)
+			err = conn.Write(writeCtx, websocket.MessageText, data)
+			cancel()
+			if err != nil {
+				return nil
+			}
+		}
+	}
+}
+
+func closeReason(err error) string {
+	if err == nil {
+		return "closed"
+	}
+	return err.Error()
+}
@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/coder/websocket"
+	"l2.18/internal/pubsub"
+)
+
+func TestWebSocketOrigins(t *testing.T) {
+	testCases := []struct {
+		name    string
+		origins []string
+		origin  string
+		allowed bool
+	}{
+		{name: "cors origin", origins: []string{"https://calendar.example"}, origin: "https://calendar.example", allowed: true},
+		{name: "other scheme", origins: []string{"https://calendar.example"}, origin: "http://calendar.example"},
+		{name: "other origin", origins: []string{"https://calendar.example"}, origin: "https://evil.example"},
+		{name: "any origin", origins: []string{"*"}, origin: "https://evil.example", allowed: true},
+		{name: "no cors", origin: "https://calendar.example"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			hub := pubsub.NewHub(1)
+			defer hub.Close()
+			sh := NewStreamHandler(hub, tc.origins)
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_ = sh.WebSocket(w, r)
+			}))
+			defer srv.Close()
+
+			conn, res, err := websocket.Dial(context.Background(), srv.URL+"?user_id=alice", &websocket.DialOptions{
+				HTTPHeader: http.Header{"Origin": {tc.origin}},
+			})
+			if err == nil {
+				conn.CloseNow()
+			}
+			if allowed := err == nil; allowed != tc.allowed {
+				t.Errorf("expected allowed %v, got %v", tc.allowed, err)
+			}
+			if !tc.allowed && res != nil && res.StatusCode != http.StatusForbidden {
+				t.Errorf("expected 403, got %d", res.StatusCode)
+			}
+		})
+	}
+}
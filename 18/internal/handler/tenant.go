@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"l2.18/internal/tenant"
+	"l2.18/pkg/models"
+)
+
+// tenantHeader - заголовок с арендатором запроса.
+const tenantHeader = "X-Tenant-ID"
+
+type tenantKey struct{}
+
+func withTenant(ctx context.Context, id models.TenantID) context.Context {
+	return context.WithValue(ctx, tenantKey{}, id)
+}
+
+// tenantFromContext возвращает арендатора запроса, по умолчанию - tenant.Default.
+func tenantFromContext(ctx context.Context) models.TenantID {
+	id, _ := ctx.Value(tenantKey{}).(models.TenantID)
+	return id
+}
+
+// Tenants направляет запросы в обработчики арендатора, которого определил
+// Authenticate. Обработчики каждого арендатора работают только с его данными.
+type Tenants struct {
+	events  func(id models.TenantID) *EventsHandler
+	streams func(id models.TenantID) *StreamHandler
+}
+
+// NewTenants создает новый Tenants. events и streams возвращают обработчики арендатора.
+func NewTenants(events func(id models.TenantID) *EventsHandler, streams func(id models.TenantID) *StreamHandler) *Tenants {
+	return &Tenants{events: events, streams: streams}
+}
+
+// Events вызывает метод m EventsHandler арендатора запроса,
+// например Events((*EventsHandler).CreateEvent).
+func (t *Tenants) Events(m func(eh *EventsHandler, w http.ResponseWriter, r *http.Request) error) ErrHandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		return m(t.events(tenantFromContext(r.Context())), w, r)
+	}
+}
+
+// Streams вызывает метод m StreamHandler арендатора запроса.
+func (t *Tenants) Streams(m func(sh *StreamHandler, w http.ResponseWriter, r *http.Request) error) ErrHandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		return m(t.streams(tenantFromContext(r.Context())), w, r)
+	}
+}
+
+// tenantUserKey возвращает ключ пользователя userID арендатора запроса,
+// не пересекающийся с пользователями других арендаторов.
+func tenantUserKey(r *http.Request, userID models.UserID) models.UserID {
+	return tenant.UserKey(tenantFromContext(r.Context()), userID)
+}
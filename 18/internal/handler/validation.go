@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"l2.18/internal/openapi"
+)
+
+type requestValidator interface {
+	Validate(r *http.Request) error
+}
+
+// Validation проверяет запросы по спецификации OpenAPI до вызова обработчика.
+type Validation struct {
+	validator requestValidator
+}
+
+// NewValidation создает новый Validation.
+func NewValidation(validator requestValidator) *Validation {
+	return &Validation{validator: validator}
+}
+
+// Validate пропускает к h только запросы, соответствующие спецификации.
+// Ошибки по полям передаются в ответ вместе с errInvalidData.
+func (v *Validation) Validate(h ErrHandlerFunc) ErrHandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		if err := v.validator.Validate(r); err != nil {
+			return fmt.Errorf("%w: %w", errInvalidData, err)
+		}
+
+		return h(w, r)
+	}
+}
+
+// OpenAPI обрабатывает GET /openapi.json - отдает спецификацию API.
+func OpenAPI(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+	_, err := w.Write(openapi.Document)
+	return err
+}
+
+// fieldErrors возвращает ошибки по полям, если err содержит *openapi.ValidationError.
+func fieldErrors(err error) []openapi.FieldError {
+	var verr *openapi.ValidationError
+	if errors.As(err, &verr) {
+		return verr.Fields
+	}
+	return nil
+}
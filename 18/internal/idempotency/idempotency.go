@@ -0,0 +1,121 @@
+// Package idempotency сохраняет ответы на запросы с ключами идемпотентности,
+// чтобы повтор запроса (например, после таймаута) вернул прежний ответ,
+// а не выполнил запрос еще раз.
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"l2.18/internal/repository"
+	"l2.18/pkg/models"
+)
+
+// ErrKeyReused возвращается, если ключ уже использован с другим запросом.
+var ErrKeyReused = errors.New("idempotency key reused with different request")
+
+// ErrInProgress возвращается, если запрос с тем же ключом еще выполняется.
+var ErrInProgress = errors.New("request with this idempotency key is in progress")
+
+// Store хранит ответы. Для постоянного хранилища ответы переживают перезапуск.
+type Store interface {
+	PutIdempotencyRecord(record models.IdempotencyRecord) error
+	GetIdempotencyRecord(userID models.UserID, key string) (*models.IdempotencyRecord, error)
+	PruneIdempotencyRecords(before time.Time) error
+}
+
+type recordKey struct {
+	userID models.UserID
+	key    string
+}
+
+// Keys выдает сохраненные ответы и не дает выполнять одновременно
+// запросы с одинаковым ключом.
+type Keys struct {
+	store Store
+	ttl   time.Duration
+	now   func() time.Time
+
+	mu       sync.Mutex
+	inFlight map[recordKey]struct{}
+}
+
+// New создает Keys, хранящий ответы ttl.
+func New(store Store, ttl time.Duration) *Keys {
+	return &Keys{
+		store:    store,
+		ttl:      ttl,
+		now:      time.Now,
+		inFlight: make(map[recordKey]struct{}),
+	}
+}
+
+// Begin начинает запрос с ключом key пользователя userID. fingerprint - хеш запроса.
+//
+// Если с ключом уже сохранен ответ не старше ttl, Begin возвращает его, а если он
+// сохранен для другого запроса - ErrKeyReused. Если запрос с ключом еще выполняется -
+// ErrInProgress. Иначе ключ занимается до вызова Finish или Abort и возвращается nil.
+func (k *Keys) Begin(userID models.UserID, key, fingerprint string) (*models.IdempotencyRecord, error) {
+	rk := recordKey{userID: userID, key: key}
+
+	k.mu.Lock()
+	if _, ok := k.inFlight[rk]; ok {
+		k.mu.Unlock()
+		return nil, ErrInProgress
+	}
+	k.inFlight[rk] = struct{}{}
+	k.mu.Unlock()
+
+	record, err := k.store.GetIdempotencyRecord(userID, key)
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return nil, nil
+	case err != nil:
+		k.Abort(userID, key)
+		return nil, err
+	case k.now().Sub(record.CreatedAt) >= k.ttl:
+		return nil, nil
+	}
+
+	k.Abort(userID, key)
+	if record.Fingerprint != fingerprint {
+		return nil, ErrKeyReused
+	}
+	return record, nil
+}
+
+// Finish сохраняет ответ на запрос, начатый Begin, и освобождает ключ.
+func (k *Keys) Finish(record models.IdempotencyRecord) error {
+	defer k.Abort(record.UserID, record.Key)
+
+	record.CreatedAt = k.now()
+	return k.store.PutIdempotencyRecord(record)
+}
+
+// Abort освобождает ключ без сохранения ответа: повтор запроса выполнит его снова.
+func (k *Keys) Abort(userID models.UserID, key string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	delete(k.inFlight, recordKey{userID: userID, key: key})
+}
+
+// Run раз в interval удаляет ответы старше ttl, пока не будет отменен ctx.
+func (k *Keys) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := k.store.PruneIdempotencyRecords(k.now().Add(-k.ttl)); err != nil {
+				return fmt.Errorf("prune idempotency records: %w", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package idempotency
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"l2.18/internal/repository/memory"
+	"l2.18/pkg/models"
+)
+
+func TestKeys(t *testing.T) {
+	now := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+	k := New(memory.NewEventsRepository(), time.Hour)
+	k.now = func() time.Time { return now }
+
+	if record, err := k.Begin("user1", "a", "f1"); record != nil || err != nil {
+		t.Fatalf("expected new key, got %+v, %v", record, err)
+	}
+	if _, err := k.Begin("user1", "a", "f1"); !errors.Is(err, ErrInProgress) {
+		t.Errorf("expected %v, got %v", ErrInProgress, err)
+	}
+	if record, err := k.Begin("user2", "a", "f1"); record != nil || err != nil {
+		t.Errorf("expected keys of another user to be separate, got %+v, %v", record, err)
+	}
+
+	if err := k.Finish(models.IdempotencyRecord{UserID: "user1", Key: "a", Fingerprint: "f1", Status: 201}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	record, err := k.Begin("user1", "a", "f1")
+	if err != nil || record == nil || record.Status != 201 {
+		t.Fatalf("expected saved response, got %+v, %v", record, err)
+	}
+	if _, err := k.Begin("user1", "a", "f2"); !errors.Is(err, ErrKeyReused) {
+		t.Errorf("expected %v, got %v", ErrKeyReused, err)
+	}
+
+	// Просроченный ответ не повторяется, ключ можно использовать снова.
+	now = now.Add(time.Hour)
+	if record, err := k.Begin("user1", "a", "f2"); record != nil || err != nil {
+		t.Errorf("expected expired key to be reusable, got %+v, %v", record, err)
+	}
+}
+
+func TestAbort(t *testing.T) {
+	k := New(memory.NewEventsRepository(), time.Hour)
+
+	if _, err := k.Begin("user1", "a", "f1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	k.Abort("user1", "a")
+
+	if record, err := k.Begin("user1", "a", "f2"); record != nil || err != nil {
+		t.Errorf("expected aborted key to be free, got %+v, %v", record, err)
+	}
+}
@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"l2.18/internal/tenant"
+	"l2.18/pkg/models"
+)
+
+// eventsCollector отдает количество событий и пользователей каждого арендатора
+// на момент сбора метрик. Метки по пользователям не заводятся: их число не
+// ограничено, а ключи хранилища раскрывают идентификаторы пользователей.
+type eventsCollector struct {
+	counter eventCounter
+	events  *prometheus.Desc
+	users   *prometheus.Desc
+}
+
+func newEventsCollector(counter eventCounter) *eventsCollector {
+	return &eventsCollector{
+		counter: counter,
+		events: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "tenant_events"),
+			"Events (series count once) stored for each tenant.",
+			[]string{"tenant"}, nil,
+		),
+		users: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "tenant_users"),
+			"Users with stored events for each tenant.",
+			[]string{"tenant"}, nil,
+		),
+	}
+}
+
+func (c *eventsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.events
+	ch <- c.users
+}
+
+func (c *eventsCollector) Collect(ch chan<- prometheus.Metric) {
+	counts, err := c.counter.EventCounts()
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(c.events, err)
+		ch <- prometheus.NewInvalidMetric(c.users, err)
+		return
+	}
+
+	events := make(map[models.TenantID]int)
+	users := make(map[models.TenantID]int)
+	for key, n := range counts {
+		id, _ := tenant.Split(key)
+		events[id] += n
+		users[id]++
+	}
+
+	for id, n := range events {
+		ch <- prometheus.MustNewConstMetric(c.events, prometheus.GaugeValue, float64(n), string(id))
+		ch <- prometheus.MustNewConstMetric(c.users, prometheus.GaugeValue, float64(users[id]), string(id))
+	}
+}
@@ -0,0 +1,89 @@
+// Package metrics собирает метрики сервера календаря и отдает их
+// в текстовом формате Prometheus.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"l2.18/pkg/models"
+)
+
+const namespace = "calendar"
+
+// eventCounter возвращает количество событий каждого пользователя.
+type eventCounter interface {
+	EventCounts() (map[models.UserID]int, error)
+}
+
+// Metrics хранит метрики сервера в собственном реестре.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests   *prometheus.CounterVec
+	latency    *prometheus.HistogramVec
+	inFlight   prometheus.Gauge
+	operations *prometheus.HistogramVec
+}
+
+// New создает Metrics. Количество событий пользователей запрашивается у counter
+// при каждом сборе метрик.
+func New(counter eventCounter) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "requests_total",
+			Help:      "HTTP requests by route, method and status code.",
+		}, []string{"route", "method", "code"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "HTTP request latency by route and method.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method"}),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "requests_in_flight",
+			Help:      "HTTP requests currently being served.",
+		}),
+		operations: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "repository",
+			Name:      "operation_duration_seconds",
+			Help:      "Repository operation latency by operation.",
+			Buckets:   []float64{.0001, .0005, .001, .005, .01, .05, .1, .5, 1},
+		}, []string{"operation"}),
+	}
+
+	m.registry.MustRegister(
+		m.requests, m.latency, m.inFlight, m.operations,
+		newEventsCollector(counter),
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	return m
+}
+
+// Handler возвращает обработчик GET /metrics. Если часть метрик собрать
+// не удалось, остальные все равно отдаются.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{ErrorHandling: promhttp.ContinueOnError})
+}
+
+// InstrumentHandler считает запросы к h, их статусы и длительность под именем
+// маршрута route (шаблоном пути из ServeMux), а не конкретным путем, чтобы
+// число рядов не росло с числом пользователей и событий.
+func (m *Metrics) InstrumentHandler(route string, h http.Handler) http.Handler {
+	labels := prometheus.Labels{"route": route}
+
+	return promhttp.InstrumentHandlerInFlight(m.inFlight,
+		promhttp.InstrumentHandlerDuration(m.latency.MustCurryWith(labels),
+			promhttp.InstrumentHandlerCounter(m.requests.MustCurryWith(labels), h)))
+}
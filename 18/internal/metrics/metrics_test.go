@@ -0,0 +1,86 @@
+package metrics
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	repomock "l2.18/internal/repository/mock"
+	"l2.18/pkg/models"
+)
+
+// scrape возвращает метрики m в текстовом формате.
+func scrape(t *testing.T, m *Metrics) string {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d", rec.Code)
+	}
+
+	body, _ := io.ReadAll(rec.Body)
+	return string(body)
+}
+
+func TestInstrumentHandler(t *testing.T) {
+	repo := &repomock.MockRepository{
+		EventCountsFn: func() (map[models.UserID]int, error) {
+			return map[models.UserID]int{"user1": 3, "user2": 1, "acme/user1": 2}, nil
+		},
+	}
+	m := New(repo)
+
+	h := m.InstrumentHandler("GET /v2/users/{user}/events/{id}", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v2/users/1/events/42", nil))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v2/users/2/events/43", nil))
+
+	out := scrape(t, m)
+	if strings.Contains(out, "user1") {
+		t.Errorf("expected no user labels in metrics:\n%s", out)
+	}
+	for _, want := range []string{
+		`calendar_http_requests_total{code="404",method="get",route="GET /v2/users/{user}/events/{id}"} 2`,
+		`calendar_http_request_duration_seconds_count{method="get",route="GET /v2/users/{user}/events/{id}"} 2`,
+		`calendar_http_requests_in_flight 0`,
+		`calendar_tenant_events{tenant=""} 4`,
+		`calendar_tenant_users{tenant=""} 2`,
+		`calendar_tenant_events{tenant="acme"} 2`,
+		`calendar_tenant_users{tenant="acme"} 1`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in metrics:\n%s", want, out)
+		}
+	}
+}
+
+func TestRepository(t *testing.T) {
+	errFailed := errors.New("failed")
+	repo := &repomock.MockRepository{
+		GetFn: func(userID models.UserID, eventID models.EventID) (*models.Event, error) {
+			return nil, errFailed
+		},
+		EventCountsFn: func() (map[models.UserID]int, error) {
+			return nil, errFailed
+		},
+	}
+	m := New(repo)
+
+	if _, err := m.Repository(repo).Get("user1", "1"); !errors.Is(err, errFailed) {
+		t.Errorf("expected %v, got %v", errFailed, err)
+	}
+
+	// Ошибка подсчета событий не должна скрывать остальные метрики.
+	out := scrape(t, m)
+	if !strings.Contains(out, `calendar_repository_operation_duration_seconds_count{operation="get"} 1`) {
+		t.Errorf("expected get operation in metrics:\n%s", out)
+	}
+	if strings.Contains(out, "calendar_tenant_events{") {
+		t.Errorf("expected no tenant events in metrics:\n%s", out)
+	}
+}
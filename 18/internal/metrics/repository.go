@@ -0,0 +1,173 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"l2.18/internal/repository"
+	"l2.18/pkg/models"
+	"l2.18/pkg/search"
+)
+
+// Repository - хранилище событий, время операций которого измеряется.
+type Repository interface {
+	Put(userID models.UserID, event models.Event) error
+	Get(userID models.UserID, eventID models.EventID) (*models.Event, error)
+	Update(userID models.UserID, event models.Event) error
+	Replace(userID models.UserID, event models.Event) error
+	Delete(userID models.UserID, eventID models.EventID, version int64) error
+	GetEventsByDateRange(userID models.UserID, start, end time.Time) ([]models.Event, error)
+	GetFilteredEvents(userID models.UserID, start, end time.Time, filter models.EventFilter) ([]models.Event, error)
+	GetRecurringEvents(userID models.UserID) ([]models.Event, error)
+	Users() ([]models.UserID, error)
+	GetInvitedEvents(userID models.UserID) ([]models.Event, error)
+	UpdateAttendee(owner models.UserID, eventID models.EventID, attendee models.Attendee) error
+	Search(userID models.UserID, query search.Query) ([]models.Event, error)
+	AddHistory(userID models.UserID, entry models.HistoryEntry) error
+	GetHistory(userID models.UserID, eventID models.EventID) ([]models.HistoryEntry, error)
+	PruneHistory(before time.Time) error
+	MarkReminder(key string, at time.Time) (bool, error)
+	PruneReminders(before time.Time) error
+	PutIdempotencyRecord(record models.IdempotencyRecord) error
+	GetIdempotencyRecord(userID models.UserID, key string) (*models.IdempotencyRecord, error)
+	PruneIdempotencyRecords(before time.Time) error
+	EventCounts() (map[models.UserID]int, error)
+	Ping(ctx context.Context) error
+	Transaction(fn func(tx repository.Tx) error) error
+}
+
+// instrumentedRepository передает вызовы repo, измеряя их длительность.
+type instrumentedRepository struct {
+	repo Repository
+	m    *Metrics
+}
+
+// Repository возвращает repo, время операций которого попадает в метрики.
+func (m *Metrics) Repository(repo Repository) Repository {
+	return &instrumentedRepository{repo: repo, m: m}
+}
+
+// observe записывает длительность операции, начатой в start.
+func (r *instrumentedRepository) observe(operation string, start time.Time) {
+	r.m.operations.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+}
+
+func (r *instrumentedRepository) Put(userID models.UserID, event models.Event) error {
+	defer r.observe("put", time.Now())
+	return r.repo.Put(userID, event)
+}
+
+func (r *instrumentedRepository) Get(userID models.UserID, eventID models.EventID) (*models.Event, error) {
+	defer r.observe("get", time.Now())
+	return r.repo.Get(userID, eventID)
+}
+
+func (r *instrumentedRepository) Update(userID models.UserID, event models.Event) error {
+	defer r.observe("update", time.Now())
+	return r.repo.Update(userID, event)
+}
+
+func (r *instrumentedRepository) Replace(userID models.UserID, event models.Event) error {
+	defer r.observe("replace", time.Now())
+	return r.repo.Replace(userID, event)
+}
+
+func (r *instrumentedRepository) Delete(userID models.UserID, eventID models.EventID, version int64) error {
+	defer r.observe("delete", time.Now())
+	return r.repo.Delete(userID, eventID, version)
+}
+
+func (r *instrumentedRepository) GetEventsByDateRange(userID models.UserID, start, end time.Time) ([]models.Event, error) {
+	defer r.observe("get_events_by_date_range", time.Now())
+	return r.repo.GetEventsByDateRange(userID, start, end)
+}
+
+func (r *instrumentedRepository) GetFilteredEvents(
+	userID models.UserID, start, end time.Time, filter models.EventFilter,
+) ([]models.Event, error) {
+	defer r.observe("get_filtered_events", time.Now())
+	return r.repo.GetFilteredEvents(userID, start, end, filter)
+}
+
+func (r *instrumentedRepository) GetRecurringEvents(userID models.UserID) ([]models.Event, error) {
+	defer r.observe("get_recurring_events", time.Now())
+	return r.repo.GetRecurringEvents(userID)
+}
+
+func (r *instrumentedRepository) Users() ([]models.UserID, error) {
+	defer r.observe("users", time.Now())
+	return r.repo.Users()
+}
+
+func (r *instrumentedRepository) GetInvitedEvents(userID models.UserID) ([]models.Event, error) {
+	defer r.observe("get_invited_events", time.Now())
+	return r.repo.GetInvitedEvents(userID)
+}
+
+func (r *instrumentedRepository) UpdateAttendee(
+	owner models.UserID, eventID models.EventID, attendee models.Attendee,
+) error {
+	defer r.observe("update_attendee", time.Now())
+	return r.repo.UpdateAttendee(owner, eventID, attendee)
+}
+
+func (r *instrumentedRepository) Search(userID models.UserID, query search.Query) ([]models.Event, error) {
+	defer r.observe("search", time.Now())
+	return r.repo.Search(userID, query)
+}
+
+func (r *instrumentedRepository) AddHistory(userID models.UserID, entry models.HistoryEntry) error {
+	defer r.observe("add_history", time.Now())
+	return r.repo.AddHistory(userID, entry)
+}
+
+func (r *instrumentedRepository) GetHistory(userID models.UserID, eventID models.EventID) ([]models.HistoryEntry, error) {
+	defer r.observe("get_history", time.Now())
+	return r.repo.GetHistory(userID, eventID)
+}
+
+func (r *instrumentedRepository) PruneHistory(before time.Time) error {
+	defer r.observe("prune_history", time.Now())
+	return r.repo.PruneHistory(before)
+}
+
+func (r *instrumentedRepository) MarkReminder(key string, at time.Time) (bool, error) {
+	defer r.observe("mark_reminder", time.Now())
+	return r.repo.MarkReminder(key, at)
+}
+
+func (r *instrumentedRepository) PruneReminders(before time.Time) error {
+	defer r.observe("prune_reminders", time.Now())
+	return r.repo.PruneReminders(before)
+}
+
+func (r *instrumentedRepository) PutIdempotencyRecord(record models.IdempotencyRecord) error {
+	defer r.observe("put_idempotency_record", time.Now())
+	return r.repo.PutIdempotencyRecord(record)
+}
+
+func (r *instrumentedRepository) GetIdempotencyRecord(userID models.UserID, key string) (*models.IdempotencyRecord, error) {
+	defer r.observe("get_idempotency_record", time.Now())
+	return r.repo.GetIdempotencyRecord(userID, key)
+}
+
+func (r *instrumentedRepository) PruneIdempotencyRecords(before time.Time) error {
+	defer r.observe("prune_idempotency_records", time.Now())
+	return r.repo.PruneIdempotencyRecords(before)
+}
+
+func (r *instrumentedRepository) EventCounts() (map[models.UserID]int, error) {
+	defer r.observe("event_counts", time.Now())
+	return r.repo.EventCounts()
+}
+
+func (r *instrumentedRepository) Ping(ctx context.Context) error {
+	defer r.observe("ping", time.Now())
+	return r.repo.Ping(ctx)
+}
+
+// Transaction измеряет транзакцию целиком, операции внутри нее не измеряются.
+func (r *instrumentedRepository) Transaction(fn func(tx repository.Tx) error) error {
+	defer r.observe("transaction", time.Now())
+	return r.repo.Transaction(fn)
+}
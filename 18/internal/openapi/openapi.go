@@ -0,0 +1,378 @@
+// Package openapi содержит спецификацию OpenAPI 3 API календаря
+// и проверяет по ней параметры и тела запросов.
+package openapi
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Document - спецификация API в формате OpenAPI 3 (JSON).
+//
+//go:embed openapi.json
+var Document []byte
+
+const (
+	schemaRefPrefix    = "#/components/schemas/"
+	parameterRefPrefix = "#/components/parameters/"
+)
+
+// maxBodySize - наибольший размер проверяемого тела JSON.
+const maxBodySize = 1 << 20
+
+// FieldError описывает ошибку в одном параметре или поле тела запроса.
+type FieldError struct {
+	// Field - имя параметра или путь к полю тела, например event.exdates[1].
+	// Пусто, если ошибка относится ко всему телу.
+	Field string `json:"field,omitempty"`
+	// In - где находится поле: query, path или body.
+	In      string `json:"in"`
+	Message string `json:"message"`
+}
+
+// ValidationError возвращается, если запрос не соответствует спецификации.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		field := f.Field
+		if field == "" {
+			field = f.In
+		}
+		msgs[i] = field + ": " + f.Message
+	}
+
+	return strings.Join(msgs, "; ")
+}
+
+type parameter struct {
+	Ref      string  `json:"$ref"`
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+}
+
+type requestBody struct {
+	Required bool `json:"required"`
+	Content  map[string]struct {
+		Schema *Schema `json:"schema"`
+	} `json:"content"`
+}
+
+type operation struct {
+	Parameters  []*parameter `json:"parameters"`
+	RequestBody *requestBody `json:"requestBody"`
+}
+
+type document struct {
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Schemas    map[string]*Schema    `json:"schemas"`
+		Parameters map[string]*parameter `json:"parameters"`
+	} `json:"components"`
+}
+
+// route - путь из спецификации и его операции по методам HTTP.
+type route struct {
+	// segments - сегменты шаблона пути, параметры записаны в фигурных скобках.
+	segments []string
+	ops      map[string]*operation
+}
+
+// Validator проверяет запросы по спецификации. Запросы к путям и методам,
+// которых нет в спецификации, не проверяются.
+type Validator struct {
+	routes  []route
+	schemas map[string]*Schema
+}
+
+// New создает Validator по встроенной спецификации Document.
+func New() (*Validator, error) {
+	return parse(Document)
+}
+
+func parse(data []byte) (*Validator, error) {
+	var doc document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parse openapi document: %w", err)
+	}
+
+	v := &Validator{schemas: doc.Components.Schemas}
+
+	resolveParam := func(p *parameter) (*parameter, error) {
+		if p.Ref == "" {
+			return p, nil
+		}
+		resolved, ok := doc.Components.Parameters[strings.TrimPrefix(p.Ref, parameterRefPrefix)]
+		if !ok {
+			return nil, fmt.Errorf("unresolved reference %s", p.Ref)
+		}
+		return resolved, nil
+	}
+
+	for path, item := range doc.Paths {
+		var common []*parameter
+		if raw, ok := item["parameters"]; ok {
+			if err := json.Unmarshal(raw, &common); err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+		}
+
+		r := route{segments: strings.Split(path, "/"), ops: make(map[string]*operation)}
+
+		for method, raw := range item {
+			if method == "parameters" {
+				continue
+			}
+
+			var op operation
+			if err := json.Unmarshal(raw, &op); err != nil {
+				return nil, fmt.Errorf("%s %s: %w", method, path, err)
+			}
+
+			// Параметры операции дополняют параметры пути.
+			params := make([]*parameter, 0, len(common)+len(op.Parameters))
+			for _, p := range append(common, op.Parameters...) {
+				resolved, err := resolveParam(p)
+				if err != nil {
+					return nil, fmt.Errorf("%s %s: %w", method, path, err)
+				}
+				params = append(params, resolved)
+			}
+			op.Parameters = params
+
+			r.ops[strings.ToUpper(method)] = &op
+		}
+
+		v.routes = append(v.routes, r)
+	}
+
+	if err := v.checkRefs(); err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+// checkRefs проверяет, что все ссылки на схемы разрешаются.
+func (v *Validator) checkRefs() error {
+	visited := make(map[*Schema]bool)
+
+	var walk func(s *Schema) error
+	walk = func(s *Schema) error {
+		if s == nil || visited[s] {
+			return nil
+		}
+		visited[s] = true
+
+		if s.Ref != "" {
+			target, ok := v.schemas[strings.TrimPrefix(s.Ref, schemaRefPrefix)]
+			if !ok {
+				return fmt.Errorf("unresolved reference %s", s.Ref)
+			}
+			return walk(target)
+		}
+
+		for _, p := range s.Properties {
+			if err := walk(p); err != nil {
+				return err
+			}
+		}
+		for _, alt := range s.AnyOf {
+			if err := walk(alt); err != nil {
+				return err
+			}
+		}
+		return walk(s.Items)
+	}
+
+	for _, s := range v.schemas {
+		if err := walk(s); err != nil {
+			return err
+		}
+	}
+	for _, r := range v.routes {
+		for _, op := range r.ops {
+			for _, p := range op.Parameters {
+				if err := walk(p.Schema); err != nil {
+					return err
+				}
+			}
+			if op.RequestBody != nil {
+				for _, media := range op.RequestBody.Content {
+					if err := walk(media.Schema); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// Validate проверяет параметры и тело запроса. Если запрос не соответствует
+// спецификации, вернет *ValidationError. Прочитанное тело восстанавливается
+// для следующего обработчика.
+func (v *Validator) Validate(r *http.Request) error {
+	op, pathParams := v.find(r.Method, r.URL.Path)
+	if op == nil {
+		return nil
+	}
+
+	val := &validator{schemas: v.schemas}
+
+	// Тело JSON не разбирается как форма, даже если клиент прислал его
+	// с Content-Type формы: обработчики читают тело целиком.
+	var media *Schema
+	if op.RequestBody != nil {
+		if content, ok := op.RequestBody.Content["application/json"]; ok {
+			media = content.Schema
+		}
+	}
+
+	query := r.URL.Query()
+	if media == nil {
+		if err := r.ParseForm(); err != nil {
+			return &ValidationError{Fields: []FieldError{{In: "query", Message: err.Error()}}}
+		}
+		query = r.Form
+	}
+
+	for _, p := range op.Parameters {
+		var value string
+		switch p.In {
+		case "query":
+			value = query.Get(p.Name)
+		case "path":
+			value = pathParams[p.Name]
+		default:
+			continue
+		}
+
+		val.in = p.In
+		if value == "" {
+			if p.Required {
+				val.fail(p.Name, "is required")
+			}
+			continue
+		}
+		if p.Schema != nil {
+			val.validate(p.Schema, val.coerce(p.Schema, value), p.Name)
+		}
+	}
+
+	if media != nil {
+		val.in = "body"
+		if err := val.validateBody(r, media, op.RequestBody.Required); err != nil {
+			return err
+		}
+	}
+
+	if len(val.errs) > 0 {
+		return &ValidationError{Fields: val.errs}
+	}
+
+	return nil
+}
+
+// validateBody читает и проверяет тело JSON, после чего восстанавливает r.Body.
+func (v *validator) validateBody(r *http.Request, schema *Schema, required bool) error {
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize+1))
+	if err != nil {
+		return err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(data))
+
+	if len(data) > maxBodySize {
+		v.fail("", "must not exceed %d bytes", maxBodySize)
+		return nil
+	}
+
+	if len(bytes.TrimSpace(data)) == 0 {
+		if required {
+			v.fail("", "is required")
+		}
+		return nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var value any
+	if err := dec.Decode(&value); err != nil {
+		v.fail("", "invalid JSON: %v", err)
+		return nil
+	}
+
+	v.validate(schema, value, "")
+	return nil
+}
+
+// coerce приводит строковое значение параметра к типу его схемы,
+// чтобы проверять его так же, как поле JSON.
+func (v *validator) coerce(s *Schema, value string) any {
+	switch v.resolve(s).Type {
+	case "boolean":
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	case "integer", "number":
+		if _, err := strconv.ParseFloat(value, 64); err == nil {
+			return json.Number(value)
+		}
+	}
+
+	return value
+}
+
+// find возвращает операцию для метода и пути и значения параметров пути.
+// Если путь подходит под несколько шаблонов, выбирается самый конкретный.
+func (v *Validator) find(method, path string) (*operation, map[string]string) {
+	segments := strings.Split(path, "/")
+
+	var (
+		best       *operation
+		bestParams map[string]string
+		bestScore  = -1
+	)
+
+	for _, r := range v.routes {
+		op, ok := r.ops[method]
+		if !ok || len(r.segments) != len(segments) {
+			continue
+		}
+
+		params := make(map[string]string)
+		score := 0
+		matched := true
+
+		for i, seg := range r.segments {
+			if name, ok := strings.CutPrefix(seg, "{"); ok {
+				params[strings.TrimSuffix(name, "}")] = segments[i]
+				continue
+			}
+			if seg != segments[i] {
+				matched = false
+				break
+			}
+			score++
+		}
+
+		if matched && score > bestScore {
+			best, bestParams, bestScore = op, params, score
+		}
+	}
+
+	return best, bestParams
+}
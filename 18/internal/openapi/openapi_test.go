@@ -0,0 +1,191 @@
+package openapi
+
+import (
+	"errors"
+	"io"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDocument(t *testing.T) {
+	v, err := New()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if op, _ := v.find("POST", "/create_event"); op == nil || op.RequestBody == nil {
+		t.Error("expected create_event operation with request body")
+	}
+
+	op, params := v.find("PATCH", "/v2/users/user1/events/42")
+	if op == nil {
+		t.Fatal("expected patch operation")
+	}
+	if !reflect.DeepEqual(params, map[string]string{"user": "user1", "id": "42"}) {
+		t.Errorf("unexpected path params %v", params)
+	}
+}
+
+func TestParseUnresolvedRef(t *testing.T) {
+	doc := `{"paths": {"/a": {"post": {"requestBody": {"content": {"application/json": {
+		"schema": {"$ref": "#/components/schemas/Missing"}}}}}}}}`
+
+	if _, err := parse([]byte(doc)); err == nil {
+		t.Error("expected error for unresolved reference")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	v, err := New()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	testCases := []struct {
+		name        string
+		method      string
+		target      string
+		contentType string
+		body        string
+		expected    []FieldError
+	}{
+		{
+			name:   "valid v1 create",
+			method: "POST", target: "/create_event",
+			contentType: "application/x-www-form-urlencoded",
+			body:        `{"user_id": "1", "event": {"start": "2025-02-15T10:00:00+03:00", "exdates": ["2025-02-16"]}}`,
+		},
+		{
+			name:   "v1 create with unknown and invalid fields",
+			method: "POST", target: "/create_event",
+			body: `{"user_id": 1, "event": {"start": "tomorrow", "title": "x", "exdates": ["2025-02-16", "bad"]}}`,
+			expected: []FieldError{
+				{Field: "event.exdates[1]", In: "body", Message: "must be a date (YYYY-MM-DD) or date-time (RFC 3339)"},
+				{Field: "event.start", In: "body", Message: "must be a date (YYYY-MM-DD) or date-time (RFC 3339)"},
+				{Field: "event.title", In: "body", Message: "unknown field"},
+				{Field: "user_id", In: "body", Message: "must be a string"},
+			},
+		},
+		{
+			name:   "v1 create without body",
+			method: "POST", target: "/create_event",
+			expected: []FieldError{{In: "body", Message: "is required"}},
+		},
+		{
+			name:   "v1 create with malformed json",
+			method: "POST", target: "/create_event",
+			body:     `{"event":`,
+			expected: []FieldError{{In: "body", Message: "invalid JSON: unexpected EOF"}},
+		},
+		{
+			name:   "missing query parameter",
+			method: "GET", target: "/events_for_day?from=x",
+			expected: []FieldError{{Field: "date", In: "query", Message: "is required"}},
+		},
+		{
+			name:   "invalid date",
+			method: "GET", target: "/events_for_week?user_id=1&date=2025-13-01",
+			expected: []FieldError{{Field: "date", In: "query", Message: "must be a date (YYYY-MM-DD)"}},
+		},
+		{
+			name:   "delete with form body",
+			method: "POST", target: "/delete_event",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "user_id=1&id=42",
+		},
+		{
+			name:   "v2 list with invalid expand",
+			method: "GET", target: "/v2/users/1/events?expand=maybe&from=2025-01-01",
+			expected: []FieldError{{Field: "expand", In: "query", Message: "must be a boolean"}},
+		},
+		{
+			name:   "v2 create without date",
+			method: "POST", target: "/v2/users/1/events",
+			body: `{"event": "x", "overrides": [{"date": "2025-01-01T10:00:00Z", "recurrence_id": null}]}`,
+			expected: []FieldError{
+				{Field: "date", In: "body", Message: "is required"},
+				{Field: "overrides[0].recurrence_id", In: "body", Message: "must not be null"},
+			},
+		},
+		{
+			name:   "v2 patch with nulls",
+			method: "PATCH", target: "/v2/users/1/events/42",
+			body: `{"end": null, "event": "renamed"}`,
+		},
+		{
+			name:   "range without to and with invalid order",
+			method: "GET", target: "/events_for_range?user_id=1&from=2025-01-01&order=up&limit=10",
+			expected: []FieldError{
+				{Field: "to", In: "query", Message: "is required"},
+				{Field: "order", In: "query", Message: "must be one of [asc desc]"},
+			},
+		},
+		{
+			name:   "rsvp with unknown status",
+			method: "POST", target: "/v2/users/1/invitations/2/42/rsvp",
+			body:     `{"status": "needs-action"}`,
+			expected: []FieldError{{Field: "status", In: "body", Message: "must be one of [accepted declined tentative]"}},
+		},
+		{
+			name:   "v2 create with attendees",
+			method: "POST", target: "/v2/users/1/events",
+			body:     `{"date": "2025-01-01T10:00:00Z", "attendees": [{"user_id": "2"}, {"status": "accepted"}]}`,
+			expected: []FieldError{{Field: "attendees[1].user_id", In: "body", Message: "is required"}},
+		},
+		{
+			name:   "v2 batch with invalid operations",
+			method: "POST", target: "/v2/users/1/events/batch",
+			body: `{"atomic": true, "operations": [{"op": "create", "event": {"event": "x"}}, {"op": "move", "id": "42"}]}`,
+			expected: []FieldError{
+				{Field: "operations[0].event.date", In: "body", Message: "is required"},
+				{Field: "operations[1].op", In: "body", Message: "must be one of [create update delete]"},
+			},
+		},
+		{
+			name:   "v2 replace with version",
+			method: "PUT", target: "/v2/users/1/events/42",
+			body: `{"date": "2025-01-01T10:00:00Z", "version": 3}`,
+		},
+		{
+			name:   "restore with invalid revision",
+			method: "POST", target: "/v2/users/1/events/42/restore?revision=last",
+			expected: []FieldError{{Field: "revision", In: "query", Message: "must be an integer"}},
+		},
+		{
+			name:   "not described in spec",
+			method: "PROPFIND", target: "/caldav/1/",
+			body: "<propfind/>",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body))
+			if tc.contentType != "" {
+				r.Header.Set("Content-Type", tc.contentType)
+			}
+
+			err := v.Validate(r)
+
+			var verr *ValidationError
+			if tc.expected == nil {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			} else if !errors.As(err, &verr) {
+				t.Fatalf("expected validation error, got %v", err)
+			} else if !reflect.DeepEqual(verr.Fields, tc.expected) {
+				t.Errorf("fields mismatch:\ngot  %+v\nwant %+v", verr.Fields, tc.expected)
+			}
+
+			if tc.contentType == "" {
+				body, _ := io.ReadAll(r.Body)
+				if string(body) != tc.body {
+					t.Errorf("expected body to be restored, got %q", body)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,195 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Schema - подмножество JSON Schema из OpenAPI 3.0, которое используется в спецификации.
+type Schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 string             `json:"type"`
+	Format               string             `json:"format"`
+	Nullable             bool               `json:"nullable"`
+	Enum                 []any              `json:"enum"`
+	MinLength            *int               `json:"minLength"`
+	Properties           map[string]*Schema `json:"properties"`
+	Required             []string           `json:"required"`
+	AdditionalProperties *bool              `json:"additionalProperties"`
+	Items                *Schema            `json:"items"`
+	AnyOf                []*Schema          `json:"anyOf"`
+}
+
+// formats - проверки значений для поддерживаемых форматов строк.
+var formats = map[string]struct {
+	check func(string) error
+	name  string
+}{
+	"date": {
+		check: func(s string) error { _, err := time.Parse(time.DateOnly, s); return err },
+		name:  "date (YYYY-MM-DD)",
+	},
+	"date-time": {
+		check: func(s string) error { _, err := time.Parse(time.RFC3339, s); return err },
+		name:  "date-time (RFC 3339)",
+	},
+}
+
+// validator проверяет значения по схемам, разрешая ссылки на components.
+type validator struct {
+	schemas map[string]*Schema
+	in      string
+	errs    []FieldError
+}
+
+func (v *validator) fail(field, format string, args ...any) {
+	v.errs = append(v.errs, FieldError{Field: field, In: v.in, Message: fmt.Sprintf(format, args...)})
+}
+
+// resolve возвращает схему, на которую ссылается s, или саму s.
+func (v *validator) resolve(s *Schema) *Schema {
+	for s.Ref != "" {
+		s = v.schemas[strings.TrimPrefix(s.Ref, schemaRefPrefix)]
+	}
+	return s
+}
+
+// validate проверяет значение, разобранное json.Decoder с UseNumber.
+func (v *validator) validate(s *Schema, value any, field string) {
+	s = v.resolve(s)
+
+	if value == nil {
+		if !s.Nullable {
+			v.fail(field, "must not be null")
+		}
+		return
+	}
+
+	if len(s.AnyOf) > 0 {
+		v.validateAnyOf(s, value, field)
+		return
+	}
+
+	switch s.Type {
+	case "object":
+		obj, ok := value.(map[string]any)
+		if !ok {
+			v.fail(field, "must be an object")
+			return
+		}
+		v.validateObject(s, obj, field)
+
+	case "array":
+		arr, ok := value.([]any)
+		if !ok {
+			v.fail(field, "must be an array")
+			return
+		}
+		if s.Items != nil {
+			for i, item := range arr {
+				v.validate(s.Items, item, fmt.Sprintf("%s[%d]", field, i))
+			}
+		}
+
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			v.fail(field, "must be a string")
+			return
+		}
+		v.validateString(s, str, field)
+
+	case "integer":
+		n, ok := value.(json.Number)
+		if _, err := n.Int64(); !ok || err != nil {
+			v.fail(field, "must be an integer")
+		}
+
+	case "number":
+		if _, ok := value.(json.Number); !ok {
+			v.fail(field, "must be a number")
+		}
+
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			v.fail(field, "must be a boolean")
+		}
+	}
+}
+
+func (v *validator) validateObject(s *Schema, obj map[string]any, field string) {
+	for _, name := range s.Required {
+		if _, ok := obj[name]; !ok {
+			v.fail(join(field, name), "is required")
+		}
+	}
+
+	// Сортируем ключи, чтобы порядок ошибок не зависел от порядка обхода map.
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		prop, ok := s.Properties[key]
+		if !ok {
+			if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+				v.fail(join(field, key), "unknown field")
+			}
+			continue
+		}
+		v.validate(prop, obj[key], join(field, key))
+	}
+}
+
+func (v *validator) validateString(s *Schema, str, field string) {
+	if s.MinLength != nil && len([]rune(str)) < *s.MinLength {
+		v.fail(field, "must be at least %d characters long", *s.MinLength)
+	}
+
+	if len(s.Enum) > 0 && !slices.Contains(s.Enum, any(str)) {
+		v.fail(field, "must be one of %v", s.Enum)
+	}
+
+	if f, ok := formats[s.Format]; ok {
+		if err := f.check(str); err != nil {
+			v.fail(field, "must be a %s", f.name)
+		}
+	}
+}
+
+// validateAnyOf проверяет, что значение подходит хотя бы под одну из схем.
+func (v *validator) validateAnyOf(s *Schema, value any, field string) {
+	names := make([]string, 0, len(s.AnyOf))
+
+	for _, alt := range s.AnyOf {
+		sub := &validator{schemas: v.schemas, in: v.in}
+		sub.validate(alt, value, field)
+		if len(sub.errs) == 0 {
+			return
+		}
+		names = append(names, describe(v.resolve(alt)))
+	}
+
+	v.fail(field, "must be a %s", strings.Join(names, " or "))
+}
+
+// describe возвращает короткое описание схемы для сообщения об ошибке.
+func describe(s *Schema) string {
+	if f, ok := formats[s.Format]; ok {
+		return f.name
+	}
+	return s.Type
+}
+
+// join добавляет имя поля к пути.
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
@@ -0,0 +1,130 @@
+// Package pubsub рассылает уведомления об изменениях событий подписчикам пользователя.
+package pubsub
+
+import (
+	"errors"
+	"sync"
+
+	"l2.18/pkg/models"
+)
+
+var (
+	// ErrSlowConsumer - подписка закрыта, потому что подписчик не успевал читать уведомления.
+	ErrSlowConsumer = errors.New("slow consumer")
+	// ErrClosed - подписка закрыта вместе с Hub.
+	ErrClosed = errors.New("hub closed")
+)
+
+// Hub хранит подписки пользователей и рассылает им уведомления.
+// Публикация не блокируется: подписка, буфер которой заполнен, закрывается
+// с ErrSlowConsumer, чтобы медленный клиент не задерживал остальных.
+type Hub struct {
+	mu     sync.Mutex
+	subs   map[models.UserID]map[*Subscription]struct{}
+	buffer int
+	closed bool
+}
+
+// NewHub создает новый Hub. buffer - сколько уведомлений может ждать чтения в подписке.
+func NewHub(buffer int) *Hub {
+	return &Hub{
+		subs:   make(map[models.UserID]map[*Subscription]struct{}),
+		buffer: buffer,
+	}
+}
+
+// Subscription - подписка на изменения событий одного пользователя.
+type Subscription struct {
+	hub    *Hub
+	userID models.UserID
+	ch     chan models.Change
+	err    error
+}
+
+// Changes возвращает канал уведомлений. Канал закрывается, когда подписка
+// закрыта, причину можно узнать через Err.
+func (s *Subscription) Changes() <-chan models.Change {
+	return s.ch
+}
+
+// Err возвращает причину закрытия подписки: ErrSlowConsumer, ErrClosed
+// или nil, если подписка закрыта через Close. Имеет смысл после закрытия Changes.
+func (s *Subscription) Err() error {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+
+	return s.err
+}
+
+// Close отменяет подписку.
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+
+	s.hub.remove(s, nil)
+}
+
+// Subscribe подписывается на изменения событий пользователя.
+// После Close возвращает уже закрытую подписку.
+func (h *Hub) Subscribe(userID models.UserID) *Subscription {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	sub := &Subscription{hub: h, userID: userID, ch: make(chan models.Change, h.buffer)}
+
+	if h.closed {
+		sub.err = ErrClosed
+		close(sub.ch)
+		return sub
+	}
+
+	if h.subs[userID] == nil {
+		h.subs[userID] = make(map[*Subscription]struct{})
+	}
+	h.subs[userID][sub] = struct{}{}
+
+	return sub
+}
+
+// Publish рассылает уведомление всем подпискам пользователя.
+func (h *Hub) Publish(userID models.UserID, change models.Change) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for sub := range h.subs[userID] {
+		select {
+		case sub.ch <- change:
+		default:
+			h.remove(sub, ErrSlowConsumer)
+		}
+	}
+}
+
+// Close закрывает все подписки с ErrClosed. Новые подписки сразу закрыты.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for _, subs := range h.subs {
+		for sub := range subs {
+			h.remove(sub, ErrClosed)
+		}
+	}
+}
+
+// remove удаляет подписку и закрывает ее канал. Вызывается под h.mu.
+func (h *Hub) remove(sub *Subscription, err error) {
+	subs := h.subs[sub.userID]
+	if _, ok := subs[sub]; !ok {
+		return
+	}
+
+	delete(subs, sub)
+	if len(subs) == 0 {
+		delete(h.subs, sub.userID)
+	}
+
+	sub.err = err
+	close(sub.ch)
+}
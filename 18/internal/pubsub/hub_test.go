@@ -0,0 +1,84 @@
+package pubsub
+
+import (
+	"errors"
+	"testing"
+
+	"l2.18/pkg/models"
+)
+
+func TestPublish(t *testing.T) {
+	hub := NewHub(1)
+
+	sub := hub.Subscribe("user1")
+	other := hub.Subscribe("user2")
+	defer other.Close()
+
+	hub.Publish("user1", models.Change{Type: models.ChangeCreated, EventID: "1"})
+
+	select {
+	case change := <-sub.Changes():
+		if change.EventID != "1" {
+			t.Errorf("unexpected change %+v", change)
+		}
+	default:
+		t.Fatal("expected a change")
+	}
+
+	select {
+	case change := <-other.Changes():
+		t.Errorf("unexpected change for other user %+v", change)
+	default:
+	}
+
+	sub.Close()
+	if _, ok := <-sub.Changes(); ok {
+		t.Error("expected closed subscription")
+	}
+	if err := sub.Err(); err != nil {
+		t.Errorf("expected nil error, got %v", err)
+	}
+
+	// Публикация после отписки не должна паниковать.
+	hub.Publish("user1", models.Change{Type: models.ChangeDeleted, EventID: "1"})
+}
+
+func TestSlowConsumer(t *testing.T) {
+	hub := NewHub(1)
+	sub := hub.Subscribe("user1")
+
+	hub.Publish("user1", models.Change{EventID: "1"})
+	hub.Publish("user1", models.Change{EventID: "2"})
+
+	if change := <-sub.Changes(); change.EventID != "1" {
+		t.Errorf("unexpected change %+v", change)
+	}
+	if _, ok := <-sub.Changes(); ok {
+		t.Fatal("expected slow consumer to be dropped")
+	}
+	if err := sub.Err(); !errors.Is(err, ErrSlowConsumer) {
+		t.Errorf("expected %v, got %v", ErrSlowConsumer, err)
+	}
+}
+
+func TestClose(t *testing.T) {
+	hub := NewHub(1)
+	sub := hub.Subscribe("user1")
+
+	hub.Close()
+
+	if _, ok := <-sub.Changes(); ok {
+		t.Fatal("expected subscription to be closed")
+	}
+	if err := sub.Err(); !errors.Is(err, ErrClosed) {
+		t.Errorf("expected %v, got %v", ErrClosed, err)
+	}
+
+	late := hub.Subscribe("user1")
+	if _, ok := <-late.Changes(); ok || !errors.Is(late.Err(), ErrClosed) {
+		t.Error("expected subscription after close to be closed")
+	}
+
+	// Повторное закрытие подписки безопасно.
+	sub.Close()
+}
@@ -0,0 +1,122 @@
+// Package ratelimit ограничивает частоту запросов отдельно для каждого ключа
+// (пользователя или адреса клиента) алгоритмом token bucket.
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// minIdleTTL - наименьший простой, после которого ведро ключа удаляется.
+const minIdleTTL = 10 * time.Minute
+
+type bucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// Limiter хранит отдельное ведро токенов для каждого ключа.
+type Limiter struct {
+	mu        sync.Mutex
+	limit     rate.Limit
+	burst     int
+	buckets   map[string]*bucket
+	idleTTL   time.Duration
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// New создает Limiter, разрешающий каждому ключу в среднем perSecond запросов
+// в секунду и до burst запросов подряд.
+func New(perSecond float64, burst int) *Limiter {
+	return &Limiter{
+		limit:   rate.Limit(perSecond),
+		burst:   burst,
+		buckets: make(map[string]*bucket),
+		idleTTL: idleTTL(rate.Limit(perSecond), burst),
+		now:     time.Now,
+	}
+}
+
+// idleTTL возвращает, через сколько простоя ведро ключа удаляется: не раньше,
+// чем пустое ведро снова наполнится (burst / limit), поэтому удаление не меняет
+// поведения. Ведра, которые не наполняются, не удаляются.
+func idleTTL(limit rate.Limit, burst int) time.Duration {
+	if limit <= 0 {
+		return time.Duration(math.MaxInt64)
+	}
+
+	refill := float64(burst) / float64(limit) * float64(time.Second)
+	if refill >= math.MaxInt64 {
+		return time.Duration(math.MaxInt64)
+	}
+	return max(minIdleTTL, time.Duration(math.Ceil(refill)))
+}
+
+// Allow расходует токен ключа key. Если токенов нет, вернет false и время,
+// через которое запрос будет разрешен.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.buckets[key] = b
+	}
+	b.lastSeen = now
+
+	r := b.limiter.ReserveN(now, 1)
+	if !r.OK() {
+		return false, time.Duration(math.MaxInt64)
+	}
+	if delay := r.DelayFrom(now); delay > 0 {
+		r.CancelAt(now)
+		return false, delay
+	}
+
+	return true, 0
+}
+
+// Peek сообщает, разрешил бы Allow запрос ключа key сейчас, не расходуя токен.
+// Если нет, вернет также время, через которое запрос будет разрешен.
+func (l *Limiter) Peek(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.buckets[key]
+	if !ok {
+		return l.burst > 0, 0
+	}
+
+	now := l.now()
+	tokens := b.limiter.TokensAt(now)
+	if tokens >= 1 {
+		return true, 0
+	}
+	if l.limit <= 0 {
+		return false, time.Duration(math.MaxInt64)
+	}
+	return false, time.Duration(math.Ceil((1 - tokens) / float64(l.limit) * float64(time.Second)))
+}
+
+// sweep удаляет ведра ключей, не встречавшихся дольше l.idleTTL.
+// Проверка выполняется не чаще раза в minIdleTTL. Вызывается под l.mu.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < minIdleTTL {
+		return
+	}
+	l.lastSweep = now
+
+	for key, b := range l.buckets {
+		if now.Sub(b.lastSeen) > l.idleTTL {
+			delete(l.buckets, key)
+		}
+	}
+}
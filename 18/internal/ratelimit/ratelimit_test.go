@@ -0,0 +1,101 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAllow(t *testing.T) {
+	now := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+	l := New(2, 3)
+	l.now = func() time.Time { return now }
+
+	for i := range 3 {
+		if ok, _ := l.Allow("user1"); !ok {
+			t.Fatalf("request %d: expected to be allowed within burst", i)
+		}
+	}
+
+	ok, retryAfter := l.Allow("user1")
+	if ok || retryAfter != 500*time.Millisecond {
+		t.Errorf("expected rejection with retry after 500ms, got %v, %s", ok, retryAfter)
+	}
+	if ok, _ := l.Allow("user2"); !ok {
+		t.Error("expected another key to have its own bucket")
+	}
+
+	// Отклоненный запрос не расходует токен: через полсекунды он появляется снова.
+	now = now.Add(500 * time.Millisecond)
+	if ok, _ := l.Allow("user1"); !ok {
+		t.Error("expected request to be allowed after refill")
+	}
+	if ok, _ := l.Allow("user1"); ok {
+		t.Error("expected bucket to be empty again")
+	}
+}
+
+func TestPeek(t *testing.T) {
+	now := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+	l := New(2, 2)
+	l.now = func() time.Time { return now }
+
+	for i := range 4 {
+		if ok, _ := l.Peek("user1"); !ok {
+			t.Fatalf("peek %d: expected to be allowed", i)
+		}
+	}
+
+	l.Allow("user1")
+	l.Allow("user1")
+	ok, retryAfter := l.Peek("user1")
+	if ok || retryAfter != 500*time.Millisecond {
+		t.Errorf("expected rejection with retry after 500ms, got %v, %s", ok, retryAfter)
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	if ok, _ := l.Peek("user1"); !ok {
+		t.Error("expected request to be allowed after refill")
+	}
+	if ok, _ := l.Allow("user1"); !ok {
+		t.Error("expected peek not to spend the token")
+	}
+}
+
+func TestSweep(t *testing.T) {
+	now := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+	l := New(1, 1)
+	l.now = func() time.Time { return now }
+
+	l.Allow("user1")
+	now = now.Add(2 * minIdleTTL)
+	l.Allow("user2")
+
+	if _, ok := l.buckets["user1"]; ok {
+		t.Error("expected idle bucket to be removed")
+	}
+	if _, ok := l.buckets["user2"]; !ok {
+		t.Error("expected active bucket to be kept")
+	}
+}
+
+func TestSweepKeepsRefillingBucket(t *testing.T) {
+	now := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+	// Пустое ведро наполняется 1000 секунд - дольше minIdleTTL.
+	l := New(1, 1000)
+	l.now = func() time.Time { return now }
+
+	for range 1000 {
+		l.Allow("tenant")
+	}
+	now = now.Add(minIdleTTL + time.Minute)
+	l.Allow("other")
+
+	if _, ok := l.buckets["tenant"]; !ok {
+		t.Fatal("expected refilling bucket to be kept")
+	}
+	for i := range 661 {
+		if ok, _ := l.Allow("tenant"); ok != (i < 660) {
+			t.Fatalf("request %d: expected allowed %v, got %v", i, i < 660, ok)
+		}
+	}
+}
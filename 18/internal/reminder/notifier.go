@@ -0,0 +1,107 @@
+package reminder
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"l2.18/pkg/models"
+)
+
+// webhookAttempts - количество попыток доставки в вебхук.
+const webhookAttempts = 3
+
+// LogNotifier записывает напоминания в лог.
+type LogNotifier struct {
+	log logger
+}
+
+// NewLogNotifier создает новый LogNotifier.
+func NewLogNotifier(log logger) *LogNotifier {
+	return &LogNotifier{log: log}
+}
+
+// Notify записывает напоминание в лог.
+func (n *LogNotifier) Notify(ctx context.Context, reminder Reminder) error {
+	n.log.Info("reminder",
+		"tenant_id", reminder.TenantID,
+		"user_id", reminder.UserID,
+		"event_id", reminder.Event.ID,
+		"event", reminder.Event.Event,
+		"start", reminder.Event.Date,
+		"before", reminder.Before.String())
+
+	return nil
+}
+
+// WebhookNotifier отправляет напоминания POST-запросом с JSON на заданный адрес.
+type WebhookNotifier struct {
+	url     string
+	client  *http.Client
+	backoff time.Duration
+}
+
+// NewWebhookNotifier создает новый WebhookNotifier.
+func NewWebhookNotifier(url string, client *http.Client) *WebhookNotifier {
+	return &WebhookNotifier{url: url, client: client, backoff: time.Second}
+}
+
+type webhookPayload struct {
+	// TenantID не передается для арендатора по умолчанию.
+	TenantID models.TenantID `json:"tenant_id,omitempty"`
+	UserID   models.UserID   `json:"user_id"`
+	Event    models.Event    `json:"event"`
+	Before   string          `json:"before"`
+}
+
+// Notify отправляет напоминание. Неудачная доставка (ошибка сети или ответ
+// не 2xx) повторяется webhookAttempts раз с растущей паузой.
+func (n *WebhookNotifier) Notify(ctx context.Context, reminder Reminder) error {
+	body, err := json.Marshal(webhookPayload{
+		TenantID: reminder.TenantID,
+		UserID:   reminder.UserID,
+		Event:    reminder.Event,
+		Before:   reminder.Before.String(),
+	})
+	if err != nil {
+		return err
+	}
+
+	backoff := n.backoff
+	for attempt := 1; ; attempt++ {
+		err = n.post(ctx, body)
+		if err == nil || attempt == webhookAttempts {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func (n *WebhookNotifier) post(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with %s", resp.Status)
+	}
+
+	return nil
+}
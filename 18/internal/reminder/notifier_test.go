@@ -0,0 +1,104 @@
+package reminder
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"l2.18/pkg/models"
+)
+
+func TestWebhookNotifier(t *testing.T) {
+	testCases := []struct {
+		name     string
+		statuses []int
+		wantErr  bool
+	}{
+		{
+			name:     "success - first attempt",
+			statuses: []int{http.StatusOK},
+			wantErr:  false,
+		},
+		{
+			name:     "success - after retry",
+			statuses: []int{http.StatusBadGateway, http.StatusNoContent},
+			wantErr:  false,
+		},
+		{
+			name:     "failure - all attempts failed",
+			statuses: []int{http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError},
+			wantErr:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls int
+			var payload webhookPayload
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+					t.Errorf("decode payload: %v", err)
+				}
+				w.WriteHeader(tc.statuses[calls])
+				calls++
+			}))
+			defer srv.Close()
+
+			n := NewWebhookNotifier(srv.URL, srv.Client())
+			n.backoff = time.Millisecond
+
+			err := n.Notify(context.Background(), Reminder{
+				UserID: "user1",
+				Event:  models.Event{ID: "1", Date: time.Now(), Event: "event"},
+				Before: 15 * time.Minute,
+			})
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if calls != len(tc.statuses) {
+				t.Errorf("expected %d calls, got %d", len(tc.statuses), calls)
+			}
+			if payload.UserID != "user1" || payload.Event.ID != "1" || payload.Before != "15m0s" {
+				t.Errorf("unexpected payload %+v", payload)
+			}
+		})
+	}
+}
+
+func TestWebhookNotifierTenant(t *testing.T) {
+	var payload map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("decode payload: %v", err)
+		}
+	}))
+	defer srv.Close()
+
+	n := NewWebhookNotifier(srv.URL, srv.Client())
+	reminder := Reminder{
+		TenantID: "acme",
+		UserID:   "bob",
+		Event:    models.Event{ID: "1", Date: time.Now(), Event: "event"},
+		Before:   15 * time.Minute,
+	}
+	if err := n.Notify(context.Background(), reminder); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if payload["tenant_id"] != "acme" || payload["user_id"] != "bob" {
+		t.Errorf("expected tenant acme and user bob, got %v", payload)
+	}
+
+	// Для арендатора по умолчанию тело не меняется.
+	reminder.TenantID, payload = "", nil
+	if err := n.Notify(context.Background(), reminder); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := payload["tenant_id"]; ok || payload["user_id"] != "bob" {
+		t.Errorf("expected no tenant for the default tenant, got %v", payload)
+	}
+}
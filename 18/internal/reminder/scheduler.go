@@ -0,0 +1,238 @@
+// Package reminder рассылает напоминания о предстоящих событиях.
+package reminder
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"l2.18/internal/tenant"
+	"l2.18/pkg/models"
+)
+
+// pruneInterval - как часто удаляются отметки напоминаний о прошедших событиях.
+const pruneInterval = time.Hour
+
+const (
+	// defaultWorkers - сколько напоминаний по умолчанию доставляется одновременно.
+	defaultWorkers = 4
+	// defaultQueue - сколько напоминаний по умолчанию может ждать доставки.
+	defaultQueue = 1000
+)
+
+// Reminder - напоминание о событии (или вхождении серии), которое начнется через Before.
+type Reminder struct {
+	// TenantID и UserID - арендатор и пользователь события (не ключ хранилища).
+	TenantID models.TenantID
+	UserID   models.UserID
+	Event    models.Event
+	Before   time.Duration
+}
+
+// Notifier доставляет напоминания.
+type Notifier interface {
+	Notify(ctx context.Context, reminder Reminder) error
+}
+
+// Store хранит отметки отправленных напоминаний. Для постоянного хранилища
+// отметки переживают перезапуск, и напоминания не отправляются повторно.
+type Store interface {
+	MarkReminder(key string, at time.Time) (bool, error)
+	PruneReminders(before time.Time) error
+}
+
+type eventsService interface {
+	GetUsers() ([]models.UserID, error)
+	GetEvents(userID models.UserID, start, end time.Time, filter models.EventFilter) ([]models.Event, error)
+}
+
+type logger interface {
+	Info(msg string, args ...any)
+	Error(msg string, args ...any)
+}
+
+// Config задает параметры планировщика.
+type Config struct {
+	// Offsets - за сколько до начала события отправлять напоминания.
+	Offsets []time.Duration
+	// Grace - насколько напоминание может опоздать (например, если сервер
+	// был остановлен). Более старые напоминания пропускаются.
+	Grace time.Duration
+	// Workers - сколько напоминаний доставляется одновременно, по умолчанию 4.
+	Workers int
+	// Queue - сколько напоминаний может ждать доставки, по умолчанию 1000.
+	// Если очередь заполнена, напоминание откладывается до следующего просмотра.
+	Queue int
+}
+
+// Scheduler периодически просматривает предстоящие события и отправляет
+// напоминания за заданное время до их начала. Напоминания доставляются
+// в фоне, поэтому медленный получатель не задерживает просмотр событий.
+type Scheduler struct {
+	events   eventsService
+	store    Store
+	notifier Notifier
+	log      logger
+
+	offsets   []time.Duration
+	grace     time.Duration
+	workers   int
+	queue     chan Reminder
+	lastPrune time.Time
+}
+
+// New создает новый Scheduler.
+func New(events eventsService, store Store, notifier Notifier, log logger, cfg Config) *Scheduler {
+	if cfg.Workers <= 0 {
+		cfg.Workers = defaultWorkers
+	}
+	if cfg.Queue <= 0 {
+		cfg.Queue = defaultQueue
+	}
+
+	return &Scheduler{
+		events:   events,
+		store:    store,
+		notifier: notifier,
+		log:      log,
+		offsets:  cfg.Offsets,
+		grace:    cfg.Grace,
+		workers:  cfg.Workers,
+		queue:    make(chan Reminder, cfg.Queue),
+	}
+}
+
+// Run просматривает события каждые interval, пока не будет отменен ctx.
+// Ошибки доставки логируются и не останавливают планировщик. Напоминания,
+// не доставленные к отмене ctx, теряются.
+func (s *Scheduler) Run(ctx context.Context, interval time.Duration) error {
+	if len(s.offsets) == 0 {
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for range s.workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.work(ctx)
+		}()
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.scan(time.Now()); err != nil {
+			s.log.Error("reminder scan failed", "error", err.Error())
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// scan ставит в очередь напоминания, время которых наступило к моменту now,
+// но не раньше, чем now - grace.
+func (s *Scheduler) scan(now time.Time) error {
+	users, err := s.events.GetUsers()
+	if err != nil {
+		return err
+	}
+
+	maxOffset := slices.Max(s.offsets)
+	start := now.Add(-s.grace)
+	end := now.Add(maxOffset + time.Nanosecond)
+
+	var deferred int
+	defer func() {
+		if deferred > 0 {
+			s.log.Error("reminder queue is full", "deferred", deferred)
+		}
+	}()
+
+	for _, userKey := range users {
+		events, err := s.events.GetEvents(userKey, start, end, models.EventFilter{})
+		if err != nil {
+			return fmt.Errorf("get events of %s: %w", userKey, err)
+		}
+		tenantID, userID := tenant.Split(userKey)
+
+		for _, event := range events {
+			for _, offset := range s.offsets {
+				fireAt := event.Date.Add(-offset)
+				if fireAt.After(now) || fireAt.Before(start) {
+					continue
+				}
+
+				// Очередь пополняет только scan, поэтому место в ней не пропадет до отправки.
+				if len(s.queue) == cap(s.queue) {
+					deferred++
+					continue
+				}
+				if err := s.fire(Reminder{TenantID: tenantID, UserID: userID, Event: event, Before: offset}); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if now.Sub(s.lastPrune) >= pruneInterval {
+		if err := s.store.PruneReminders(start); err != nil {
+			return fmt.Errorf("prune reminders: %w", err)
+		}
+		s.lastPrune = now
+	}
+
+	return nil
+}
+
+// fire отмечает напоминание и ставит его в очередь доставки. Отметка ставится
+// до отправки, поэтому при сбое во время доставки напоминание не будет отправлено дважды.
+func (s *Scheduler) fire(reminder Reminder) error {
+	marked, err := s.store.MarkReminder(key(reminder), reminder.Event.Date)
+	if err != nil {
+		return fmt.Errorf("mark reminder: %w", err)
+	}
+	if marked {
+		s.queue <- reminder
+	}
+
+	return nil
+}
+
+// work доставляет напоминания из очереди, пока не будет отменен ctx.
+func (s *Scheduler) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case reminder := <-s.queue:
+			s.deliver(ctx, reminder)
+		}
+	}
+}
+
+// deliver отправляет напоминание. Ошибка доставки логируется.
+func (s *Scheduler) deliver(ctx context.Context, reminder Reminder) {
+	if err := s.notifier.Notify(ctx, reminder); err != nil {
+		s.log.Error("reminder was not delivered",
+			"tenant_id", reminder.TenantID,
+			"user_id", reminder.UserID,
+			"event_id", reminder.Event.ID,
+			"error", err.Error())
+	}
+}
+
+// key однозначно определяет напоминание. Вхождения серии различаются началом,
+// а перенос события меняет ключ, и напоминание отправляется заново.
+func key(reminder Reminder) string {
+	return fmt.Sprintf("%s/%s/%d/%s", tenant.UserKey(reminder.TenantID, reminder.UserID), reminder.Event.ID,
+		reminder.Event.Date.UnixNano(), reminder.Before)
+}
@@ -0,0 +1,253 @@
+package reminder
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"l2.18/internal/repository/memory"
+	"l2.18/internal/tenant"
+	"l2.18/pkg/models"
+)
+
+type fakeEvents struct {
+	events map[models.UserID][]models.Event
+}
+
+func (f *fakeEvents) GetUsers() ([]models.UserID, error) {
+	users := make([]models.UserID, 0, len(f.events))
+	for userID := range f.events {
+		users = append(users, userID)
+	}
+	return users, nil
+}
+
+func (f *fakeEvents) GetEvents(userID models.UserID, start, end time.Time, filter models.EventFilter) ([]models.Event, error) {
+	var result []models.Event
+	for _, e := range f.events[userID] {
+		if e.Overlaps(start, end) {
+			result = append(result, e)
+		}
+	}
+	return result, nil
+}
+
+type recordingNotifier struct {
+	reminders []Reminder
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, reminder Reminder) error {
+	n.reminders = append(n.reminders, reminder)
+	return nil
+}
+
+type nopLogger struct{}
+
+// deliverQueued доставляет напоминания из очереди s, как это делают обработчики Run.
+func deliverQueued(s *Scheduler) {
+	for {
+		select {
+		case reminder := <-s.queue:
+			s.deliver(context.Background(), reminder)
+		default:
+			return
+		}
+	}
+}
+
+func (nopLogger) Info(msg string, args ...any)  {}
+func (nopLogger) Error(msg string, args ...any) {}
+
+func TestScan(t *testing.T) {
+	now := time.Date(2025, time.February, 15, 12, 0, 0, 0, time.UTC)
+
+	events := &fakeEvents{events: map[models.UserID][]models.Event{
+		"user1": {
+			{ID: "soon", Date: now.Add(10 * time.Minute), Event: "in 10 minutes"},
+			{ID: "later", Date: now.Add(50 * time.Minute), Event: "in 50 minutes"},
+			{ID: "missed", Date: now.Add(-time.Hour), Event: "started an hour ago"},
+		},
+	}}
+
+	testCases := []struct {
+		name     string
+		now      time.Time
+		expected []string
+	}{
+		{
+			name:     "fires due reminders",
+			now:      now,
+			expected: []string{"soon/15m0s"},
+		},
+		{
+			name:     "does not fire twice",
+			now:      now.Add(time.Minute),
+			expected: nil,
+		},
+		{
+			name:     "fires next offset",
+			now:      now.Add(10 * time.Minute),
+			expected: []string{"soon/0s"},
+		},
+		{
+			// Напоминание за час опоздало больше, чем на Grace, и пропущено.
+			name:     "skips stale reminders",
+			now:      now.Add(35 * time.Minute),
+			expected: []string{"later/15m0s"},
+		},
+	}
+
+	notifier := &recordingNotifier{}
+	s := New(events, memory.NewEventsRepository(), notifier, nopLogger{}, Config{
+		Offsets: []time.Duration{time.Hour, 15 * time.Minute, 0},
+		Grace:   5 * time.Minute,
+	})
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			notifier.reminders = nil
+
+			if err := s.scan(tc.now); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			deliverQueued(s)
+
+			if len(notifier.reminders) != len(tc.expected) {
+				t.Fatalf("expected %v, got %+v", tc.expected, notifier.reminders)
+			}
+			for i, r := range notifier.reminders {
+				if got := string(r.Event.ID) + "/" + r.Before.String(); got != tc.expected[i] {
+					t.Errorf("reminder[%d]: got %s, want %s", i, got, tc.expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestScanSurvivesRestart(t *testing.T) {
+	now := time.Date(2025, time.February, 15, 12, 0, 0, 0, time.UTC)
+	events := &fakeEvents{events: map[models.UserID][]models.Event{
+		"user1": {{ID: "1", Date: now.Add(5 * time.Minute), Event: "event"}},
+	}}
+	store := memory.NewEventsRepository()
+	cfg := Config{Offsets: []time.Duration{15 * time.Minute}, Grace: 15 * time.Minute}
+
+	first := &recordingNotifier{}
+	s := New(events, store, first, nopLogger{}, cfg)
+	_ = s.scan(now)
+	deliverQueued(s)
+
+	// Новый планировщик с тем же хранилищем - как после перезапуска сервера.
+	second := &recordingNotifier{}
+	s = New(events, store, second, nopLogger{}, cfg)
+	_ = s.scan(now.Add(time.Minute))
+	deliverQueued(s)
+
+	if len(first.reminders) != 1 || len(second.reminders) != 0 {
+		t.Errorf("expected exactly one reminder, got %d and %d",
+			len(first.reminders), len(second.reminders))
+	}
+}
+
+func TestScanTenants(t *testing.T) {
+	now := time.Date(2025, time.February, 15, 12, 0, 0, 0, time.UTC)
+	events := &fakeEvents{events: map[models.UserID][]models.Event{
+		"acme/bob": {{ID: "1", Date: now.Add(5 * time.Minute), Event: "event"}},
+		"bob":      {{ID: "1", Date: now.Add(5 * time.Minute), Event: "event"}},
+	}}
+
+	notifier := &recordingNotifier{}
+	s := New(events, memory.NewEventsRepository(), notifier, nopLogger{}, Config{
+		Offsets: []time.Duration{15 * time.Minute},
+		Grace:   15 * time.Minute,
+	})
+	if err := s.scan(now); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	deliverQueued(s)
+
+	got := make(map[models.TenantID]models.UserID)
+	for _, r := range notifier.reminders {
+		got[r.TenantID] = r.UserID
+	}
+	if len(notifier.reminders) != 2 || got["acme"] != "bob" || got[tenant.Default] != "bob" {
+		t.Errorf("expected reminders for bob of acme and of the default tenant, got %+v", notifier.reminders)
+	}
+}
+
+func TestScanFullQueue(t *testing.T) {
+	now := time.Date(2025, time.February, 15, 12, 0, 0, 0, time.UTC)
+	events := &fakeEvents{events: map[models.UserID][]models.Event{
+		"user1": {
+			{ID: "1", Date: now.Add(5 * time.Minute), Event: "first"},
+			{ID: "2", Date: now.Add(5 * time.Minute), Event: "second"},
+		},
+	}}
+
+	notifier := &recordingNotifier{}
+	s := New(events, memory.NewEventsRepository(), notifier, nopLogger{}, Config{
+		Offsets: []time.Duration{15 * time.Minute},
+		Grace:   15 * time.Minute,
+		Queue:   1,
+	})
+
+	// Второе напоминание не помещается в очередь и не отмечается.
+	_ = s.scan(now)
+	deliverQueued(s)
+	if len(notifier.reminders) != 1 {
+		t.Fatalf("expected 1 reminder, got %+v", notifier.reminders)
+	}
+
+	_ = s.scan(now.Add(time.Minute))
+	deliverQueued(s)
+	if len(notifier.reminders) != 2 || notifier.reminders[0].Event.ID == notifier.reminders[1].Event.ID {
+		t.Errorf("expected both reminders, got %+v", notifier.reminders)
+	}
+}
+
+// blockingNotifier не доставляет напоминания о событии blocked до отмены ctx.
+type blockingNotifier struct {
+	blocked   models.EventID
+	delivered chan models.EventID
+}
+
+func (n *blockingNotifier) Notify(ctx context.Context, reminder Reminder) error {
+	if reminder.Event.ID == n.blocked {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	n.delivered <- reminder.Event.ID
+	return nil
+}
+
+func TestRunSlowNotifier(t *testing.T) {
+	now := time.Now()
+	events := &fakeEvents{events: map[models.UserID][]models.Event{
+		"user1": {{ID: "slow", Date: now.Add(time.Minute), Event: "slow"}},
+		"user2": {{ID: "fast", Date: now.Add(time.Minute), Event: "fast"}},
+	}}
+
+	notifier := &blockingNotifier{blocked: "slow", delivered: make(chan models.EventID, 1)}
+	s := New(events, memory.NewEventsRepository(), notifier, nopLogger{}, Config{
+		Offsets: []time.Duration{15 * time.Minute},
+		Grace:   15 * time.Minute,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.Run(ctx, time.Hour) }()
+
+	select {
+	case id := <-notifier.delivered:
+		if id != "fast" {
+			t.Errorf("expected fast reminder, got %s", id)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("expected reminder to be delivered while another one is stuck")
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
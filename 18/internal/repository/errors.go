@@ -8,3 +8,10 @@ var ErrNotFound = errors.New("entity not found")
 // ErrAlreadyExist возвращается, если сущность уже существует,
 // при попытке ее добавить.
 var ErrAlreadyExist = errors.New("entity already exist")
+
+// ErrVersionMismatch возвращается, если версия события не совпадает
+// с ожидаемой при его изменении или удалении.
+var ErrVersionMismatch = errors.New("version mismatch")
+
+// ErrQuotaExceeded возвращается, если добавление события превысит квоту арендатора.
+var ErrQuotaExceeded = errors.New("quota exceeded")
@@ -0,0 +1,475 @@
+package file
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"l2.18/internal/repository"
+	"l2.18/internal/repository/memory"
+	"l2.18/pkg/models"
+)
+
+// EventsRepository хранит события в оперативной памяти и журналирует каждое
+// изменение в файл (write-ahead log) до его применения. При старте состояние
+// восстанавливается из снапшота и журнала, периодически журнал сжимается в снапшот.
+//
+// Операции чтения полностью обслуживаются встроенным memory.EventsRepository.
+type EventsRepository struct {
+	*memory.EventsRepository
+
+	mu     sync.Mutex
+	dir    string
+	wal    *os.File
+	seq    uint64
+	unsync int // количество записей журнала, не вошедших в снапшот
+
+	// inTx - хранилище внутри Transaction: изменения копятся в batch, а не пишутся в журнал.
+	inTx  bool
+	batch []record
+}
+
+// NewEventsRepository открывает (или создает) хранилище в директории dir.
+func NewEventsRepository(dir string) (*EventsRepository, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	er := &EventsRepository{
+		EventsRepository: memory.NewEventsRepository(),
+		dir:              dir,
+	}
+
+	snap, err := readSnapshot(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for userID, numbers := range snap.Numbers {
+		for eventID, n := range numbers {
+			er.EventsRepository.SetNumbers(userID, eventID, n)
+		}
+	}
+	for userID, events := range snap.Events {
+		for _, event := range events {
+			if err := er.EventsRepository.Put(userID, event); err != nil {
+				return nil, fmt.Errorf("load snapshot: %w", err)
+			}
+		}
+	}
+	for key, at := range snap.Reminders {
+		if _, err := er.EventsRepository.MarkReminder(key, at); err != nil {
+			return nil, fmt.Errorf("load snapshot: %w", err)
+		}
+	}
+	for userID, entries := range snap.History {
+		for _, entry := range entries {
+			if err := er.EventsRepository.AddHistory(userID, entry); err != nil {
+				return nil, fmt.Errorf("load snapshot: %w", err)
+			}
+		}
+	}
+	for _, rec := range snap.Idempotency {
+		if err := er.EventsRepository.PutIdempotencyRecord(rec); err != nil {
+			return nil, fmt.Errorf("load snapshot: %w", err)
+		}
+	}
+	er.seq = snap.Seq
+
+	walPath := filepath.Join(dir, walFileName)
+	err = readWAL(walPath, func(rec record) error {
+		if rec.Seq <= er.seq {
+			return nil
+		}
+		er.seq = rec.Seq
+		er.unsync++
+		return er.apply(rec)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	er.wal, err = os.OpenFile(walPath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	return er, nil
+}
+
+// Transaction выполняет fn в транзакции состояния в памяти (memory.EventsRepository.Atomic).
+// Если fn завершилась без ошибки, ее изменения дописываются в журнал одной записью:
+// после сбоя они восстановятся либо все, либо ни одно. Если записать журнал не удалось,
+// изменения в памяти отменяются.
+func (er *EventsRepository) Transaction(fn func(tx repository.Tx) error) error {
+	er.mu.Lock()
+	defer er.mu.Unlock()
+
+	return er.EventsRepository.Atomic(func(state *memory.EventsRepository) error {
+		tx := &EventsRepository{EventsRepository: state, inTx: true}
+		if err := fn(tx); err != nil {
+			return err
+		}
+		if len(tx.batch) == 0 {
+			return nil
+		}
+
+		return er.append(record{Op: opBatch, Batch: tx.batch})
+	})
+}
+
+// Put добавляет новое событие. Если событие уже существует - вернет ошибку.
+func (er *EventsRepository) Put(userID models.UserID, event models.Event) error {
+	er.mu.Lock()
+	defer er.mu.Unlock()
+
+	if _, err := er.EventsRepository.Get(userID, event.ID); err == nil {
+		return repository.ErrAlreadyExist
+	}
+
+	if err := er.append(record{Op: opPut, UserID: userID, Event: &event}); err != nil {
+		return err
+	}
+
+	return er.EventsRepository.Put(userID, event)
+}
+
+// Update обновляет событие пользователя, заменяя существующие поля,
+// полями переданными в функцию в event.
+func (er *EventsRepository) Update(userID models.UserID, event models.Event) error {
+	er.mu.Lock()
+	defer er.mu.Unlock()
+
+	stored, err := er.EventsRepository.Get(userID, event.ID)
+	if err != nil {
+		return err
+	}
+	if !stored.MatchesVersion(event.Version) {
+		return repository.ErrVersionMismatch
+	}
+
+	merged := *stored
+	merged.Merge(event)
+	merged.Version++
+
+	if err := er.append(record{Op: opUpdate, UserID: userID, Event: &merged}); err != nil {
+		return err
+	}
+
+	return er.EventsRepository.Update(userID, event)
+}
+
+// Replace полностью заменяет существующее событие пользователя на event.
+func (er *EventsRepository) Replace(userID models.UserID, event models.Event) error {
+	er.mu.Lock()
+	defer er.mu.Unlock()
+
+	stored, err := er.EventsRepository.Get(userID, event.ID)
+	if err != nil {
+		return err
+	}
+	if !stored.MatchesVersion(event.Version) {
+		return repository.ErrVersionMismatch
+	}
+
+	replaced := event
+	replaced.Version = stored.Version + 1
+	if err := er.append(record{Op: opUpdate, UserID: userID, Event: &replaced}); err != nil {
+		return err
+	}
+
+	return er.EventsRepository.Replace(userID, event)
+}
+
+// UpdateAttendee заменяет ответ участника attendee.UserID события eventID пользователя owner.
+func (er *EventsRepository) UpdateAttendee(owner models.UserID, eventID models.EventID, attendee models.Attendee) error {
+	er.mu.Lock()
+	defer er.mu.Unlock()
+
+	stored, err := er.EventsRepository.Get(owner, eventID)
+	if err != nil {
+		return err
+	}
+
+	updated := *stored
+	updated.Attendees = slices.Clone(stored.Attendees)
+	a, ok := updated.Attendee(attendee.UserID)
+	if !ok {
+		return repository.ErrNotFound
+	}
+	*a = attendee
+	updated.Version++
+
+	if err := er.append(record{Op: opUpdate, UserID: owner, Event: &updated}); err != nil {
+		return err
+	}
+
+	return er.EventsRepository.UpdateAttendee(owner, eventID, attendee)
+}
+
+// Delete удаляет события пользователя по айди. Если задана version,
+// а версия события другая - вернет ошибку.
+func (er *EventsRepository) Delete(userID models.UserID, eventID models.EventID, version int64) error {
+	er.mu.Lock()
+	defer er.mu.Unlock()
+
+	stored, err := er.EventsRepository.Get(userID, eventID)
+	if err != nil {
+		return err
+	}
+	if !stored.MatchesVersion(version) {
+		return repository.ErrVersionMismatch
+	}
+
+	if err := er.append(record{Op: opDelete, UserID: userID, EventID: eventID}); err != nil {
+		return err
+	}
+
+	return er.EventsRepository.Delete(userID, eventID, version)
+}
+
+// MarkReminder отмечает напоминание key как отправленное. Вернет false,
+// если напоминание уже было отмечено.
+func (er *EventsRepository) MarkReminder(key string, at time.Time) (bool, error) {
+	er.mu.Lock()
+	defer er.mu.Unlock()
+
+	if er.HasReminder(key) {
+		return false, nil
+	}
+
+	if err := er.append(record{Op: opRemind, Key: key, At: at}); err != nil {
+		return false, err
+	}
+
+	return er.EventsRepository.MarkReminder(key, at)
+}
+
+// PruneReminders удаляет отметки напоминаний о событиях, начавшихся раньше before.
+// Если удалять нечего, журнал не изменяется.
+func (er *EventsRepository) PruneReminders(before time.Time) error {
+	er.mu.Lock()
+	defer er.mu.Unlock()
+
+	if !er.HasRemindersBefore(before) {
+		return nil
+	}
+
+	if err := er.append(record{Op: opPrune, At: before}); err != nil {
+		return err
+	}
+
+	return er.EventsRepository.PruneReminders(before)
+}
+
+// AddHistory сохраняет запись истории события entry.EventID пользователя userID.
+func (er *EventsRepository) AddHistory(userID models.UserID, entry models.HistoryEntry) error {
+	er.mu.Lock()
+	defer er.mu.Unlock()
+
+	// Номер назначается до записи в журнал, чтобы при воспроизведении он не изменился.
+	entry.Revision = er.NextRevision(userID, entry.EventID)
+	if err := er.append(record{Op: opHistory, UserID: userID, Entry: &entry}); err != nil {
+		return err
+	}
+
+	return er.EventsRepository.AddHistory(userID, entry)
+}
+
+// PruneHistory удаляет записи истории, сделанные раньше before.
+// Если удалять нечего, журнал не изменяется.
+func (er *EventsRepository) PruneHistory(before time.Time) error {
+	er.mu.Lock()
+	defer er.mu.Unlock()
+
+	if !er.HasHistoryBefore(before) {
+		return nil
+	}
+
+	if err := er.append(record{Op: opPruneHistory, At: before}); err != nil {
+		return err
+	}
+
+	return er.EventsRepository.PruneHistory(before)
+}
+
+// PutIdempotencyRecord сохраняет ответ на запрос с ключом идемпотентности,
+// заменяя прежний ответ с тем же ключом.
+func (er *EventsRepository) PutIdempotencyRecord(response models.IdempotencyRecord) error {
+	er.mu.Lock()
+	defer er.mu.Unlock()
+
+	if err := er.append(record{Op: opIdempotency, Response: &response}); err != nil {
+		return err
+	}
+
+	return er.EventsRepository.PutIdempotencyRecord(response)
+}
+
+// PruneIdempotencyRecords удаляет ответы, сохраненные раньше before.
+// Если удалять нечего, журнал не изменяется.
+func (er *EventsRepository) PruneIdempotencyRecords(before time.Time) error {
+	er.mu.Lock()
+	defer er.mu.Unlock()
+
+	if !er.HasIdempotencyRecordsBefore(before) {
+		return nil
+	}
+
+	if err := er.append(record{Op: opPruneIdempotency, At: before}); err != nil {
+		return err
+	}
+
+	return er.EventsRepository.PruneIdempotencyRecords(before)
+}
+
+// Ping проверяет, что файл журнала все еще открыт и доступен.
+func (er *EventsRepository) Ping(ctx context.Context) error {
+	er.mu.Lock()
+	defer er.mu.Unlock()
+
+	if _, err := er.wal.Stat(); err != nil {
+		return fmt.Errorf("wal: %w", err)
+	}
+	return nil
+}
+
+// Compact записывает текущее состояние в снапшот и очищает журнал. Журнал
+// очищается только после того, как снапшот и запись о нем в директории
+// сброшены на диск: иначе сбой между ними потерял бы изменения из журнала.
+func (er *EventsRepository) Compact() error {
+	er.mu.Lock()
+	defer er.mu.Unlock()
+
+	if er.unsync == 0 {
+		return nil
+	}
+
+	snap := &snapshot{
+		Seq:         er.seq,
+		Events:      er.All(),
+		Reminders:   er.Reminders(),
+		History:     er.History(),
+		Idempotency: er.IdempotencyRecords(),
+		Numbers:     er.Numbers(),
+	}
+	if err := writeSnapshot(er.dir, snap); err != nil {
+		return fmt.Errorf("write snapshot: %w", err)
+	}
+
+	if err := er.wal.Truncate(0); err != nil {
+		return fmt.Errorf("truncate wal: %w", err)
+	}
+	if err := er.wal.Sync(); err != nil {
+		return fmt.Errorf("sync wal: %w", err)
+	}
+	er.unsync = 0
+
+	return nil
+}
+
+// Run периодически сжимает журнал, пока не будет отменен ctx.
+// Перед выходом выполняет финальное сжатие.
+func (er *EventsRepository) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return er.Compact()
+		case <-ticker.C:
+			if err := er.Compact(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Close закрывает файл журнала.
+func (er *EventsRepository) Close() error {
+	er.mu.Lock()
+	defer er.mu.Unlock()
+
+	return er.wal.Close()
+}
+
+// append дописывает запись в журнал и сбрасывает ее на диск, а внутри
+// Transaction - откладывает до ее завершения. Вызывается под er.mu.
+func (er *EventsRepository) append(rec record) error {
+	if er.inTx {
+		er.batch = append(er.batch, rec)
+		return nil
+	}
+
+	rec.Seq = er.seq + 1
+
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	if _, err := er.wal.Write(data); err != nil {
+		return fmt.Errorf("write wal: %w", err)
+	}
+	if err := er.wal.Sync(); err != nil {
+		return fmt.Errorf("sync wal: %w", err)
+	}
+
+	er.seq = rec.Seq
+	er.unsync++
+	return nil
+}
+
+// apply применяет запись журнала к состоянию в памяти.
+func (er *EventsRepository) apply(rec record) error {
+	if rec.Event == nil && (rec.Op == opPut || rec.Op == opUpdate) {
+		return fmt.Errorf("record %q without event", rec.Op)
+	}
+	if rec.Entry == nil && rec.Op == opHistory {
+		return fmt.Errorf("record %q without entry", rec.Op)
+	}
+	if rec.Response == nil && rec.Op == opIdempotency {
+		return fmt.Errorf("record %q without response", rec.Op)
+	}
+
+	switch rec.Op {
+	case opPut:
+		return er.EventsRepository.Put(rec.UserID, *rec.Event)
+	case opUpdate:
+		// В журнале записана версия после изменения, ее снова назначит Replace.
+		event := *rec.Event
+		event.Version = 0
+		return er.EventsRepository.Replace(rec.UserID, event)
+	case opDelete:
+		return er.EventsRepository.Delete(rec.UserID, rec.EventID, 0)
+	case opRemind:
+		_, err := er.EventsRepository.MarkReminder(rec.Key, rec.At)
+		return err
+	case opPrune:
+		return er.EventsRepository.PruneReminders(rec.At)
+	case opHistory:
+		return er.EventsRepository.AddHistory(rec.UserID, *rec.Entry)
+	case opPruneHistory:
+		return er.EventsRepository.PruneHistory(rec.At)
+	case opIdempotency:
+		return er.EventsRepository.PutIdempotencyRecord(*rec.Response)
+	case opPruneIdempotency:
+		return er.EventsRepository.PruneIdempotencyRecords(rec.At)
+	case opBatch:
+		for _, r := range rec.Batch {
+			if err := er.apply(r); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown operation %q", rec.Op)
+	}
+}
@@ -0,0 +1,598 @@
+package file
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"l2.18/internal/repository"
+	"l2.18/pkg/models"
+	"l2.18/pkg/search"
+)
+
+func TestReopen(t *testing.T) {
+	day := time.Date(2025, time.February, 15, 0, 0, 0, 0, time.UTC)
+	userID := models.UserID("user1")
+
+	testCases := []struct {
+		name     string
+		compact  bool
+		expected []models.Event
+	}{
+		{
+			name:    "replay from wal",
+			compact: false,
+			expected: []models.Event{
+				{ID: "2", Date: day.Add(2 * time.Hour), Event: "updated"},
+				{ID: "3", Date: day.Add(5 * time.Hour), Event: "event3"},
+			},
+		},
+		{
+			name:    "restore from snapshot",
+			compact: true,
+			expected: []models.Event{
+				{ID: "2", Date: day.Add(2 * time.Hour), Event: "updated"},
+				{ID: "3", Date: day.Add(5 * time.Hour), Event: "event3"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+
+			repo, err := NewEventsRepository(dir)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			_ = repo.Put(userID, models.Event{ID: "1", Date: day.Add(time.Hour), Event: "event1"})
+			_ = repo.Put(userID, models.Event{ID: "2", Date: day.Add(9 * time.Hour), Event: "event2"})
+			_ = repo.Put(userID, models.Event{ID: "3", Date: day.Add(5 * time.Hour), Event: "event3"})
+			_ = repo.Update(userID, models.Event{ID: "2", Date: day.Add(2 * time.Hour), Event: "updated"})
+			_ = repo.Delete(userID, "1", 0)
+
+			if tc.compact {
+				if err := repo.Compact(); err != nil {
+					t.Fatalf("compact: %v", err)
+				}
+			}
+			_ = repo.Close()
+
+			reopened, err := NewEventsRepository(dir)
+			if err != nil {
+				t.Fatalf("reopen: %v", err)
+			}
+			defer reopened.Close()
+
+			got, err := reopened.GetEventsByDateRange(userID, day, day.AddDate(0, 0, 1))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(got) != len(tc.expected) {
+				t.Fatalf("expected %d events, got %d", len(tc.expected), len(got))
+			}
+			for i, expectedEvent := range tc.expected {
+				if got[i].ID != expectedEvent.ID {
+					t.Errorf("event[%d].ID: got %v, want %v", i, got[i].ID, expectedEvent.ID)
+				}
+				if !got[i].Date.Equal(expectedEvent.Date) {
+					t.Errorf("event[%d].Date: got %v, want %v", i, got[i].Date, expectedEvent.Date)
+				}
+				if got[i].Event != expectedEvent.Event {
+					t.Errorf("event[%d].Event: got %q, want %q", i, got[i].Event, expectedEvent.Event)
+				}
+			}
+		})
+	}
+}
+
+func TestCompact(t *testing.T) {
+	dir := t.TempDir()
+	userID := models.UserID("user1")
+
+	repo, err := NewEventsRepository(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer repo.Close()
+
+	_ = repo.Put(userID, models.Event{ID: "1", Date: time.Now(), Event: "event1"})
+
+	if err := repo.Compact(); err != nil {
+		t.Fatalf("compact: %v", err)
+	}
+
+	info, err := os.Stat(filepath.Join(dir, walFileName))
+	if err != nil {
+		t.Fatalf("stat wal: %v", err)
+	}
+	if info.Size() != 0 {
+		t.Errorf("expected empty wal after compaction, got %d bytes", info.Size())
+	}
+
+	_ = repo.Put(userID, models.Event{ID: "2", Date: time.Now(), Event: "event2"})
+	_ = repo.Close()
+
+	reopened, err := NewEventsRepository(dir)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer reopened.Close()
+
+	for _, id := range []models.EventID{"1", "2"} {
+		if _, err := reopened.Get(userID, id); err != nil {
+			t.Errorf("event %s: unexpected error: %v", id, err)
+		}
+	}
+}
+
+func TestTornWrite(t *testing.T) {
+	dir := t.TempDir()
+	userID := models.UserID("user1")
+
+	repo, err := NewEventsRepository(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = repo.Put(userID, models.Event{ID: "1", Date: time.Now(), Event: "event1"})
+	_ = repo.Close()
+
+	f, err := os.OpenFile(filepath.Join(dir, walFileName), os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		t.Fatalf("open wal: %v", err)
+	}
+	_, _ = f.WriteString(`{"seq":2,"op":"put","user_id":"us`)
+	_ = f.Close()
+
+	reopened, err := NewEventsRepository(dir)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer reopened.Close()
+
+	if _, err := reopened.Get(userID, "1"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	err = reopened.Put(userID, models.Event{ID: "1", Date: time.Now(), Event: "event1"})
+	if !errors.Is(err, repository.ErrAlreadyExist) {
+		t.Errorf("expected %v, got %v", repository.ErrAlreadyExist, err)
+	}
+}
+
+func TestRemindersSurviveReopen(t *testing.T) {
+	day := time.Date(2025, time.February, 15, 0, 0, 0, 0, time.UTC)
+
+	for _, compact := range []bool{false, true} {
+		dir := t.TempDir()
+
+		repo, err := NewEventsRepository(dir)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		_, _ = repo.MarkReminder("old", day)
+		_, _ = repo.MarkReminder("new", day.AddDate(0, 0, 1))
+		_ = repo.PruneReminders(day.Add(time.Hour))
+
+		if compact {
+			if err := repo.Compact(); err != nil {
+				t.Fatalf("compact: %v", err)
+			}
+		}
+		_ = repo.Close()
+
+		reopened, err := NewEventsRepository(dir)
+		if err != nil {
+			t.Fatalf("reopen: %v", err)
+		}
+
+		marked, err := reopened.MarkReminder("new", day.AddDate(0, 0, 1))
+		if err != nil || marked {
+			t.Errorf("compact=%v: expected reminder to stay marked, got %v, %v", compact, marked, err)
+		}
+		if reopened.HasReminder("old") {
+			t.Errorf("compact=%v: expected pruned reminder to stay pruned", compact)
+		}
+
+		_ = reopened.Close()
+	}
+}
+
+func TestPruneRemindersWithoutChanges(t *testing.T) {
+	day := time.Date(2025, time.February, 15, 0, 0, 0, 0, time.UTC)
+
+	repo, err := NewEventsRepository(t.TempDir())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer repo.Close()
+
+	_, _ = repo.MarkReminder("new", day.AddDate(0, 0, 1))
+	seq := repo.seq
+
+	if err := repo.PruneReminders(day); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if repo.seq != seq {
+		t.Errorf("expected no wal records, got %d", repo.seq-seq)
+	}
+}
+
+func TestPruneHistoryWithoutChanges(t *testing.T) {
+	day := time.Date(2025, time.February, 15, 0, 0, 0, 0, time.UTC)
+
+	repo, err := NewEventsRepository(t.TempDir())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer repo.Close()
+
+	_ = repo.AddHistory("user1", models.HistoryEntry{EventID: "1", Type: models.ChangeCreated, At: day})
+	seq := repo.seq
+
+	if err := repo.PruneHistory(day); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if repo.seq != seq {
+		t.Errorf("expected no wal records, got %d", repo.seq-seq)
+	}
+
+	if err := repo.PruneHistory(day.Add(time.Second)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if history, _ := repo.GetHistory("user1", "1"); len(history) != 0 {
+		t.Errorf("expected pruned history, got %v", history)
+	}
+}
+
+func TestPruneIdempotencyRecordsWithoutChanges(t *testing.T) {
+	day := time.Date(2025, time.February, 15, 0, 0, 0, 0, time.UTC)
+
+	repo, err := NewEventsRepository(t.TempDir())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer repo.Close()
+
+	_ = repo.PutIdempotencyRecord(models.IdempotencyRecord{UserID: "user1", Key: "abc", CreatedAt: day})
+	seq := repo.seq
+
+	if err := repo.PruneIdempotencyRecords(day); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if repo.seq != seq {
+		t.Errorf("expected no wal records, got %d", repo.seq-seq)
+	}
+
+	if err := repo.PruneIdempotencyRecords(day.Add(time.Second)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := repo.GetIdempotencyRecord("user1", "abc"); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("expected pruned record, got %v", err)
+	}
+}
+
+func TestReplaceSurvivesReopen(t *testing.T) {
+	dir := t.TempDir()
+	userID := models.UserID("user1")
+	now := time.Now()
+
+	repo, err := NewEventsRepository(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_ = repo.Put(userID, models.Event{ID: "1", Date: now, Event: "series", RRule: "FREQ=DAILY"})
+	if err := repo.Replace(userID, models.Event{ID: "1", Date: now, Event: "single"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := repo.Replace(userID, models.Event{ID: "2", Date: now}); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("expected %v, got %v", repository.ErrNotFound, err)
+	}
+	_ = repo.Close()
+
+	reopened, err := NewEventsRepository(dir)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer reopened.Close()
+
+	got, err := reopened.Get(userID, "1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Event != "single" || got.RRule != "" {
+		t.Errorf("expected replaced event, got %+v", got)
+	}
+
+	series, _ := reopened.GetRecurringEvents(userID)
+	if len(series) != 0 {
+		t.Errorf("expected no series, got %+v", series)
+	}
+}
+
+func TestAttendeesSurviveReopen(t *testing.T) {
+	dir := t.TempDir()
+
+	repo, err := NewEventsRepository(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_ = repo.Put("owner", models.Event{ID: "1", Date: time.Now(), Attendees: []models.Attendee{
+		{UserID: "alice", Status: models.RSVPNeedsAction},
+	}})
+	err = repo.UpdateAttendee("owner", "1", models.Attendee{UserID: "alice", Status: models.RSVPTentative})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = repo.Close()
+
+	reopened, err := NewEventsRepository(dir)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer reopened.Close()
+
+	got, err := reopened.GetInvitedEvents("alice")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 1 || got[0].Attendees[0].Status != models.RSVPTentative {
+		t.Errorf("unexpected events %+v", got)
+	}
+}
+
+func TestSearchSurvivesReopen(t *testing.T) {
+	dir := t.TempDir()
+
+	repo, err := NewEventsRepository(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_ = repo.Put("1", models.Event{ID: "1", Date: time.Now(), Event: "Запись к стоматологу"})
+	_ = repo.Update("1", models.Event{ID: "1", Event: "Dentist"})
+	if err := repo.Compact(); err != nil {
+		t.Fatalf("compact: %v", err)
+	}
+	_ = repo.Close()
+
+	reopened, err := NewEventsRepository(dir)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer reopened.Close()
+
+	q, _ := search.Parse("dent*")
+	got, err := reopened.Search("1", q)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 1 || got[0].ID != "1" {
+		t.Errorf("unexpected events %+v", got)
+	}
+}
+
+func TestVersionSurvivesReopen(t *testing.T) {
+	dir := t.TempDir()
+	userID := models.UserID("user1")
+	now := time.Now()
+
+	repo, err := NewEventsRepository(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_ = repo.Put(userID, models.Event{ID: "1", Date: now, Event: "first"})
+	_ = repo.Update(userID, models.Event{ID: "1", Event: "second"})
+	if err := repo.Replace(userID, models.Event{ID: "1", Date: now, Event: "stale", Version: 1}); !errors.Is(err, repository.ErrVersionMismatch) {
+		t.Errorf("expected %v, got %v", repository.ErrVersionMismatch, err)
+	}
+	_ = repo.Replace(userID, models.Event{ID: "1", Date: now, Event: "third", Version: 2})
+	_ = repo.Close()
+
+	reopened, err := NewEventsRepository(dir)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer reopened.Close()
+
+	got, err := reopened.Get(userID, "1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Version != 3 || got.Event != "third" {
+		t.Errorf("expected third event in version 3, got %+v", got)
+	}
+}
+
+func TestNumbersSurviveCompact(t *testing.T) {
+	dir := t.TempDir()
+	userID := models.UserID("user1")
+	now := time.Now()
+
+	repo, err := NewEventsRepository(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_ = repo.Put(userID, models.Event{ID: "1", Date: now, Event: "first"})
+	_ = repo.Update(userID, models.Event{ID: "1", Event: "second"})
+	_ = repo.AddHistory(userID, models.HistoryEntry{EventID: "1", Type: models.ChangeCreated, At: now.Add(-time.Hour)})
+	_ = repo.Delete(userID, "1", 0)
+	_ = repo.PruneHistory(now)
+	if err := repo.Compact(); err != nil {
+		t.Fatalf("compact: %v", err)
+	}
+	_ = repo.Close()
+
+	reopened, err := NewEventsRepository(dir)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer reopened.Close()
+
+	_ = reopened.Put(userID, models.Event{ID: "1", Date: now, Event: "restored"})
+	if event, _ := reopened.Get(userID, "1"); event == nil || event.Version != 3 {
+		t.Errorf("expected version 3, got %+v", event)
+	}
+	if next := reopened.NextRevision(userID, "1"); next != 2 {
+		t.Errorf("expected next revision 2, got %d", next)
+	}
+}
+
+func TestHistorySurvivesReopen(t *testing.T) {
+	dir := t.TempDir()
+	userID := models.UserID("user1")
+	now := time.Now()
+
+	repo, err := NewEventsRepository(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	event := models.Event{ID: "1", Date: now, Event: "first"}
+	_ = repo.AddHistory(userID, models.HistoryEntry{EventID: "1", Type: models.ChangeCreated, At: now.Add(-time.Hour), After: &event})
+	_ = repo.AddHistory(userID, models.HistoryEntry{EventID: "1", Type: models.ChangeDeleted, At: now, Before: &event})
+	if err := repo.Compact(); err != nil {
+		t.Fatalf("compact: %v", err)
+	}
+	_ = repo.PruneHistory(now.Add(-time.Minute))
+	_ = repo.AddHistory(userID, models.HistoryEntry{EventID: "1", Type: models.ChangeCreated, At: now, After: &event})
+	_ = repo.Close()
+
+	reopened, err := NewEventsRepository(dir)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer reopened.Close()
+
+	entries, _ := reopened.GetHistory(userID, "1")
+	if len(entries) != 2 || entries[0].Revision != 2 || entries[1].Revision != 3 {
+		t.Fatalf("unexpected history %+v", entries)
+	}
+	if entries[0].Before == nil || entries[0].Before.Event != "first" {
+		t.Errorf("expected deleted event in history, got %+v", entries[0])
+	}
+}
+
+func TestTransactionSurvivesReopen(t *testing.T) {
+	dir := t.TempDir()
+	userID := models.UserID("user1")
+	now := time.Now()
+	errStop := errors.New("stop")
+
+	repo, err := NewEventsRepository(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_ = repo.Put(userID, models.Event{ID: "a", Date: now, Event: "meeting"})
+
+	err = repo.Transaction(func(tx repository.Tx) error {
+		_ = tx.Put(userID, models.Event{ID: "c", Date: now, Event: "rolled back"})
+		return errStop
+	})
+	if !errors.Is(err, errStop) {
+		t.Fatalf("expected %v, got %v", errStop, err)
+	}
+
+	err = repo.Transaction(func(tx repository.Tx) error {
+		if err := tx.Put(userID, models.Event{ID: "b", Date: now, Event: "lunch"}); err != nil {
+			return err
+		}
+		if err := tx.AddHistory(userID, models.HistoryEntry{EventID: "b", Type: models.ChangeCreated, At: now}); err != nil {
+			return err
+		}
+		return tx.Update(userID, models.Event{ID: "a", Event: "renamed", Version: 1})
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = repo.Close()
+
+	reopened, err := NewEventsRepository(dir)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer reopened.Close()
+
+	if _, err := reopened.Get(userID, "c"); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("expected rolled back event to be missing, got %v", err)
+	}
+	if got, _ := reopened.Get(userID, "a"); got == nil || got.Event != "renamed" || got.Version != 2 {
+		t.Errorf("expected renamed event in version 2, got %+v", got)
+	}
+	if _, err := reopened.Get(userID, "b"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if entries, _ := reopened.GetHistory(userID, "b"); len(entries) != 1 || entries[0].Revision != 1 {
+		t.Errorf("unexpected history %+v", entries)
+	}
+}
+
+func TestIdempotencyRecordsSurviveReopen(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+
+	repo, err := NewEventsRepository(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_ = repo.PutIdempotencyRecord(models.IdempotencyRecord{UserID: "1", Key: "old", Status: 201, CreatedAt: now.Add(-time.Hour)})
+	if err := repo.Compact(); err != nil {
+		t.Fatalf("compact: %v", err)
+	}
+	_ = repo.PutIdempotencyRecord(models.IdempotencyRecord{UserID: "1", Key: "new", Status: 201, Body: []byte("{}"), CreatedAt: now})
+	_ = repo.PruneIdempotencyRecords(now.Add(-time.Minute))
+	_ = repo.Close()
+
+	reopened, err := NewEventsRepository(dir)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer reopened.Close()
+
+	if _, err := reopened.GetIdempotencyRecord("1", "old"); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("expected pruned record to be missing, got %v", err)
+	}
+	if record, err := reopened.GetIdempotencyRecord("1", "new"); err != nil || string(record.Body) != "{}" {
+		t.Errorf("expected saved record, got %+v, %v", record, err)
+	}
+}
+
+func TestMetadataSurvivesReopen(t *testing.T) {
+	dir := t.TempDir()
+	userID := models.UserID("user1")
+	day := time.Date(2025, time.February, 15, 0, 0, 0, 0, time.UTC)
+
+	repo, err := NewEventsRepository(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_ = repo.Put(userID, models.Event{ID: "1", Date: day, Event: "review", Category: "work", Tags: []string{"team"}})
+	_ = repo.Update(userID, models.Event{ID: "1", Tags: []string{"team", "urgent"}, Attributes: map[string]string{"jira": "CAL-1"}})
+	_ = repo.Close()
+
+	reopened, err := NewEventsRepository(dir)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer reopened.Close()
+
+	events, err := reopened.GetFilteredEvents(userID, day, day.AddDate(0, 0, 1), models.EventFilter{Tags: []string{"urgent"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(events) != 1 || events[0].Category != "work" || events[0].Attributes["jira"] != "CAL-1" {
+		t.Errorf("expected tagged event after reopen, got %+v", events)
+	}
+}
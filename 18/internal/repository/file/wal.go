@@ -0,0 +1,173 @@
+package file
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"l2.18/internal/repository/memory"
+	"l2.18/pkg/models"
+)
+
+const (
+	walFileName      = "wal.log"
+	snapshotFileName = "snapshot.json"
+)
+
+// operation определяет тип изменения, записываемого в журнал.
+type operation string
+
+const (
+	opPut    operation = "put"
+	opUpdate operation = "update"
+	opDelete operation = "delete"
+	opRemind operation = "remind"
+	opPrune  operation = "prune"
+	// opHistory и opPruneHistory - запись истории изменений и удаление старых записей.
+	opHistory      operation = "history"
+	opPruneHistory operation = "prune_history"
+	// opIdempotency и opPruneIdempotency - ответ на запрос с ключом идемпотентности и удаление старых ответов.
+	opIdempotency      operation = "idempotency"
+	opPruneIdempotency operation = "prune_idempotency"
+	// opBatch - изменения одной транзакции. Запись журнала дописывается
+	// целиком или отрезается при восстановлении, поэтому они применяются вместе.
+	opBatch operation = "batch"
+)
+
+// record - одна запись журнала. Seq монотонно возрастает и позволяет
+// пропускать при воспроизведении записи, уже вошедшие в снапшот.
+// Для update в Event хранится итоговое состояние события, а не изменения.
+// Для remind в Key и At - отметка напоминания, для prune в At - граница удаления.
+// Для history в Entry - запись истории с уже назначенным номером.
+// Для idempotency в Response - сохраненный ответ.
+// Для batch в Batch - записи транзакции без собственных номеров.
+type record struct {
+	Seq      uint64                    `json:"seq"`
+	Op       operation                 `json:"op"`
+	UserID   models.UserID             `json:"user_id,omitempty"`
+	EventID  models.EventID            `json:"event_id,omitempty"`
+	Event    *models.Event             `json:"event,omitempty"`
+	Key      string                    `json:"key,omitempty"`
+	At       time.Time                 `json:"at,omitzero"`
+	Entry    *models.HistoryEntry      `json:"entry,omitempty"`
+	Response *models.IdempotencyRecord `json:"response,omitempty"`
+	Batch    []record                  `json:"batch,omitempty"`
+}
+
+// snapshot - полное состояние хранилища на момент записи с номером Seq.
+type snapshot struct {
+	Seq         uint64                                  `json:"seq"`
+	Events      map[models.UserID][]models.Event        `json:"events"`
+	Reminders   map[string]time.Time                    `json:"reminders,omitempty"`
+	History     map[models.UserID][]models.HistoryEntry `json:"history,omitempty"`
+	Idempotency []models.IdempotencyRecord              `json:"idempotency,omitempty"`
+	// Numbers - версии удаленных событий и номера удаленных записей истории.
+	Numbers map[models.UserID]map[models.EventID]memory.Numbers `json:"numbers,omitempty"`
+}
+
+// readSnapshot читает снапшот из директории. Если снапшота нет - вернет пустой.
+func readSnapshot(dir string) (*snapshot, error) {
+	data, err := os.ReadFile(filepath.Join(dir, snapshotFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return &snapshot{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var snap snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, fmt.Errorf("corrupted snapshot: %w", err)
+	}
+
+	return &snap, nil
+}
+
+// writeSnapshot атомарно записывает снапшот: сначала во временный файл,
+// затем переименовывает его поверх старого. Когда writeSnapshot вернула nil,
+// и содержимое файла, и переименование уже сброшены на диск.
+func writeSnapshot(dir string, snap *snapshot) error {
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, snapshotFileName+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	path := filepath.Join(dir, snapshotFileName)
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	if err := syncPath(path); err != nil {
+		return err
+	}
+	return syncPath(dir)
+}
+
+// readWAL вызывает apply для каждой записи журнала по порядку.
+// Недописанная последняя строка (обрыв при записи) отрезается от файла.
+func readWAL(path string, apply func(record) error) error {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	reader := bufio.NewReader(f)
+	var offset int64
+
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			if len(line) > 0 {
+				return f.Truncate(offset)
+			}
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		var rec record
+		if err := json.Unmarshal(line, &rec); err != nil {
+			return fmt.Errorf("corrupted wal at offset %d: %w", offset, err)
+		}
+
+		if err := apply(rec); err != nil {
+			return fmt.Errorf("replay wal record %d: %w", rec.Seq, err)
+		}
+
+		offset += int64(len(line))
+	}
+}
+
+// syncPath сбрасывает на диск файл или директорию path.
+func syncPath(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return f.Sync()
+}
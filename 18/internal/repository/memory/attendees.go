@@ -0,0 +1,80 @@
+package memory
+
+import (
+	"slices"
+	"sort"
+
+	"l2.18/internal/repository"
+	"l2.18/pkg/models"
+)
+
+// eventKey определяет событие среди событий всех пользователей.
+type eventKey struct {
+	owner models.UserID
+	id    models.EventID
+}
+
+// GetInvitedEvents возвращает события других пользователей, в которые приглашен userID,
+// отсортированные по началу. У каждого события заполнен Owner.
+func (er *EventsRepository) GetInvitedEvents(userID models.UserID) ([]models.Event, error) {
+	er.RLock()
+	defer er.RUnlock()
+
+	result := make([]models.Event, 0, len(er.invitations[userID]))
+	for key := range er.invitations[userID] {
+		event := *er.events[key.owner][key.id]
+		event.Owner = key.owner
+		result = append(result, event)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Date.Before(result[j].Date)
+	})
+
+	return result, nil
+}
+
+// UpdateAttendee заменяет ответ участника attendee.UserID события eventID пользователя owner.
+// Если события или такого участника нет - вернет ошибку.
+func (er *EventsRepository) UpdateAttendee(owner models.UserID, eventID models.EventID, attendee models.Attendee) error {
+	er.Lock()
+	defer er.Unlock()
+
+	eventPtr, exists := er.events[owner][eventID]
+	if !exists {
+		return repository.ErrNotFound
+	}
+
+	// Срез копируется: события, выданные читателям, разделяют его с хранилищем.
+	updated := *eventPtr
+	updated.Attendees = slices.Clone(eventPtr.Attendees)
+	i := slices.IndexFunc(updated.Attendees, func(a models.Attendee) bool { return a.UserID == attendee.UserID })
+	if i == -1 {
+		return repository.ErrNotFound
+	}
+
+	updated.Attendees[i] = attendee
+	updated.Version++
+	er.swap(owner, eventPtr, &updated)
+
+	return nil
+}
+
+// indexAttendees обновляет индекс приглашений после изменения участников события.
+func (er *EventsRepository) indexAttendees(owner models.UserID, eventID models.EventID, old, cur []models.Attendee) {
+	key := eventKey{owner: owner, id: eventID}
+
+	for _, a := range old {
+		delete(er.invitations[a.UserID], key)
+		if len(er.invitations[a.UserID]) == 0 {
+			delete(er.invitations, a.UserID)
+		}
+	}
+
+	for _, a := range cur {
+		if er.invitations[a.UserID] == nil {
+			er.invitations[a.UserID] = make(map[eventKey]struct{})
+		}
+		er.invitations[a.UserID][key] = struct{}{}
+	}
+}
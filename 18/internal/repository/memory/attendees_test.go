@@ -0,0 +1,95 @@
+package memory
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"l2.18/internal/repository"
+	"l2.18/pkg/models"
+)
+
+func invitedIDs(t *testing.T, repo *EventsRepository, userID models.UserID) []models.EventID {
+	t.Helper()
+
+	events, err := repo.GetInvitedEvents(userID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ids := make([]models.EventID, len(events))
+	for i, e := range events {
+		if e.Owner != "owner" {
+			t.Errorf("expected owner to be set, got %q", e.Owner)
+		}
+		ids[i] = e.ID
+	}
+	return ids
+}
+
+func TestGetInvitedEvents(t *testing.T) {
+	now := time.Now()
+	repo := NewEventsRepository()
+
+	_ = repo.Put("owner", models.Event{ID: "1", Date: now, Attendees: []models.Attendee{
+		{UserID: "alice", Status: models.RSVPNeedsAction},
+		{UserID: "bob", Status: models.RSVPNeedsAction},
+	}})
+	_ = repo.Put("owner", models.Event{ID: "2", Date: now.Add(time.Hour), Attendees: []models.Attendee{
+		{UserID: "alice", Status: models.RSVPNeedsAction},
+	}})
+
+	if ids := invitedIDs(t, repo, "alice"); len(ids) != 2 || ids[0] != "1" || ids[1] != "2" {
+		t.Errorf("alice: unexpected events %v", ids)
+	}
+
+	// Bob исключен из участников.
+	_ = repo.Update("owner", models.Event{ID: "1", Attendees: []models.Attendee{
+		{UserID: "alice", Status: models.RSVPNeedsAction},
+	}})
+	if ids := invitedIDs(t, repo, "bob"); len(ids) != 0 {
+		t.Errorf("bob: unexpected events %v", ids)
+	}
+
+	_ = repo.Replace("owner", models.Event{ID: "2", Date: now})
+	_ = repo.Delete("owner", "1", 0)
+	if ids := invitedIDs(t, repo, "alice"); len(ids) != 0 {
+		t.Errorf("alice: unexpected events %v", ids)
+	}
+
+	// Собственные события владельца не считаются приглашениями.
+	if ids := invitedIDs(t, repo, "owner"); len(ids) != 0 {
+		t.Errorf("owner: unexpected events %v", ids)
+	}
+}
+
+func TestUpdateAttendee(t *testing.T) {
+	repo := NewEventsRepository()
+	_ = repo.Put("owner", models.Event{ID: "1", Date: time.Now(), Attendees: []models.Attendee{
+		{UserID: "alice", Status: models.RSVPNeedsAction},
+	}})
+
+	before, _ := repo.GetInvitedEvents("alice")
+
+	err := repo.UpdateAttendee("owner", "1", models.Attendee{UserID: "alice", Status: models.RSVPAccepted})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, _ := repo.Get("owner", "1")
+	if got.Attendees[0].Status != models.RSVPAccepted {
+		t.Errorf("expected accepted, got %q", got.Attendees[0].Status)
+	}
+	if before[0].Attendees[0].Status != models.RSVPNeedsAction {
+		t.Error("previously returned event was modified")
+	}
+
+	err = repo.UpdateAttendee("owner", "1", models.Attendee{UserID: "bob", Status: models.RSVPAccepted})
+	if !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("expected %v, got %v", repository.ErrNotFound, err)
+	}
+	err = repo.UpdateAttendee("owner", "2", models.Attendee{UserID: "alice", Status: models.RSVPAccepted})
+	if !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("expected %v, got %v", repository.ErrNotFound, err)
+	}
+}
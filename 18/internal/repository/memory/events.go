@@ -1,12 +1,15 @@
 package memory
 
 import (
+	"context"
+	"slices"
 	"sort"
 	"sync"
 	"time"
 
 	"l2.18/internal/repository"
 	"l2.18/pkg/models"
+	"l2.18/pkg/search"
 )
 
 // EventsRepository хранит в оперативной памяти информацию о событиях.
@@ -15,50 +18,83 @@ type EventsRepository struct {
 
 	events    map[models.UserID]map[models.EventID]*models.Event
 	dateIndex map[models.UserID][]*models.Event
+	recurring map[models.UserID]map[models.EventID]*models.Event
+	// maxDuration - наибольшая длительность события пользователя. Позволяет
+	// ограничить поиск событий, начавшихся до запрошенного диапазона.
+	maxDuration map[models.UserID]time.Duration
+	// reminders - отметки отправленных напоминаний и время событий, к которым они относятся.
+	reminders map[string]time.Time
+	// invitations - события других пользователей, в которые приглашен пользователь.
+	invitations map[models.UserID]map[eventKey]struct{}
+	// texts - полнотекстовые индексы текстов событий пользователей.
+	texts map[models.UserID]*search.Index[models.EventID]
+	// tags - события пользователей по меткам.
+	tags map[models.UserID]map[string]map[models.EventID]*models.Event
+	// history - истории изменений событий по возрастанию номера записи.
+	history map[eventKey][]models.HistoryEntry
+	// idempotency - сохраненные ответы на запросы с ключами идемпотентности.
+	idempotency map[idempotencyKey]models.IdempotencyRecord
+	// numbers - номера удаленных событий и событий с удаленной историей.
+	numbers map[eventKey]Numbers
+
+	// undo - отмена изменений внутри Atomic, nil вне транзакции.
+	undo *undoLog
 }
 
 // NewEventsRepository создает новый EventsRepository.
 func NewEventsRepository() *EventsRepository {
 	return &EventsRepository{
-		events:     make(map[models.UserID]map[models.EventID]*models.Event),
-		dateIndex:  make(map[models.UserID][]*models.Event),
+		events:      make(map[models.UserID]map[models.EventID]*models.Event),
+		dateIndex:   make(map[models.UserID][]*models.Event),
+		recurring:   make(map[models.UserID]map[models.EventID]*models.Event),
+		maxDuration: make(map[models.UserID]time.Duration),
+		reminders:   make(map[string]time.Time),
+		invitations: make(map[models.UserID]map[eventKey]struct{}),
+		texts:       make(map[models.UserID]*search.Index[models.EventID]),
+		tags:        make(map[models.UserID]map[string]map[models.EventID]*models.Event),
+		history:     make(map[eventKey][]models.HistoryEntry),
+		idempotency: make(map[idempotencyKey]models.IdempotencyRecord),
+		numbers:     make(map[eventKey]Numbers),
 	}
 }
 
 // Put добавляет новое событие. Если событие уже существует - вернет ошибку.
+// Событие без версии получает версию 1, а ранее удаленное - следующую
+// после версии удаленного события.
 func (er *EventsRepository) Put(userID models.UserID, event models.Event) error {
 	er.Lock()
 	defer er.Unlock()
 
-	if er.events[userID] == nil {
-		er.events[userID] = make(map[models.EventID]*models.Event)
-		er.dateIndex[userID] = make([]*models.Event, 0)
-	}
-
 	if _, exists := er.events[userID][event.ID]; exists {
 		return repository.ErrAlreadyExist
 	}
 
+	if event.Version == 0 {
+		event.Version = er.numbers[eventKey{owner: userID, id: event.ID}].Version + 1
+	}
 
-	er.events[userID][event.ID] = &event
-	er.dateIndex[userID] = insertSorted(er.dateIndex[userID], &event)
+	er.insert(userID, &event)
+	er.record(func() { er.remove(userID, &event) })
 	return nil
 }
 
-// Get возвращает событие пользователя по его айди. Если события нет - вернет ошибку.
+// Get возвращает копию события пользователя по его айди. Если события нет - вернет ошибку.
 func (er *EventsRepository) Get(userID models.UserID, eventID models.EventID) (*models.Event, error) {
 	er.RLock()
 	defer er.RUnlock()
 
-	if _, ok := er.events[userID][eventID]; !ok {
+	eventPtr, ok := er.events[userID][eventID]
+	if !ok {
 		return nil, repository.ErrNotFound
 	}
 
-	return er.events[userID][eventID], nil
+	event := *eventPtr
+	return &event, nil
 }
 
 // Update обновляет событие пользователя, заменяя существующие поля,
-// полями переданными в функцию в event.
+// полями переданными в функцию в event. Если задана event.Version,
+// а версия события другая - вернет ошибку.
 func (er *EventsRepository) Update(userID models.UserID, event models.Event) error {
 	er.Lock()
 	defer er.Unlock()
@@ -67,51 +103,115 @@ func (er *EventsRepository) Update(userID models.UserID, event models.Event) err
 	if !exists {
 		return repository.ErrNotFound
 	}
-
-	oldDate := eventPtr.Date
-	if !event.Date.IsZero() {
-		eventPtr.Date = event.Date
-	}
-	if event.Event != "" {
-		eventPtr.Event = event.Event
+	if !eventPtr.MatchesVersion(event.Version) {
+		return repository.ErrVersionMismatch
 	}
 
-	if !event.Date.IsZero() && !event.Date.Equal(oldDate) {
-		er.dateIndex[userID] = nil
-		for _, e := range er.events[userID] {
-			er.dateIndex[userID] = insertSorted(er.dateIndex[userID], e)
-		}
-	}
+	updated := *eventPtr
+	updated.Merge(event)
+	updated.Version++
+	er.swap(userID, eventPtr, &updated)
+
 	return nil
 }
 
-// Delete удаляет события пользователя по айди.
-func (er *EventsRepository) Delete(userID models.UserID, eventID models.EventID) error {
+// Replace полностью заменяет существующее событие пользователя на event.
+// Если задана event.Version, а версия события другая - вернет ошибку.
+func (er *EventsRepository) Replace(userID models.UserID, event models.Event) error {
 	er.Lock()
 	defer er.Unlock()
 
-	eventPtr, exists := er.events[userID][eventID]
+	eventPtr, exists := er.events[userID][event.ID]
 	if !exists {
 		return repository.ErrNotFound
 	}
+	if !eventPtr.MatchesVersion(event.Version) {
+		return repository.ErrVersionMismatch
+	}
 
-	delete(er.events[userID], eventID)
+	event.Version = eventPtr.Version + 1
+	er.swap(userID, eventPtr, &event)
 
-	idx := -1
-	for i, e := range er.dateIndex[userID] {
-		if e == eventPtr {
-			idx = i
-			break
-		}
+	return nil
+}
+
+// insert добавляет событие пользователя в хранилище и индексы.
+func (er *EventsRepository) insert(userID models.UserID, event *models.Event) {
+	if er.events[userID] == nil {
+		er.events[userID] = make(map[models.EventID]*models.Event)
+		er.dateIndex[userID] = make([]*models.Event, 0)
+	}
+
+	er.events[userID][event.ID] = event
+	er.dateIndex[userID] = insertSorted(er.dateIndex[userID], event)
+	er.indexRecurring(userID, event)
+	er.trackDuration(userID, event)
+	er.indexAttendees(userID, event.ID, nil, event.Attendees)
+	er.indexText(userID, event)
+	er.indexTags(userID, event, nil)
+}
+
+// remove удаляет событие пользователя из хранилища и индексов.
+func (er *EventsRepository) remove(userID models.UserID, event *models.Event) {
+	delete(er.events[userID], event.ID)
+	delete(er.recurring[userID], event.ID)
+	er.indexAttendees(userID, event.ID, event.Attendees, nil)
+	er.texts[userID].Remove(event.ID)
+	er.unindexTags(userID, event.ID, event.Tags)
+
+	if i := slices.Index(er.dateIndex[userID], event); i != -1 {
+		er.dateIndex[userID] = slices.Delete(er.dateIndex[userID], i, i+1)
+	}
+}
+
+// swap заменяет событие old пользователя новым событием cur в хранилище и индексах.
+// События не изменяются на месте: читатели могут держать их после снятия блокировки.
+func (er *EventsRepository) swap(userID models.UserID, old, cur *models.Event) {
+	er.record(func() { er.swap(userID, cur, old) })
+
+	er.events[userID][cur.ID] = cur
+	delete(er.recurring[userID], cur.ID)
+	er.indexRecurring(userID, cur)
+	er.trackDuration(userID, cur)
+	er.indexAttendees(userID, cur.ID, old.Attendees, cur.Attendees)
+	er.indexText(userID, cur)
+	er.indexTags(userID, cur, old.Tags)
+
+	if !cur.Date.Equal(old.Date) {
+		er.reindexDates(userID)
+		return
+	}
+	if i := slices.Index(er.dateIndex[userID], old); i != -1 {
+		er.dateIndex[userID][i] = cur
+	}
+}
+
+// Delete удаляет события пользователя по айди. Если задана version,
+// а версия события другая - вернет ошибку.
+func (er *EventsRepository) Delete(userID models.UserID, eventID models.EventID, version int64) error {
+	er.Lock()
+	defer er.Unlock()
+
+	eventPtr, exists := er.events[userID][eventID]
+	if !exists {
+		return repository.ErrNotFound
 	}
-	if idx != -1 {
-		er.dateIndex[userID] = append(er.dateIndex[userID][:idx], er.dateIndex[userID][idx+1:]...)
+	if !eventPtr.MatchesVersion(version) {
+		return repository.ErrVersionMismatch
 	}
 
+	er.remove(userID, eventPtr)
+	er.record(func() { er.insert(userID, eventPtr) })
+
+	key := eventKey{owner: userID, id: eventID}
+	n := er.numbers[key]
+	n.Version = eventPtr.Version
+	er.setNumbers(key, n)
 	return nil
 }
 
-// GetEventsByDateRange возвращает все события пользователя в диапазоне [start, end).
+// GetEventsByDateRange возвращает все события пользователя, пересекающиеся
+// с диапазоном [start, end), отсортированные по началу.
 func (er *EventsRepository) GetEventsByDateRange(
 	userID models.UserID,
 	start, end time.Time,
@@ -124,26 +224,127 @@ func (er *EventsRepository) GetEventsByDateRange(
 		return []models.Event{}, nil
 	}
 
+	// События, начавшиеся раньше start - maxDuration, закончились до start.
+	earliest := start.Add(-er.maxDuration[userID])
 	left := sort.Search(len(index), func(i int) bool {
-		return !index[i].Date.Before(start)
+		return !index[i].Date.Before(earliest)
 	})
 
-	right := sort.Search(len(index), func(i int) bool {
-		return !index[i].Date.Before(end)
+	result := []models.Event{}
+	for i := left; i < len(index) && index[i].Date.Before(end); i++ {
+		if index[i].Overlaps(start, end) {
+			result = append(result, *index[i])
+		}
+	}
+
+	return result, nil
+}
+
+// GetRecurringEvents возвращает все серии повторяющихся событий пользователя.
+func (er *EventsRepository) GetRecurringEvents(userID models.UserID) ([]models.Event, error) {
+	er.RLock()
+	defer er.RUnlock()
+
+	result := make([]models.Event, 0, len(er.recurring[userID]))
+	for _, e := range er.recurring[userID] {
+		result = append(result, *e)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Date.Before(result[j].Date)
 	})
 
-	if left >= right {
-		return []models.Event{}, nil
+	return result, nil
+}
+
+// Users возвращает пользователей, у которых есть хотя бы одно событие.
+func (er *EventsRepository) Users() ([]models.UserID, error) {
+	er.RLock()
+	defer er.RUnlock()
+
+	result := make([]models.UserID, 0, len(er.events))
+	for userID, events := range er.events {
+		if len(events) > 0 {
+			result = append(result, userID)
+		}
 	}
 
-	result := make([]models.Event, right-left)
-	for i, j := left, 0; i < right; i, j = i+1, j+1 {
-		result[j] = *index[i]
+	sort.Slice(result, func(i, j int) bool {
+		return result[i] < result[j]
+	})
+
+	return result, nil
+}
+
+// EventCounts возвращает количество событий каждого пользователя, у которого они есть.
+func (er *EventsRepository) EventCounts() (map[models.UserID]int, error) {
+	er.RLock()
+	defer er.RUnlock()
+
+	result := make(map[models.UserID]int, len(er.events))
+	for userID, events := range er.events {
+		if len(events) > 0 {
+			result[userID] = len(events)
+		}
 	}
 
 	return result, nil
 }
 
+// Ping проверяет доступность хранилища. Хранилище в памяти доступно всегда.
+func (er *EventsRepository) Ping(ctx context.Context) error {
+	return nil
+}
+
+// All возвращает копию всех событий, сгруппированных по пользователям.
+// События каждого пользователя отсортированы по дате.
+func (er *EventsRepository) All() map[models.UserID][]models.Event {
+	er.RLock()
+	defer er.RUnlock()
+
+	result := make(map[models.UserID][]models.Event, len(er.dateIndex))
+	for userID, index := range er.dateIndex {
+		if len(index) == 0 {
+			continue
+		}
+
+		events := make([]models.Event, len(index))
+		for i, e := range index {
+			events[i] = *e
+		}
+		result[userID] = events
+	}
+
+	return result
+}
+
+// reindexDates перестраивает индекс дат пользователя.
+func (er *EventsRepository) reindexDates(userID models.UserID) {
+	er.dateIndex[userID] = nil
+	for _, e := range er.events[userID] {
+		er.dateIndex[userID] = insertSorted(er.dateIndex[userID], e)
+	}
+}
+
+// trackDuration обновляет наибольшую длительность события пользователя.
+func (er *EventsRepository) trackDuration(userID models.UserID, event *models.Event) {
+	if d := event.Duration(); d > er.maxDuration[userID] {
+		er.maxDuration[userID] = d
+	}
+}
+
+// indexRecurring добавляет серию в индекс повторяющихся событий.
+func (er *EventsRepository) indexRecurring(userID models.UserID, event *models.Event) {
+	if !event.IsRecurring() {
+		return
+	}
+
+	if er.recurring[userID] == nil {
+		er.recurring[userID] = make(map[models.EventID]*models.Event)
+	}
+	er.recurring[userID][event.ID] = event
+}
+
 func insertSorted(slice []*models.Event, event *models.Event) []*models.Event {
 	i := sort.Search(len(slice), func(i int) bool {
 		return !slice[i].Date.Before(event.Date)
@@ -1,7 +1,9 @@
 package memory
 
 import (
+	"context"
 	"errors"
+	"strconv"
 	"testing"
 	"time"
 
@@ -234,7 +236,7 @@ func TestDelete(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			repo := NewEventsRepository()
 			tc.setup(repo)
-			err := repo.Delete(userID, eventID)
+			err := repo.Delete(userID, eventID, 0)
 			if !errors.Is(err, tc.expected) {
 				t.Errorf("expected %v, got %v", tc.expected, err)
 			}
@@ -343,6 +345,33 @@ func TestGetEventsByDateRange(t *testing.T) {
 				{ID: models.EventID("5"), Date: now, Event: "at_start"},
 			},
 		},
+		{
+			name: "success - event started before range overlaps it",
+			setup: func(r *EventsRepository) {
+				overnight := models.Event{
+					ID:    models.EventID("7"),
+					Date:  now.Add(-2 * time.Hour),
+					End:   now.Add(time.Hour),
+					Event: "overnight",
+				}
+				finished := models.Event{
+					ID:    models.EventID("8"),
+					Date:  now.Add(-3 * time.Hour),
+					End:   now,
+					Event: "finished",
+				}
+				_ = r.Put(userID, overnight)
+				_ = r.Put(userID, finished)
+				_ = r.Put(userID, event1)
+			},
+			userID: userID,
+			start:  now,
+			end:    now.Add(24 * time.Hour),
+			expected: []models.Event{
+				{ID: models.EventID("7"), Date: now.Add(-2 * time.Hour), Event: "overnight"},
+				event1,
+			},
+		},
 		{
 			name: "success - multiple events, sorted by date",
 			setup: func(r *EventsRepository) {
@@ -384,3 +413,148 @@ func TestGetEventsByDateRange(t *testing.T) {
 		})
 	}
 }
+
+func TestGetRecurringEvents(t *testing.T) {
+	now := time.Now()
+	userID := models.UserID("user1")
+
+	repo := NewEventsRepository()
+	_ = repo.Put(userID, models.Event{ID: "1", Date: now, Event: "single"})
+	_ = repo.Put(userID, models.Event{ID: "2", Date: now, Event: "series", RRule: "FREQ=DAILY"})
+	_ = repo.Update(userID, models.Event{ID: "1", RRule: "FREQ=WEEKLY"})
+	_ = repo.Put(userID, models.Event{ID: "3", Date: now, Event: "deleted", RRule: "FREQ=DAILY"})
+	_ = repo.Delete(userID, "3", 0)
+
+	got, err := repo.GetRecurringEvents(userID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected 2 series, got %d", len(got))
+	}
+
+	_ = repo.Replace(userID, models.Event{ID: "2", Date: now, Event: "no longer series"})
+
+	got, _ = repo.GetRecurringEvents(userID)
+	if len(got) != 1 || got[0].ID != "1" {
+		t.Errorf("expected only series 1, got %+v", got)
+	}
+}
+
+func TestUsers(t *testing.T) {
+	now := time.Now()
+
+	repo := NewEventsRepository()
+	_ = repo.Put("user2", models.Event{ID: "1", Date: now, Event: "event"})
+	_ = repo.Put("user1", models.Event{ID: "1", Date: now, Event: "event"})
+	_ = repo.Put("user3", models.Event{ID: "1", Date: now, Event: "deleted"})
+	_ = repo.Delete("user3", "1", 0)
+
+	got, err := repo.Users()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []models.UserID{"user1", "user2"}
+	if len(got) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, got)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Errorf("user[%d]: got %v, want %v", i, got[i], expected[i])
+		}
+	}
+}
+
+func TestVersion(t *testing.T) {
+	userID := models.UserID("user1")
+	now := time.Now()
+	repo := NewEventsRepository()
+
+	_ = repo.Put(userID, models.Event{ID: "1", Date: now, Event: "first"})
+	if got, _ := repo.Get(userID, "1"); got.Version != 1 {
+		t.Fatalf("expected version 1, got %d", got.Version)
+	}
+
+	if err := repo.Update(userID, models.Event{ID: "1", Event: "second", Version: 1}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := repo.Update(userID, models.Event{ID: "1", Event: "stale", Version: 1}); !errors.Is(err, repository.ErrVersionMismatch) {
+		t.Errorf("expected %v, got %v", repository.ErrVersionMismatch, err)
+	}
+	if err := repo.Replace(userID, models.Event{ID: "1", Date: now, Event: "third"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, _ := repo.Get(userID, "1")
+	if got.Version != 3 || got.Event != "third" {
+		t.Errorf("expected third event in version 3, got %+v", got)
+	}
+
+	if err := repo.Delete(userID, "1", 2); !errors.Is(err, repository.ErrVersionMismatch) {
+		t.Errorf("expected %v, got %v", repository.ErrVersionMismatch, err)
+	}
+	if err := repo.Delete(userID, "1", 3); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestEventCounts(t *testing.T) {
+	now := time.Now()
+	repo := NewEventsRepository()
+
+	_ = repo.Put("user1", models.Event{ID: "1", Date: now})
+	_ = repo.Put("user1", models.Event{ID: "2", Date: now, RRule: "FREQ=DAILY"})
+	_ = repo.Put("user2", models.Event{ID: "1", Date: now})
+	_ = repo.Put("user3", models.Event{ID: "1", Date: now})
+	_ = repo.Delete("user3", "1", 0)
+
+	counts, err := repo.EventCounts()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(counts) != 2 || counts["user1"] != 2 || counts["user2"] != 1 {
+		t.Errorf("unexpected counts %v", counts)
+	}
+
+	if err := repo.Ping(context.Background()); err != nil {
+		t.Errorf("unexpected ping error: %v", err)
+	}
+}
+
+func TestGetDuringUpdate(t *testing.T) {
+	now := time.Now()
+	userID := models.UserID("user1")
+	repo := NewEventsRepository()
+	_ = repo.Put(userID, models.Event{ID: "1", Date: now, Event: "0"})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 1; i <= 200; i++ {
+			if i%2 == 0 {
+				_ = repo.Update(userID, models.Event{ID: "1", Event: strconv.Itoa(i)})
+			} else {
+				_ = repo.Replace(userID, models.Event{ID: "1", Date: now, Event: strconv.Itoa(i)})
+			}
+		}
+	}()
+
+	// Версия и текст события меняются вместе: копия не должна видеть их по отдельности.
+	for range 200 {
+		got, err := repo.Get(userID, "1")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got.Event != strconv.FormatInt(got.Version-1, 10) {
+			t.Fatalf("inconsistent event %+v", got)
+		}
+		got.Event = "changed"
+	}
+	<-done
+
+	got, _ := repo.Get(userID, "1")
+	if got.Event != "200" || got.Version != 201 {
+		t.Errorf("expected event 200 in version 201, got %+v", got)
+	}
+}
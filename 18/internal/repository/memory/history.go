@@ -0,0 +1,125 @@
+package memory
+
+import (
+	"maps"
+	"slices"
+	"time"
+
+	"l2.18/internal/repository"
+	"l2.18/pkg/models"
+)
+
+// AddHistory сохраняет запись истории события entry.EventID пользователя userID.
+// Запись без номера получает следующий номер в истории события, в том числе
+// после удаленных записей.
+func (er *EventsRepository) AddHistory(userID models.UserID, entry models.HistoryEntry) error {
+	er.Lock()
+	defer er.Unlock()
+
+	key := eventKey{owner: userID, id: entry.EventID}
+	entries := er.history[key]
+	if entry.Revision == 0 {
+		entry.Revision = er.nextRevision(key)
+	} else if entry.Revision < er.nextRevision(key) {
+		return repository.ErrAlreadyExist
+	}
+
+	er.history[key] = append(entries, entry)
+	er.record(func() {
+		if len(entries) == 0 {
+			delete(er.history, key)
+		} else {
+			er.history[key] = entries
+		}
+	})
+	return nil
+}
+
+// GetHistory возвращает записи истории события по возрастанию номера.
+func (er *EventsRepository) GetHistory(userID models.UserID, eventID models.EventID) ([]models.HistoryEntry, error) {
+	er.RLock()
+	defer er.RUnlock()
+
+	return slices.Clone(er.history[eventKey{owner: userID, id: eventID}]), nil
+}
+
+// PruneHistory удаляет записи истории, сделанные раньше before.
+func (er *EventsRepository) PruneHistory(before time.Time) error {
+	er.Lock()
+	defer er.Unlock()
+
+	// Записи удаляются на месте, поэтому для отмены истории событий копируются.
+	if er.undo != nil {
+		saved := make(map[eventKey][]models.HistoryEntry, len(er.history))
+		for key, entries := range er.history {
+			saved[key] = slices.Clone(entries)
+		}
+		er.record(func() {
+			clear(er.history)
+			maps.Copy(er.history, saved)
+		})
+	}
+
+	for key, entries := range er.history {
+		last := entries[len(entries)-1].Revision
+		kept := slices.DeleteFunc(entries, func(e models.HistoryEntry) bool {
+			return e.At.Before(before)
+		})
+		if len(kept) == 0 {
+			delete(er.history, key)
+			n := er.numbers[key]
+			n.Revision = last
+			er.setNumbers(key, n)
+		} else {
+			er.history[key] = kept
+		}
+	}
+
+	return nil
+}
+
+// HasHistoryBefore сообщает, есть ли записи истории, сделанные раньше before.
+func (er *EventsRepository) HasHistoryBefore(before time.Time) bool {
+	er.RLock()
+	defer er.RUnlock()
+
+	for _, entries := range er.history {
+		for _, e := range entries {
+			if e.At.Before(before) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// History возвращает копию истории всех событий пользователей.
+func (er *EventsRepository) History() map[models.UserID][]models.HistoryEntry {
+	er.RLock()
+	defer er.RUnlock()
+
+	result := make(map[models.UserID][]models.HistoryEntry)
+	for key, entries := range er.history {
+		result[key.owner] = append(result[key.owner], entries...)
+	}
+
+	return result
+}
+
+// NextRevision возвращает номер, который получит следующая запись истории события.
+func (er *EventsRepository) NextRevision(userID models.UserID, eventID models.EventID) int64 {
+	er.RLock()
+	defer er.RUnlock()
+
+	return er.nextRevision(eventKey{owner: userID, id: eventID})
+}
+
+// nextRevision возвращает номер следующей записи истории события key.
+// Вызывается под блокировкой.
+func (er *EventsRepository) nextRevision(key eventKey) int64 {
+	entries := er.history[key]
+	if len(entries) == 0 {
+		return er.numbers[key].Revision + 1
+	}
+	return entries[len(entries)-1].Revision + 1
+}
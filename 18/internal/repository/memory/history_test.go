@@ -0,0 +1,41 @@
+package memory
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"l2.18/internal/repository"
+	"l2.18/pkg/models"
+)
+
+func TestHistory(t *testing.T) {
+	now := time.Now()
+	repo := NewEventsRepository()
+
+	_ = repo.AddHistory("1", models.HistoryEntry{EventID: "a", Type: models.ChangeCreated, At: now.Add(-time.Hour)})
+	_ = repo.AddHistory("1", models.HistoryEntry{EventID: "a", Type: models.ChangeUpdated, At: now})
+	_ = repo.AddHistory("1", models.HistoryEntry{EventID: "b", Type: models.ChangeCreated, At: now})
+	_ = repo.AddHistory("2", models.HistoryEntry{EventID: "a", Type: models.ChangeCreated, At: now})
+
+	entries, _ := repo.GetHistory("1", "a")
+	if len(entries) != 2 || entries[0].Revision != 1 || entries[1].Revision != 2 {
+		t.Fatalf("unexpected history %+v", entries)
+	}
+	if err := repo.AddHistory("1", models.HistoryEntry{EventID: "a", Revision: 2, At: now}); !errors.Is(err, repository.ErrAlreadyExist) {
+		t.Errorf("expected %v, got %v", repository.ErrAlreadyExist, err)
+	}
+
+	_ = repo.PruneHistory(now.Add(-time.Minute))
+
+	entries, _ = repo.GetHistory("1", "a")
+	if len(entries) != 1 || entries[0].Revision != 2 {
+		t.Errorf("expected only revision 2 after prune, got %+v", entries)
+	}
+	if next := repo.NextRevision("1", "a"); next != 3 {
+		t.Errorf("expected next revision 3, got %d", next)
+	}
+	if entries, _ := repo.GetHistory("2", "a"); len(entries) != 1 {
+		t.Errorf("expected history of another user to be kept, got %+v", entries)
+	}
+}
@@ -0,0 +1,75 @@
+package memory
+
+import (
+	"time"
+
+	"l2.18/internal/repository"
+	"l2.18/pkg/models"
+)
+
+// idempotencyKey - ключ идемпотентности пользователя.
+type idempotencyKey struct {
+	userID models.UserID
+	key    string
+}
+
+// PutIdempotencyRecord сохраняет ответ на запрос с ключом идемпотентности,
+// заменяя прежний ответ с тем же ключом.
+func (er *EventsRepository) PutIdempotencyRecord(record models.IdempotencyRecord) error {
+	er.Lock()
+	defer er.Unlock()
+
+	er.idempotency[idempotencyKey{userID: record.UserID, key: record.Key}] = record
+	return nil
+}
+
+// GetIdempotencyRecord возвращает ответ, сохраненный с ключом key пользователя userID.
+// Если ответа нет - вернет ошибку.
+func (er *EventsRepository) GetIdempotencyRecord(userID models.UserID, key string) (*models.IdempotencyRecord, error) {
+	er.RLock()
+	defer er.RUnlock()
+
+	record, ok := er.idempotency[idempotencyKey{userID: userID, key: key}]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	return &record, nil
+}
+
+// PruneIdempotencyRecords удаляет ответы, сохраненные раньше before.
+func (er *EventsRepository) PruneIdempotencyRecords(before time.Time) error {
+	er.Lock()
+	defer er.Unlock()
+
+	for key, record := range er.idempotency {
+		if record.CreatedAt.Before(before) {
+			delete(er.idempotency, key)
+		}
+	}
+	return nil
+}
+
+// HasIdempotencyRecordsBefore сообщает, есть ли ответы, сохраненные раньше before.
+func (er *EventsRepository) HasIdempotencyRecordsBefore(before time.Time) bool {
+	er.RLock()
+	defer er.RUnlock()
+
+	for _, record := range er.idempotency {
+		if record.CreatedAt.Before(before) {
+			return true
+		}
+	}
+	return false
+}
+
+// IdempotencyRecords возвращает все сохраненные ответы.
+func (er *EventsRepository) IdempotencyRecords() []models.IdempotencyRecord {
+	er.RLock()
+	defer er.RUnlock()
+
+	result := make([]models.IdempotencyRecord, 0, len(er.idempotency))
+	for _, record := range er.idempotency {
+		result = append(result, record)
+	}
+	return result
+}
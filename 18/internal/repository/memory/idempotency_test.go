@@ -0,0 +1,36 @@
+package memory
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"l2.18/internal/repository"
+	"l2.18/pkg/models"
+)
+
+func TestIdempotencyRecords(t *testing.T) {
+	now := time.Now()
+	repo := NewEventsRepository()
+
+	_ = repo.PutIdempotencyRecord(models.IdempotencyRecord{UserID: "1", Key: "a", Status: 200, CreatedAt: now.Add(-time.Hour)})
+	_ = repo.PutIdempotencyRecord(models.IdempotencyRecord{UserID: "1", Key: "a", Status: 201, CreatedAt: now})
+	_ = repo.PutIdempotencyRecord(models.IdempotencyRecord{UserID: "2", Key: "a", Status: 200, CreatedAt: now.Add(-time.Hour)})
+
+	record, err := repo.GetIdempotencyRecord("1", "a")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if record.Status != 201 {
+		t.Errorf("expected replaced record, got %+v", record)
+	}
+
+	_ = repo.PruneIdempotencyRecords(now.Add(-time.Minute))
+
+	if _, err := repo.GetIdempotencyRecord("2", "a"); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("expected %v after prune, got %v", repository.ErrNotFound, err)
+	}
+	if _, err := repo.GetIdempotencyRecord("1", "a"); err != nil {
+		t.Errorf("expected fresh record to be kept, got %v", err)
+	}
+}
@@ -0,0 +1,50 @@
+package memory
+
+import (
+	"l2.18/pkg/models"
+)
+
+// Numbers - последние номера события, которые пропали бы вместе с ним или с его
+// историей: версия удаленного события и номер последней удаленной записи истории.
+// С них продолжается нумерация, поэтому версии и номера записей не повторяются.
+type Numbers struct {
+	Version  int64 `json:"version,omitempty"`
+	Revision int64 `json:"revision,omitempty"`
+}
+
+// Numbers возвращает сохраненные номера событий пользователей.
+func (er *EventsRepository) Numbers() map[models.UserID]map[models.EventID]Numbers {
+	er.RLock()
+	defer er.RUnlock()
+
+	result := make(map[models.UserID]map[models.EventID]Numbers)
+	for key, n := range er.numbers {
+		if result[key.owner] == nil {
+			result[key.owner] = make(map[models.EventID]Numbers)
+		}
+		result[key.owner][key.id] = n
+	}
+
+	return result
+}
+
+// SetNumbers сохраняет номера события eventID пользователя userID.
+func (er *EventsRepository) SetNumbers(userID models.UserID, eventID models.EventID, n Numbers) {
+	er.Lock()
+	defer er.Unlock()
+
+	er.setNumbers(eventKey{owner: userID, id: eventID}, n)
+}
+
+// setNumbers сохраняет номера события key. Вызывается под блокировкой.
+func (er *EventsRepository) setNumbers(key eventKey, n Numbers) {
+	prev, existed := er.numbers[key]
+	er.numbers[key] = n
+	er.record(func() {
+		if existed {
+			er.numbers[key] = prev
+		} else {
+			delete(er.numbers, key)
+		}
+	})
+}
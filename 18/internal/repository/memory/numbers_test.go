@@ -0,0 +1,47 @@
+package memory
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"l2.18/pkg/models"
+)
+
+func TestNumbers(t *testing.T) {
+	now := time.Now()
+	repo := NewEventsRepository()
+
+	_ = repo.Put("1", models.Event{ID: "a", Date: now, Event: "first"})
+	_ = repo.Update("1", models.Event{ID: "a", Event: "second"})
+	_ = repo.Delete("1", "a", 0)
+	_ = repo.Put("1", models.Event{ID: "a", Date: now, Event: "restored"})
+
+	if event, _ := repo.Get("1", "a"); event.Version != 3 {
+		t.Errorf("expected version 3 after delete, got %d", event.Version)
+	}
+
+	_ = repo.AddHistory("1", models.HistoryEntry{EventID: "a", Type: models.ChangeCreated, At: now.Add(-time.Hour)})
+	_ = repo.AddHistory("1", models.HistoryEntry{EventID: "a", Type: models.ChangeUpdated, At: now.Add(-time.Hour)})
+	_ = repo.PruneHistory(now)
+	_ = repo.AddHistory("1", models.HistoryEntry{EventID: "a", Type: models.ChangeUpdated, At: now})
+
+	if entries, _ := repo.GetHistory("1", "a"); len(entries) != 1 || entries[0].Revision != 3 {
+		t.Errorf("expected revision 3 after prune, got %+v", entries)
+	}
+
+	// Отмененная транзакция не меняет номера.
+	errRollback := errors.New("rollback")
+	err := repo.Atomic(func(tx *EventsRepository) error {
+		_ = tx.Update("1", models.Event{ID: "a", Event: "fourth"})
+		_ = tx.Delete("1", "a", 0)
+		_ = tx.PruneHistory(now.Add(time.Hour))
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		t.Fatalf("expected %v, got %v", errRollback, err)
+	}
+	if n := repo.Numbers()["1"]["a"]; n != (Numbers{Version: 2, Revision: 2}) {
+		t.Errorf("expected numbers before transaction, got %+v", n)
+	}
+}
@@ -0,0 +1,67 @@
+package memory
+
+import "time"
+
+// MarkReminder отмечает напоминание key как отправленное. at - время события,
+// после которого отметку можно удалить через PruneReminders.
+// Вернет false, если напоминание уже было отмечено.
+func (er *EventsRepository) MarkReminder(key string, at time.Time) (bool, error) {
+	er.Lock()
+	defer er.Unlock()
+
+	if _, ok := er.reminders[key]; ok {
+		return false, nil
+	}
+	er.reminders[key] = at
+
+	return true, nil
+}
+
+// HasReminder сообщает, отмечено ли напоминание key.
+func (er *EventsRepository) HasReminder(key string) bool {
+	er.RLock()
+	defer er.RUnlock()
+
+	_, ok := er.reminders[key]
+	return ok
+}
+
+// HasRemindersBefore сообщает, есть ли отметки напоминаний о событиях, начавшихся раньше before.
+func (er *EventsRepository) HasRemindersBefore(before time.Time) bool {
+	er.RLock()
+	defer er.RUnlock()
+
+	for _, at := range er.reminders {
+		if at.Before(before) {
+			return true
+		}
+	}
+	return false
+}
+
+// PruneReminders удаляет отметки напоминаний о событиях, начавшихся раньше before.
+func (er *EventsRepository) PruneReminders(before time.Time) error {
+	er.Lock()
+	defer er.Unlock()
+
+	for key, at := range er.reminders {
+		if at.Before(before) {
+			delete(er.reminders, key)
+		}
+	}
+
+	return nil
+}
+
+// Reminders возвращает копию всех отметок напоминаний.
+func (er *EventsRepository) Reminders() map[string]time.Time {
+	er.RLock()
+	defer er.RUnlock()
+
+	result := make(map[string]time.Time, len(er.reminders))
+	for key, at := range er.reminders {
+		result[key] = at
+	}
+
+	return result
+}
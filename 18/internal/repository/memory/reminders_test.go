@@ -0,0 +1,34 @@
+package memory
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMarkReminder(t *testing.T) {
+	now := time.Now()
+	repo := NewEventsRepository()
+
+	marked, err := repo.MarkReminder("a", now)
+	if err != nil || !marked {
+		t.Fatalf("expected first mark to succeed, got %v, %v", marked, err)
+	}
+
+	marked, err = repo.MarkReminder("a", now)
+	if err != nil || marked {
+		t.Fatalf("expected second mark to be rejected, got %v, %v", marked, err)
+	}
+
+	_, _ = repo.MarkReminder("b", now.Add(time.Hour))
+
+	if err := repo.PruneReminders(now.Add(time.Minute)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if repo.HasReminder("a") {
+		t.Error("expected reminder a to be pruned")
+	}
+	if !repo.HasReminder("b") {
+		t.Error("expected reminder b to be kept")
+	}
+}
@@ -0,0 +1,43 @@
+package memory
+
+import (
+	"sort"
+
+	"l2.18/pkg/models"
+	"l2.18/pkg/search"
+)
+
+// Search возвращает события пользователя, текст которых соответствует запросу,
+// отсортированные по началу.
+func (er *EventsRepository) Search(userID models.UserID, query search.Query) ([]models.Event, error) {
+	er.RLock()
+	defer er.RUnlock()
+
+	index, ok := er.texts[userID]
+	if !ok {
+		return []models.Event{}, nil
+	}
+
+	ids := index.Search(query)
+	result := make([]models.Event, 0, len(ids))
+	for _, id := range ids {
+		result = append(result, *er.events[userID][id])
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].Date.Equal(result[j].Date) {
+			return result[i].Date.Before(result[j].Date)
+		}
+		return result[i].ID < result[j].ID
+	})
+
+	return result, nil
+}
+
+// indexText обновляет полнотекстовый индекс пользователя после изменения события.
+func (er *EventsRepository) indexText(userID models.UserID, event *models.Event) {
+	if er.texts[userID] == nil {
+		er.texts[userID] = search.NewIndex[models.EventID]()
+	}
+	er.texts[userID].Add(event.ID, event.Event)
+}
@@ -0,0 +1,57 @@
+package memory
+
+import (
+	"testing"
+	"time"
+
+	"l2.18/pkg/models"
+	"l2.18/pkg/search"
+)
+
+func searchIDs(t *testing.T, repo *EventsRepository, userID models.UserID, query string) []models.EventID {
+	t.Helper()
+
+	q, err := search.Parse(query)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	events, err := repo.Search(userID, q)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ids := make([]models.EventID, len(events))
+	for i, e := range events {
+		ids[i] = e.ID
+	}
+	return ids
+}
+
+func TestSearch(t *testing.T) {
+	now := time.Now()
+	repo := NewEventsRepository()
+
+	_ = repo.Put("1", models.Event{ID: "b", Date: now.Add(time.Hour), Event: "Стоматолог"})
+	_ = repo.Put("1", models.Event{ID: "a", Date: now, Event: "Запись к стоматологу"})
+	_ = repo.Put("1", models.Event{ID: "c", Date: now, Event: "Планерка"})
+	_ = repo.Put("2", models.Event{ID: "d", Date: now, Event: "стоматолог"})
+
+	if ids := searchIDs(t, repo, "1", "стомат*"); len(ids) != 2 || ids[0] != "a" || ids[1] != "b" {
+		t.Errorf("expected [a b] sorted by date, got %v", ids)
+	}
+	if ids := searchIDs(t, repo, "3", "стомат*"); len(ids) != 0 {
+		t.Errorf("expected no events of unknown user, got %v", ids)
+	}
+
+	_ = repo.Update("1", models.Event{ID: "c", Event: "Стоматолог, повторно"})
+	_ = repo.Replace("1", models.Event{ID: "b", Date: now, Event: "Отпуск"})
+	_ = repo.Delete("1", "a", 0)
+
+	if ids := searchIDs(t, repo, "1", "стомат*"); len(ids) != 1 || ids[0] != "c" {
+		t.Errorf("expected [c] after changes, got %v", ids)
+	}
+	if ids := searchIDs(t, repo, "1", "отпуск"); len(ids) != 1 || ids[0] != "b" {
+		t.Errorf("expected [b] after replace, got %v", ids)
+	}
+}
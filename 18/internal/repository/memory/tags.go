@@ -0,0 +1,91 @@
+package memory
+
+import (
+	"sort"
+	"time"
+
+	"l2.18/pkg/models"
+)
+
+// GetFilteredEvents возвращает события пользователя, пересекающиеся с диапазоном
+// [start, end) и подходящие filter, отсортированные по началу. Если в фильтре есть
+// метки, просматриваются только события с самой редкой из них.
+func (er *EventsRepository) GetFilteredEvents(
+	userID models.UserID,
+	start, end time.Time,
+	filter models.EventFilter,
+) ([]models.Event, error) {
+	if len(filter.Tags) == 0 {
+		events, err := er.GetEventsByDateRange(userID, start, end)
+		if err != nil {
+			return nil, err
+		}
+
+		result := events[:0]
+		for _, e := range events {
+			if filter.Matches(&e) {
+				result = append(result, e)
+			}
+		}
+		return result, nil
+	}
+
+	er.RLock()
+	defer er.RUnlock()
+
+	var candidates map[models.EventID]*models.Event
+	for _, tag := range filter.Tags {
+		tagged := er.tags[userID][tag]
+		if len(tagged) == 0 {
+			return []models.Event{}, nil
+		}
+		if candidates == nil || len(tagged) < len(candidates) {
+			candidates = tagged
+		}
+	}
+
+	result := []models.Event{}
+	for _, e := range candidates {
+		if e.Overlaps(start, end) && filter.Matches(e) {
+			result = append(result, *e)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].Date.Equal(result[j].Date) {
+			return result[i].Date.Before(result[j].Date)
+		}
+		return result[i].ID < result[j].ID
+	})
+
+	return result, nil
+}
+
+// indexTags обновляет индекс меток пользователя после изменения события.
+// old - метки события до изменения.
+func (er *EventsRepository) indexTags(userID models.UserID, event *models.Event, old []string) {
+	er.unindexTags(userID, event.ID, old)
+
+	if len(event.Tags) > 0 && er.tags[userID] == nil {
+		er.tags[userID] = make(map[string]map[models.EventID]*models.Event)
+	}
+	for _, tag := range event.Tags {
+		if er.tags[userID][tag] == nil {
+			er.tags[userID][tag] = make(map[models.EventID]*models.Event)
+		}
+		er.tags[userID][tag][event.ID] = event
+	}
+}
+
+// unindexTags удаляет событие eventID из индекса меток tags пользователя.
+func (er *EventsRepository) unindexTags(userID models.UserID, eventID models.EventID, tags []string) {
+	for _, tag := range tags {
+		delete(er.tags[userID][tag], eventID)
+		if len(er.tags[userID][tag]) == 0 {
+			delete(er.tags[userID], tag)
+		}
+	}
+	if len(er.tags[userID]) == 0 {
+		delete(er.tags, userID)
+	}
+}
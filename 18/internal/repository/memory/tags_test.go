@@ -0,0 +1,100 @@
+package memory
+
+import (
+	"testing"
+	"time"
+
+	"l2.18/internal/repository"
+	"l2.18/pkg/models"
+)
+
+func filteredIDs(t *testing.T, repo *EventsRepository, start, end time.Time, filter models.EventFilter) []models.EventID {
+	t.Helper()
+
+	events, err := repo.GetFilteredEvents("1", start, end, filter)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ids := make([]models.EventID, len(events))
+	for i, e := range events {
+		ids[i] = e.ID
+	}
+	return ids
+}
+
+func TestGetFilteredEvents(t *testing.T) {
+	day := time.Date(2025, time.February, 15, 0, 0, 0, 0, time.UTC)
+	end := day.AddDate(0, 0, 1)
+	repo := NewEventsRepository()
+
+	_ = repo.Put("1", models.Event{ID: "a", Date: day.Add(2 * time.Hour), Tags: []string{"team", "urgent"}, Category: "work"})
+	_ = repo.Put("1", models.Event{ID: "b", Date: day.Add(time.Hour), Tags: []string{"team"}})
+	_ = repo.Put("1", models.Event{ID: "c", Date: day.AddDate(0, 0, 2), Tags: []string{"team"}})
+	_ = repo.Put("1", models.Event{ID: "d", Date: day, Category: "work"})
+	_ = repo.Put("2", models.Event{ID: "e", Date: day, Tags: []string{"team"}})
+
+	testCases := []struct {
+		name     string
+		filter   models.EventFilter
+		expected []models.EventID
+	}{
+		{name: "tag", filter: models.EventFilter{Tags: []string{"team"}}, expected: []models.EventID{"b", "a"}},
+		{name: "all tags", filter: models.EventFilter{Tags: []string{"team", "urgent"}}, expected: []models.EventID{"a"}},
+		{name: "unknown tag", filter: models.EventFilter{Tags: []string{"team", "none"}}, expected: []models.EventID{}},
+		{name: "category", filter: models.EventFilter{Category: "work"}, expected: []models.EventID{"d", "a"}},
+		{name: "tag and category", filter: models.EventFilter{Tags: []string{"team"}, Category: "work"}, expected: []models.EventID{"a"}},
+		{name: "empty", filter: models.EventFilter{}, expected: []models.EventID{"d", "b", "a"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ids := filteredIDs(t, repo, day, end, tc.filter)
+			if len(ids) != len(tc.expected) {
+				t.Fatalf("expected %v, got %v", tc.expected, ids)
+			}
+			for i, id := range tc.expected {
+				if ids[i] != id {
+					t.Errorf("expected %v, got %v", tc.expected, ids)
+				}
+			}
+		})
+	}
+}
+
+func TestTagIndexFollowsChanges(t *testing.T) {
+	day := time.Date(2025, time.February, 15, 0, 0, 0, 0, time.UTC)
+	end := day.AddDate(0, 0, 1)
+	team := models.EventFilter{Tags: []string{"team"}}
+	repo := NewEventsRepository()
+
+	_ = repo.Put("1", models.Event{ID: "a", Date: day, Tags: []string{"team"}})
+	_ = repo.Put("1", models.Event{ID: "b", Date: day})
+	_ = repo.Put("1", models.Event{ID: "c", Date: day, Tags: []string{"team"}})
+
+	_ = repo.Update("1", models.Event{ID: "a", Tags: []string{"private"}})
+	_ = repo.Replace("1", models.Event{ID: "b", Date: day, Tags: []string{"team"}})
+	_ = repo.Delete("1", "c", 0)
+
+	if ids := filteredIDs(t, repo, day, end, team); len(ids) != 1 || ids[0] != "b" {
+		t.Errorf("expected [b] after changes, got %v", ids)
+	}
+
+	err := repo.Transaction(func(tx repository.Tx) error {
+		_ = tx.Put("1", models.Event{ID: "d", Date: day, Tags: []string{"team"}})
+		return repository.ErrNotFound
+	})
+	if err == nil {
+		t.Fatal("expected transaction error")
+	}
+	if ids := filteredIDs(t, repo, day, end, team); len(ids) != 1 {
+		t.Errorf("expected rolled back transaction to keep index, got %v", ids)
+	}
+
+	_ = repo.Transaction(func(tx repository.Tx) error {
+		return tx.Put("1", models.Event{ID: "d", Date: day, Tags: []string{"team"}})
+	})
+	if ids := filteredIDs(t, repo, day, end, team); len(ids) != 2 {
+		t.Errorf("expected committed event in index, got %v", ids)
+	}
+}
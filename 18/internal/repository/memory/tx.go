@@ -0,0 +1,59 @@
+package memory
+
+import (
+	"l2.18/internal/repository"
+)
+
+// undoLog - действия, отменяющие изменения транзакции, в порядке изменений.
+type undoLog []func()
+
+// Transaction выполняет fn в транзакции хранилища, см. Atomic.
+func (er *EventsRepository) Transaction(fn func(tx repository.Tx) error) error {
+	return er.Atomic(func(tx *EventsRepository) error {
+		return fn(tx)
+	})
+}
+
+// Atomic выполняет fn над хранилищем tx, которое изменяет состояние er сразу
+// и запоминает, как отменить каждое изменение событий и истории. Если fn вернула
+// ошибку, изменения отменяются в обратном порядке. Пока выполняется fn, другие
+// операции с хранилищем ждут, поэтому незавершенную транзакцию никто не видит.
+func (er *EventsRepository) Atomic(fn func(tx *EventsRepository) error) error {
+	er.Lock()
+	defer er.Unlock()
+
+	// tx разделяет с er все состояние, но блокирует только себя: er уже заблокировано.
+	tx := &EventsRepository{
+		events:      er.events,
+		dateIndex:   er.dateIndex,
+		recurring:   er.recurring,
+		maxDuration: er.maxDuration,
+		reminders:   er.reminders,
+		invitations: er.invitations,
+		texts:       er.texts,
+		tags:        er.tags,
+		history:     er.history,
+		idempotency: er.idempotency,
+		numbers:     er.numbers,
+		undo:        &undoLog{},
+	}
+
+	err := fn(tx)
+	if err == nil {
+		return nil
+	}
+
+	undo := *tx.undo
+	tx.undo = nil
+	for i := len(undo) - 1; i >= 0; i-- {
+		undo[i]()
+	}
+	return err
+}
+
+// record запоминает отмену изменения, если хранилище внутри Atomic.
+func (er *EventsRepository) record(undo func()) {
+	if er.undo != nil {
+		*er.undo = append(*er.undo, undo)
+	}
+}
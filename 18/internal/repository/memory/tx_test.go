@@ -0,0 +1,116 @@
+package memory
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"l2.18/internal/repository"
+	"l2.18/pkg/models"
+)
+
+func TestTransaction(t *testing.T) {
+	day := time.Date(2025, time.February, 15, 10, 0, 0, 0, time.UTC)
+	userID := models.UserID("1")
+	errStop := errors.New("stop")
+
+	testCases := []struct {
+		name     string
+		fn       func(tx repository.Tx) error
+		expected error
+		events   []models.EventID
+	}{
+		{
+			name: "commit",
+			fn: func(tx repository.Tx) error {
+				_ = tx.Put(userID, models.Event{ID: "b", Date: day.Add(time.Hour), Event: "lunch"})
+				_ = tx.AddHistory(userID, models.HistoryEntry{EventID: "b", Type: models.ChangeCreated, At: day})
+				return tx.Delete(userID, "a", 0)
+			},
+			events: []models.EventID{"b"},
+		},
+		{
+			name: "rollback",
+			fn: func(tx repository.Tx) error {
+				_ = tx.Put(userID, models.Event{ID: "b", Date: day.Add(time.Hour), Event: "lunch"})
+				_ = tx.AddHistory(userID, models.HistoryEntry{EventID: "b", Type: models.ChangeCreated, At: day})
+				_ = tx.Delete(userID, "a", 0)
+				return errStop
+			},
+			expected: errStop,
+			events:   []models.EventID{"a"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := NewEventsRepository()
+			_ = repo.Put(userID, models.Event{ID: "a", Date: day, Event: "meeting"})
+
+			if err := repo.Transaction(tc.fn); !errors.Is(err, tc.expected) {
+				t.Fatalf("expected %v, got %v", tc.expected, err)
+			}
+
+			events, _ := repo.GetEventsByDateRange(userID, day.Add(-time.Hour), day.Add(24*time.Hour))
+			var ids []models.EventID
+			for _, e := range events {
+				ids = append(ids, e.ID)
+			}
+			if !slices.Equal(ids, tc.events) {
+				t.Errorf("expected events %v, got %v", tc.events, ids)
+			}
+
+			found := searchIDs(t, repo, userID, "lunch")
+			history, _ := repo.GetHistory(userID, "b")
+			if committed := tc.expected == nil; (len(found) == 1) != committed || (len(history) == 1) != committed {
+				t.Errorf("expected search and history to follow the transaction, got %v and %v", found, history)
+			}
+		})
+	}
+}
+
+func TestTransactionRollbackRestoresState(t *testing.T) {
+	day := time.Date(2025, time.February, 15, 10, 0, 0, 0, time.UTC)
+	owner, guest := models.UserID("1"), models.UserID("2")
+	errStop := errors.New("stop")
+
+	repo := NewEventsRepository()
+	_ = repo.Put(owner, models.Event{ID: "a", Date: day, Event: "meeting", Tags: []string{"work"},
+		Attendees: []models.Attendee{{UserID: guest, Status: models.RSVPNeedsAction}}})
+	_ = repo.Put(owner, models.Event{ID: "b", Date: day.Add(time.Hour), Event: "lunch"})
+	_ = repo.AddHistory(owner, models.HistoryEntry{EventID: "a", Type: models.ChangeCreated, At: day})
+	before := repo.All()
+
+	err := repo.Transaction(func(tx repository.Tx) error {
+		_ = tx.Update(owner, models.Event{ID: "a", Date: day.Add(2 * time.Hour), Tags: []string{"home"}})
+		_ = tx.UpdateAttendee(owner, "a", models.Attendee{UserID: guest, Status: models.RSVPAccepted})
+		_ = tx.Replace(owner, models.Event{ID: "b", Date: day, Event: "dinner"})
+		_ = tx.Delete(owner, "b", 0)
+		_ = tx.Put(owner, models.Event{ID: "c", Date: day, Event: "call"})
+		_ = tx.AddHistory(owner, models.HistoryEntry{EventID: "a", Type: models.ChangeUpdated, At: day})
+		_ = tx.PruneHistory(day.Add(time.Hour))
+		return errStop
+	})
+	if !errors.Is(err, errStop) {
+		t.Fatalf("expected %v, got %v", errStop, err)
+	}
+
+	if after := repo.All(); !reflect.DeepEqual(after, before) {
+		t.Errorf("expected events %+v, got %+v", before, after)
+	}
+	if history, _ := repo.GetHistory(owner, "a"); len(history) != 1 {
+		t.Errorf("expected history to be restored, got %+v", history)
+	}
+	if found := searchIDs(t, repo, owner, "dinner"); len(found) != 0 {
+		t.Errorf("expected no search results, got %v", found)
+	}
+	if invited, _ := repo.GetInvitedEvents(guest); len(invited) != 1 || invited[0].Attendees[0].Status != models.RSVPNeedsAction {
+		t.Errorf("expected invitation to be restored, got %+v", invited)
+	}
+	filtered, _ := repo.GetFilteredEvents(owner, day, day.Add(24*time.Hour), models.EventFilter{Tags: []string{"work"}})
+	if len(filtered) != 1 || filtered[0].ID != "a" {
+		t.Errorf("expected tag index to be restored, got %+v", filtered)
+	}
+}
@@ -1,33 +1,78 @@
 package repomock
 
 import (
+	"context"
 	"time"
 
+	"l2.18/internal/repository"
 	"l2.18/pkg/models"
+	"l2.18/pkg/search"
 )
 
 // MockRepository - repository mock.
 type MockRepository struct {
-	GetEventsByDateRangeFn func(userID models.UserID, start, end time.Time) ([]models.Event, error)
+	PutFn                     func(userID models.UserID, event models.Event) error
+	GetFn                     func(userID models.UserID, eventID models.EventID) (*models.Event, error)
+	UpdateFn                  func(userID models.UserID, event models.Event) error
+	ReplaceFn                 func(userID models.UserID, event models.Event) error
+	DeleteFn                  func(userID models.UserID, eventID models.EventID, version int64) error
+	GetEventsByDateRangeFn    func(userID models.UserID, start, end time.Time) ([]models.Event, error)
+	GetFilteredEventsFn       func(userID models.UserID, start, end time.Time, filter models.EventFilter) ([]models.Event, error)
+	GetRecurringEventsFn      func(userID models.UserID) ([]models.Event, error)
+	UsersFn                   func() ([]models.UserID, error)
+	GetInvitedEventsFn        func(userID models.UserID) ([]models.Event, error)
+	UpdateAttendeeFn          func(owner models.UserID, eventID models.EventID, attendee models.Attendee) error
+	SearchFn                  func(userID models.UserID, query search.Query) ([]models.Event, error)
+	AddHistoryFn              func(userID models.UserID, entry models.HistoryEntry) error
+	GetHistoryFn              func(userID models.UserID, eventID models.EventID) ([]models.HistoryEntry, error)
+	PruneHistoryFn            func(before time.Time) error
+	MarkReminderFn            func(key string, at time.Time) (bool, error)
+	PruneRemindersFn          func(before time.Time) error
+	PutIdempotencyRecordFn    func(record models.IdempotencyRecord) error
+	GetIdempotencyRecordFn    func(userID models.UserID, key string) (*models.IdempotencyRecord, error)
+	PruneIdempotencyRecordsFn func(before time.Time) error
+	EventCountsFn             func() (map[models.UserID]int, error)
+	PingFn                    func(ctx context.Context) error
+	TransactionFn             func(fn func(tx repository.Tx) error) error
 }
 
 // Put mock.
 func (m *MockRepository) Put(userID models.UserID, event models.Event) error {
+	if m.PutFn != nil {
+		return m.PutFn(userID, event)
+	}
 	panic("not implemented")
 }
 
 // Get mock.
 func (m *MockRepository) Get(userID models.UserID, eventID models.EventID) (*models.Event, error) {
+	if m.GetFn != nil {
+		return m.GetFn(userID, eventID)
+	}
 	panic("not implemented")
 }
 
 // Update mock.
 func (m *MockRepository) Update(userID models.UserID, event models.Event) error {
+	if m.UpdateFn != nil {
+		return m.UpdateFn(userID, event)
+	}
+	panic("not implemented")
+}
+
+// Replace mock.
+func (m *MockRepository) Replace(userID models.UserID, event models.Event) error {
+	if m.ReplaceFn != nil {
+		return m.ReplaceFn(userID, event)
+	}
 	panic("not implemented")
 }
 
 // Delete mock.
-func (m *MockRepository) Delete(userID models.UserID, eventID models.EventID) error {
+func (m *MockRepository) Delete(userID models.UserID, eventID models.EventID, version int64) error {
+	if m.DeleteFn != nil {
+		return m.DeleteFn(userID, eventID, version)
+	}
 	panic("not implemented")
 }
 
@@ -38,3 +83,141 @@ func (m *MockRepository) GetEventsByDateRange(userID models.UserID, start, end t
 	}
 	return nil, nil
 }
+
+// GetFilteredEvents mock.
+func (m *MockRepository) GetFilteredEvents(
+	userID models.UserID, start, end time.Time, filter models.EventFilter,
+) ([]models.Event, error) {
+	if m.GetFilteredEventsFn != nil {
+		return m.GetFilteredEventsFn(userID, start, end, filter)
+	}
+	return nil, nil
+}
+
+// GetRecurringEvents mock.
+func (m *MockRepository) GetRecurringEvents(userID models.UserID) ([]models.Event, error) {
+	if m.GetRecurringEventsFn != nil {
+		return m.GetRecurringEventsFn(userID)
+	}
+	return nil, nil
+}
+
+// Users mock.
+func (m *MockRepository) Users() ([]models.UserID, error) {
+	if m.UsersFn != nil {
+		return m.UsersFn()
+	}
+	return nil, nil
+}
+
+// GetInvitedEvents mock. Без GetInvitedEventsFn приглашений нет.
+func (m *MockRepository) GetInvitedEvents(userID models.UserID) ([]models.Event, error) {
+	if m.GetInvitedEventsFn != nil {
+		return m.GetInvitedEventsFn(userID)
+	}
+	return nil, nil
+}
+
+// UpdateAttendee mock.
+func (m *MockRepository) UpdateAttendee(owner models.UserID, eventID models.EventID, attendee models.Attendee) error {
+	if m.UpdateAttendeeFn != nil {
+		return m.UpdateAttendeeFn(owner, eventID, attendee)
+	}
+	panic("not implemented")
+}
+
+// Search mock.
+func (m *MockRepository) Search(userID models.UserID, query search.Query) ([]models.Event, error) {
+	if m.SearchFn != nil {
+		return m.SearchFn(userID, query)
+	}
+	panic("not implemented")
+}
+
+// AddHistory mock.
+func (m *MockRepository) AddHistory(userID models.UserID, entry models.HistoryEntry) error {
+	if m.AddHistoryFn != nil {
+		return m.AddHistoryFn(userID, entry)
+	}
+	panic("not implemented")
+}
+
+// GetHistory mock.
+func (m *MockRepository) GetHistory(userID models.UserID, eventID models.EventID) ([]models.HistoryEntry, error) {
+	if m.GetHistoryFn != nil {
+		return m.GetHistoryFn(userID, eventID)
+	}
+	panic("not implemented")
+}
+
+// PruneHistory mock.
+func (m *MockRepository) PruneHistory(before time.Time) error {
+	if m.PruneHistoryFn != nil {
+		return m.PruneHistoryFn(before)
+	}
+	panic("not implemented")
+}
+
+// MarkReminder mock.
+func (m *MockRepository) MarkReminder(key string, at time.Time) (bool, error) {
+	if m.MarkReminderFn != nil {
+		return m.MarkReminderFn(key, at)
+	}
+	panic("not implemented")
+}
+
+// PruneReminders mock.
+func (m *MockRepository) PruneReminders(before time.Time) error {
+	if m.PruneRemindersFn != nil {
+		return m.PruneRemindersFn(before)
+	}
+	panic("not implemented")
+}
+
+// PutIdempotencyRecord mock.
+func (m *MockRepository) PutIdempotencyRecord(record models.IdempotencyRecord) error {
+	if m.PutIdempotencyRecordFn != nil {
+		return m.PutIdempotencyRecordFn(record)
+	}
+	panic("not implemented")
+}
+
+// GetIdempotencyRecord mock.
+func (m *MockRepository) GetIdempotencyRecord(userID models.UserID, key string) (*models.IdempotencyRecord, error) {
+	if m.GetIdempotencyRecordFn != nil {
+		return m.GetIdempotencyRecordFn(userID, key)
+	}
+	panic("not implemented")
+}
+
+// PruneIdempotencyRecords mock.
+func (m *MockRepository) PruneIdempotencyRecords(before time.Time) error {
+	if m.PruneIdempotencyRecordsFn != nil {
+		return m.PruneIdempotencyRecordsFn(before)
+	}
+	panic("not implemented")
+}
+
+// EventCounts mock.
+func (m *MockRepository) EventCounts() (map[models.UserID]int, error) {
+	if m.EventCountsFn != nil {
+		return m.EventCountsFn()
+	}
+	panic("not implemented")
+}
+
+// Ping mock.
+func (m *MockRepository) Ping(ctx context.Context) error {
+	if m.PingFn != nil {
+		return m.PingFn(ctx)
+	}
+	return nil
+}
+
+// Transaction mock. По умолчанию вызывает fn с самим моком, без отката.
+func (m *MockRepository) Transaction(fn func(tx repository.Tx) error) error {
+	if m.TransactionFn != nil {
+		return m.TransactionFn(fn)
+	}
+	return fn(m)
+}
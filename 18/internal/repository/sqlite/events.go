@@ -0,0 +1,675 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	sqlitedriver "modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+
+	"l2.18/internal/repository"
+	"l2.18/pkg/models"
+)
+
+// eventColumns - порядок колонок, в котором scanEvent читает событие.
+const eventColumns = `id, date, end_date, timezone, event, rrule, exdates, overrides, attendees, version,
+	location, description, category, color, tags, attributes`
+
+// EventsRepository хранит события во встроенной базе SQLite.
+type EventsRepository struct {
+	db *sql.DB
+	// tx - транзакция Transaction. Если задана, все запросы выполняются в ней.
+	tx *sql.Tx
+}
+
+// NewEventsRepository открывает (или создает) базу по пути path
+// и применяет к ней миграции.
+func NewEventsRepository(path string) (*EventsRepository, error) {
+	dsn := "file:" + path +
+		"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=synchronous(NORMAL)" +
+		// Транзакции сразу берут блокировку записи, иначе транзакция, начавшаяся
+		// с чтения, может получить SQLITE_BUSY при переходе к записи без ожидания.
+		"&_txlock=immediate"
+
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := migrate(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &EventsRepository{db: db}, nil
+}
+
+// Close закрывает соединение с базой.
+func (er *EventsRepository) Close() error {
+	return er.db.Close()
+}
+
+// Transaction выполняет fn в транзакции базы. Если fn вернула ошибку,
+// транзакция откатывается и ошибка возвращается.
+func (er *EventsRepository) Transaction(fn func(tx repository.Tx) error) error {
+	tx, err := er.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(&EventsRepository{db: er.db, tx: tx}); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// conn возвращает транзакцию Transaction, если она есть, иначе базу.
+func (er *EventsRepository) conn() querier {
+	if er.tx != nil {
+		return er.tx
+	}
+	return er.db
+}
+
+// write выполняет изменение fn в отдельной транзакции, а внутри
+// Transaction - в ее транзакции.
+func (er *EventsRepository) write(fn func(tx *sql.Tx) error) error {
+	if er.tx != nil {
+		return fn(er.tx)
+	}
+
+	tx, err := er.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Put добавляет новое событие. Если событие уже существует - вернет ошибку.
+// Событие без версии получает версию 1, а ранее удаленное - следующую
+// после версии удаленного события.
+func (er *EventsRepository) Put(userID models.UserID, event models.Event) error {
+	exdates, overrides, attendees, err := encodeLists(&event)
+	if err != nil {
+		return err
+	}
+	tags, attributes, err := encodeMetadata(&event)
+	if err != nil {
+		return err
+	}
+	version := any(event.Version)
+	if event.Version == 0 {
+		version = nil
+	}
+
+	return er.write(func(tx *sql.Tx) error {
+		_, err := tx.Exec(
+			`INSERT INTO events (user_id, `+eventColumns+`)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, COALESCE(?,
+				(SELECT version + 1 FROM event_numbers WHERE user_id = ?1 AND event_id = ?2), 1),
+				?, ?, ?, ?, ?, ?)`,
+			userID, event.ID, event.Date.UnixNano(), unixNano(event.End), event.TimeZone,
+			event.Event, event.RRule, exdates, overrides, attendees, version,
+			event.Place, event.Description, event.Category, event.Color, tags, attributes,
+		)
+		if err != nil {
+			return mapError(err)
+		}
+
+		if err := trackDuration(tx, userID, event); err != nil {
+			return err
+		}
+		if err := indexAttendees(tx, userID, event.ID, event.Attendees); err != nil {
+			return err
+		}
+		if err := indexTags(tx, userID, event.ID, event.Tags); err != nil {
+			return err
+		}
+		return indexText(tx, userID, event.ID, event.Event)
+	})
+}
+
+// Get возвращает событие пользователя по его айди. Если события нет - вернет ошибку.
+func (er *EventsRepository) Get(userID models.UserID, eventID models.EventID) (*models.Event, error) {
+	return get(er.conn(), userID, eventID)
+}
+
+// Update обновляет событие пользователя, заменяя существующие поля,
+// полями переданными в функцию в event. Если задана event.Version,
+// а версия события другая - вернет ошибку.
+func (er *EventsRepository) Update(userID models.UserID, event models.Event) error {
+	return er.write(func(tx *sql.Tx) error {
+		stored, err := get(tx, userID, event.ID)
+		if err != nil {
+			return err
+		}
+		if !stored.MatchesVersion(event.Version) {
+			return repository.ErrVersionMismatch
+		}
+		stored.Merge(event)
+
+		return replace(tx, userID, *stored)
+	})
+}
+
+// Replace полностью заменяет существующее событие пользователя на event.
+// Если задана event.Version, а версия события другая - вернет ошибку.
+func (er *EventsRepository) Replace(userID models.UserID, event models.Event) error {
+	return er.write(func(tx *sql.Tx) error {
+		stored, err := get(tx, userID, event.ID)
+		if err != nil {
+			return err
+		}
+		if !stored.MatchesVersion(event.Version) {
+			return repository.ErrVersionMismatch
+		}
+
+		return replace(tx, userID, event)
+	})
+}
+
+// UpdateAttendee заменяет ответ участника attendee.UserID события eventID пользователя owner.
+func (er *EventsRepository) UpdateAttendee(owner models.UserID, eventID models.EventID, attendee models.Attendee) error {
+	return er.write(func(tx *sql.Tx) error {
+		stored, err := get(tx, owner, eventID)
+		if err != nil {
+			return err
+		}
+
+		a, ok := stored.Attendee(attendee.UserID)
+		if !ok {
+			return repository.ErrNotFound
+		}
+		*a = attendee
+
+		return replace(tx, owner, *stored)
+	})
+}
+
+// Delete удаляет события пользователя по айди. Если задана version,
+// а версия события другая - вернет ошибку.
+func (er *EventsRepository) Delete(userID models.UserID, eventID models.EventID, version int64) error {
+	return er.write(func(tx *sql.Tx) error {
+		stored, err := get(tx, userID, eventID)
+		if err != nil {
+			return err
+		}
+		if !stored.MatchesVersion(version) {
+			return repository.ErrVersionMismatch
+		}
+
+		_, err = tx.Exec(`DELETE FROM events WHERE user_id = ? AND id = ?`, userID, eventID)
+		if err != nil {
+			return mapError(err)
+		}
+		_, err = tx.Exec(
+			`INSERT INTO event_numbers (user_id, event_id, version) VALUES (?, ?, ?)
+			ON CONFLICT (user_id, event_id) DO UPDATE SET version = excluded.version`,
+			userID, eventID, stored.Version)
+		if err != nil {
+			return mapError(err)
+		}
+
+		if err := indexAttendees(tx, userID, eventID, nil); err != nil {
+			return err
+		}
+		if err := indexTags(tx, userID, eventID, nil); err != nil {
+			return err
+		}
+		return removeText(tx, userID, eventID)
+	})
+}
+
+// GetInvitedEvents возвращает события других пользователей, в которые приглашен userID,
+// отсортированные по началу. У каждого события заполнен Owner.
+func (er *EventsRepository) GetInvitedEvents(userID models.UserID) ([]models.Event, error) {
+	rows, err := er.conn().Query(
+		`SELECT events.user_id, `+eventColumns+` FROM invitations
+		JOIN events ON events.user_id = invitations.owner_id AND events.id = invitations.event_id
+		WHERE invitations.user_id = ?
+		ORDER BY date`,
+		userID,
+	)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	defer rows.Close()
+
+	result := []models.Event{}
+	for rows.Next() {
+		var owner models.UserID
+		event, err := scanEvent(ownerScanner{rows, &owner})
+		if err != nil {
+			return nil, err
+		}
+		event.Owner = owner
+		result = append(result, *event)
+	}
+
+	return result, rows.Err()
+}
+
+// rangeFilter отбирает события пользователя, пересекающиеся с диапазоном
+// [start, end). События, начавшиеся раньше start - max_duration, закончились
+// до start, поэтому индекс (user_id, date) ограничивает выборку с обеих сторон.
+const rangeFilter = `user_id = ? AND date < ?
+	AND date >= ? - COALESCE((SELECT max_duration FROM user_durations WHERE user_id = ?), 0)
+	AND (date >= ? OR end_date > ?)`
+
+// rangeArgs возвращает аргументы rangeFilter.
+func rangeArgs(userID models.UserID, start, end time.Time) []any {
+	return []any{userID, end.UnixNano(), start.UnixNano(), userID, start.UnixNano(), start.UnixNano()}
+}
+
+// GetEventsByDateRange возвращает все события пользователя, пересекающиеся
+// с диапазоном [start, end), отсортированные по началу.
+func (er *EventsRepository) GetEventsByDateRange(
+	userID models.UserID,
+	start, end time.Time,
+) ([]models.Event, error) {
+	return query(er.conn(),
+		`SELECT `+eventColumns+` FROM events WHERE `+rangeFilter+` ORDER BY date`,
+		rangeArgs(userID, start, end)...,
+	)
+}
+
+// GetFilteredEvents возвращает события пользователя, пересекающиеся с диапазоном
+// [start, end) и подходящие filter, отсортированные по началу.
+func (er *EventsRepository) GetFilteredEvents(
+	userID models.UserID,
+	start, end time.Time,
+	filter models.EventFilter,
+) ([]models.Event, error) {
+	q := `SELECT ` + eventColumns + ` FROM events WHERE ` + rangeFilter
+	args := rangeArgs(userID, start, end)
+
+	if filter.Category != "" {
+		q += ` AND category = ?`
+		args = append(args, filter.Category)
+	}
+	for _, tag := range filter.Tags {
+		q += ` AND id IN (SELECT event_id FROM event_tags WHERE user_id = ? AND tag = ?)`
+		args = append(args, userID, tag)
+	}
+
+	return query(er.conn(), q+` ORDER BY date`, args...)
+}
+
+// GetRecurringEvents возвращает все серии повторяющихся событий пользователя.
+func (er *EventsRepository) GetRecurringEvents(userID models.UserID) ([]models.Event, error) {
+	return query(er.conn(),
+		`SELECT `+eventColumns+` FROM events
+		WHERE user_id = ? AND rrule != ''
+		ORDER BY date`,
+		userID,
+	)
+}
+
+// Users возвращает пользователей, у которых есть хотя бы одно событие.
+func (er *EventsRepository) Users() ([]models.UserID, error) {
+	rows, err := er.conn().Query(`SELECT DISTINCT user_id FROM events ORDER BY user_id`)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	defer rows.Close()
+
+	var result []models.UserID
+	for rows.Next() {
+		var userID models.UserID
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		result = append(result, userID)
+	}
+
+	return result, rows.Err()
+}
+
+// EventCounts возвращает количество событий каждого пользователя, у которого они есть.
+func (er *EventsRepository) EventCounts() (map[models.UserID]int, error) {
+	rows, err := er.conn().Query(`SELECT user_id, COUNT(*) FROM events GROUP BY user_id`)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	defer rows.Close()
+
+	result := make(map[models.UserID]int)
+	for rows.Next() {
+		var (
+			userID models.UserID
+			n      int
+		)
+		if err := rows.Scan(&userID, &n); err != nil {
+			return nil, err
+		}
+		result[userID] = n
+	}
+
+	return result, rows.Err()
+}
+
+// Ping проверяет соединение с базой.
+func (er *EventsRepository) Ping(ctx context.Context) error {
+	return er.db.PingContext(ctx)
+}
+
+// MarkReminder отмечает напоминание key как отправленное. Вернет false,
+// если напоминание уже было отмечено.
+func (er *EventsRepository) MarkReminder(key string, at time.Time) (bool, error) {
+	res, err := er.conn().Exec(
+		`INSERT INTO reminders (key, at) VALUES (?, ?) ON CONFLICT (key) DO NOTHING`,
+		key, at.UnixNano())
+	if err != nil {
+		return false, mapError(err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return n > 0, nil
+}
+
+// PruneReminders удаляет отметки напоминаний о событиях, начавшихся раньше before.
+func (er *EventsRepository) PruneReminders(before time.Time) error {
+	_, err := er.conn().Exec(`DELETE FROM reminders WHERE at < ?`, before.UnixNano())
+	return mapError(err)
+}
+
+// replace записывает все поля существующего события, увеличивает его версию
+// и обновляет индексы приглашений и текста.
+func replace(tx *sql.Tx, userID models.UserID, event models.Event) error {
+	exdates, overrides, attendees, err := encodeLists(&event)
+	if err != nil {
+		return err
+	}
+	tags, attributes, err := encodeMetadata(&event)
+	if err != nil {
+		return err
+	}
+
+	res, err := tx.Exec(
+		`UPDATE events SET date = ?, end_date = ?, timezone = ?,
+			event = ?, rrule = ?, exdates = ?, overrides = ?, attendees = ?, version = version + 1,
+			location = ?, description = ?, category = ?, color = ?, tags = ?, attributes = ?
+		WHERE user_id = ? AND id = ?`,
+		event.Date.UnixNano(), unixNano(event.End), event.TimeZone,
+		event.Event, event.RRule, exdates, overrides, attendees,
+		event.Place, event.Description, event.Category, event.Color, tags, attributes,
+		userID, event.ID,
+	)
+	if err != nil {
+		return mapError(err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return repository.ErrNotFound
+	}
+
+	if err := trackDuration(tx, userID, event); err != nil {
+		return err
+	}
+	if err := indexAttendees(tx, userID, event.ID, event.Attendees); err != nil {
+		return err
+	}
+	if err := indexTags(tx, userID, event.ID, event.Tags); err != nil {
+		return err
+	}
+
+	return indexText(tx, userID, event.ID, event.Event)
+}
+
+// trackDuration запоминает длительность события, если она больше
+// наибольшей известной длительности событий пользователя.
+func trackDuration(tx *sql.Tx, userID models.UserID, event models.Event) error {
+	d := unixNano(event.End) - event.Date.UnixNano()
+	if d <= 0 {
+		return nil
+	}
+
+	_, err := tx.Exec(
+		`INSERT INTO user_durations (user_id, max_duration) VALUES (?, ?)
+		ON CONFLICT (user_id) DO UPDATE SET max_duration = MAX(max_duration, excluded.max_duration)`,
+		userID, d)
+	return mapError(err)
+}
+
+// indexAttendees перезаписывает приглашения участников события.
+func indexAttendees(tx *sql.Tx, owner models.UserID, eventID models.EventID, attendees []models.Attendee) error {
+	_, err := tx.Exec(`DELETE FROM invitations WHERE owner_id = ? AND event_id = ?`, owner, eventID)
+	if err != nil {
+		return mapError(err)
+	}
+
+	for _, a := range attendees {
+		_, err := tx.Exec(
+			`INSERT INTO invitations (user_id, owner_id, event_id) VALUES (?, ?, ?)`,
+			a.UserID, owner, eventID)
+		if err != nil {
+			return mapError(err)
+		}
+	}
+
+	return nil
+}
+
+// indexTags перезаписывает метки события в индексе меток.
+func indexTags(tx *sql.Tx, userID models.UserID, eventID models.EventID, tags []string) error {
+	_, err := tx.Exec(`DELETE FROM event_tags WHERE user_id = ? AND event_id = ?`, userID, eventID)
+	if err != nil {
+		return mapError(err)
+	}
+
+	for _, tag := range tags {
+		_, err := tx.Exec(
+			`INSERT INTO event_tags (user_id, tag, event_id) VALUES (?, ?, ?)`,
+			userID, tag, eventID)
+		if err != nil {
+			return mapError(err)
+		}
+	}
+
+	return nil
+}
+
+// ownerScanner читает перед колонками события владельца события.
+type ownerScanner struct {
+	scanner
+	owner *models.UserID
+}
+
+func (s ownerScanner) Scan(dest ...any) error {
+	return s.scanner.Scan(append([]any{s.owner}, dest...)...)
+}
+
+// querier - общее подмножество *sql.DB и *sql.Tx.
+type querier interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func get(q querier, userID models.UserID, eventID models.EventID) (*models.Event, error) {
+	row := q.QueryRow(
+		`SELECT `+eventColumns+` FROM events WHERE user_id = ? AND id = ?`,
+		userID, eventID,
+	)
+
+	event, err := scanEvent(row)
+	if err != nil {
+		return nil, mapError(err)
+	}
+
+	return event, nil
+}
+
+func query(q querier, query string, args ...any) ([]models.Event, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []models.Event{}
+	for rows.Next() {
+		event, err := scanEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *event)
+	}
+
+	return result, rows.Err()
+}
+
+func scanEvent(s scanner) (*models.Event, error) {
+	var (
+		event                         models.Event
+		date, end                     int64
+		exdates, overrides, attendees string
+		tags, attributes              string
+	)
+
+	err := s.Scan(&event.ID, &date, &end, &event.TimeZone,
+		&event.Event, &event.RRule, &exdates, &overrides, &attendees, &event.Version,
+		&event.Place, &event.Description, &event.Category, &event.Color, &tags, &attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	event.Date = time.Unix(0, date).UTC()
+	if end != 0 {
+		event.End = time.Unix(0, end).UTC()
+	}
+	if event.TimeZone != "" {
+		loc := event.Location()
+		event.Date = event.Date.In(loc)
+		if !event.End.IsZero() {
+			event.End = event.End.In(loc)
+		}
+	}
+
+	if exdates != "" {
+		if err := json.Unmarshal([]byte(exdates), &event.ExDates); err != nil {
+			return nil, err
+		}
+	}
+	if overrides != "" {
+		if err := json.Unmarshal([]byte(overrides), &event.Overrides); err != nil {
+			return nil, err
+		}
+	}
+	if attendees != "" {
+		if err := json.Unmarshal([]byte(attendees), &event.Attendees); err != nil {
+			return nil, err
+		}
+	}
+	if tags != "" {
+		if err := json.Unmarshal([]byte(tags), &event.Tags); err != nil {
+			return nil, err
+		}
+	}
+	if attributes != "" {
+		if err := json.Unmarshal([]byte(attributes), &event.Attributes); err != nil {
+			return nil, err
+		}
+	}
+
+	return &event, nil
+}
+
+// unixNano возвращает время в наносекундах, а для нулевого времени - 0.
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+// encodeLists сериализует списки события (исключения и изменения серии, участников) в JSON.
+// Для пустых списков возвращает пустые строки.
+func encodeLists(event *models.Event) (exdates, overrides, attendees string, err error) {
+	if exdates, err = encodeList(event.ExDates); err != nil {
+		return "", "", "", err
+	}
+	if overrides, err = encodeList(event.Overrides); err != nil {
+		return "", "", "", err
+	}
+	if attendees, err = encodeList(event.Attendees); err != nil {
+		return "", "", "", err
+	}
+
+	return exdates, overrides, attendees, nil
+}
+
+// encodeMetadata сериализует метки и атрибуты события в JSON.
+// Для пустых меток и атрибутов возвращает пустые строки.
+func encodeMetadata(event *models.Event) (tags, attributes string, err error) {
+	if tags, err = encodeList(event.Tags); err != nil {
+		return "", "", err
+	}
+	if len(event.Attributes) == 0 {
+		return tags, "", nil
+	}
+
+	data, err := json.Marshal(event.Attributes)
+	if err != nil {
+		return "", "", err
+	}
+
+	return tags, string(data), nil
+}
+
+func encodeList[T any](list []T) (string, error) {
+	if len(list) == 0 {
+		return "", nil
+	}
+
+	data, err := json.Marshal(list)
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+// mapError переводит ошибки SQLite в ошибки пакета repository.
+func mapError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return repository.ErrNotFound
+	}
+
+	var sqliteErr *sqlitedriver.Error
+	if errors.As(err, &sqliteErr) {
+		switch sqliteErr.Code() {
+		case sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY, sqlite3.SQLITE_CONSTRAINT_UNIQUE:
+			return repository.ErrAlreadyExist
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,703 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"l2.18/internal/repository"
+	"l2.18/pkg/models"
+	"l2.18/pkg/search"
+)
+
+func newTestRepository(t *testing.T) *EventsRepository {
+	t.Helper()
+
+	repo, err := NewEventsRepository(filepath.Join(t.TempDir(), "calendar.db"))
+	if err != nil {
+		t.Fatalf("open repository: %v", err)
+	}
+	t.Cleanup(func() { repo.Close() })
+
+	return repo
+}
+
+func TestPut(t *testing.T) {
+	userID := models.UserID("user1")
+	event := models.Event{ID: "1", Date: time.Now(), Event: "test_event"}
+
+	testCases := []struct {
+		name     string
+		setup    func(*EventsRepository)
+		expected error
+	}{
+		{
+			name:     "success - new event",
+			setup:    func(r *EventsRepository) {},
+			expected: nil,
+		},
+		{
+			name: "failure - already exists",
+			setup: func(r *EventsRepository) {
+				_ = r.Put(userID, event)
+			},
+			expected: repository.ErrAlreadyExist,
+		},
+		{
+			name: "success - different user",
+			setup: func(r *EventsRepository) {
+				_ = r.Put(models.UserID("user2"), event)
+			},
+			expected: nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := newTestRepository(t)
+			tc.setup(repo)
+			err := repo.Put(userID, event)
+			if !errors.Is(err, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, err)
+			}
+		})
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	now := time.Now()
+	userID := models.UserID("user1")
+	event := models.Event{ID: "1", Date: now, Event: "test_event"}
+
+	testCases := []struct {
+		name     string
+		input    models.Event
+		expected error
+		want     models.Event
+	}{
+		{
+			name:     "success - only description",
+			input:    models.Event{ID: "1", Event: "updated"},
+			expected: nil,
+			want:     models.Event{ID: "1", Date: now, Event: "updated"},
+		},
+		{
+			name:     "success - only date",
+			input:    models.Event{ID: "1", Date: now.Add(time.Hour)},
+			expected: nil,
+			want:     models.Event{ID: "1", Date: now.Add(time.Hour), Event: "test_event"},
+		},
+		{
+			name:     "failure - not found",
+			input:    models.Event{ID: "2", Event: "updated"},
+			expected: repository.ErrNotFound,
+			want:     event,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := newTestRepository(t)
+			_ = repo.Put(userID, event)
+
+			err := repo.Update(userID, tc.input)
+			if !errors.Is(err, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, err)
+			}
+
+			got, err := repo.Get(userID, "1")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !got.Date.Equal(tc.want.Date) {
+				t.Errorf("Date mismatch: got %v, want %v", got.Date, tc.want.Date)
+			}
+			if got.Event != tc.want.Event {
+				t.Errorf("Event mismatch: got %q, want %q", got.Event, tc.want.Event)
+			}
+		})
+	}
+}
+
+func TestReplace(t *testing.T) {
+	now := time.Now()
+	userID := models.UserID("user1")
+
+	repo := newTestRepository(t)
+	_ = repo.Put(userID, models.Event{ID: "1", Date: now, Event: "series", RRule: "FREQ=DAILY"})
+
+	err := repo.Replace(userID, models.Event{ID: "1", Date: now.Add(time.Hour), Event: "single"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, err := repo.Get(userID, "1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Event != "single" || got.RRule != "" || !got.Date.Equal(now.Add(time.Hour)) {
+		t.Errorf("expected event to be replaced, got %+v", got)
+	}
+
+	err = repo.Replace(userID, models.Event{ID: "2", Date: now})
+	if !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("expected %v, got %v", repository.ErrNotFound, err)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	userID := models.UserID("user1")
+	event := models.Event{ID: "1", Date: time.Now(), Event: "test_event"}
+
+	repo := newTestRepository(t)
+	_ = repo.Put(userID, event)
+
+	if err := repo.Delete(userID, event.ID, 0); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := repo.Get(userID, event.ID); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("expected %v, got %v", repository.ErrNotFound, err)
+	}
+
+	if err := repo.Delete(userID, event.ID, 0); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("expected %v, got %v", repository.ErrNotFound, err)
+	}
+}
+
+func TestGetEventsByDateRange(t *testing.T) {
+	day := time.Date(2025, time.February, 15, 0, 0, 0, 0, time.UTC)
+	userID := models.UserID("user1")
+
+	repo := newTestRepository(t)
+	_ = repo.Put(userID, models.Event{ID: "1", Date: day.Add(5 * time.Hour), Event: "event1"})
+	_ = repo.Put(userID, models.Event{ID: "2", Date: day, Event: "at_start"})
+	_ = repo.Put(userID, models.Event{ID: "3", Date: day.AddDate(0, 0, 1), Event: "at_end"})
+	_ = repo.Put(models.UserID("user2"), models.Event{ID: "4", Date: day.Add(time.Hour), Event: "other"})
+	_ = repo.Put(userID, models.Event{
+		ID: "5", Date: day.Add(-time.Hour), End: day.Add(time.Hour), Event: "overnight"})
+	_ = repo.Put(userID, models.Event{
+		ID: "6", Date: day.Add(-time.Hour), End: day, Event: "finished"})
+
+	got, err := repo.GetEventsByDateRange(userID, day, day.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []models.EventID{"5", "2", "1"}
+	if len(got) != len(expected) {
+		t.Fatalf("expected %d events, got %d", len(expected), len(got))
+	}
+	for i, id := range expected {
+		if got[i].ID != id {
+			t.Errorf("event[%d].ID: got %v, want %v", i, got[i].ID, id)
+		}
+	}
+}
+
+func TestGetEventsByDateRangeLongEvents(t *testing.T) {
+	day := time.Date(2025, time.February, 15, 0, 0, 0, 0, time.UTC)
+	userID := models.UserID("user1")
+
+	repo := newTestRepository(t)
+	_ = repo.Put(userID, models.Event{ID: "1", Date: day, End: day.Add(time.Hour), Event: "short"})
+	_ = repo.Put(userID, models.Event{ID: "2", Date: day, End: day.Add(2 * time.Hour), Event: "trip"})
+	// Событие становится длиннее после изменения.
+	_ = repo.Update(userID, models.Event{ID: "2", End: day.AddDate(0, 0, 10)})
+
+	got, err := repo.GetEventsByDateRange(userID, day.AddDate(0, 0, 5), day.AddDate(0, 0, 6))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 1 || got[0].ID != "2" {
+		t.Errorf("expected long event 2, got %v", got)
+	}
+
+	// Индекс (user_id, date) должен ограничивать выборку и снизу.
+	rows, err := repo.db.Query(`EXPLAIN QUERY PLAN SELECT id FROM events WHERE `+rangeFilter,
+		rangeArgs(userID, day, day.AddDate(0, 0, 1))...)
+	if err != nil {
+		t.Fatalf("explain: %v", err)
+	}
+	defer rows.Close()
+	var plan []string
+	for rows.Next() {
+		var id, parent, unused int
+		var detail string
+		if err := rows.Scan(&id, &parent, &unused, &detail); err != nil {
+			t.Fatalf("scan plan: %v", err)
+		}
+		plan = append(plan, detail)
+	}
+	if !slices.ContainsFunc(plan, func(detail string) bool {
+		return strings.Contains(detail, "events_user_date (user_id=? AND date>? AND date<?)")
+	}) {
+		t.Errorf("expected bounded range on events_user_date, got plan %q", plan)
+	}
+}
+
+func TestMigrateTwice(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "calendar.db")
+
+	repo, err := NewEventsRepository(path)
+	if err != nil {
+		t.Fatalf("open repository: %v", err)
+	}
+	_ = repo.Put("user1", models.Event{ID: "1", Date: time.Now(), Event: "event"})
+	_ = repo.Close()
+
+	repo, err = NewEventsRepository(path)
+	if err != nil {
+		t.Fatalf("reopen repository: %v", err)
+	}
+	defer repo.Close()
+
+	if _, err := repo.Get("user1", "1"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestUsers(t *testing.T) {
+	repo := newTestRepository(t)
+	_ = repo.Put("user2", models.Event{ID: "1", Date: time.Now(), Event: "event"})
+	_ = repo.Put("user1", models.Event{ID: "1", Date: time.Now(), Event: "event"})
+	_ = repo.Put("user1", models.Event{ID: "2", Date: time.Now(), Event: "event"})
+
+	got, err := repo.Users()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 2 || got[0] != "user1" || got[1] != "user2" {
+		t.Errorf("unexpected users %v", got)
+	}
+}
+
+func TestMarkReminder(t *testing.T) {
+	now := time.Now()
+	repo := newTestRepository(t)
+
+	if marked, err := repo.MarkReminder("a", now); err != nil || !marked {
+		t.Fatalf("expected first mark to succeed, got %v, %v", marked, err)
+	}
+	if marked, err := repo.MarkReminder("a", now); err != nil || marked {
+		t.Fatalf("expected second mark to be rejected, got %v, %v", marked, err)
+	}
+
+	if err := repo.PruneReminders(now.Add(time.Minute)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if marked, err := repo.MarkReminder("a", now); err != nil || !marked {
+		t.Errorf("expected pruned reminder to be marked again, got %v, %v", marked, err)
+	}
+}
+
+func TestInvitations(t *testing.T) {
+	now := time.Now()
+	repo := newTestRepository(t)
+
+	_ = repo.Put("owner", models.Event{ID: "1", Date: now, Attendees: []models.Attendee{
+		{UserID: "alice", Status: models.RSVPNeedsAction},
+		{UserID: "bob", Status: models.RSVPNeedsAction},
+	}})
+	_ = repo.Put("owner", models.Event{ID: "2", Date: now.Add(time.Hour), Attendees: []models.Attendee{
+		{UserID: "alice", Status: models.RSVPNeedsAction},
+	}})
+
+	err := repo.UpdateAttendee("owner", "1", models.Attendee{UserID: "alice", Status: models.RSVPDeclined})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err = repo.UpdateAttendee("owner", "1", models.Attendee{UserID: "carol", Status: models.RSVPDeclined})
+	if !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("expected %v, got %v", repository.ErrNotFound, err)
+	}
+
+	got, err := repo.GetInvitedEvents("alice")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 2 || got[0].ID != "1" || got[0].Owner != "owner" || got[1].ID != "2" {
+		t.Fatalf("unexpected events %+v", got)
+	}
+	if a, _ := got[0].Attendee("alice"); a.Status != models.RSVPDeclined {
+		t.Errorf("expected declined, got %q", a.Status)
+	}
+
+	_ = repo.Update("owner", models.Event{ID: "1", Attendees: []models.Attendee{
+		{UserID: "alice", Status: models.RSVPDeclined},
+	}})
+	if got, _ := repo.GetInvitedEvents("bob"); len(got) != 0 {
+		t.Errorf("bob: unexpected events %+v", got)
+	}
+
+	_ = repo.Delete("owner", "1", 0)
+	_ = repo.Replace("owner", models.Event{ID: "2", Date: now})
+	if got, _ := repo.GetInvitedEvents("alice"); len(got) != 0 {
+		t.Errorf("alice: unexpected events %+v", got)
+	}
+}
+
+func searchIDs(t *testing.T, repo *EventsRepository, userID models.UserID, query string) []models.EventID {
+	t.Helper()
+
+	q, err := search.Parse(query)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	events, err := repo.Search(userID, q)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ids := make([]models.EventID, len(events))
+	for i, e := range events {
+		ids[i] = e.ID
+	}
+	return ids
+}
+
+func TestSearch(t *testing.T) {
+	now := time.Now()
+	repo := newTestRepository(t)
+
+	_ = repo.Put("1", models.Event{ID: "b", Date: now.Add(time.Hour), Event: "Стоматолог"})
+	_ = repo.Put("1", models.Event{ID: "a", Date: now, Event: "Запись к СТОМАТОЛОГУ"})
+	_ = repo.Put("1", models.Event{ID: "c", Date: now, Event: "Café"})
+	_ = repo.Put("2", models.Event{ID: "d", Date: now, Event: "стоматолог"})
+
+	testCases := []struct {
+		query    string
+		expected []models.EventID
+	}{
+		{query: "стомат*", expected: []models.EventID{"a", "b"}},
+		{query: `"к стоматологу"`, expected: []models.EventID{"a"}},
+		{query: `"запись стоматологу"`, expected: []models.EventID{}},
+		{query: "CAFE", expected: []models.EventID{"c"}},
+	}
+
+	for _, tc := range testCases {
+		if ids := searchIDs(t, repo, "1", tc.query); !slices.Equal(ids, tc.expected) {
+			t.Errorf("%s: expected %v, got %v", tc.query, tc.expected, ids)
+		}
+	}
+
+	_ = repo.Update("1", models.Event{ID: "c", Event: "Стоматолог, повторно"})
+	_ = repo.Replace("1", models.Event{ID: "b", Date: now, Event: "Отпуск"})
+	_ = repo.Delete("1", "a", 0)
+
+	if ids := searchIDs(t, repo, "1", "стомат*"); !slices.Equal(ids, []models.EventID{"c"}) {
+		t.Errorf("expected [c] after changes, got %v", ids)
+	}
+}
+
+func TestSearchIndexesExistingEvents(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "calendar.db")
+
+	// База в состоянии до появления полнотекстового индекса.
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	for _, m := range migrations[:14] {
+		if _, err := db.Exec(m); err != nil {
+			t.Fatalf("migrate: %v", err)
+		}
+	}
+	_, err = db.Exec(`INSERT INTO events (user_id, id, date, event) VALUES ('1', '1', 0, 'Dentist appointment'); PRAGMA user_version = 14`)
+	if err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	_ = db.Close()
+
+	repo, err := NewEventsRepository(path)
+	if err != nil {
+		t.Fatalf("open repository: %v", err)
+	}
+	defer repo.Close()
+
+	if ids := searchIDs(t, repo, "1", "dent*"); !slices.Equal(ids, []models.EventID{"1"}) {
+		t.Errorf("expected existing event to be indexed, got %v", ids)
+	}
+}
+
+func TestVersion(t *testing.T) {
+	userID := models.UserID("user1")
+	now := time.Now()
+	repo := newTestRepository(t)
+
+	_ = repo.Put(userID, models.Event{ID: "1", Date: now, Event: "first"})
+	if got, _ := repo.Get(userID, "1"); got.Version != 1 {
+		t.Fatalf("expected version 1, got %d", got.Version)
+	}
+
+	if err := repo.Update(userID, models.Event{ID: "1", Event: "second", Version: 1}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := repo.Replace(userID, models.Event{ID: "1", Date: now, Event: "stale", Version: 1}); !errors.Is(err, repository.ErrVersionMismatch) {
+		t.Errorf("expected %v, got %v", repository.ErrVersionMismatch, err)
+	}
+
+	got, _ := repo.Get(userID, "1")
+	if got.Version != 2 || got.Event != "second" {
+		t.Errorf("expected second event in version 2, got %+v", got)
+	}
+
+	if err := repo.Delete(userID, "1", 1); !errors.Is(err, repository.ErrVersionMismatch) {
+		t.Errorf("expected %v, got %v", repository.ErrVersionMismatch, err)
+	}
+	if err := repo.Delete(userID, "1", 2); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestHistory(t *testing.T) {
+	now := time.Now()
+	repo := newTestRepository(t)
+
+	before := models.Event{ID: "a", Date: now.UTC(), Event: "first", Version: 1}
+	after := models.Event{ID: "a", Date: now.UTC(), Event: "second", Version: 2}
+
+	_ = repo.AddHistory("1", models.HistoryEntry{EventID: "a", Type: models.ChangeCreated, Actor: "1", At: now.Add(-time.Hour), After: &before})
+	_ = repo.AddHistory("1", models.HistoryEntry{EventID: "a", Type: models.ChangeUpdated, Actor: "2", At: now, Before: &before, After: &after})
+	_ = repo.AddHistory("2", models.HistoryEntry{EventID: "a", Type: models.ChangeCreated, At: now})
+
+	entries, err := repo.GetHistory("1", "a")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Revision != 1 || entries[1].Revision != 2 {
+		t.Fatalf("unexpected history %+v", entries)
+	}
+	if e := entries[1]; e.Actor != "2" || e.Before == nil || e.Before.Event != "first" || e.After == nil || e.After.Event != "second" || !e.At.Equal(now) {
+		t.Errorf("unexpected entry %+v", e)
+	}
+	if entries[0].Before != nil {
+		t.Errorf("expected no state before creation, got %+v", entries[0].Before)
+	}
+
+	if err := repo.AddHistory("1", models.HistoryEntry{EventID: "a", Revision: 2, At: now}); !errors.Is(err, repository.ErrAlreadyExist) {
+		t.Errorf("expected %v, got %v", repository.ErrAlreadyExist, err)
+	}
+
+	_ = repo.PruneHistory(now.Add(-time.Minute))
+
+	entries, _ = repo.GetHistory("1", "a")
+	if len(entries) != 1 || entries[0].Revision != 2 {
+		t.Errorf("expected only revision 2 after prune, got %+v", entries)
+	}
+}
+
+func TestNumbersAfterDelete(t *testing.T) {
+	now := time.Now()
+	repo := newTestRepository(t)
+
+	_ = repo.Put("1", models.Event{ID: "a", Date: now, Event: "first"})
+	_ = repo.Update("1", models.Event{ID: "a", Event: "second"})
+	_ = repo.AddHistory("1", models.HistoryEntry{EventID: "a", Type: models.ChangeCreated, At: now.Add(-time.Hour)})
+	_ = repo.AddHistory("1", models.HistoryEntry{EventID: "a", Type: models.ChangeUpdated, At: now.Add(-time.Hour)})
+	_ = repo.Delete("1", "a", 0)
+	if err := repo.PruneHistory(now); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := repo.Put("1", models.Event{ID: "a", Date: now, Event: "restored"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if event, _ := repo.Get("1", "a"); event == nil || event.Version != 3 || event.Event != "restored" {
+		t.Errorf("expected restored event with version 3, got %+v", event)
+	}
+	if err := repo.Put("1", models.Event{ID: "b", Date: now, Event: "new"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if event, _ := repo.Get("1", "b"); event == nil || event.Version != 1 {
+		t.Errorf("expected new event with version 1, got %+v", event)
+	}
+
+	_ = repo.AddHistory("1", models.HistoryEntry{EventID: "a", Type: models.ChangeCreated, At: now})
+	if entries, _ := repo.GetHistory("1", "a"); len(entries) != 1 || entries[0].Revision != 3 {
+		t.Errorf("expected revision 3 after prune, got %+v", entries)
+	}
+}
+
+func TestEventCounts(t *testing.T) {
+	now := time.Now()
+	repo := newTestRepository(t)
+
+	_ = repo.Put("user1", models.Event{ID: "1", Date: now})
+	_ = repo.Put("user1", models.Event{ID: "2", Date: now, RRule: "FREQ=DAILY"})
+	_ = repo.Put("user2", models.Event{ID: "1", Date: now})
+	_ = repo.Put("user3", models.Event{ID: "1", Date: now})
+	_ = repo.Delete("user3", "1", 0)
+
+	counts, err := repo.EventCounts()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(counts) != 2 || counts["user1"] != 2 || counts["user2"] != 1 {
+		t.Errorf("unexpected counts %v", counts)
+	}
+
+	if err := repo.Ping(context.Background()); err != nil {
+		t.Errorf("unexpected ping error: %v", err)
+	}
+}
+
+func TestTransaction(t *testing.T) {
+	userID := models.UserID("user1")
+	now := time.Now()
+	repo := newTestRepository(t)
+	errStop := errors.New("stop")
+
+	_ = repo.Put(userID, models.Event{ID: "a", Date: now, Event: "meeting"})
+
+	err := repo.Transaction(func(tx repository.Tx) error {
+		_ = tx.Put(userID, models.Event{ID: "b", Date: now, Event: "lunch"})
+		_ = tx.Update(userID, models.Event{ID: "a", Event: "renamed"})
+		_ = tx.AddHistory(userID, models.HistoryEntry{EventID: "b", Type: models.ChangeCreated, At: now})
+		return errStop
+	})
+	if !errors.Is(err, errStop) {
+		t.Fatalf("expected %v, got %v", errStop, err)
+	}
+	if _, err := repo.Get(userID, "b"); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("expected rolled back event to be missing, got %v", err)
+	}
+	if got, _ := repo.Get(userID, "a"); got.Event != "meeting" || got.Version != 1 {
+		t.Errorf("expected unchanged event, got %+v", got)
+	}
+	if entries, _ := repo.GetHistory(userID, "b"); len(entries) != 0 {
+		t.Errorf("expected no history, got %+v", entries)
+	}
+
+	err = repo.Transaction(func(tx repository.Tx) error {
+		if err := tx.Put(userID, models.Event{ID: "b", Date: now, Event: "lunch"}); err != nil {
+			return err
+		}
+		return tx.Delete(userID, "a", 1)
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := repo.Get(userID, "a"); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("expected deleted event, got %v", err)
+	}
+	if ids := searchIDs(t, repo, userID, "lunch"); !slices.Equal(ids, []models.EventID{"b"}) {
+		t.Errorf("expected committed event to be indexed, got %v", ids)
+	}
+}
+
+func TestIdempotencyRecords(t *testing.T) {
+	now := time.Now()
+	repo := newTestRepository(t)
+
+	stored := models.IdempotencyRecord{
+		UserID: "1", Key: "a", Fingerprint: "f", Status: 201,
+		Header: map[string]string{"Location": "/v2/users/1/events/x"}, Body: []byte(`{"id":"x"}`),
+		CreatedAt: now,
+	}
+	_ = repo.PutIdempotencyRecord(models.IdempotencyRecord{UserID: "1", Key: "a", Status: 200, CreatedAt: now.Add(-time.Hour)})
+	_ = repo.PutIdempotencyRecord(stored)
+	_ = repo.PutIdempotencyRecord(models.IdempotencyRecord{UserID: "2", Key: "a", Status: 200, CreatedAt: now.Add(-time.Hour)})
+
+	record, err := repo.GetIdempotencyRecord("1", "a")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if record.Status != 201 || record.Fingerprint != "f" || string(record.Body) != `{"id":"x"}` ||
+		record.Header["Location"] != "/v2/users/1/events/x" || !record.CreatedAt.Equal(now) {
+		t.Errorf("unexpected record %+v", record)
+	}
+
+	_ = repo.PruneIdempotencyRecords(now.Add(-time.Minute))
+
+	if _, err := repo.GetIdempotencyRecord("2", "a"); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("expected %v after prune, got %v", repository.ErrNotFound, err)
+	}
+	if _, err := repo.GetIdempotencyRecord("1", "a"); err != nil {
+		t.Errorf("expected fresh record to be kept, got %v", err)
+	}
+}
+
+func TestMetadata(t *testing.T) {
+	day := time.Date(2025, time.February, 15, 0, 0, 0, 0, time.UTC)
+	userID := models.UserID("user1")
+
+	repo := newTestRepository(t)
+	event := models.Event{
+		ID: "1", Date: day, Event: "review", Place: "room 1", Description: "quarterly",
+		Category: "work", Color: "#ff8800", Tags: []string{"team", "urgent"},
+		Attributes: map[string]string{"jira": "CAL-1"},
+	}
+	if err := repo.Put(userID, event); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, err := repo.Get(userID, "1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Place != event.Place || got.Description != event.Description || got.Category != event.Category ||
+		got.Color != event.Color || !slices.Equal(got.Tags, event.Tags) || got.Attributes["jira"] != "CAL-1" {
+		t.Errorf("metadata mismatch: got %+v", got)
+	}
+
+	if err := repo.Update(userID, models.Event{ID: "1", Tags: []string{"done"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, _ := repo.Get(userID, "1"); got.Category != "work" || !slices.Equal(got.Tags, []string{"done"}) {
+		t.Errorf("expected tags to be replaced, got %+v", got)
+	}
+}
+
+func TestGetFilteredEvents(t *testing.T) {
+	day := time.Date(2025, time.February, 15, 0, 0, 0, 0, time.UTC)
+	end := day.AddDate(0, 0, 1)
+	userID := models.UserID("user1")
+
+	repo := newTestRepository(t)
+	_ = repo.Put(userID, models.Event{ID: "a", Date: day.Add(2 * time.Hour), Tags: []string{"team", "urgent"}, Category: "work"})
+	_ = repo.Put(userID, models.Event{ID: "b", Date: day.Add(time.Hour), Tags: []string{"team"}})
+	_ = repo.Put(userID, models.Event{ID: "c", Date: day.AddDate(0, 0, 2), Tags: []string{"team"}})
+	_ = repo.Put(userID, models.Event{ID: "d", Date: day, Category: "work"})
+	_ = repo.Put("user2", models.Event{ID: "e", Date: day, Tags: []string{"team"}})
+
+	testCases := []struct {
+		name     string
+		filter   models.EventFilter
+		expected []models.EventID
+	}{
+		{name: "tag", filter: models.EventFilter{Tags: []string{"team"}}, expected: []models.EventID{"b", "a"}},
+		{name: "all tags", filter: models.EventFilter{Tags: []string{"team", "urgent"}}, expected: []models.EventID{"a"}},
+		{name: "category", filter: models.EventFilter{Category: "work"}, expected: []models.EventID{"d", "a"}},
+		{name: "tag and category", filter: models.EventFilter{Tags: []string{"team"}, Category: "work"}, expected: []models.EventID{"a"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			events, err := repo.GetFilteredEvents(userID, day, end, tc.filter)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			ids := make([]models.EventID, len(events))
+			for i, e := range events {
+				ids[i] = e.ID
+			}
+			if !slices.Equal(ids, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, ids)
+			}
+		})
+	}
+
+	_ = repo.Delete(userID, "a", 0)
+	events, _ := repo.GetFilteredEvents(userID, day, end, models.EventFilter{Tags: []string{"urgent"}})
+	if len(events) != 0 {
+		t.Errorf("expected deleted event to leave tag index, got %+v", events)
+	}
+}
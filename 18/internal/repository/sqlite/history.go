@@ -0,0 +1,114 @@
+package sqlite
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"l2.18/pkg/models"
+)
+
+// AddHistory сохраняет запись истории события entry.EventID пользователя userID.
+// Запись без номера получает следующий номер в истории события, в том числе
+// после удаленных записей.
+func (er *EventsRepository) AddHistory(userID models.UserID, entry models.HistoryEntry) error {
+	before, err := encodeSnapshot(entry.Before)
+	if err != nil {
+		return err
+	}
+	after, err := encodeSnapshot(entry.After)
+	if err != nil {
+		return err
+	}
+
+	revision := any(entry.Revision)
+	if entry.Revision == 0 {
+		revision = nil
+	}
+
+	_, err = er.conn().Exec(
+		`INSERT INTO history (user_id, event_id, revision, type, actor, at, before, after)
+		VALUES (?1, ?2, COALESCE(?3, MAX(
+			(SELECT COALESCE(MAX(revision), 0) FROM history WHERE user_id = ?1 AND event_id = ?2),
+			(SELECT COALESCE(MAX(revision), 0) FROM event_numbers WHERE user_id = ?1 AND event_id = ?2)) + 1),
+			?4, ?5, ?6, ?7, ?8)`,
+		userID, entry.EventID, revision, entry.Type, entry.Actor, entry.At.UnixNano(), before, after,
+	)
+	return mapError(err)
+}
+
+// GetHistory возвращает записи истории события по возрастанию номера.
+func (er *EventsRepository) GetHistory(userID models.UserID, eventID models.EventID) ([]models.HistoryEntry, error) {
+	rows, err := er.conn().Query(
+		`SELECT revision, type, actor, at, before, after FROM history
+		WHERE user_id = ? AND event_id = ? ORDER BY revision`,
+		userID, eventID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []models.HistoryEntry
+	for rows.Next() {
+		entry := models.HistoryEntry{EventID: eventID}
+		var (
+			at            int64
+			before, after string
+		)
+		if err := rows.Scan(&entry.Revision, &entry.Type, &entry.Actor, &at, &before, &after); err != nil {
+			return nil, err
+		}
+		entry.At = time.Unix(0, at).UTC()
+
+		if entry.Before, err = decodeSnapshot(before); err != nil {
+			return nil, err
+		}
+		if entry.After, err = decodeSnapshot(after); err != nil {
+			return nil, err
+		}
+
+		result = append(result, entry)
+	}
+
+	return result, rows.Err()
+}
+
+// PruneHistory удаляет записи истории, сделанные раньше before. Номер последней
+// удаленной записи события сохраняется в event_numbers.
+func (er *EventsRepository) PruneHistory(before time.Time) error {
+	return er.write(func(tx *sql.Tx) error {
+		_, err := tx.Exec(
+			`INSERT INTO event_numbers (user_id, event_id, revision)
+			SELECT user_id, event_id, MAX(revision) FROM history WHERE at < ? GROUP BY user_id, event_id
+			ON CONFLICT (user_id, event_id) DO UPDATE SET revision = MAX(revision, excluded.revision)`,
+			before.UnixNano())
+		if err != nil {
+			return mapError(err)
+		}
+
+		_, err = tx.Exec(`DELETE FROM history WHERE at < ?`, before.UnixNano())
+		return mapError(err)
+	})
+}
+
+func encodeSnapshot(event *models.Event) (string, error) {
+	if event == nil {
+		return "", nil
+	}
+
+	data, err := json.Marshal(event)
+	return string(data), err
+}
+
+func decodeSnapshot(s string) (*models.Event, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	var event models.Event
+	if err := json.Unmarshal([]byte(s), &event); err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
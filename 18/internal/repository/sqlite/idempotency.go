@@ -0,0 +1,69 @@
+package sqlite
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"l2.18/internal/repository"
+	"l2.18/pkg/models"
+)
+
+// PutIdempotencyRecord сохраняет ответ на запрос с ключом идемпотентности,
+// заменяя прежний ответ с тем же ключом.
+func (er *EventsRepository) PutIdempotencyRecord(record models.IdempotencyRecord) error {
+	header, err := json.Marshal(record.Header)
+	if err != nil {
+		return err
+	}
+	body := record.Body
+	if body == nil {
+		body = []byte{}
+	}
+
+	_, err = er.conn().Exec(
+		`INSERT INTO idempotency (user_id, key, fingerprint, status, header, body, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (user_id, key) DO UPDATE SET fingerprint = excluded.fingerprint,
+			status = excluded.status, header = excluded.header, body = excluded.body,
+			created_at = excluded.created_at`,
+		record.UserID, record.Key, record.Fingerprint, record.Status, string(header), body,
+		record.CreatedAt.UnixNano(),
+	)
+	return mapError(err)
+}
+
+// GetIdempotencyRecord возвращает ответ, сохраненный с ключом key пользователя userID.
+// Если ответа нет - вернет ошибку.
+func (er *EventsRepository) GetIdempotencyRecord(userID models.UserID, key string) (*models.IdempotencyRecord, error) {
+	record := models.IdempotencyRecord{UserID: userID, Key: key}
+	var (
+		header    string
+		createdAt int64
+	)
+
+	err := er.conn().QueryRow(
+		`SELECT fingerprint, status, header, body, created_at FROM idempotency
+		WHERE user_id = ? AND key = ?`,
+		userID, key,
+	).Scan(&record.Fingerprint, &record.Status, &header, &record.Body, &createdAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, repository.ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(header), &record.Header); err != nil {
+		return nil, err
+	}
+	record.CreatedAt = time.Unix(0, createdAt).UTC()
+
+	return &record, nil
+}
+
+// PruneIdempotencyRecords удаляет ответы, сохраненные раньше before.
+func (er *EventsRepository) PruneIdempotencyRecords(before time.Time) error {
+	_, err := er.conn().Exec(`DELETE FROM idempotency WHERE created_at < ?`, before.UnixNano())
+	return mapError(err)
+}
@@ -0,0 +1,153 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// migrations - упорядоченный список изменений схемы. Номер применённой
+// миграции хранится в PRAGMA user_version, поэтому новые миграции
+// добавляются только в конец списка.
+var migrations = []string{
+	`CREATE TABLE events (
+		user_id TEXT    NOT NULL,
+		id      TEXT    NOT NULL,
+		date    INTEGER NOT NULL,
+		event   TEXT    NOT NULL,
+		PRIMARY KEY (user_id, id)
+	)`,
+	`CREATE INDEX events_user_date ON events (user_id, date)`,
+	`ALTER TABLE events ADD COLUMN rrule TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE events ADD COLUMN exdates TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE events ADD COLUMN overrides TEXT NOT NULL DEFAULT ''`,
+	`CREATE INDEX events_user_recurring ON events (user_id) WHERE rrule != ''`,
+	`ALTER TABLE events ADD COLUMN end_date INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE events ADD COLUMN timezone TEXT NOT NULL DEFAULT ''`,
+	`CREATE INDEX events_user_end_date ON events (user_id, end_date)`,
+	`CREATE TABLE reminders (
+		key TEXT    NOT NULL PRIMARY KEY,
+		at  INTEGER NOT NULL
+	)`,
+	`CREATE INDEX reminders_at ON reminders (at)`,
+	`ALTER TABLE events ADD COLUMN attendees TEXT NOT NULL DEFAULT ''`,
+	`CREATE TABLE invitations (
+		user_id  TEXT NOT NULL,
+		owner_id TEXT NOT NULL,
+		event_id TEXT NOT NULL,
+		PRIMARY KEY (user_id, owner_id, event_id)
+	)`,
+	`CREATE INDEX invitations_event ON invitations (owner_id, event_id)`,
+	// search_docs связывает события со строками полнотекстового индекса:
+	// rowid events может измениться после VACUUM, а doc_id - нет.
+	`CREATE TABLE search_docs (
+		doc_id   INTEGER PRIMARY KEY,
+		user_id  TEXT NOT NULL,
+		event_id TEXT NOT NULL,
+		UNIQUE (user_id, event_id)
+	)`,
+	// Слова нормализуются пакетом search и разделяются пробелами,
+	// поэтому токенизатор ascii разбивает текст так же.
+	`CREATE VIRTUAL TABLE events_search USING fts5(text, tokenize = 'ascii')`,
+	`ALTER TABLE events ADD COLUMN version INTEGER NOT NULL DEFAULT 1`,
+	// Состояния события до и после изменения хранятся в JSON, пустая строка - нет состояния.
+	`CREATE TABLE history (
+		user_id  TEXT    NOT NULL,
+		event_id TEXT    NOT NULL,
+		revision INTEGER NOT NULL,
+		type     TEXT    NOT NULL,
+		actor    TEXT    NOT NULL,
+		at       INTEGER NOT NULL,
+		before   TEXT    NOT NULL,
+		after    TEXT    NOT NULL,
+		PRIMARY KEY (user_id, event_id, revision)
+	)`,
+	`CREATE INDEX history_at ON history (at)`,
+	// Заголовки ответа хранятся в JSON.
+	`CREATE TABLE idempotency (
+		user_id     TEXT    NOT NULL,
+		key         TEXT    NOT NULL,
+		fingerprint TEXT    NOT NULL,
+		status      INTEGER NOT NULL,
+		header      TEXT    NOT NULL,
+		body        BLOB    NOT NULL,
+		created_at  INTEGER NOT NULL,
+		PRIMARY KEY (user_id, key)
+	)`,
+	`CREATE INDEX idempotency_created_at ON idempotency (created_at)`,
+	`ALTER TABLE events ADD COLUMN location TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE events ADD COLUMN description TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE events ADD COLUMN category TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE events ADD COLUMN color TEXT NOT NULL DEFAULT ''`,
+	// Метки и атрибуты хранятся в JSON, пустая строка - их нет. Для отбора
+	// по меткам они дублируются в event_tags.
+	`ALTER TABLE events ADD COLUMN tags TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE events ADD COLUMN attributes TEXT NOT NULL DEFAULT ''`,
+	`CREATE INDEX events_user_category ON events (user_id, category, date) WHERE category != ''`,
+	`CREATE TABLE event_tags (
+		user_id  TEXT NOT NULL,
+		tag      TEXT NOT NULL,
+		event_id TEXT NOT NULL,
+		PRIMARY KEY (user_id, tag, event_id)
+	)`,
+	`CREATE INDEX event_tags_event ON event_tags (user_id, event_id)`,
+	// max_duration - наибольшая длительность события пользователя в наносекундах.
+	// Она не уменьшается при изменении и удалении событий и задает нижнюю
+	// границу date в выборках по диапазону, чтобы они использовали индекс.
+	`CREATE TABLE user_durations (
+		user_id      TEXT    NOT NULL PRIMARY KEY,
+		max_duration INTEGER NOT NULL
+	)`,
+	`INSERT INTO user_durations (user_id, max_duration)
+		SELECT user_id, MAX(end_date - date) FROM events WHERE end_date > date GROUP BY user_id`,
+	// Версия удаленного события и номер последней удаленной записи его истории,
+	// с которых продолжается нумерация, если событие или история появятся снова.
+	`CREATE TABLE event_numbers (
+		user_id  TEXT    NOT NULL,
+		event_id TEXT    NOT NULL,
+		version  INTEGER NOT NULL DEFAULT 0,
+		revision INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (user_id, event_id)
+	)`,
+}
+
+// dataMigrations - изменения данных, которые нельзя выразить на SQL, по номеру
+// миграции (с 1), после которой они выполняются.
+var dataMigrations = map[int]func(tx *sql.Tx) error{
+	16: indexAllText,
+}
+
+// migrate применяет к базе все еще не применённые миграции в одной транзакции.
+func migrate(db *sql.DB) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var version int
+	if err := tx.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil {
+		return err
+	}
+
+	if version > len(migrations) {
+		return fmt.Errorf("database schema version %d is newer than supported %d",
+			version, len(migrations))
+	}
+
+	for i := version; i < len(migrations); i++ {
+		if _, err := tx.Exec(migrations[i]); err != nil {
+			return fmt.Errorf("migration %d: %w", i+1, err)
+		}
+		if fn, ok := dataMigrations[i+1]; ok {
+			if err := fn(tx); err != nil {
+				return fmt.Errorf("migration %d: %w", i+1, err)
+			}
+		}
+	}
+
+	if _, err := tx.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, len(migrations))); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
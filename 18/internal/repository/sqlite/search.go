@@ -0,0 +1,121 @@
+package sqlite
+
+import (
+	"database/sql"
+	"strings"
+
+	"l2.18/pkg/models"
+	"l2.18/pkg/search"
+)
+
+// Search возвращает события пользователя, текст которых соответствует запросу,
+// отсортированные по началу. В events_search хранятся нормализованные пакетом
+// search слова, поэтому поиск совпадает с поиском по индексу в памяти.
+func (er *EventsRepository) Search(userID models.UserID, q search.Query) ([]models.Event, error) {
+	events, err := query(er.conn(),
+		`SELECT `+eventColumns+` FROM events_search
+		JOIN search_docs ON search_docs.doc_id = events_search.rowid
+		JOIN events ON events.user_id = search_docs.user_id AND events.id = search_docs.event_id
+		WHERE events_search MATCH ? AND search_docs.user_id = ?
+		ORDER BY events.date, events.id`,
+		matchExpr(q), userID,
+	)
+	if err != nil {
+		return nil, mapError(err)
+	}
+
+	return events, nil
+}
+
+// indexText перезаписывает текст события в полнотекстовом индексе.
+func indexText(tx *sql.Tx, userID models.UserID, eventID models.EventID, text string) error {
+	_, err := tx.Exec(
+		`INSERT INTO search_docs (user_id, event_id) VALUES (?, ?) ON CONFLICT DO NOTHING`,
+		userID, eventID)
+	if err != nil {
+		return mapError(err)
+	}
+
+	var id int64
+	err = tx.QueryRow(
+		`SELECT doc_id FROM search_docs WHERE user_id = ? AND event_id = ?`,
+		userID, eventID).Scan(&id)
+	if err != nil {
+		return mapError(err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM events_search WHERE rowid = ?`, id); err != nil {
+		return mapError(err)
+	}
+
+	_, err = tx.Exec(
+		`INSERT INTO events_search (rowid, text) VALUES (?, ?)`,
+		id, strings.Join(search.Tokenize(text), " "))
+	return mapError(err)
+}
+
+// removeText удаляет событие из полнотекстового индекса.
+func removeText(tx *sql.Tx, userID models.UserID, eventID models.EventID) error {
+	_, err := tx.Exec(
+		`DELETE FROM events_search WHERE rowid IN
+			(SELECT doc_id FROM search_docs WHERE user_id = ? AND event_id = ?)`,
+		userID, eventID)
+	if err != nil {
+		return mapError(err)
+	}
+
+	_, err = tx.Exec(
+		`DELETE FROM search_docs WHERE user_id = ? AND event_id = ?`, userID, eventID)
+	return mapError(err)
+}
+
+// indexAllText индексирует тексты всех событий. Выполняется один раз
+// после создания индекса на базе, в которой уже есть события.
+func indexAllText(tx *sql.Tx) error {
+	type doc struct {
+		userID  models.UserID
+		eventID models.EventID
+		text    string
+	}
+
+	rows, err := tx.Query(`SELECT user_id, id, event FROM events`)
+	if err != nil {
+		return err
+	}
+
+	var docs []doc
+	for rows.Next() {
+		var d doc
+		if err := rows.Scan(&d.userID, &d.eventID, &d.text); err != nil {
+			rows.Close()
+			return err
+		}
+		docs = append(docs, d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, d := range docs {
+		if err := indexText(tx, d.userID, d.eventID, d.text); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// matchExpr строит выражение MATCH FTS5: условия запроса - фразы из
+// нормализованных слов, которые не содержат кавычек.
+func matchExpr(q search.Query) string {
+	parts := make([]string, len(q.Clauses))
+	for i, c := range q.Clauses {
+		parts[i] = `"` + strings.Join(c.Terms, " ") + `"`
+		if c.Prefix {
+			parts[i] += "*"
+		}
+	}
+
+	return strings.Join(parts, " ")
+}
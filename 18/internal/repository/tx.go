@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"time"
+
+	"l2.18/pkg/models"
+	"l2.18/pkg/search"
+)
+
+// Tx - хранилище событий внутри транзакции. Изменения, сделанные через Tx,
+// применяются все вместе после успешного завершения транзакции или не применяются вовсе.
+type Tx interface {
+	Put(userID models.UserID, event models.Event) error
+	Get(userID models.UserID, eventID models.EventID) (*models.Event, error)
+	Update(userID models.UserID, event models.Event) error
+	Replace(userID models.UserID, event models.Event) error
+	Delete(userID models.UserID, eventID models.EventID, version int64) error
+	GetEventsByDateRange(userID models.UserID, start, end time.Time) ([]models.Event, error)
+	GetFilteredEvents(userID models.UserID, start, end time.Time, filter models.EventFilter) ([]models.Event, error)
+	GetRecurringEvents(userID models.UserID) ([]models.Event, error)
+	Users() ([]models.UserID, error)
+	GetInvitedEvents(userID models.UserID) ([]models.Event, error)
+	UpdateAttendee(owner models.UserID, eventID models.EventID, attendee models.Attendee) error
+	Search(userID models.UserID, query search.Query) ([]models.Event, error)
+
+	AddHistory(userID models.UserID, entry models.HistoryEntry) error
+	GetHistory(userID models.UserID, eventID models.EventID) ([]models.HistoryEntry, error)
+	PruneHistory(before time.Time) error
+}
@@ -8,3 +8,22 @@ var ErrNotFound = errors.New("entity not found")
 // ErrAlreadyExist возвращается, если сущность уже существует,
 // при попытке ее добавить.
 var ErrAlreadyExist = errors.New("entity already exist")
+
+// ErrInvalidEvent возвращается, если событие не прошло проверку
+// (например, содержит некорректное правило повторения).
+var ErrInvalidEvent = errors.New("invalid event")
+
+// ErrConflict возвращается, если событие пересекается с другими событиями
+// пользователя, а проверка пересечений включена.
+var ErrConflict = errors.New("event conflicts with another event")
+
+// ErrVersionMismatch возвращается, если событие было изменено после того,
+// как клиент получил его версию.
+var ErrVersionMismatch = errors.New("event version mismatch")
+
+// ErrBatchAborted возвращается для операций атомарного пакета, отмененных
+// из-за ошибки другой операции.
+var ErrBatchAborted = errors.New("batch aborted")
+
+// ErrQuotaExceeded возвращается, если арендатор исчерпал квоту событий.
+var ErrQuotaExceeded = errors.New("tenant event quota exceeded")
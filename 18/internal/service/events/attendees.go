@@ -0,0 +1,122 @@
+package events
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"l2.18/internal/repository"
+	"l2.18/internal/service"
+	"l2.18/pkg/models"
+)
+
+// RespondToInvitation сохраняет ответ userID на приглашение в событие eventID пользователя owner.
+// Если события нет или userID в него не приглашен - вернет service.ErrNotFound.
+func (s *Service) RespondToInvitation(
+	userID, owner models.UserID, eventID models.EventID, status models.RSVPStatus,
+) error {
+	if !status.Valid() || status == models.RSVPNeedsAction {
+		return fmt.Errorf("%w: unknown rsvp status %q", service.ErrInvalidEvent, status)
+	}
+
+	before := s.current(owner, eventID)
+	err := s.repo.UpdateAttendee(owner, eventID, models.Attendee{UserID: userID, Status: status})
+	if errors.Is(err, repository.ErrNotFound) {
+		return service.ErrNotFound
+	} else if err != nil {
+		return err
+	}
+
+	return s.changed(owner, userID, models.ChangeUpdated, eventID, before)
+}
+
+// GetInvitations возвращает события других пользователей, в которые приглашен userID,
+// в том виде, в котором они хранятся. У каждого события заполнен Owner.
+func (s *Service) GetInvitations(userID models.UserID) ([]models.Event, error) {
+	return s.repo.GetInvitedEvents(userID)
+}
+
+// invitedEvents возвращает вхождения событий, в которые приглашен userID,
+// пересекающиеся с диапазоном [start, end). Отклоненные приглашения пропускаются.
+func (s *Service) invitedEvents(userID models.UserID, start, end time.Time) ([]models.Event, error) {
+	invited, err := s.repo.GetInvitedEvents(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []models.Event
+	for _, e := range invited {
+		if a, ok := e.Attendee(userID); !ok || a.Status == models.RSVPDeclined {
+			continue
+		}
+
+		if !e.IsRecurring() {
+			if e.Overlaps(start, end) {
+				result = append(result, e)
+			}
+			continue
+		}
+
+		occurrences, err := expand(e, start, end)
+		if err != nil {
+			return nil, fmt.Errorf("expand series %s: %w", e.ID, err)
+		}
+		result = append(result, occurrences...)
+	}
+
+	return result, nil
+}
+
+// prepareAttendees проверяет участников события owner и сохраняет ответы
+// тех, кто уже был приглашен (previous). Новые участники получают needs-action,
+// ответы, переданные владельцем, не учитываются.
+func prepareAttendees(owner models.UserID, attendees, previous []models.Attendee) ([]models.Attendee, error) {
+	if attendees == nil {
+		return nil, nil
+	}
+
+	statuses := make(map[models.UserID]models.RSVPStatus, len(previous))
+	for _, a := range previous {
+		statuses[a.UserID] = a.Status
+	}
+
+	result := make([]models.Attendee, 0, len(attendees))
+	seen := make(map[models.UserID]bool, len(attendees))
+
+	for _, a := range attendees {
+		switch {
+		case a.UserID == "":
+			return nil, fmt.Errorf("%w: attendee without user id", service.ErrInvalidEvent)
+		case a.UserID == owner:
+			return nil, fmt.Errorf("%w: owner cannot be an attendee", service.ErrInvalidEvent)
+		case seen[a.UserID]:
+			continue
+		}
+		seen[a.UserID] = true
+
+		status, ok := statuses[a.UserID]
+		if !ok {
+			status = models.RSVPNeedsAction
+		}
+		result = append(result, models.Attendee{UserID: a.UserID, Status: status})
+	}
+
+	return result, nil
+}
+
+// previousAttendees возвращает текущих участников события, если они нужны
+// для проверки новых участников.
+func (s *Service) previousAttendees(userID models.UserID, event models.Event) ([]models.Attendee, error) {
+	if event.Attendees == nil {
+		return nil, nil
+	}
+
+	stored, err := s.repo.Get(userID, event.ID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, service.ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return stored.Attendees, nil
+}
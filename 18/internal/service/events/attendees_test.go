@@ -0,0 +1,136 @@
+package events
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"l2.18/internal/repository"
+	repomock "l2.18/internal/repository/mock"
+	"l2.18/internal/service"
+	"l2.18/pkg/models"
+)
+
+func TestPrepareAttendees(t *testing.T) {
+	previous := []models.Attendee{{UserID: "alice", Status: models.RSVPAccepted}}
+
+	got, err := prepareAttendees("owner", []models.Attendee{
+		{UserID: "alice", Status: models.RSVPDeclined},
+		{UserID: "bob", Status: models.RSVPAccepted},
+		{UserID: "bob"},
+	}, previous)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []models.Attendee{
+		{UserID: "alice", Status: models.RSVPAccepted},
+		{UserID: "bob", Status: models.RSVPNeedsAction},
+	}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	for _, attendees := range [][]models.Attendee{{{UserID: "owner"}}, {{UserID: ""}}} {
+		if _, err := prepareAttendees("owner", attendees, nil); !errors.Is(err, service.ErrInvalidEvent) {
+			t.Errorf("%v: expected %v, got %v", attendees, service.ErrInvalidEvent, err)
+		}
+	}
+}
+
+func TestGetEventsIncludesInvitations(t *testing.T) {
+	day := time.Date(2025, time.February, 17, 0, 0, 0, 0, time.UTC)
+	invite := func(id models.EventID, status models.RSVPStatus, rrule string) models.Event {
+		return models.Event{
+			ID: id, Date: day.Add(10 * time.Hour), End: day.Add(11 * time.Hour), RRule: rrule, Owner: "owner",
+			Attendees: []models.Attendee{{UserID: "alice", Status: status}},
+		}
+	}
+
+	mockRepo := &repomock.MockRepository{
+		GetEventsByDateRangeFn: func(userID models.UserID, start, end time.Time) ([]models.Event, error) {
+			return []models.Event{{ID: "own", Date: day.Add(9 * time.Hour)}}, nil
+		},
+		GetRecurringEventsFn: func(userID models.UserID) ([]models.Event, error) {
+			return nil, nil
+		},
+		GetInvitedEventsFn: func(userID models.UserID) ([]models.Event, error) {
+			return []models.Event{
+				invite("accepted", models.RSVPAccepted, ""),
+				invite("declined", models.RSVPDeclined, ""),
+				invite("series", models.RSVPNeedsAction, "FREQ=DAILY;COUNT=3"),
+				invite("tomorrow", models.RSVPTentative, ""),
+			}, nil
+		},
+	}
+
+	got, err := New(mockRepo).GetEventsForDay("alice", day.AddDate(0, 0, 1), models.EventFilter{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// На следующий день попадают только вхождение серии и собственное событие
+	// (мок возвращает его для любого диапазона).
+	if len(got) != 2 || got[0].ID != "own" || got[1].ID != "series" {
+		t.Fatalf("unexpected events %+v", got)
+	}
+	if got[1].Owner != "owner" || got[1].RecurrenceID == nil {
+		t.Errorf("expected occurrence of invited series, got %+v", got[1])
+	}
+
+	got, err = New(mockRepo).GetEventsForDay("alice", day, models.EventFilter{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, e := range got {
+		if e.ID == "declined" {
+			t.Error("declined invitation was returned")
+		}
+	}
+	if len(got) != 4 {
+		t.Errorf("expected 4 events, got %+v", got)
+	}
+}
+
+func TestRespondToInvitation(t *testing.T) {
+	event := models.Event{ID: "1", Date: time.Now(), Attendees: []models.Attendee{
+		{UserID: "alice", Status: models.RSVPNeedsAction},
+	}}
+
+	mockRepo := &repomock.MockRepository{
+		GetFn: func(userID models.UserID, eventID models.EventID) (*models.Event, error) {
+			e := event
+			return &e, nil
+		},
+		UpdateAttendeeFn: func(owner models.UserID, eventID models.EventID, attendee models.Attendee) error {
+			a, ok := event.Attendee(attendee.UserID)
+			if owner != "owner" || eventID != event.ID || !ok {
+				return repository.ErrNotFound
+			}
+			*a = attendee
+			return nil
+		},
+	}
+
+	pub := &recordingPublisher{}
+	svc := New(mockRepo, WithPublisher(pub))
+
+	if err := svc.RespondToInvitation("alice", "owner", "1", models.RSVPAccepted); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if event.Attendees[0].Status != models.RSVPAccepted {
+		t.Errorf("expected accepted, got %q", event.Attendees[0].Status)
+	}
+
+	// Уведомление получают владелец и участник, у участника заполнен Owner.
+	if len(pub.changes) != 2 || pub.changes[0].Event.Owner != "" || pub.changes[1].Event.Owner != "owner" {
+		t.Errorf("unexpected changes %+v", pub.changes)
+	}
+
+	if err := svc.RespondToInvitation("bob", "owner", "1", models.RSVPAccepted); !errors.Is(err, service.ErrNotFound) {
+		t.Errorf("expected %v, got %v", service.ErrNotFound, err)
+	}
+	if err := svc.RespondToInvitation("alice", "owner", "1", "maybe"); !errors.Is(err, service.ErrInvalidEvent) {
+		t.Errorf("expected %v, got %v", service.ErrInvalidEvent, err)
+	}
+}
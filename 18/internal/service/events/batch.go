@@ -0,0 +1,134 @@
+package events
+
+import (
+	"fmt"
+
+	"l2.18/internal/repository"
+	"l2.18/internal/service"
+	"l2.18/pkg/models"
+)
+
+// Batch выполняет операции пакета по порядку и возвращает результат каждой.
+//
+// Если atomic, пакет выполняется в одной транзакции хранилища: при первой ошибке
+// транзакция отменяется, следующие операции не выполняются, а результаты всех
+// операций, кроме ошибочной, получают service.ErrBatchAborted. Подписчики
+// уведомляются только после фиксации транзакции, история пишется в ту же транзакцию.
+// Иначе каждая операция выполняется отдельно и ее ошибка не влияет на остальные.
+//
+// Ошибка возвращается, только если не удалось выполнить саму транзакцию.
+func (s *Service) Batch(userID models.UserID, ops []models.BatchOperation, atomic bool) ([]models.BatchResult, error) {
+	results := make([]models.BatchResult, len(ops))
+	if !atomic {
+		for i, op := range ops {
+			results[i] = s.apply(userID, op)
+		}
+		return results, nil
+	}
+
+	failed := -1
+	err := s.atomically(func(txs *Service) error {
+		for i, op := range ops {
+			results[i] = txs.apply(userID, op)
+			if results[i].Err != nil {
+				failed = i
+				return results[i].Err
+			}
+		}
+		return nil
+	})
+	if failed >= 0 {
+		for i := range results {
+			if i != failed {
+				results[i] = models.BatchResult{Err: service.ErrBatchAborted}
+			}
+		}
+		return results, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// atomically выполняет fn с копией сервиса, работающей в одной транзакции
+// хранилища: чтения и изменения fn не перемежаются с другими изменениями.
+// Подписчики уведомляются только после фиксации транзакции. Если сервис уже
+// работает в транзакции (или хранилище их не поддерживает), fn вызывается
+// с самим сервисом.
+func (s *Service) atomically(fn func(txs *Service) error) error {
+	if s.txRepo == nil {
+		return fn(s)
+	}
+
+	var pending pendingChanges
+	err := s.txRepo.Transaction(func(tx repository.Tx) error {
+		return fn(s.inTx(tx, &pending))
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, p := range pending {
+		s.pub.Publish(p.userID, p.change)
+	}
+	return nil
+}
+
+// inTx возвращает копию сервиса, работающую внутри транзакции tx.
+// Уведомления копятся в pending до ее фиксации.
+func (s *Service) inTx(tx repository.Tx, pending *pendingChanges) *Service {
+	txs := *s
+	txs.repo = tx
+	txs.txRepo = nil
+	if s.history != nil {
+		txs.history = tx
+	}
+	if s.pub != nil {
+		txs.pub = pending
+	}
+	return &txs
+}
+
+// apply выполняет одну операцию пакета.
+func (s *Service) apply(userID models.UserID, op models.BatchOperation) models.BatchResult {
+	switch op.Op {
+	case models.BatchCreate:
+		if op.Event == nil {
+			return models.BatchResult{Err: fmt.Errorf("%w: event is required", service.ErrInvalidEvent)}
+		}
+		id, err := s.AddEvent(userID, *op.Event)
+		return models.BatchResult{EventID: id, Err: err}
+	case models.BatchUpdate:
+		if op.ID == "" || op.Event == nil {
+			return models.BatchResult{Err: fmt.Errorf("%w: id and event are required", service.ErrInvalidEvent)}
+		}
+		if op.Event.ID != "" && op.Event.ID != op.ID {
+			return models.BatchResult{Err: fmt.Errorf("%w: event id does not match id", service.ErrInvalidEvent)}
+		}
+		event := *op.Event
+		event.ID, event.Version = op.ID, op.Version
+		return models.BatchResult{EventID: op.ID, Err: s.ReplaceEvent(userID, event)}
+	case models.BatchDelete:
+		if op.ID == "" {
+			return models.BatchResult{Err: fmt.Errorf("%w: id is required", service.ErrInvalidEvent)}
+		}
+		return models.BatchResult{EventID: op.ID, Err: s.RemoveEvent(userID, op.ID, op.Version)}
+	default:
+		return models.BatchResult{Err: fmt.Errorf("%w: unknown operation %q", service.ErrInvalidEvent, op.Op)}
+	}
+}
+
+type pendingChange struct {
+	userID models.UserID
+	change models.Change
+}
+
+// pendingChanges откладывает уведомления до фиксации транзакции.
+type pendingChanges []pendingChange
+
+// Publish запоминает уведомление.
+func (p *pendingChanges) Publish(userID models.UserID, change models.Change) {
+	*p = append(*p, pendingChange{userID: userID, change: change})
+}
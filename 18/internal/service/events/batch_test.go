@@ -0,0 +1,176 @@
+package events
+
+import (
+	"errors"
+	"maps"
+	"testing"
+	"time"
+
+	"l2.18/internal/repository"
+	repomock "l2.18/internal/repository/mock"
+	"l2.18/internal/service"
+	"l2.18/pkg/models"
+)
+
+// newBatchRepo возвращает мок с событиями в stored, транзакция которого
+// восстанавливает stored при ошибке. commits считает зафиксированные транзакции.
+func newBatchRepo(stored map[models.EventID]models.Event, commits *int) *repomock.MockRepository {
+	m := &repomock.MockRepository{
+		PutFn: func(userID models.UserID, event models.Event) error {
+			if _, ok := stored[event.ID]; ok {
+				return repository.ErrAlreadyExist
+			}
+			stored[event.ID] = event
+			return nil
+		},
+		GetFn: func(userID models.UserID, eventID models.EventID) (*models.Event, error) {
+			e, ok := stored[eventID]
+			if !ok {
+				return nil, repository.ErrNotFound
+			}
+			return &e, nil
+		},
+		ReplaceFn: func(userID models.UserID, event models.Event) error {
+			if _, ok := stored[event.ID]; !ok {
+				return repository.ErrNotFound
+			}
+			stored[event.ID] = event
+			return nil
+		},
+		DeleteFn: func(userID models.UserID, eventID models.EventID, version int64) error {
+			if _, ok := stored[eventID]; !ok {
+				return repository.ErrNotFound
+			}
+			delete(stored, eventID)
+			return nil
+		},
+	}
+	m.TransactionFn = func(fn func(tx repository.Tx) error) error {
+		saved := maps.Clone(stored)
+		if err := fn(m); err != nil {
+			clear(stored)
+			maps.Copy(stored, saved)
+			return err
+		}
+		*commits++
+		return nil
+	}
+	return m
+}
+
+func TestBatch(t *testing.T) {
+	now := time.Now()
+	ops := []models.BatchOperation{
+		{Op: models.BatchCreate, Event: &models.Event{ID: "b", Date: now, Event: "new"}},
+		{Op: models.BatchUpdate, ID: "a", Event: &models.Event{Date: now, Event: "renamed"}},
+		{Op: models.BatchDelete, ID: "missing"},
+		{Op: models.BatchDelete, ID: "a"},
+	}
+
+	testCases := []struct {
+		name     string
+		ops      []models.BatchOperation
+		atomic   bool
+		expected []error
+		stored   []models.EventID
+		changes  int
+		commits  int
+	}{
+		{
+			name:     "best effort",
+			ops:      ops,
+			expected: []error{nil, nil, service.ErrNotFound, nil},
+			stored:   []models.EventID{"b"},
+			changes:  3,
+		},
+		{
+			name:     "atomic rollback",
+			ops:      ops,
+			atomic:   true,
+			expected: []error{service.ErrBatchAborted, service.ErrBatchAborted, service.ErrNotFound, service.ErrBatchAborted},
+			stored:   []models.EventID{"a"},
+		},
+		{
+			name:     "atomic commit",
+			ops:      []models.BatchOperation{ops[0], ops[1]},
+			atomic:   true,
+			expected: []error{nil, nil},
+			stored:   []models.EventID{"a", "b"},
+			changes:  2,
+			commits:  1,
+		},
+		{
+			name: "invalid operations",
+			ops: []models.BatchOperation{
+				{Op: "move", ID: "a"},
+				{Op: models.BatchCreate},
+				{Op: models.BatchUpdate, ID: "a", Event: &models.Event{ID: "b", Date: now}},
+			},
+			expected: []error{service.ErrInvalidEvent, service.ErrInvalidEvent, service.ErrInvalidEvent},
+			stored:   []models.EventID{"a"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			stored := map[models.EventID]models.Event{"a": {ID: "a", Date: now, Event: "old"}}
+			commits := 0
+			pub := &recordingPublisher{}
+			svc := New(newBatchRepo(stored, &commits), WithPublisher(pub))
+
+			results, err := svc.Batch("user1", tc.ops, tc.atomic)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(results) != len(tc.expected) {
+				t.Fatalf("expected %d results, got %+v", len(tc.expected), results)
+			}
+			for i, expected := range tc.expected {
+				if !errors.Is(results[i].Err, expected) {
+					t.Errorf("result[%d]: expected %v, got %v", i, expected, results[i].Err)
+				}
+			}
+
+			for _, id := range tc.stored {
+				if _, ok := stored[id]; !ok {
+					t.Errorf("expected event %s to be stored", id)
+				}
+			}
+			if len(stored) != len(tc.stored) {
+				t.Errorf("expected events %v, got %v", tc.stored, stored)
+			}
+			if len(pub.changes) != tc.changes {
+				t.Errorf("expected %d changes, got %+v", tc.changes, pub.changes)
+			}
+			if commits != tc.commits {
+				t.Errorf("expected %d commits, got %d", tc.commits, commits)
+			}
+		})
+	}
+}
+
+func TestBatchPublishesAfterCommit(t *testing.T) {
+	stored := map[models.EventID]models.Event{}
+	commits := 0
+	pub := &recordingPublisher{}
+	repo := newBatchRepo(stored, &commits)
+
+	inner := repo.TransactionFn
+	repo.TransactionFn = func(fn func(tx repository.Tx) error) error {
+		err := inner(fn)
+		if len(pub.changes) != 0 {
+			t.Errorf("expected no changes before commit, got %+v", pub.changes)
+		}
+		return err
+	}
+
+	svc := New(repo, WithPublisher(pub))
+	ops := []models.BatchOperation{{Op: models.BatchCreate, Event: &models.Event{Date: time.Now(), Event: "new"}}}
+	results, err := svc.Batch("user1", ops, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(pub.changes) != 1 || pub.changes[0].EventID != results[0].EventID {
+		t.Errorf("expected created event to be published, got %+v", pub.changes)
+	}
+}
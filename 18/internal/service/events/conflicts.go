@@ -0,0 +1,95 @@
+package events
+
+import (
+	"fmt"
+	"time"
+
+	"l2.18/internal/service"
+	"l2.18/pkg/models"
+)
+
+// conflictHorizon - насколько вперед от начала серии ее вхождения проверяются на пересечения.
+const conflictHorizon = 366 * 24 * time.Hour
+
+// WithConflictCheck включает отказ в создании и изменении событий, пересекающихся
+// с другими событиями пользователя. В этом случае возвращается service.ErrConflict.
+// Проверка и запись события выполняются в одной транзакции хранилища.
+func WithConflictCheck() Option {
+	return func(s *Service) {
+		s.rejectConflicts = true
+	}
+}
+
+// Conflicts возвращает события пользователя (вхождения серий), пересекающиеся с event.
+// Само событие и другие вхождения его серии не учитываются. События без длительности
+// ни с чем не пересекаются. Вхождения серии проверяются на год вперед от ее начала.
+func (s *Service) Conflicts(userID models.UserID, event models.Event) ([]models.Event, error) {
+	occurrences := []models.Event{event}
+	if event.IsRecurring() {
+		var err error
+		occurrences, err = expand(event, event.Date, event.Date.Add(conflictHorizon))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", service.ErrInvalidEvent, err)
+		}
+	}
+
+	var (
+		blocking   []models.Event
+		start, end time.Time
+	)
+	for _, o := range occurrences {
+		if o.Duration() <= 0 {
+			continue
+		}
+		if len(blocking) == 0 || o.Date.Before(start) {
+			start = o.Date
+		}
+		if o.End.After(end) {
+			end = o.End
+		}
+		blocking = append(blocking, o)
+	}
+	if len(blocking) == 0 {
+		return nil, nil
+	}
+
+	others, err := s.getEvents(userID, start, end, models.EventFilter{})
+	if err != nil {
+		return nil, err
+	}
+
+	var result []models.Event
+	for _, other := range others {
+		if other.ID == event.ID || other.Duration() <= 0 {
+			continue
+		}
+
+		for _, o := range blocking {
+			if o.Date.Before(other.End) && other.Date.Before(o.End) {
+				result = append(result, other)
+				break
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// checkConflicts вернет service.ErrConflict, если проверка пересечений
+// включена и event пересекается с другими событиями пользователя.
+func (s *Service) checkConflicts(userID models.UserID, event models.Event) error {
+	if !s.rejectConflicts {
+		return nil
+	}
+
+	conflicts, err := s.Conflicts(userID, event)
+	if err != nil {
+		return err
+	}
+	if len(conflicts) > 0 {
+		c := conflicts[0]
+		return fmt.Errorf("%w: overlaps event %s at %s", service.ErrConflict, c.ID, c.Date.Format(time.RFC3339))
+	}
+
+	return nil
+}
@@ -0,0 +1,185 @@
+package events
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"l2.18/internal/repository/memory"
+	repomock "l2.18/internal/repository/mock"
+	"l2.18/internal/service"
+	"l2.18/pkg/models"
+)
+
+// newEventsMock возвращает мок, который хранит события пользователя в срезе.
+func newEventsMock(events ...models.Event) *repomock.MockRepository {
+	return &repomock.MockRepository{
+		GetFn: func(userID models.UserID, eventID models.EventID) (*models.Event, error) {
+			for _, e := range events {
+				if e.ID == eventID {
+					return &e, nil
+				}
+			}
+			return nil, service.ErrNotFound
+		},
+		GetEventsByDateRangeFn: func(userID models.UserID, start, end time.Time) ([]models.Event, error) {
+			var result []models.Event
+			for _, e := range events {
+				if !e.IsRecurring() && e.Overlaps(start, end) {
+					result = append(result, e)
+				}
+			}
+			return result, nil
+		},
+		GetRecurringEventsFn: func(userID models.UserID) ([]models.Event, error) {
+			var result []models.Event
+			for _, e := range events {
+				if e.IsRecurring() {
+					result = append(result, e)
+				}
+			}
+			return result, nil
+		},
+		PutFn:     func(userID models.UserID, event models.Event) error { return nil },
+		UpdateFn:  func(userID models.UserID, event models.Event) error { return nil },
+		ReplaceFn: func(userID models.UserID, event models.Event) error { return nil },
+	}
+}
+
+func TestConflicts(t *testing.T) {
+	day := time.Date(2025, time.February, 17, 0, 0, 0, 0, time.UTC)
+	at := func(hour int) time.Time { return day.Add(time.Duration(hour) * time.Hour) }
+
+	meeting := models.Event{ID: "meeting", Date: at(10), End: at(11), Event: "meeting"}
+	standup := models.Event{ID: "standup", Date: at(9), End: at(9).Add(15 * time.Minute), Event: "standup",
+		RRule: "FREQ=DAILY;COUNT=5"}
+	reminder := models.Event{ID: "reminder", Date: at(10), Event: "no duration"}
+
+	svc := New(newEventsMock(meeting, standup, reminder))
+
+	testCases := []struct {
+		name     string
+		event    models.Event
+		expected []models.EventID
+	}{
+		{
+			name:     "overlaps single event",
+			event:    models.Event{Date: at(10).Add(30 * time.Minute), End: at(12)},
+			expected: []models.EventID{"meeting"},
+		},
+		{
+			name:  "adjacent events do not overlap",
+			event: models.Event{Date: at(11), End: at(12)},
+		},
+		{
+			name:  "event without duration",
+			event: models.Event{Date: at(10).Add(30 * time.Minute)},
+		},
+		{
+			name:  "event itself is ignored",
+			event: models.Event{ID: "meeting", Date: at(10), End: at(11)},
+		},
+		{
+			name: "series overlaps occurrence of another series",
+			event: models.Event{
+				Date:  day.AddDate(0, 0, -7).Add(9 * time.Hour),
+				End:   day.AddDate(0, 0, -7).Add(10*time.Hour + 30*time.Minute),
+				RRule: "FREQ=WEEKLY",
+			},
+			expected: []models.EventID{"standup", "meeting"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := svc.Conflicts("user1", tc.event)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			ids := make([]models.EventID, len(got))
+			for i, e := range got {
+				ids[i] = e.ID
+			}
+			if len(ids) != len(tc.expected) {
+				t.Fatalf("expected %v, got %v", tc.expected, ids)
+			}
+			for i := range ids {
+				if ids[i] != tc.expected[i] {
+					t.Errorf("expected %v, got %v", tc.expected, ids)
+				}
+			}
+		})
+	}
+}
+
+func TestConflictCheck(t *testing.T) {
+	day := time.Date(2025, time.February, 17, 0, 0, 0, 0, time.UTC)
+	meeting := models.Event{ID: "meeting", Date: day.Add(10 * time.Hour), End: day.Add(11 * time.Hour)}
+	lunch := models.Event{ID: "lunch", Date: day.Add(13 * time.Hour), End: day.Add(14 * time.Hour)}
+	overlapping := models.Event{Date: day.Add(10 * time.Hour), End: day.Add(12 * time.Hour)}
+
+	// Без WithConflictCheck пересечения разрешены.
+	if _, err := New(newEventsMock(meeting, lunch)).AddEvent("user1", overlapping); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	svc := New(newEventsMock(meeting, lunch), WithConflictCheck())
+
+	if _, err := svc.AddEvent("user1", overlapping); !errors.Is(err, service.ErrConflict) {
+		t.Errorf("add: expected %v, got %v", service.ErrConflict, err)
+	}
+	if err := svc.UpdateEvent("user1", models.Event{ID: "lunch", Date: day.Add(10 * time.Hour)}); !errors.Is(err, service.ErrConflict) {
+		t.Errorf("update: expected %v, got %v", service.ErrConflict, err)
+	}
+	if err := svc.ReplaceEvent("user1", models.Event{ID: "lunch", Date: day.Add(15 * time.Hour), End: day.Add(16 * time.Hour)}); err != nil {
+		t.Errorf("replace: unexpected error: %v", err)
+	}
+	if err := svc.UpdateEvent("user1", models.Event{ID: "missing", Event: "x"}); !errors.Is(err, service.ErrNotFound) {
+		t.Errorf("update missing: expected %v, got %v", service.ErrNotFound, err)
+	}
+}
+
+// slowRepo - хранилище в памяти, в котором Put вне транзакции выполняется
+// с задержкой: так параллельные проверки успевают пройти до записи.
+type slowRepo struct {
+	*memory.EventsRepository
+}
+
+func (r slowRepo) Put(userID models.UserID, event models.Event) error {
+	time.Sleep(time.Millisecond)
+	return r.EventsRepository.Put(userID, event)
+}
+
+func TestConflictCheckConcurrentAdds(t *testing.T) {
+	day := time.Date(2025, time.February, 17, 0, 0, 0, 0, time.UTC)
+	svc := New(slowRepo{memory.NewEventsRepository()}, WithConflictCheck())
+
+	const n = 20
+	errs := make(chan error, n)
+	var wg sync.WaitGroup
+	for range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := svc.AddEvent("user1", models.Event{Date: day.Add(10 * time.Hour), End: day.Add(11 * time.Hour)})
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	added := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			added++
+		case !errors.Is(err, service.ErrConflict):
+			t.Errorf("expected %v, got %v", service.ErrConflict, err)
+		}
+	}
+	if added != 1 {
+		t.Errorf("expected exactly one event to be added, got %d", added)
+	}
+}
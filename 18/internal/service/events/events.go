@@ -2,90 +2,484 @@ package events
 
 import (
 	"errors"
+	"fmt"
+	"sort"
 	"time"
 
 	"github.com/google/uuid"
 	"l2.18/internal/repository"
 	"l2.18/internal/service"
 	"l2.18/pkg/models"
+	"l2.18/pkg/rrule"
+	"l2.18/pkg/search"
 )
 
-type eventsRepository interface {
+type eventsStore interface {
 	Put(userID models.UserID, event models.Event) error
+	Get(userID models.UserID, eventID models.EventID) (*models.Event, error)
 	Update(userID models.UserID, event models.Event) error
-	Delete(userID models.UserID, eventID models.EventID) error
+	Replace(userID models.UserID, event models.Event) error
+	Delete(userID models.UserID, eventID models.EventID, version int64) error
 	GetEventsByDateRange(userID models.UserID, start, end time.Time) ([]models.Event, error)
+	GetFilteredEvents(userID models.UserID, start, end time.Time, filter models.EventFilter) ([]models.Event, error)
+	GetRecurringEvents(userID models.UserID) ([]models.Event, error)
+	Users() ([]models.UserID, error)
+	GetInvitedEvents(userID models.UserID) ([]models.Event, error)
+	UpdateAttendee(owner models.UserID, eventID models.EventID, attendee models.Attendee) error
+	Search(userID models.UserID, query search.Query) ([]models.Event, error)
+}
+
+type eventsRepository interface {
+	eventsStore
+	Transaction(fn func(tx repository.Tx) error) error
+}
+
+type publisher interface {
+	Publish(userID models.UserID, change models.Change)
 }
 
 // Service реализует сервис работы с событиями.
 type Service struct {
-	repo eventsRepository
+	repo eventsStore
+	// txRepo - то же хранилище, выполняющее транзакции пакетов (Batch).
+	txRepo eventsRepository
+	pub    publisher
+	// history и retention - хранилище и срок хранения истории изменений (WithHistory).
+	history   historyStore
+	retention time.Duration
+	// rejectConflicts - отказывать в пересекающихся событиях (WithConflictCheck).
+	rejectConflicts bool
+}
+
+// Option настраивает Service.
+type Option func(*Service)
+
+// WithPublisher включает уведомления об изменениях событий через pub.
+func WithPublisher(pub publisher) Option {
+	return func(s *Service) {
+		s.pub = pub
+	}
 }
 
 // New создает новый Service.
-func New(repo eventsRepository) *Service {
-	return &Service{repo: repo}
+func New(repo eventsRepository, opts ...Option) *Service {
+	s := &Service{repo: repo, txRepo: repo}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
 }
 
-// AddEvent добавляет новое событие.
-func (s *Service) AddEvent(userID models.UserID, event models.Event) error {
-	eventUID := uuid.NewString()
-	event.ID = models.EventID(eventUID)
+// AddEvent добавляет новое событие и возвращает его ID. Если задано event.RRule,
+// событие становится серией повторяющихся событий, начинающейся в event.Date.
+// Серия может сразу содержать измененные вхождения (например, при импорте).
+// Если event.ID не задан, генерируется новый. Если событие с таким ID уже
+// есть - вернет service.ErrAlreadyExist.
+func (s *Service) AddEvent(userID models.UserID, event models.Event) (models.EventID, error) {
+	if err := validateEvent(event); err != nil {
+		return "", err
+	}
+	if err := validateOverrides(event); err != nil {
+		return "", err
+	}
+	if err := prepareMetadata(&event); err != nil {
+		return "", err
+	}
+
+	attendees, err := prepareAttendees(userID, event.Attendees, nil)
+	if err != nil {
+		return "", err
+	}
+	event.Attendees = attendees
+
+	if event.ID == "" {
+		event.ID = models.EventID(uuid.NewString())
+	}
+	event.RecurrenceID = nil
+	event.Owner = ""
+	event.Version = 0
+
+	if !s.rejectConflicts {
+		return s.putEvent(userID, event)
+	}
+
+	// Проверка пересечений и запись выполняются в одной транзакции, иначе
+	// параллельно создаваемые пересекающиеся события обе пройдут проверку.
+	err = s.atomically(func(txs *Service) error {
+		_, err := txs.putEvent(userID, event)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return event.ID, nil
+}
+
+// putEvent сохраняет подготовленное AddEvent событие.
+func (s *Service) putEvent(userID models.UserID, event models.Event) (models.EventID, error) {
+	if err := s.checkConflicts(userID, event); err != nil {
+		return "", err
+	}
 
 	err := s.repo.Put(userID, event)
 	if errors.Is(err, repository.ErrAlreadyExist) {
-		return service.ErrAlreadyExist
+		return "", service.ErrAlreadyExist
 	} else if err != nil {
-		return err
+		return "", mapError(err)
 	}
 
-	return nil
+	return event.ID, s.changed(userID, userID, models.ChangeCreated, event.ID, nil)
 }
 
-// UpdateEvent обновляет событие.
+// UpdateEvent обновляет событие. Для серии изменяются все ее вхождения.
+// Если задана event.Version, а событие уже изменено - вернет service.ErrVersionMismatch.
 func (s *Service) UpdateEvent(userID models.UserID, event models.Event) error {
-	err := s.repo.Update(userID, event)
-	if errors.Is(err, repository.ErrNotFound) {
-		return service.ErrNotFound
-	} else if err != nil {
+	if err := validateEvent(event); err != nil {
+		return err
+	}
+	if err := prepareMetadata(&event); err != nil {
 		return err
 	}
 
-	return nil
+	event.Overrides = nil
+	event.RecurrenceID = nil
+	event.Owner = ""
+
+	// Событие читается один раз, и изменение записывается в той же транзакции:
+	// проверки не увидят состояние, которое успели изменить другие запросы.
+	return s.atomically(func(txs *Service) error {
+		return txs.updateEvent(userID, event)
+	})
 }
 
-// RemoveEvent удаляет событие.
-func (s *Service) RemoveEvent(userID models.UserID, eventID models.EventID) error {
-	err := s.repo.Delete(userID, eventID)
+// updateEvent применяет к сохраненному событию подготовленное UpdateEvent изменение patch.
+func (s *Service) updateEvent(userID models.UserID, patch models.Event) error {
+	stored, err := s.repo.Get(userID, patch.ID)
+	if err != nil {
+		return mapError(err)
+	}
+
+	var previous []models.Attendee
+	if patch.Attendees != nil {
+		previous = stored.Attendees
+	}
+	if patch.Attendees, err = prepareAttendees(userID, patch.Attendees, previous); err != nil {
+		return err
+	}
+
+	// Проверяется событие, которое получится после изменения: например, новое
+	// начало не должно оказаться позже сохраненного окончания.
+	merged := *stored
+	merged.Merge(patch)
+	if err := validateEvent(merged); err != nil {
+		return err
+	}
+	if s.rejectConflicts {
+		if err := s.checkConflicts(userID, merged); err != nil {
+			return err
+		}
+	}
+
+	if err := mapError(s.repo.Update(userID, patch)); err != nil {
+		return err
+	}
+
+	return s.changed(userID, userID, models.ChangeUpdated, patch.ID, stored)
+}
+
+// ReplaceEvent полностью заменяет событие, включая правило повторения
+// и измененные вхождения серии. Незаданные поля event сбрасываются.
+// Версия проверяется так же, как в UpdateEvent.
+func (s *Service) ReplaceEvent(userID models.UserID, event models.Event) error {
+	if err := validateEvent(event); err != nil {
+		return err
+	}
+	if err := validateOverrides(event); err != nil {
+		return err
+	}
+	if err := prepareMetadata(&event); err != nil {
+		return err
+	}
+
+	event.RecurrenceID = nil
+	event.Owner = ""
+
+	if !s.rejectConflicts {
+		return s.replaceEvent(userID, event)
+	}
+
+	// См. AddEvent.
+	return s.atomically(func(txs *Service) error {
+		return txs.replaceEvent(userID, event)
+	})
+}
+
+// replaceEvent заменяет событие подготовленным ReplaceEvent event.
+func (s *Service) replaceEvent(userID models.UserID, event models.Event) error {
+	previous, err := s.previousAttendees(userID, event)
+	if err != nil {
+		return err
+	}
+	if event.Attendees, err = prepareAttendees(userID, event.Attendees, previous); err != nil {
+		return err
+	}
+
+	if err := s.checkConflicts(userID, event); err != nil {
+		return err
+	}
+
+	before := s.current(userID, event.ID)
+	if err := mapError(s.repo.Replace(userID, event)); err != nil {
+		return err
+	}
+
+	return s.changed(userID, userID, models.ChangeUpdated, event.ID, before)
+}
+
+// RemoveEvent удаляет событие. Для серии удаляются все ее вхождения.
+// Если задана version, а событие уже изменено - вернет service.ErrVersionMismatch.
+func (s *Service) RemoveEvent(userID models.UserID, eventID models.EventID, version int64) error {
+	// Удаленное событие (и его участников) уже не узнать из хранилища.
+	before := s.current(userID, eventID)
+	if err := mapError(s.repo.Delete(userID, eventID, version)); err != nil {
+		return err
+	}
+
+	return s.changed(userID, userID, models.ChangeDeleted, eventID, before)
+}
+
+// GetEvent возвращает событие по ID. Серия возвращается целиком, без разворачивания во вхождения.
+func (s *Service) GetEvent(userID models.UserID, eventID models.EventID) (*models.Event, error) {
+	event, err := s.repo.Get(userID, eventID)
 	if errors.Is(err, repository.ErrNotFound) {
-		return service.ErrNotFound
+		return nil, service.ErrNotFound
 	} else if err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	result := *event
+	return &result, nil
 }
 
-// GetEventsForDay возвращает все события пользователя на указанный день.
-func (s *Service) GetEventsForDay(userID models.UserID, day time.Time) ([]models.Event, error) {
+// GetEventsForDay возвращает все события пользователя, пересекающиеся с указанным днем
+// и подходящие filter. Границы дня вычисляются в часовом поясе day.
+func (s *Service) GetEventsForDay(userID models.UserID, day time.Time, filter models.EventFilter) ([]models.Event, error) {
 	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
 	end := start.AddDate(0, 0, 1)
 
-	return s.repo.GetEventsByDateRange(userID, start, end)
+	return s.getEvents(userID, start, end, prepareFilter(filter))
 }
 
-// GetEventsForWeek возвращает события на неделю (понедельник–воскресенье или просто 7 дней от даты).
-func (s *Service) GetEventsForWeek(userID models.UserID, weekStart time.Time) ([]models.Event, error) {
+// GetEventsForWeek возвращает события на неделю (понедельник–воскресенье или просто 7 дней от даты),
+// подходящие filter. Границы вычисляются в часовом поясе weekStart.
+func (s *Service) GetEventsForWeek(userID models.UserID, weekStart time.Time, filter models.EventFilter) ([]models.Event, error) {
 	start := time.Date(weekStart.Year(), weekStart.Month(), weekStart.Day(), 0, 0, 0, 0, weekStart.Location())
 	end := start.AddDate(0, 0, 7)
 
-	return s.repo.GetEventsByDateRange(userID, start, end)
+	return s.getEvents(userID, start, end, prepareFilter(filter))
 }
 
-// GetEventsForMonth возвращает события на месяц.
-func (s *Service) GetEventsForMonth(userID models.UserID, month time.Time) ([]models.Event, error) {
+// GetEventsForMonth возвращает события на месяц, подходящие filter. Границы вычисляются
+// в часовом поясе month.
+func (s *Service) GetEventsForMonth(userID models.UserID, month time.Time, filter models.EventFilter) ([]models.Event, error) {
 	start := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, month.Location())
 	end := start.AddDate(0, 1, 0)
 
-	return s.repo.GetEventsByDateRange(userID, start, end)
+	return s.getEvents(userID, start, end, prepareFilter(filter))
+}
+
+// GetEvents возвращает события пользователя, пересекающиеся с диапазоном [start, end)
+// и подходящие filter. Серии разворачиваются во вхождения.
+func (s *Service) GetEvents(userID models.UserID, start, end time.Time, filter models.EventFilter) ([]models.Event, error) {
+	return s.getEvents(userID, start, end, prepareFilter(filter))
+}
+
+// GetUsers возвращает пользователей, у которых есть события.
+func (s *Service) GetUsers() ([]models.UserID, error) {
+	return s.repo.Users()
+}
+
+// ListEvents возвращает события пользователя, пересекающиеся с диапазоном [start, end)
+// и подходящие filter, в том виде, в котором они хранятся: серии не разворачиваются
+// во вхождения.
+func (s *Service) ListEvents(userID models.UserID, start, end time.Time, filter models.EventFilter) ([]models.Event, error) {
+	filter = prepareFilter(filter)
+
+	events, err := s.eventsInRange(userID, start, end, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	series, err := s.recurringEvents(userID, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]models.Event, 0, len(events)+len(series))
+	for _, e := range events {
+		if !e.IsRecurring() {
+			result = append(result, e)
+		}
+	}
+
+	for _, master := range series {
+		ok, err := hasOccurrences(master, start, end)
+		if err != nil {
+			return nil, fmt.Errorf("expand series %s: %w", master.ID, err)
+		}
+		if ok {
+			result = append(result, master)
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Date.Before(result[j].Date)
+	})
+
+	return result, nil
+}
+
+// current возвращает событие перед его изменением, если состояние до изменения
+// нужно истории или уведомлениям. Иначе (и если события нет) вернет nil.
+func (s *Service) current(userID models.UserID, eventID models.EventID) *models.Event {
+	if s.pub == nil && s.history == nil {
+		return nil
+	}
+
+	// Копия: хранилище в памяти возвращает само хранимое событие.
+	event, err := s.GetEvent(userID, eventID)
+	if err != nil {
+		return nil
+	}
+	return event
+}
+
+// changed сохраняет изменение события пользователя userID, сделанное actor,
+// в истории и уведомляет о нем подписчиков. before - состояние события до изменения
+// (nil для созданного). Изменение к этому моменту уже сохранено, поэтому ошибка
+// записи истории не отменяет его, а только возвращается вызывающему. В транзакции
+// (atomically) ошибка отменяет транзакцию вместе с изменением.
+func (s *Service) changed(
+	userID, actor models.UserID, changeType models.ChangeType, eventID models.EventID, before *models.Event,
+) error {
+	if s.pub == nil && s.history == nil {
+		return nil
+	}
+
+	var after *models.Event
+	if changeType != models.ChangeDeleted {
+		event, err := s.GetEvent(userID, eventID)
+		if err != nil {
+			return nil
+		}
+		after = event
+	}
+
+	now := time.Now()
+
+	var err error
+	if s.history != nil {
+		err = s.history.AddHistory(userID, models.HistoryEntry{
+			EventID: eventID,
+			Type:    changeType,
+			Actor:   actor,
+			At:      now,
+			Before:  before,
+			After:   after,
+		})
+		if err != nil {
+			err = fmt.Errorf("record history: %w", err)
+		}
+	}
+
+	s.publish(userID, models.Change{Type: changeType, EventID: eventID, Event: after, At: now}, before)
+	return err
+}
+
+// publish уведомляет подписчиков владельца и участников об изменении события.
+// Участники получают событие с заполненным Owner. Участники удаленного события
+// берутся из его состояния до удаления before.
+func (s *Service) publish(userID models.UserID, change models.Change, before *models.Event) {
+	if s.pub == nil {
+		return
+	}
+
+	var attendees []models.Attendee
+	if change.Event != nil {
+		e := *change.Event
+		change.Event = &e
+		attendees = e.Attendees
+	} else if before != nil {
+		attendees = before.Attendees
+	}
+
+	s.pub.Publish(userID, change)
+
+	for _, a := range attendees {
+		shared := change
+		if change.Event != nil {
+			e := *change.Event
+			e.Owner = userID
+			shared.Event = &e
+		}
+		s.pub.Publish(a.UserID, shared)
+	}
+}
+
+// validateEvent проверяет заданные поля события.
+func validateEvent(event models.Event) error {
+	if event.RRule != "" {
+		if _, err := rrule.Parse(event.RRule); err != nil {
+			return fmt.Errorf("%w: %v", service.ErrInvalidEvent, err)
+		}
+	}
+
+	if event.TimeZone != "" {
+		if _, err := time.LoadLocation(event.TimeZone); err != nil {
+			return fmt.Errorf("%w: %v", service.ErrInvalidEvent, err)
+		}
+	}
+
+	if !event.Date.IsZero() && !event.End.IsZero() && event.End.Before(event.Date) {
+		return fmt.Errorf("%w: end is before start", service.ErrInvalidEvent)
+	}
+
+	return nil
+}
+
+// validateOverrides проверяет измененные вхождения серии.
+func validateOverrides(event models.Event) error {
+	if len(event.Overrides) == 0 {
+		return nil
+	}
+
+	if !event.IsRecurring() {
+		return fmt.Errorf("%w: overrides require rrule", service.ErrInvalidEvent)
+	}
+
+	for _, o := range event.Overrides {
+		if o.RecurrenceID == nil {
+			return fmt.Errorf("%w: override without recurrence id", service.ErrInvalidEvent)
+		}
+		if !o.End.IsZero() && o.End.Before(o.Date) {
+			return fmt.Errorf("%w: end is before start", service.ErrInvalidEvent)
+		}
+	}
+
+	return nil
+}
+
+// mapError переводит ошибки изменения события в хранилище в ошибки пакета service.
+func mapError(err error) error {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return service.ErrNotFound
+	case errors.Is(err, repository.ErrVersionMismatch):
+		return service.ErrVersionMismatch
+	case errors.Is(err, repository.ErrQuotaExceeded):
+		return service.ErrQuotaExceeded
+	default:
+		return err
+	}
 }
@@ -1,10 +1,13 @@
 package events
 
 import (
+	"errors"
 	"testing"
 	"time"
 
+	"l2.18/internal/repository"
 	repomock "l2.18/internal/repository/mock"
+	"l2.18/internal/service"
 	"l2.18/pkg/models"
 )
 
@@ -37,7 +40,7 @@ func TestGetEventsForDay(t *testing.T) {
 
 	svc := &Service{repo: mockRepo}
 
-	got, err := svc.GetEventsForDay(userID, now)
+	got, err := svc.GetEventsForDay(userID, now, models.EventFilter{})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -81,7 +84,7 @@ func TestGetEventsForWeek(t *testing.T) {
 
 	svc := &Service{repo: mockRepo}
 
-	got, err := svc.GetEventsForWeek(userID, now)
+	got, err := svc.GetEventsForWeek(userID, now, models.EventFilter{})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -121,7 +124,7 @@ func TestGetEventsForMonth(t *testing.T) {
 
 	svc := &Service{repo: mockRepo}
 
-	got, err := svc.GetEventsForMonth(userID, refDate)
+	got, err := svc.GetEventsForMonth(userID, refDate, models.EventFilter{})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -130,3 +133,241 @@ func TestGetEventsForMonth(t *testing.T) {
 		t.Fatalf("expected %d events, got %d", len(testEvents), len(got))
 	}
 }
+
+func TestAddEventKeepsID(t *testing.T) {
+	stored := map[models.EventID]models.Event{}
+
+	mockRepo := &repomock.MockRepository{
+		PutFn: func(userID models.UserID, event models.Event) error {
+			if _, ok := stored[event.ID]; ok {
+				return repository.ErrAlreadyExist
+			}
+			stored[event.ID] = event
+			return nil
+		},
+	}
+
+	svc := New(mockRepo)
+	event := models.Event{ID: "meeting", Date: time.Now(), Event: "event"}
+
+	id, err := svc.AddEvent("user1", event)
+	if err != nil || id != "meeting" {
+		t.Fatalf("expected id %q, got %q, %v", "meeting", id, err)
+	}
+
+	if _, err := svc.AddEvent("user1", event); !errors.Is(err, service.ErrAlreadyExist) {
+		t.Errorf("expected %v, got %v", service.ErrAlreadyExist, err)
+	}
+
+	event.ID = ""
+	id, err = svc.AddEvent("user1", event)
+	if err != nil || id == "" {
+		t.Errorf("expected generated id, got %q, %v", id, err)
+	}
+}
+
+func TestAddEventQuotaExceeded(t *testing.T) {
+	mockRepo := &repomock.MockRepository{
+		PutFn: func(userID models.UserID, event models.Event) error {
+			return repository.ErrQuotaExceeded
+		},
+	}
+
+	svc := New(mockRepo)
+
+	_, err := svc.AddEvent("user1", models.Event{Date: time.Now(), Event: "event"})
+	if !errors.Is(err, service.ErrQuotaExceeded) {
+		t.Errorf("expected %v, got %v", service.ErrQuotaExceeded, err)
+	}
+}
+
+func TestReplaceEvent(t *testing.T) {
+	now := time.Now()
+
+	testCases := []struct {
+		name     string
+		input    models.Event
+		expected error
+	}{
+		{
+			name:  "success",
+			input: models.Event{ID: "1", Date: now, Event: "replaced"},
+		},
+		{
+			name:     "failure - not found",
+			input:    models.Event{ID: "2", Date: now, Event: "replaced"},
+			expected: service.ErrNotFound,
+		},
+		{
+			name:     "failure - overrides without rrule",
+			input:    models.Event{ID: "1", Date: now, Overrides: []models.Event{{Date: now, RecurrenceID: &now}}},
+			expected: service.ErrInvalidEvent,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var replaced *models.Event
+
+			mockRepo := &repomock.MockRepository{
+				ReplaceFn: func(userID models.UserID, event models.Event) error {
+					if event.ID != "1" {
+						return repository.ErrNotFound
+					}
+					replaced = &event
+					return nil
+				},
+			}
+
+			err := New(mockRepo).ReplaceEvent("user1", tc.input)
+			if !errors.Is(err, tc.expected) {
+				t.Fatalf("expected %v, got %v", tc.expected, err)
+			}
+			if err == nil && (replaced == nil || replaced.Event != tc.input.Event) {
+				t.Errorf("expected event to be replaced, got %+v", replaced)
+			}
+		})
+	}
+}
+
+func TestVersionMismatch(t *testing.T) {
+	monday := time.Date(2025, time.January, 6, 0, 0, 0, 0, time.UTC)
+	series := models.Event{ID: "s", Date: monday, Event: "standup", RRule: "FREQ=DAILY", Version: 2}
+
+	mockRepo := &repomock.MockRepository{
+		GetFn: func(userID models.UserID, eventID models.EventID) (*models.Event, error) {
+			e := series
+			return &e, nil
+		},
+		UpdateFn: func(userID models.UserID, event models.Event) error {
+			if !series.MatchesVersion(event.Version) {
+				return repository.ErrVersionMismatch
+			}
+			return nil
+		},
+		DeleteFn: func(userID models.UserID, eventID models.EventID, version int64) error {
+			return repository.ErrVersionMismatch
+		},
+	}
+
+	svc := New(mockRepo)
+
+	if err := svc.UpdateEvent("user1", models.Event{ID: "s", Event: "x", Version: 1}); !errors.Is(err, service.ErrVersionMismatch) {
+		t.Errorf("update: expected %v, got %v", service.ErrVersionMismatch, err)
+	}
+	if err := svc.RemoveEvent("user1", "s", 1); !errors.Is(err, service.ErrVersionMismatch) {
+		t.Errorf("remove: expected %v, got %v", service.ErrVersionMismatch, err)
+	}
+	if err := svc.RemoveOccurrence("user1", "s", monday.AddDate(0, 0, 1), 1); !errors.Is(err, service.ErrVersionMismatch) {
+		t.Errorf("remove occurrence: expected %v, got %v", service.ErrVersionMismatch, err)
+	}
+	if err := svc.RemoveOccurrence("user1", "s", monday.AddDate(0, 0, 1), 2); err != nil {
+		t.Errorf("remove occurrence: unexpected error: %v", err)
+	}
+}
+
+func TestUpdateEventValidatesMergedEvent(t *testing.T) {
+	start := time.Date(2025, time.February, 17, 10, 0, 0, 0, time.UTC)
+	stored := models.Event{ID: "1", Date: start, End: start.Add(time.Hour), Event: "meeting", Version: 1}
+
+	testCases := []struct {
+		name     string
+		patch    models.Event
+		expected error
+	}{
+		{
+			name:     "end before stored start",
+			patch:    models.Event{ID: "1", End: start.Add(-time.Hour)},
+			expected: service.ErrInvalidEvent,
+		},
+		{
+			name:     "start after stored end",
+			patch:    models.Event{ID: "1", Date: start.Add(2 * time.Hour)},
+			expected: service.ErrInvalidEvent,
+		},
+		{
+			name:  "start within stored end",
+			patch: models.Event{ID: "1", Date: start.Add(30 * time.Minute)},
+		},
+		{
+			name:     "not found",
+			patch:    models.Event{ID: "2", Event: "x"},
+			expected: service.ErrNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			updated := false
+			mockRepo := &repomock.MockRepository{
+				GetFn: func(userID models.UserID, eventID models.EventID) (*models.Event, error) {
+					if eventID != stored.ID {
+						return nil, repository.ErrNotFound
+					}
+					e := stored
+					return &e, nil
+				},
+				UpdateFn: func(userID models.UserID, event models.Event) error {
+					updated = true
+					return nil
+				},
+			}
+
+			err := New(mockRepo).UpdateEvent("user1", tc.patch)
+			if !errors.Is(err, tc.expected) {
+				t.Fatalf("expected %v, got %v", tc.expected, err)
+			}
+			if updated != (tc.expected == nil) {
+				t.Errorf("expected update %v, got %v", tc.expected == nil, updated)
+			}
+		})
+	}
+}
+
+func TestUpdateEventReadsOnceInTransaction(t *testing.T) {
+	start := time.Date(2025, time.February, 17, 10, 0, 0, 0, time.UTC)
+	stored := models.Event{
+		ID: "1", Date: start, End: start.Add(time.Hour), Event: "meeting", Version: 1,
+		Attendees: []models.Attendee{{UserID: "bob", Status: models.RSVPAccepted}},
+	}
+
+	var (
+		inTx    bool
+		gets    int
+		updated models.Event
+	)
+	txRepo := &repomock.MockRepository{
+		GetFn: func(userID models.UserID, eventID models.EventID) (*models.Event, error) {
+			gets++
+			e := stored
+			return &e, nil
+		},
+		UpdateFn: func(userID models.UserID, event models.Event) error {
+			if !inTx {
+				t.Error("expected update inside transaction")
+			}
+			updated = event
+			return nil
+		},
+	}
+	mockRepo := &repomock.MockRepository{
+		TransactionFn: func(fn func(tx repository.Tx) error) error {
+			inTx = true
+			defer func() { inTx = false }()
+			return fn(txRepo)
+		},
+	}
+
+	s := New(mockRepo, WithConflictCheck())
+	patch := models.Event{ID: "1", Date: start.Add(30 * time.Minute), Attendees: []models.Attendee{{UserID: "bob"}}}
+	if err := s.UpdateEvent("user1", patch); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if gets != 1 {
+		t.Errorf("expected event to be read once, got %d reads", gets)
+	}
+	if len(updated.Attendees) != 1 || updated.Attendees[0].Status != models.RSVPAccepted {
+		t.Errorf("expected attendee status to be kept, got %+v", updated.Attendees)
+	}
+}
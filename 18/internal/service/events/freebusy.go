@@ -0,0 +1,109 @@
+package events
+
+import (
+	"sort"
+	"time"
+
+	"l2.18/pkg/models"
+)
+
+// FreeBusy возвращает объединенную занятость пользователей в окне [start, end)
+// и до limit свободных для всех промежутков длительностью duration.
+// limit <= 0 снимает ограничение. События без длительности время не занимают.
+func (s *Service) FreeBusy(
+	userIDs []models.UserID, start, end time.Time, duration time.Duration, limit int,
+) (*models.FreeBusy, error) {
+	var busy []models.Interval
+
+	for _, userID := range userIDs {
+		events, err := s.getEvents(userID, start, end, models.EventFilter{})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, e := range events {
+			if e.Duration() <= 0 {
+				continue
+			}
+			busy = append(busy, models.Interval{Start: maxTime(e.Date, start), End: minTime(e.End, end)})
+		}
+	}
+
+	busy = mergeIntervals(busy)
+
+	return &models.FreeBusy{
+		Busy: busy,
+		Free: freeSlots(busy, start, end, duration, limit),
+	}, nil
+}
+
+// mergeIntervals объединяет пересекающиеся и смежные промежутки.
+func mergeIntervals(intervals []models.Interval) []models.Interval {
+	result := make([]models.Interval, 0, len(intervals))
+	if len(intervals) == 0 {
+		return result
+	}
+
+	sort.Slice(intervals, func(i, j int) bool {
+		return intervals[i].Start.Before(intervals[j].Start)
+	})
+
+	cur := intervals[0]
+	for _, in := range intervals[1:] {
+		if in.Start.After(cur.End) {
+			result = append(result, cur)
+			cur = in
+			continue
+		}
+		cur.End = maxTime(cur.End, in.End)
+	}
+
+	return append(result, cur)
+}
+
+// freeSlots разбивает промежутки окна [start, end), не занятые busy, на слоты
+// длительностью duration, начиная с начала каждого свободного промежутка.
+// busy должны быть объединены и отсортированы.
+func freeSlots(busy []models.Interval, start, end time.Time, duration time.Duration, limit int) []models.Interval {
+	result := make([]models.Interval, 0)
+	if duration <= 0 {
+		return result
+	}
+
+	gaps := make([]models.Interval, 0, len(busy)+1)
+	cur := start
+	for _, b := range busy {
+		if b.Start.After(cur) {
+			gaps = append(gaps, models.Interval{Start: cur, End: b.Start})
+		}
+		cur = maxTime(cur, b.End)
+	}
+	if end.After(cur) {
+		gaps = append(gaps, models.Interval{Start: cur, End: end})
+	}
+
+	for _, gap := range gaps {
+		for t := gap.Start; !t.Add(duration).After(gap.End); t = t.Add(duration) {
+			if limit > 0 && len(result) == limit {
+				return result
+			}
+			result = append(result, models.Interval{Start: t, End: t.Add(duration)})
+		}
+	}
+
+	return result
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,93 @@
+package events
+
+import (
+	"testing"
+	"time"
+
+	repomock "l2.18/internal/repository/mock"
+	"l2.18/pkg/models"
+)
+
+func TestFreeBusy(t *testing.T) {
+	day := time.Date(2025, time.February, 17, 0, 0, 0, 0, time.UTC)
+	at := func(hour, minute int) time.Time {
+		return day.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+	}
+
+	calendars := map[models.UserID][]models.Event{
+		"alice": {
+			{ID: "1", Date: at(8, 0), End: at(9, 30)},
+			{ID: "2", Date: at(12, 0), End: at(13, 0)},
+			{ID: "3", Date: at(11, 0), Event: "no duration"},
+		},
+		"bob": {
+			{ID: "4", Date: at(9, 0), End: at(10, 0)},
+			{ID: "5", Date: at(13, 0), End: at(14, 0)},
+			{ID: "6", Date: at(17, 30), End: at(19, 0)},
+		},
+	}
+
+	mockRepo := &repomock.MockRepository{
+		GetEventsByDateRangeFn: func(userID models.UserID, start, end time.Time) ([]models.Event, error) {
+			var result []models.Event
+			for _, e := range calendars[userID] {
+				if e.Overlaps(start, end) {
+					result = append(result, e)
+				}
+			}
+			return result, nil
+		},
+		GetRecurringEventsFn: func(userID models.UserID) ([]models.Event, error) {
+			return nil, nil
+		},
+	}
+
+	svc := New(mockRepo)
+
+	got, err := svc.FreeBusy([]models.UserID{"alice", "bob"}, at(9, 0), at(18, 0), time.Hour, 4)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	wantBusy := []models.Interval{
+		{Start: at(9, 0), End: at(10, 0)},
+		{Start: at(12, 0), End: at(14, 0)},
+		{Start: at(17, 30), End: at(18, 0)},
+	}
+	wantFree := []models.Interval{
+		{Start: at(10, 0), End: at(11, 0)},
+		{Start: at(11, 0), End: at(12, 0)},
+		{Start: at(14, 0), End: at(15, 0)},
+		{Start: at(15, 0), End: at(16, 0)},
+	}
+
+	assertIntervals(t, "busy", got.Busy, wantBusy)
+	assertIntervals(t, "free", got.Free, wantFree)
+}
+
+func TestFreeSlots(t *testing.T) {
+	start := time.Date(2025, time.February, 17, 9, 0, 0, 0, time.UTC)
+	end := start.Add(3 * time.Hour)
+
+	// Слот не помещается в свободный промежуток короче его.
+	busy := []models.Interval{{Start: start.Add(40 * time.Minute), End: start.Add(2 * time.Hour)}}
+	got := freeSlots(busy, start, end, time.Hour, 0)
+	assertIntervals(t, "free", got, []models.Interval{{Start: start.Add(2 * time.Hour), End: end}})
+
+	if got := freeSlots(nil, start, end, 0, 0); len(got) != 0 {
+		t.Errorf("expected no slots for zero duration, got %v", got)
+	}
+}
+
+func assertIntervals(t *testing.T, name string, got, want []models.Interval) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Fatalf("%s: expected %v, got %v", name, want, got)
+	}
+	for i := range got {
+		if !got[i].Start.Equal(want[i].Start) || !got[i].End.Equal(want[i].End) {
+			t.Errorf("%s[%d]: expected %v, got %v", name, i, want[i], got[i])
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"l2.18/internal/repository"
+	"l2.18/internal/service"
+	"l2.18/pkg/models"
+)
+
+type historyStore interface {
+	AddHistory(userID models.UserID, entry models.HistoryEntry) error
+	GetHistory(userID models.UserID, eventID models.EventID) ([]models.HistoryEntry, error)
+	PruneHistory(before time.Time) error
+}
+
+// WithHistory включает запись истории изменений событий в store. Записи старше
+// retention не возвращаются, не восстанавливаются и удаляются RunHistoryPruning;
+// 0 - хранить историю бессрочно.
+func WithHistory(store historyStore, retention time.Duration) Option {
+	return func(s *Service) {
+		s.history = store
+		s.retention = retention
+	}
+}
+
+// History возвращает записи истории события в пределах срока хранения по возрастанию
+// номера. История удаленного события тоже доступна. Если записей нет - вернет service.ErrNotFound.
+func (s *Service) History(userID models.UserID, eventID models.EventID) ([]models.HistoryEntry, error) {
+	if s.history == nil {
+		return nil, service.ErrNotFound
+	}
+
+	entries, err := s.history.GetHistory(userID, eventID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := entries[:0]
+	for _, e := range entries {
+		if s.retained(e) {
+			result = append(result, e)
+		}
+	}
+	if len(result) == 0 {
+		return nil, service.ErrNotFound
+	}
+
+	return result, nil
+}
+
+// RestoreEvent возвращает событие к состоянию из записи истории revision: для удаления -
+// к состоянию перед удалением, для остальных изменений - после изменения. revision 0
+// восстанавливает удаленное событие в состоянии перед последним удалением, если событие
+// не удалено - вернет service.ErrAlreadyExist. version - ожидаемая версия существующего
+// события, как в ReplaceEvent. Восстановление записывается в историю как новое изменение.
+func (s *Service) RestoreEvent(userID models.UserID, eventID models.EventID, revision, version int64) error {
+	entries, err := s.History(userID, eventID)
+	if err != nil {
+		return err
+	}
+
+	var target *models.Event
+	if revision == 0 {
+		last := entries[len(entries)-1]
+		if last.Type != models.ChangeDeleted {
+			return fmt.Errorf("%w: event is not deleted", service.ErrAlreadyExist)
+		}
+		target = last.Before
+	} else {
+		for _, e := range entries {
+			if e.Revision == revision {
+				target = e.Snapshot()
+			}
+		}
+	}
+	if target == nil {
+		return service.ErrNotFound
+	}
+
+	restored := *target
+	restored.ID = eventID
+	restored.Owner = ""
+	restored.Version = version
+
+	_, err = s.repo.Get(userID, eventID)
+	if errors.Is(err, repository.ErrNotFound) {
+		// У удаленного события нет версии, с которой можно сравнить ожидаемую.
+		if version != 0 {
+			return service.ErrVersionMismatch
+		}
+		_, err = s.AddEvent(userID, restored)
+		return err
+	} else if err != nil {
+		return err
+	}
+
+	return s.ReplaceEvent(userID, restored)
+}
+
+// RunHistoryPruning раз в interval удаляет записи истории старше срока хранения,
+// пока не будет отменен ctx.
+func (s *Service) RunHistoryPruning(ctx context.Context, interval time.Duration) error {
+	if s.history == nil || s.retention == 0 {
+		return nil
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := s.history.PruneHistory(time.Now().Add(-s.retention)); err != nil {
+				return fmt.Errorf("prune history: %w", err)
+			}
+		}
+	}
+}
+
+// retained сообщает, не истек ли срок хранения записи истории.
+func (s *Service) retained(entry models.HistoryEntry) bool {
+	return s.retention == 0 || time.Since(entry.At) < s.retention
+}
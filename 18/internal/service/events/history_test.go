@@ -0,0 +1,173 @@
+package events
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"l2.18/internal/repository"
+	repomock "l2.18/internal/repository/mock"
+	"l2.18/internal/service"
+	"l2.18/pkg/models"
+)
+
+// newHistoryService создает сервис над одним хранимым событием и историей в памяти.
+func newHistoryService(retention time.Duration) (*Service, *[]models.HistoryEntry) {
+	var (
+		stored  *models.Event
+		history []models.HistoryEntry
+	)
+
+	mockRepo := &repomock.MockRepository{
+		PutFn: func(userID models.UserID, event models.Event) error {
+			if stored != nil {
+				return repository.ErrAlreadyExist
+			}
+			event.Version = 1
+			stored = &event
+			return nil
+		},
+		GetFn: func(userID models.UserID, eventID models.EventID) (*models.Event, error) {
+			if stored == nil || stored.ID != eventID {
+				return nil, repository.ErrNotFound
+			}
+			e := *stored
+			return &e, nil
+		},
+		ReplaceFn: func(userID models.UserID, event models.Event) error {
+			if stored == nil {
+				return repository.ErrNotFound
+			}
+			if !stored.MatchesVersion(event.Version) {
+				return repository.ErrVersionMismatch
+			}
+			event.Version = stored.Version + 1
+			stored = &event
+			return nil
+		},
+		DeleteFn: func(userID models.UserID, eventID models.EventID, version int64) error {
+			stored = nil
+			return nil
+		},
+		AddHistoryFn: func(userID models.UserID, entry models.HistoryEntry) error {
+			entry.Revision = int64(len(history) + 1)
+			history = append(history, entry)
+			return nil
+		},
+		GetHistoryFn: func(userID models.UserID, eventID models.EventID) ([]models.HistoryEntry, error) {
+			return append([]models.HistoryEntry(nil), history...), nil
+		},
+	}
+
+	return New(mockRepo, WithHistory(mockRepo, retention)), &history
+}
+
+func TestHistoryAndRestore(t *testing.T) {
+	svc, history := newHistoryService(time.Hour)
+	date := time.Date(2025, time.January, 6, 10, 0, 0, 0, time.UTC)
+
+	id, err := svc.AddEvent("user1", models.Event{ID: "e", Date: date, Event: "first"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := svc.ReplaceEvent("user1", models.Event{ID: id, Date: date, Event: "second"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := svc.RestoreEvent("user1", id, 0, 0); !errors.Is(err, service.ErrAlreadyExist) {
+		t.Errorf("undelete existing: expected %v, got %v", service.ErrAlreadyExist, err)
+	}
+	if err := svc.RemoveEvent("user1", id, 0); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	entries, err := svc.History("user1", id)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []models.ChangeType{models.ChangeCreated, models.ChangeUpdated, models.ChangeDeleted}
+	if len(entries) != len(expected) {
+		t.Fatalf("expected %d entries, got %+v", len(expected), entries)
+	}
+	for i, changeType := range expected {
+		if entries[i].Type != changeType || entries[i].Actor != "user1" {
+			t.Errorf("entry[%d]: got %+v, want %s", i, entries[i], changeType)
+		}
+	}
+	if entries[1].Before.Event != "first" || entries[1].After.Event != "second" || entries[2].After != nil {
+		t.Errorf("unexpected snapshots %+v", entries)
+	}
+
+	if err := svc.RestoreEvent("user1", id, 0, 1); !errors.Is(err, service.ErrVersionMismatch) {
+		t.Errorf("undelete with version: expected %v, got %v", service.ErrVersionMismatch, err)
+	}
+	if err := svc.RestoreEvent("user1", id, 0, 0); err != nil {
+		t.Fatalf("undelete: unexpected error: %v", err)
+	}
+	if event, _ := svc.GetEvent("user1", id); event == nil || event.Event != "second" {
+		t.Errorf("expected undeleted event, got %+v", event)
+	}
+
+	if err := svc.RestoreEvent("user1", id, 1, 0); err != nil {
+		t.Fatalf("restore: unexpected error: %v", err)
+	}
+	if event, _ := svc.GetEvent("user1", id); event.Event != "first" || event.Version != 2 {
+		t.Errorf("expected first revision in version 2, got %+v", event)
+	}
+	if err := svc.RestoreEvent("user1", id, 42, 0); !errors.Is(err, service.ErrNotFound) {
+		t.Errorf("unknown revision: expected %v, got %v", service.ErrNotFound, err)
+	}
+
+	last := (*history)[len(*history)-1]
+	if len(*history) != 5 || last.Type != models.ChangeUpdated || last.After.Event != "first" {
+		t.Errorf("expected restore to be recorded, got %+v", *history)
+	}
+}
+
+func TestHistoryRetention(t *testing.T) {
+	svc, history := newHistoryService(time.Hour)
+
+	*history = []models.HistoryEntry{{
+		Revision: 1, EventID: "e", Type: models.ChangeDeleted, At: time.Now().Add(-2 * time.Hour),
+		Before: &models.Event{ID: "e", Date: time.Now()},
+	}}
+
+	if _, err := svc.History("user1", "e"); !errors.Is(err, service.ErrNotFound) {
+		t.Errorf("expected %v, got %v", service.ErrNotFound, err)
+	}
+	if err := svc.RestoreEvent("user1", "e", 0, 0); !errors.Is(err, service.ErrNotFound) {
+		t.Errorf("expected %v, got %v", service.ErrNotFound, err)
+	}
+}
+
+func TestHistorySnapshotsAreCopies(t *testing.T) {
+	// Как хранилище в памяти: Get возвращает хранимое событие, Replace изменяет его на месте.
+	stored := &models.Event{}
+	var history []models.HistoryEntry
+
+	mockRepo := &repomock.MockRepository{
+		PutFn: func(userID models.UserID, event models.Event) error {
+			*stored = event
+			return nil
+		},
+		GetFn: func(userID models.UserID, eventID models.EventID) (*models.Event, error) {
+			return stored, nil
+		},
+		ReplaceFn: func(userID models.UserID, event models.Event) error {
+			*stored = event
+			return nil
+		},
+		AddHistoryFn: func(userID models.UserID, entry models.HistoryEntry) error {
+			history = append(history, entry)
+			return nil
+		},
+	}
+	svc := New(mockRepo, WithHistory(mockRepo, 0))
+
+	date := time.Date(2025, time.January, 6, 10, 0, 0, 0, time.UTC)
+	_, _ = svc.AddEvent("user1", models.Event{ID: "e", Date: date, Event: "first"})
+	_ = svc.ReplaceEvent("user1", models.Event{ID: "e", Date: date, Event: "second"})
+
+	if len(history) != 2 || history[0].After.Event != "first" || history[1].Before.Event != "first" {
+		t.Errorf("expected snapshots before replace to be kept, got %+v", history)
+	}
+}
@@ -0,0 +1,122 @@
+package events
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"l2.18/internal/service"
+	"l2.18/pkg/models"
+)
+
+// Ограничения метаданных события.
+const (
+	maxTags           = 32
+	maxTagLength      = 64
+	maxAttributes     = 64
+	maxAttributeKey   = 64
+	maxAttributeValue = 1024
+)
+
+var colorPattern = regexp.MustCompile(`^#[0-9a-f]{6}$`)
+
+// prepareMetadata проверяет метаданные события и приводит их к виду, в котором
+// они хранятся: категория, цвет и метки - в нижнем регистре, метки - без повторов
+// и по возрастанию.
+func prepareMetadata(event *models.Event) error {
+	event.Category = normalizeTag(event.Category)
+	if len(event.Category) > maxTagLength {
+		return fmt.Errorf("%w: category is longer than %d bytes", service.ErrInvalidEvent, maxTagLength)
+	}
+
+	event.Color = strings.ToLower(event.Color)
+	if event.Color != "" && !colorPattern.MatchString(event.Color) {
+		return fmt.Errorf("%w: color must be #rrggbb", service.ErrInvalidEvent)
+	}
+
+	if event.Tags != nil {
+		tags, err := normalizeTags(event.Tags)
+		if err != nil {
+			return err
+		}
+		event.Tags = tags
+	}
+
+	if len(event.Attributes) > maxAttributes {
+		return fmt.Errorf("%w: more than %d attributes", service.ErrInvalidEvent, maxAttributes)
+	}
+	for key, value := range event.Attributes {
+		if key == "" || len(key) > maxAttributeKey {
+			return fmt.Errorf("%w: attribute name must be 1 to %d bytes", service.ErrInvalidEvent, maxAttributeKey)
+		}
+		if len(value) > maxAttributeValue {
+			return fmt.Errorf("%w: attribute %q is longer than %d bytes", service.ErrInvalidEvent, key, maxAttributeValue)
+		}
+	}
+
+	return nil
+}
+
+// normalizeTags приводит метки к нижнему регистру, удаляет повторы и сортирует.
+func normalizeTags(tags []string) ([]string, error) {
+	result := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = normalizeTag(tag)
+		if tag == "" {
+			return nil, fmt.Errorf("%w: empty tag", service.ErrInvalidEvent)
+		}
+		if len(tag) > maxTagLength {
+			return nil, fmt.Errorf("%w: tag is longer than %d bytes", service.ErrInvalidEvent, maxTagLength)
+		}
+		result = append(result, tag)
+	}
+
+	sort.Strings(result)
+	result = slices.Compact(result)
+	if len(result) > maxTags {
+		return nil, fmt.Errorf("%w: more than %d tags", service.ErrInvalidEvent, maxTags)
+	}
+
+	return result, nil
+}
+
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
+
+// prepareFilter приводит метки и категорию фильтра к виду, в котором они хранятся.
+// Пустые метки пропускаются.
+func prepareFilter(filter models.EventFilter) models.EventFilter {
+	filter.Category = normalizeTag(filter.Category)
+	if len(filter.Tags) == 0 {
+		return filter
+	}
+
+	tags := make([]string, 0, len(filter.Tags))
+	for _, tag := range filter.Tags {
+		if tag = normalizeTag(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	sort.Strings(tags)
+	filter.Tags = slices.Compact(tags)
+
+	return filter
+}
+
+// filterEvents возвращает события, подходящие filter. Исходный срез не изменяется.
+func filterEvents(events []models.Event, filter models.EventFilter) []models.Event {
+	if filter.IsEmpty() {
+		return events
+	}
+
+	result := make([]models.Event, 0, len(events))
+	for _, e := range events {
+		if filter.Matches(&e) {
+			result = append(result, e)
+		}
+	}
+	return result
+}
@@ -0,0 +1,114 @@
+package events
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	repomock "l2.18/internal/repository/mock"
+	"l2.18/internal/service"
+	"l2.18/pkg/models"
+)
+
+func TestAddEventNormalizesMetadata(t *testing.T) {
+	var stored models.Event
+
+	mockRepo := &repomock.MockRepository{
+		PutFn: func(userID models.UserID, event models.Event) error {
+			stored = event
+			return nil
+		},
+	}
+
+	_, err := New(mockRepo).AddEvent("user1", models.Event{
+		Date:     time.Now(),
+		Event:    "review",
+		Category: " Work ",
+		Color:    "#FF8800",
+		Tags:     []string{"Team", "urgent", " team"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if stored.Category != "work" || stored.Color != "#ff8800" || !slices.Equal(stored.Tags, []string{"team", "urgent"}) {
+		t.Errorf("metadata is not normalized: %+v", stored)
+	}
+}
+
+func TestAddEventInvalidMetadata(t *testing.T) {
+	testCases := []struct {
+		name  string
+		event models.Event
+	}{
+		{name: "empty tag", event: models.Event{Tags: []string{"team", " "}}},
+		{name: "long tag", event: models.Event{Tags: []string{strings.Repeat("t", maxTagLength+1)}}},
+		{name: "color name", event: models.Event{Color: "orange"}},
+		{name: "short color", event: models.Event{Color: "#f80"}},
+		{name: "empty attribute name", event: models.Event{Attributes: map[string]string{"": "x"}}},
+		{name: "long attribute", event: models.Event{Attributes: map[string]string{"a": strings.Repeat("x", maxAttributeValue+1)}}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockRepo := &repomock.MockRepository{
+				PutFn: func(userID models.UserID, event models.Event) error {
+					t.Error("invalid event must not be stored")
+					return nil
+				},
+			}
+
+			tc.event.Date = time.Now()
+			if _, err := New(mockRepo).AddEvent("user1", tc.event); !errors.Is(err, service.ErrInvalidEvent) {
+				t.Errorf("expected %v, got %v", service.ErrInvalidEvent, err)
+			}
+		})
+	}
+}
+
+func TestGetEventsFilter(t *testing.T) {
+	monday := time.Date(2025, time.January, 6, 0, 0, 0, 0, time.UTC)
+
+	tagged := models.Event{ID: "s", Date: monday, Event: "standup", RRule: "FREQ=DAILY", Tags: []string{"team"}}
+	untagged := models.Event{ID: "u", Date: monday, Event: "lunch", RRule: "FREQ=DAILY"}
+
+	mockRepo := &repomock.MockRepository{
+		GetEventsByDateRangeFn: func(userID models.UserID, start, end time.Time) ([]models.Event, error) {
+			t.Error("filtered query must use GetFilteredEvents")
+			return nil, nil
+		},
+		GetFilteredEventsFn: func(userID models.UserID, start, end time.Time, filter models.EventFilter) ([]models.Event, error) {
+			if !slices.Equal(filter.Tags, []string{"team"}) {
+				t.Errorf("expected normalized filter, got %+v", filter)
+			}
+			return []models.Event{{ID: "1", Date: monday.Add(time.Hour), Tags: []string{"team"}}}, nil
+		},
+		GetRecurringEventsFn: func(userID models.UserID) ([]models.Event, error) {
+			return []models.Event{tagged, untagged}, nil
+		},
+	}
+
+	svc := New(mockRepo)
+	filter := models.EventFilter{Tags: []string{"Team", ""}}
+
+	got, err := svc.GetEventsForDay("user1", monday, filter)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 2 || got[0].ID != "s" || got[1].ID != "1" {
+		t.Fatalf("expected [s 1], got %+v", got)
+	}
+	if !slices.Equal(got[0].Tags, []string{"team"}) {
+		t.Errorf("occurrence must inherit series tags, got %+v", got[0])
+	}
+
+	list, err := svc.ListEvents("user1", monday, monday.AddDate(0, 0, 7), filter)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(list) != 2 || list[0].ID != "s" || list[1].ID != "1" {
+		t.Errorf("expected [s 1], got %+v", list)
+	}
+}
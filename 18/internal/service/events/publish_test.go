@@ -0,0 +1,76 @@
+package events
+
+import (
+	"testing"
+	"time"
+
+	"l2.18/internal/repository"
+	repomock "l2.18/internal/repository/mock"
+	"l2.18/pkg/models"
+)
+
+type recordingPublisher struct {
+	changes []models.Change
+}
+
+func (p *recordingPublisher) Publish(userID models.UserID, change models.Change) {
+	p.changes = append(p.changes, change)
+}
+
+func TestPublishChanges(t *testing.T) {
+	stored := models.Event{Date: time.Now(), Event: "event"}
+
+	mockRepo := &repomock.MockRepository{
+		PutFn: func(userID models.UserID, event models.Event) error {
+			stored = event
+			return nil
+		},
+		GetFn: func(userID models.UserID, eventID models.EventID) (*models.Event, error) {
+			e := stored
+			return &e, nil
+		},
+		UpdateFn: func(userID models.UserID, event models.Event) error {
+			if event.ID != stored.ID {
+				return repository.ErrNotFound
+			}
+			stored.Merge(event)
+			return nil
+		},
+		DeleteFn: func(userID models.UserID, eventID models.EventID, version int64) error {
+			return nil
+		},
+	}
+
+	pub := &recordingPublisher{}
+	svc := New(mockRepo, WithPublisher(pub))
+
+	id, err := svc.AddEvent("user1", stored)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := svc.UpdateEvent("user1", models.Event{ID: id, Event: "updated"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := svc.UpdateEvent("user1", models.Event{ID: "missing", Event: "updated"}); err == nil {
+		t.Fatal("expected error")
+	}
+	if err := svc.RemoveEvent("user1", id, 0); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []models.ChangeType{models.ChangeCreated, models.ChangeUpdated, models.ChangeDeleted}
+	if len(pub.changes) != len(expected) {
+		t.Fatalf("expected %d changes, got %+v", len(expected), pub.changes)
+	}
+	for i, changeType := range expected {
+		if pub.changes[i].Type != changeType || pub.changes[i].EventID != id {
+			t.Errorf("change[%d]: got %+v, want %s", i, pub.changes[i], changeType)
+		}
+	}
+	if pub.changes[1].Event == nil || pub.changes[1].Event.Event != "updated" {
+		t.Errorf("expected updated event in change, got %+v", pub.changes[1].Event)
+	}
+	if pub.changes[2].Event != nil {
+		t.Errorf("expected no event in delete change, got %+v", pub.changes[2].Event)
+	}
+}
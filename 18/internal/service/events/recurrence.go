@@ -0,0 +1,306 @@
+package events
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"l2.18/internal/repository"
+	"l2.18/internal/service"
+	"l2.18/pkg/models"
+	"l2.18/pkg/rrule"
+)
+
+// UpdateOccurrence изменяет только одно вхождение серии eventID, начинающееся
+// в occurrence. Остальные вхождения серии не затрагиваются. event.Version -
+// ожидаемая версия серии.
+func (s *Service) UpdateOccurrence(userID models.UserID, event models.Event, occurrence time.Time) error {
+	master, rule, err := s.getSeries(userID, event.ID)
+	if err != nil {
+		return err
+	}
+	if !master.MatchesVersion(event.Version) {
+		return service.ErrVersionMismatch
+	}
+
+	if !isOccurrence(master, rule, occurrence) {
+		return service.ErrNotFound
+	}
+
+	override := models.Event{Date: occurrence, Event: master.Event}
+	if !master.End.IsZero() {
+		override.End = occurrence.Add(master.Duration())
+	}
+	overrides := make([]models.Event, 0, len(master.Overrides)+1)
+	for _, o := range master.Overrides {
+		if o.RecurrenceID != nil && o.RecurrenceID.Equal(occurrence) {
+			override = o
+			continue
+		}
+		overrides = append(overrides, o)
+	}
+
+	override.Merge(models.Event{Date: event.Date, End: event.End, Event: event.Event})
+	if !override.End.IsZero() && override.End.Before(override.Date) {
+		return fmt.Errorf("%w: end is before start", service.ErrInvalidEvent)
+	}
+	override.RecurrenceID = &occurrence
+	overrides = append(overrides, override)
+
+	probe := override
+	probe.ID = master.ID
+	if err := s.checkConflicts(userID, probe); err != nil {
+		return err
+	}
+
+	return s.updateSeries(userID, models.Event{ID: master.ID, Overrides: overrides, Version: master.Version})
+}
+
+// RemoveOccurrence удаляет только одно вхождение серии eventID,
+// начинающееся в occurrence, добавляя его в исключения серии.
+// version - ожидаемая версия серии, 0 - без проверки.
+func (s *Service) RemoveOccurrence(
+	userID models.UserID, eventID models.EventID, occurrence time.Time, version int64,
+) error {
+	master, rule, err := s.getSeries(userID, eventID)
+	if err != nil {
+		return err
+	}
+	if !master.MatchesVersion(version) {
+		return service.ErrVersionMismatch
+	}
+
+	if !isOccurrence(master, rule, occurrence) {
+		return service.ErrNotFound
+	}
+
+	overrides := make([]models.Event, 0, len(master.Overrides))
+	for _, o := range master.Overrides {
+		if o.RecurrenceID == nil || !o.RecurrenceID.Equal(occurrence) {
+			overrides = append(overrides, o)
+		}
+	}
+
+	return s.updateSeries(userID, models.Event{
+		ID:        master.ID,
+		ExDates:   append(slices.Clone(master.ExDates), occurrence),
+		Overrides: overrides,
+		Version:   master.Version,
+	})
+}
+
+// getEvents возвращает события пользователя, пересекающиеся с диапазоном [start, end),
+// разворачивая серии повторяющихся событий во вхождения. В результат попадают
+// и события, в которые пользователь приглашен и от которых не отказался.
+// Возвращаются только события, подходящие filter.
+func (s *Service) getEvents(userID models.UserID, start, end time.Time, filter models.EventFilter) ([]models.Event, error) {
+	events, err := s.eventsInRange(userID, start, end, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	series, err := s.recurringEvents(userID, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	invited, err := s.invitedEvents(userID, start, end)
+	if err != nil {
+		return nil, err
+	}
+	invited = filterEvents(invited, filter)
+	if len(series) == 0 && len(invited) == 0 {
+		return events, nil
+	}
+
+	result := make([]models.Event, 0, len(events)+len(invited))
+	for _, e := range events {
+		if !e.IsRecurring() {
+			result = append(result, e)
+		}
+	}
+	result = append(result, invited...)
+
+	for _, master := range series {
+		occurrences, err := expand(master, start, end)
+		if err != nil {
+			return nil, fmt.Errorf("expand series %s: %w", master.ID, err)
+		}
+		result = append(result, occurrences...)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Date.Before(result[j].Date)
+	})
+
+	return result, nil
+}
+
+// eventsInRange возвращает события пользователя, пересекающиеся с диапазоном
+// [start, end) и подходящие filter. Непустой фильтр применяет хранилище по своим индексам.
+func (s *Service) eventsInRange(userID models.UserID, start, end time.Time, filter models.EventFilter) ([]models.Event, error) {
+	if filter.IsEmpty() {
+		return s.repo.GetEventsByDateRange(userID, start, end)
+	}
+	return s.repo.GetFilteredEvents(userID, start, end, filter)
+}
+
+// recurringEvents возвращает серии пользователя, подходящие filter.
+// Вхождения наследуют метаданные серии, поэтому фильтр применяется к ней.
+func (s *Service) recurringEvents(userID models.UserID, filter models.EventFilter) ([]models.Event, error) {
+	series, err := s.repo.GetRecurringEvents(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return filterEvents(series, filter), nil
+}
+
+// getSeries возвращает серию и ее разобранное правило повторения.
+func (s *Service) getSeries(userID models.UserID, eventID models.EventID) (*models.Event, *rrule.Rule, error) {
+	master, err := s.repo.Get(userID, eventID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil, service.ErrNotFound
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	if !master.IsRecurring() {
+		return nil, nil, fmt.Errorf("%w: event is not recurring", service.ErrInvalidEvent)
+	}
+
+	rule, err := rrule.Parse(master.RRule)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return master, rule, nil
+}
+
+// updateSeries изменяет серию, прочитанную в версии patch.Version: если серию
+// изменили после getSeries, вернет service.ErrVersionMismatch.
+func (s *Service) updateSeries(userID models.UserID, patch models.Event) error {
+	before := s.current(userID, patch.ID)
+	if err := mapError(s.repo.Update(userID, patch)); err != nil {
+		return err
+	}
+
+	return s.changed(userID, userID, models.ChangeUpdated, patch.ID, before)
+}
+
+// expand разворачивает серию во вхождения, пересекающиеся с [start, end),
+// с учетом исключенных и измененных вхождений. Вхождения вычисляются
+// в часовом поясе серии.
+func expand(master models.Event, start, end time.Time) ([]models.Event, error) {
+	rule, err := rrule.Parse(master.RRule)
+	if err != nil {
+		return nil, err
+	}
+
+	loc := master.Location()
+	duration := master.Duration()
+
+	var result []models.Event
+	for _, o := range master.Overrides {
+		if o.RecurrenceID != nil && o.Overlaps(start, end) {
+			result = append(result, occurrence(master, o, *o.RecurrenceID))
+		}
+	}
+
+	skip := skipped(master)
+	for _, t := range rule.Between(master.Date.In(loc), start.Add(-duration), end) {
+		if skip[t.UnixNano()] {
+			continue
+		}
+
+		data := models.Event{Date: t, Event: master.Event}
+		if !master.End.IsZero() {
+			data.End = t.Add(duration)
+		}
+		if data.Overlaps(start, end) {
+			result = append(result, occurrence(master, data, t))
+		}
+	}
+
+	return result, nil
+}
+
+// hasOccurrences сообщает, пересекается ли с диапазоном [start, end) хотя бы одно
+// вхождение серии master. В отличие от expand вхождения не создаются, а перебор
+// останавливается на первом подходящем: для серии без конца и широкого диапазона
+// (например, выгрузки всех событий) это одно вхождение вместо тысяч.
+func hasOccurrences(master models.Event, start, end time.Time) (bool, error) {
+	rule, err := rrule.Parse(master.RRule)
+	if err != nil {
+		return false, err
+	}
+
+	for _, o := range master.Overrides {
+		if o.RecurrenceID != nil && o.Overlaps(start, end) {
+			return true, nil
+		}
+	}
+
+	duration := master.Duration()
+	skip := skipped(master)
+	return rule.Any(master.Date.In(master.Location()), start.Add(-duration), end, func(t time.Time) bool {
+		if skip[t.UnixNano()] {
+			return false
+		}
+
+		data := models.Event{Date: t}
+		if !master.End.IsZero() {
+			data.End = t.Add(duration)
+		}
+		return data.Overlaps(start, end)
+	}), nil
+}
+
+// skipped возвращает начала вхождений серии master, которые не берутся из правила:
+// исключенные и измененные.
+func skipped(master models.Event) map[int64]bool {
+	skip := make(map[int64]bool, len(master.ExDates)+len(master.Overrides))
+	for _, t := range master.ExDates {
+		skip[t.UnixNano()] = true
+	}
+	for _, o := range master.Overrides {
+		if o.RecurrenceID != nil {
+			skip[o.RecurrenceID.UnixNano()] = true
+		}
+	}
+	return skip
+}
+
+// occurrence создает вхождение серии master с данными data.
+func occurrence(master, data models.Event, recurrenceID time.Time) models.Event {
+	return models.Event{
+		ID:           master.ID,
+		Date:         data.Date,
+		End:          data.End,
+		TimeZone:     master.TimeZone,
+		Event:        data.Event,
+		Place:        master.Place,
+		Description:  master.Description,
+		Category:     master.Category,
+		Color:        master.Color,
+		Tags:         master.Tags,
+		Attributes:   master.Attributes,
+		RRule:        master.RRule,
+		RecurrenceID: &recurrenceID,
+		Attendees:    master.Attendees,
+		Owner:        master.Owner,
+		Version:      master.Version,
+	}
+}
+
+func isOccurrence(master *models.Event, rule *rrule.Rule, t time.Time) bool {
+	for _, exdate := range master.ExDates {
+		if exdate.Equal(t) {
+			return false
+		}
+	}
+
+	return rule.Contains(master.Date.In(master.Location()), t)
+}
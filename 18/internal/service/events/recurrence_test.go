@@ -0,0 +1,344 @@
+package events
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	repomock "l2.18/internal/repository/mock"
+	"l2.18/internal/service"
+	"l2.18/pkg/models"
+)
+
+func TestGetEventsExpandsSeries(t *testing.T) {
+	// 2025-01-06 - понедельник.
+	monday := time.Date(2025, time.January, 6, 0, 0, 0, 0, time.UTC)
+	moved := monday.AddDate(0, 0, 7).Add(3 * time.Hour)
+	recurrenceID := monday.AddDate(0, 0, 7)
+
+	series := models.Event{
+		ID:      "s",
+		Date:    monday,
+		Event:   "standup",
+		RRule:   "FREQ=WEEKLY;BYDAY=MO,WE",
+		ExDates: []time.Time{monday.AddDate(0, 0, 9)},
+		Overrides: []models.Event{
+			{Date: moved, Event: "moved standup", RecurrenceID: &recurrenceID},
+		},
+	}
+	single := models.Event{ID: "1", Date: monday.AddDate(0, 0, 8), Event: "single"}
+
+	mockRepo := &repomock.MockRepository{
+		GetEventsByDateRangeFn: func(userID models.UserID, start, end time.Time) ([]models.Event, error) {
+			return []models.Event{single}, nil
+		},
+		GetRecurringEventsFn: func(userID models.UserID) ([]models.Event, error) {
+			return []models.Event{series}, nil
+		},
+	}
+
+	svc := &Service{repo: mockRepo}
+
+	got, err := svc.GetEventsForWeek("user1", monday.AddDate(0, 0, 7), models.EventFilter{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []struct {
+		id    models.EventID
+		date  time.Time
+		event string
+	}{
+		{"s", moved, "moved standup"},
+		{"1", monday.AddDate(0, 0, 8), "single"},
+	}
+
+	if len(got) != len(expected) {
+		t.Fatalf("expected %d events, got %d: %+v", len(expected), len(got), got)
+	}
+	for i, e := range expected {
+		if got[i].ID != e.id || !got[i].Date.Equal(e.date) || got[i].Event != e.event {
+			t.Errorf("event[%d] mismatch: got %+v, want %+v", i, got[i], e)
+		}
+	}
+	if got[0].RecurrenceID == nil || !got[0].RecurrenceID.Equal(recurrenceID) {
+		t.Errorf("expected recurrence id %v, got %v", recurrenceID, got[0].RecurrenceID)
+	}
+}
+
+func TestUpdateOccurrence(t *testing.T) {
+	monday := time.Date(2025, time.January, 6, 0, 0, 0, 0, time.UTC)
+	series := models.Event{ID: "s", Date: monday, Event: "standup", RRule: "FREQ=DAILY"}
+
+	testCases := []struct {
+		name       string
+		occurrence time.Time
+		expected   error
+	}{
+		{
+			name:       "success - valid occurrence",
+			occurrence: monday.AddDate(0, 0, 2),
+			expected:   nil,
+		},
+		{
+			name:       "failure - not an occurrence",
+			occurrence: monday.Add(time.Hour),
+			expected:   service.ErrNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var updated models.Event
+			mockRepo := &repomock.MockRepository{
+				GetFn: func(userID models.UserID, eventID models.EventID) (*models.Event, error) {
+					e := series
+					return &e, nil
+				},
+				UpdateFn: func(userID models.UserID, event models.Event) error {
+					updated = event
+					return nil
+				},
+			}
+
+			svc := &Service{repo: mockRepo}
+
+			err := svc.UpdateOccurrence("user1", models.Event{ID: "s", Event: "changed"}, tc.occurrence)
+			if !errors.Is(err, tc.expected) {
+				t.Fatalf("expected %v, got %v", tc.expected, err)
+			}
+			if err != nil {
+				return
+			}
+
+			if len(updated.Overrides) != 1 {
+				t.Fatalf("expected 1 override, got %d", len(updated.Overrides))
+			}
+			override := updated.Overrides[0]
+			if override.Event != "changed" || !override.RecurrenceID.Equal(tc.occurrence) {
+				t.Errorf("unexpected override %+v", override)
+			}
+		})
+	}
+}
+
+func TestRemoveOccurrence(t *testing.T) {
+	monday := time.Date(2025, time.January, 6, 0, 0, 0, 0, time.UTC)
+	occurrence := monday.AddDate(0, 0, 1)
+	series := models.Event{
+		ID:    "s",
+		Date:  monday,
+		Event: "standup",
+		RRule: "FREQ=DAILY",
+		Overrides: []models.Event{
+			{Date: occurrence, Event: "changed", RecurrenceID: &occurrence},
+		},
+	}
+
+	var updated models.Event
+	mockRepo := &repomock.MockRepository{
+		GetFn: func(userID models.UserID, eventID models.EventID) (*models.Event, error) {
+			e := series
+			return &e, nil
+		},
+		UpdateFn: func(userID models.UserID, event models.Event) error {
+			updated = event
+			return nil
+		},
+	}
+
+	svc := &Service{repo: mockRepo}
+
+	if err := svc.RemoveOccurrence("user1", "s", occurrence, 0); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(updated.ExDates) != 1 || !updated.ExDates[0].Equal(occurrence) {
+		t.Errorf("unexpected exdates %v", updated.ExDates)
+	}
+	if updated.Overrides == nil || len(updated.Overrides) != 0 {
+		t.Errorf("expected overrides to be cleared, got %v", updated.Overrides)
+	}
+}
+
+func TestGetEventsExpandsSeriesInItsTimeZone(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("tzdata unavailable: %v", err)
+	}
+
+	// Хранилище может вернуть начало серии в UTC - вхождения все равно
+	// должны оставаться в 09:00 по Берлину после перехода на летнее время.
+	dtstart := time.Date(2025, time.March, 28, 9, 0, 0, 0, loc)
+	series := models.Event{
+		ID:       "s",
+		Date:     dtstart.UTC(),
+		End:      dtstart.Add(time.Hour).UTC(),
+		TimeZone: "Europe/Berlin",
+		Event:    "standup",
+		RRule:    "FREQ=DAILY",
+	}
+
+	mockRepo := &repomock.MockRepository{
+		GetRecurringEventsFn: func(userID models.UserID) ([]models.Event, error) {
+			return []models.Event{series}, nil
+		},
+	}
+
+	svc := &Service{repo: mockRepo}
+
+	day := time.Date(2025, time.March, 31, 0, 0, 0, 0, loc)
+	got, err := svc.GetEventsForDay("user1", day, models.EventFilter{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(got) != 1 {
+		t.Fatalf("expected 1 occurrence, got %d", len(got))
+	}
+	if got[0].Date.In(loc).Hour() != 9 || got[0].Duration() != time.Hour {
+		t.Errorf("unexpected occurrence %v - %v", got[0].Date, got[0].End)
+	}
+}
+
+func TestAddEventWithOverrides(t *testing.T) {
+	monday := time.Date(2025, time.January, 6, 0, 0, 0, 0, time.UTC)
+	recurrenceID := monday.AddDate(0, 0, 1)
+	override := models.Event{Date: recurrenceID.Add(time.Hour), Event: "moved", RecurrenceID: &recurrenceID}
+
+	testCases := []struct {
+		name     string
+		event    models.Event
+		expected error
+	}{
+		{
+			name:     "success - series with override",
+			event:    models.Event{Date: monday, RRule: "FREQ=DAILY", Overrides: []models.Event{override}},
+			expected: nil,
+		},
+		{
+			name:     "failure - override without series",
+			event:    models.Event{Date: monday, Overrides: []models.Event{override}},
+			expected: service.ErrInvalidEvent,
+		},
+		{
+			name: "failure - override without recurrence id",
+			event: models.Event{Date: monday, RRule: "FREQ=DAILY", Overrides: []models.Event{
+				{Date: monday, Event: "moved"},
+			}},
+			expected: service.ErrInvalidEvent,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var stored models.Event
+			mockRepo := &repomock.MockRepository{
+				PutFn: func(userID models.UserID, event models.Event) error {
+					stored = event
+					return nil
+				},
+			}
+
+			svc := &Service{repo: mockRepo}
+
+			id, err := svc.AddEvent("user1", tc.event)
+			if !errors.Is(err, tc.expected) {
+				t.Fatalf("expected %v, got %v", tc.expected, err)
+			}
+			if err != nil {
+				return
+			}
+
+			if id == "" || stored.ID != id {
+				t.Errorf("unexpected id %q, stored %q", id, stored.ID)
+			}
+			if len(stored.Overrides) != 1 {
+				t.Errorf("expected override to be stored, got %+v", stored.Overrides)
+			}
+		})
+	}
+}
+
+func TestListEventsKeepsSeries(t *testing.T) {
+	monday := time.Date(2025, time.January, 6, 0, 0, 0, 0, time.UTC)
+	single := models.Event{ID: "1", Date: monday.Add(time.Hour), Event: "single"}
+	series := models.Event{ID: "s", Date: monday, Event: "standup", RRule: "FREQ=WEEKLY;COUNT=2"}
+
+	mockRepo := &repomock.MockRepository{
+		GetEventsByDateRangeFn: func(userID models.UserID, start, end time.Time) ([]models.Event, error) {
+			return []models.Event{single}, nil
+		},
+		GetRecurringEventsFn: func(userID models.UserID) ([]models.Event, error) {
+			return []models.Event{series}, nil
+		},
+	}
+
+	svc := &Service{repo: mockRepo}
+
+	got, err := svc.ListEvents("user1", monday, monday.AddDate(0, 1, 0), models.EventFilter{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 2 || got[0].ID != "s" || got[0].RecurrenceID != nil || got[1].ID != "1" {
+		t.Errorf("unexpected events %+v", got)
+	}
+
+	// Серия закончилась до диапазона.
+	got, err = svc.ListEvents("user1", monday.AddDate(0, 1, 0), monday.AddDate(0, 2, 0), models.EventFilter{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, e := range got {
+		if e.ID == "s" {
+			t.Errorf("series outside of range was listed")
+		}
+	}
+}
+
+func TestHasOccurrences(t *testing.T) {
+	monday := time.Date(2025, time.January, 6, 10, 0, 0, 0, time.UTC)
+	weekly := models.Event{ID: "s", Date: monday, End: monday.Add(time.Hour), RRule: "FREQ=WEEKLY"}
+
+	excluded := weekly
+	excluded.ExDates = []time.Time{monday.AddDate(0, 0, 7)}
+
+	moved := weekly
+	recurrenceID := monday.AddDate(0, 0, 7)
+	moved.Overrides = []models.Event{{Date: monday.AddDate(0, 0, 9), End: monday.AddDate(0, 0, 9).Add(time.Hour), RecurrenceID: &recurrenceID}}
+
+	week2 := monday.AddDate(0, 0, 7).Truncate(24 * time.Hour)
+
+	testCases := []struct {
+		name       string
+		master     models.Event
+		start, end time.Time
+		expected   bool
+	}{
+		{name: "open-ended series in a wide range", master: weekly,
+			start: time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC), end: time.Date(2200, 1, 1, 0, 0, 0, 0, time.UTC), expected: true},
+		{name: "occurrence started before the range", master: weekly,
+			start: week2.Add(10*time.Hour + 30*time.Minute), end: week2.Add(12 * time.Hour), expected: true},
+		{name: "no occurrence in the range", master: weekly,
+			start: week2.AddDate(0, 0, 1), end: week2.AddDate(0, 0, 2), expected: false},
+		{name: "excluded occurrence", master: excluded,
+			start: week2, end: week2.AddDate(0, 0, 1), expected: false},
+		{name: "moved occurrence", master: moved,
+			start: week2.AddDate(0, 0, 2), end: week2.AddDate(0, 0, 3), expected: true},
+		{name: "moved away occurrence", master: moved,
+			start: week2, end: week2.AddDate(0, 0, 1), expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := hasOccurrences(tc.master, tc.start, tc.end)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			occurrences, _ := expand(tc.master, tc.start, tc.end)
+			if got != tc.expected || got != (len(occurrences) > 0) {
+				t.Errorf("expected %v (expand found %d), got %v", tc.expected, len(occurrences), got)
+			}
+		})
+	}
+}
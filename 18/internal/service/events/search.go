@@ -0,0 +1,40 @@
+package events
+
+import (
+	"fmt"
+	"time"
+
+	"l2.18/pkg/models"
+	"l2.18/pkg/search"
+)
+
+// Search возвращает события пользователя, текст которых соответствует запросу
+// и которые пересекаются с диапазоном [start, end), отсортированные по началу.
+// Серия попадает в результат целиком, если хотя бы одно ее вхождение
+// пересекается с диапазоном.
+func (s *Service) Search(userID models.UserID, query search.Query, start, end time.Time) ([]models.Event, error) {
+	found, err := s.repo.Search(userID, query)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]models.Event, 0, len(found))
+	for _, e := range found {
+		if !e.IsRecurring() {
+			if e.Overlaps(start, end) {
+				result = append(result, e)
+			}
+			continue
+		}
+
+		ok, err := hasOccurrences(e, start, end)
+		if err != nil {
+			return nil, fmt.Errorf("expand series %s: %w", e.ID, err)
+		}
+		if ok {
+			result = append(result, e)
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,41 @@
+package events
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	repomock "l2.18/internal/repository/mock"
+	"l2.18/pkg/models"
+	"l2.18/pkg/search"
+)
+
+func TestSearch(t *testing.T) {
+	day := time.Date(2025, time.February, 17, 0, 0, 0, 0, time.UTC)
+
+	mockRepo := &repomock.MockRepository{
+		SearchFn: func(userID models.UserID, query search.Query) ([]models.Event, error) {
+			return []models.Event{
+				{ID: "before", Date: day.AddDate(0, 0, -1)},
+				{ID: "series", Date: day.AddDate(0, 0, -7), RRule: "FREQ=WEEKLY"},
+				{ID: "inside", Date: day.Add(10 * time.Hour)},
+				{ID: "ended", Date: day.AddDate(0, 0, -14), RRule: "FREQ=DAILY;COUNT=3"},
+				{ID: "after", Date: day.AddDate(0, 0, 1)},
+			}, nil
+		},
+	}
+
+	q, _ := search.Parse("dentist")
+	got, err := New(mockRepo).Search("1", q, day, day.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ids := make([]models.EventID, len(got))
+	for i, e := range got {
+		ids[i] = e.ID
+	}
+	if expected := []models.EventID{"series", "inside"}; !slices.Equal(ids, expected) {
+		t.Errorf("expected %v, got %v", expected, ids)
+	}
+}
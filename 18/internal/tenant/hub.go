@@ -0,0 +1,33 @@
+package tenant
+
+import (
+	"l2.18/internal/pubsub"
+	"l2.18/pkg/models"
+)
+
+type changesHub interface {
+	Publish(userID models.UserID, change models.Change)
+	Subscribe(userID models.UserID) *pubsub.Subscription
+}
+
+// Hub - уведомления об изменениях событий пользователей одного арендатора
+// поверх общего pubsub.Hub. Подписки разделяются по ключам UserKey.
+type Hub struct {
+	hub changesHub
+	id  models.TenantID
+}
+
+// NewHub создает Hub арендатора id.
+func NewHub(hub changesHub, id models.TenantID) *Hub {
+	return &Hub{hub: hub, id: id}
+}
+
+// Publish рассылает уведомление подпискам пользователя арендатора.
+func (h *Hub) Publish(userID models.UserID, change models.Change) {
+	h.hub.Publish(UserKey(h.id, userID), change)
+}
+
+// Subscribe подписывается на изменения событий пользователя арендатора.
+func (h *Hub) Subscribe(userID models.UserID) *pubsub.Subscription {
+	return h.hub.Subscribe(UserKey(h.id, userID))
+}
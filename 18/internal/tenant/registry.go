@@ -0,0 +1,186 @@
+package tenant
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"l2.18/internal/ratelimit"
+	"l2.18/pkg/models"
+)
+
+// requestCounters - запросы арендатора с запуска сервера.
+type requestCounters struct {
+	requests uint64
+	rejected uint64
+}
+
+// Registry хранит квоты арендаторов, ограничивает частоту их запросов
+// и собирает статистику использования.
+type Registry struct {
+	store  Store
+	quota  models.TenantQuota
+	quotas map[models.TenantID]models.TenantQuota
+
+	// limiter ограничивает арендаторов с квотой по умолчанию, limiters -
+	// арендаторов с собственной квотой. Ключ ведра - ID арендатора.
+	limiter  *ratelimit.Limiter
+	limiters map[models.TenantID]*ratelimit.Limiter
+
+	mu       sync.Mutex
+	counters map[models.TenantID]*requestCounters
+	// events - счетчики событий арендаторов с квотой, общие для их Repository.
+	events map[models.TenantID]*counter
+}
+
+// NewRegistry создает Registry. quota - квота по умолчанию, quotas - квоты
+// отдельных арендаторов, которые заменяют ее целиком.
+func NewRegistry(store Store, quota models.TenantQuota, quotas map[models.TenantID]models.TenantQuota) (*Registry, error) {
+	r := &Registry{
+		store:    store,
+		quota:    quota,
+		quotas:   quotas,
+		limiter:  newLimiter(quota),
+		limiters: make(map[models.TenantID]*ratelimit.Limiter, len(quotas)),
+		counters: make(map[models.TenantID]*requestCounters),
+		events:   make(map[models.TenantID]*counter),
+	}
+
+	for id, q := range quotas {
+		if !Valid(id) {
+			return nil, fmt.Errorf("invalid tenant id %q", id)
+		}
+		r.limiters[id] = newLimiter(q)
+	}
+
+	return r, nil
+}
+
+func newLimiter(quota models.TenantQuota) *ratelimit.Limiter {
+	if quota.RateLimit <= 0 {
+		return nil
+	}
+	return ratelimit.New(quota.RateLimit, quota.RateBurst)
+}
+
+// Quota возвращает квоту арендатора id.
+func (r *Registry) Quota(id models.TenantID) models.TenantQuota {
+	if q, ok := r.quotas[id]; ok {
+		return q
+	}
+	return r.quota
+}
+
+// Repository возвращает хранилище событий арендатора id с его квотой.
+func (r *Registry) Repository(id models.TenantID) *Repository {
+	quota := r.Quota(id)
+	if quota.MaxEvents <= 0 {
+		return newRepository(r.store, id, quota, nil)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c, ok := r.events[id]
+	if !ok {
+		c = &counter{}
+		r.events[id] = c
+	}
+	return newRepository(r.store, id, quota, c)
+}
+
+// Allow учитывает запрос арендатора с ID key и расходует токен его квоты
+// частоты запросов. Если токенов нет, вернет false и время, через которое
+// запрос будет разрешен.
+func (r *Registry) Allow(key string) (bool, time.Duration) {
+	id := models.TenantID(key)
+
+	limiter, ok := r.limiters[id]
+	if !ok {
+		limiter = r.limiter
+	}
+
+	allowed, retryAfter := true, time.Duration(0)
+	if limiter != nil {
+		allowed, retryAfter = limiter.Allow(key)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c, ok := r.counters[id]
+	if !ok {
+		c = &requestCounters{}
+		r.counters[id] = c
+	}
+	if allowed {
+		c.requests++
+	} else {
+		c.rejected++
+	}
+
+	return allowed, retryAfter
+}
+
+// Usage возвращает использование ресурсов арендаторами, у которых есть
+// события, запросы или собственная квота, в порядке ID.
+func (r *Registry) Usage() ([]models.TenantUsage, error) {
+	counts, err := r.store.EventCounts()
+	if err != nil {
+		return nil, err
+	}
+
+	usage := make(map[models.TenantID]*models.TenantUsage)
+	get := func(id models.TenantID) *models.TenantUsage {
+		u, ok := usage[id]
+		if !ok {
+			u = &models.TenantUsage{Tenant: id, Quota: r.Quota(id)}
+			usage[id] = u
+		}
+		return u
+	}
+
+	for key, count := range counts {
+		id, _ := Split(key)
+		u := get(id)
+		u.Users++
+		u.Events += count
+	}
+	for id := range r.quotas {
+		get(id)
+	}
+
+	r.mu.Lock()
+	for id, c := range r.counters {
+		u := get(id)
+		u.Requests, u.RejectedRequests = c.requests, c.rejected
+	}
+	r.mu.Unlock()
+
+	result := make([]models.TenantUsage, 0, len(usage))
+	for _, u := range usage {
+		result = append(result, *u)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Tenant < result[j].Tenant
+	})
+
+	return result, nil
+}
+
+// TenantUsage возвращает использование ресурсов арендатором id.
+// Для неизвестного арендатора вернет нулевое использование и квоту по умолчанию.
+func (r *Registry) TenantUsage(id models.TenantID) (models.TenantUsage, error) {
+	all, err := r.Usage()
+	if err != nil {
+		return models.TenantUsage{}, err
+	}
+
+	for _, u := range all {
+		if u.Tenant == id {
+			return u, nil
+		}
+	}
+	return models.TenantUsage{Tenant: id, Quota: r.Quota(id)}, nil
+}
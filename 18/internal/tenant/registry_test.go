@@ -0,0 +1,129 @@
+package tenant
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"l2.18/internal/repository"
+	"l2.18/internal/repository/memory"
+	"l2.18/pkg/models"
+)
+
+func TestRegistry(t *testing.T) {
+	day := time.Date(2025, time.February, 15, 10, 0, 0, 0, time.UTC)
+	store := memory.NewEventsRepository()
+
+	r, err := NewRegistry(store, models.TenantQuota{MaxEvents: 10}, map[models.TenantID]models.TenantQuota{
+		"acme": {MaxEvents: 100, RateLimit: 1, RateBurst: 2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_ = r.Repository("acme").Put("alice", models.Event{ID: "a", Date: day, Event: "a"})
+	_ = r.Repository("acme").Put("bob", models.Event{ID: "b", Date: day, Event: "b"})
+	_ = r.Repository(Default).Put("alice", models.Event{ID: "c", Date: day, Event: "c"})
+
+	for i := range 3 {
+		ok, _ := r.Allow("acme")
+		if ok != (i < 2) {
+			t.Errorf("request %d: expected allowed %v, got %v", i, i < 2, ok)
+		}
+	}
+	for range 3 {
+		if ok, _ := r.Allow("other"); !ok {
+			t.Error("expected tenant without rate quota to be allowed")
+		}
+	}
+
+	usage, err := r.Usage()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []models.TenantUsage{
+		{Tenant: Default, Users: 1, Events: 1, Quota: models.TenantQuota{MaxEvents: 10}},
+		{Tenant: "acme", Users: 2, Events: 2, Requests: 2, RejectedRequests: 1,
+			Quota: models.TenantQuota{MaxEvents: 100, RateLimit: 1, RateBurst: 2}},
+		{Tenant: "other", Requests: 3, Quota: models.TenantQuota{MaxEvents: 10}},
+	}
+	if len(usage) != len(expected) {
+		t.Fatalf("expected %+v, got %+v", expected, usage)
+	}
+	for i := range expected {
+		if usage[i] != expected[i] {
+			t.Errorf("expected %+v, got %+v", expected[i], usage[i])
+		}
+	}
+
+	u, _ := r.TenantUsage("unknown")
+	if u != (models.TenantUsage{Tenant: "unknown", Quota: models.TenantQuota{MaxEvents: 10}}) {
+		t.Errorf("expected empty usage of unknown tenant, got %+v", u)
+	}
+}
+
+func TestRegistryInvalidTenant(t *testing.T) {
+	_, err := NewRegistry(memory.NewEventsRepository(), models.TenantQuota{}, map[models.TenantID]models.TenantQuota{
+		"Acme/ops": {},
+	})
+	if err == nil {
+		t.Error("expected error for invalid tenant id")
+	}
+}
+
+// slowStore медленно добавляет события, чтобы параллельные запросы пересекались.
+type slowStore struct {
+	*memory.EventsRepository
+}
+
+func (s slowStore) Put(userID models.UserID, event models.Event) error {
+	time.Sleep(time.Millisecond)
+	return s.EventsRepository.Put(userID, event)
+}
+
+func TestRegistryQuotaConcurrentPuts(t *testing.T) {
+	day := time.Date(2025, time.February, 15, 10, 0, 0, 0, time.UTC)
+	store := slowStore{memory.NewEventsRepository()}
+
+	r, err := NewRegistry(store, models.TenantQuota{MaxEvents: 5}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		wg      sync.WaitGroup
+		created atomic.Int32
+	)
+	for i := range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Каждый запрос получает свой Repository, как в обработчиках.
+			event := models.Event{ID: models.EventID(strconv.Itoa(i)), Date: day, Event: "e"}
+			if err := r.Repository("acme").Put("alice", event); err == nil {
+				created.Add(1)
+			} else if !errors.Is(err, repository.ErrQuotaExceeded) {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	usage, _ := r.TenantUsage("acme")
+	if created.Load() != 5 || usage.Events != 5 {
+		t.Fatalf("expected 5 events, created %d, stored %d", created.Load(), usage.Events)
+	}
+
+	// Удаленное событие освобождает место в квоте.
+	events, _ := r.Repository("acme").GetEventsByDateRange("alice", day, day.Add(time.Hour))
+	if err := r.Repository("acme").Delete("alice", events[0].ID, 0); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := r.Repository("acme").Put("alice", models.Event{ID: "new", Date: day, Event: "e"}); err != nil {
+		t.Errorf("expected deleted event to free the quota, got %v", err)
+	}
+}
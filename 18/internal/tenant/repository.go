@@ -0,0 +1,357 @@
+package tenant
+
+import (
+	"sync"
+	"time"
+
+	"l2.18/internal/repository"
+	"l2.18/pkg/models"
+	"l2.18/pkg/search"
+)
+
+// Store - общее хранилище событий всех арендаторов.
+type Store interface {
+	repository.Tx
+	Transaction(fn func(tx repository.Tx) error) error
+	EventCounts() (map[models.UserID]int, error)
+}
+
+// Repository - хранилище событий одного арендатора поверх общего Store.
+// ID пользователей в аргументах и результатах - ID внутри арендатора:
+// в Store они передаются ключами UserKey, в том числе у участников событий
+// и в истории изменений.
+type Repository struct {
+	scope
+	store   Store
+	counter *counter
+}
+
+// NewRepository создает Repository арендатора id. Если quota.MaxEvents больше
+// нуля, Put отказывает с repository.ErrQuotaExceeded, когда у арендатора
+// уже столько событий.
+//
+// Число событий считается по store один раз и дальше ведется в памяти, поэтому
+// все изменения событий арендатора с квотой должны идти через один Repository
+// или через Registry.Repository.
+func NewRepository(store Store, id models.TenantID, quota models.TenantQuota) *Repository {
+	return newRepository(store, id, quota, &counter{})
+}
+
+func newRepository(store Store, id models.TenantID, quota models.TenantQuota, c *counter) *Repository {
+	return &Repository{
+		scope:   scope{tx: store, id: id, maxEvents: quota.MaxEvents},
+		store:   store,
+		counter: c,
+	}
+}
+
+// Put добавляет событие. Проверка квоты и запись выполняются под блокировкой
+// счетчика событий арендатора, поэтому параллельные запросы не превысят квоту.
+func (r *Repository) Put(userID models.UserID, event models.Event) error {
+	if r.maxEvents <= 0 {
+		return r.scope.Put(userID, event)
+	}
+
+	events, err := r.counter.lock(r.store, r.id)
+	if err != nil {
+		return err
+	}
+	defer r.counter.mu.Unlock()
+
+	s := r.scope
+	s.events = events
+	return s.Put(userID, event)
+}
+
+// Delete удаляет событие и учитывает его в счетчике событий арендатора.
+func (r *Repository) Delete(userID models.UserID, eventID models.EventID, version int64) error {
+	if r.maxEvents <= 0 {
+		return r.scope.Delete(userID, eventID, version)
+	}
+
+	events, err := r.counter.lock(r.store, r.id)
+	if err != nil {
+		return err
+	}
+	defer r.counter.mu.Unlock()
+
+	s := r.scope
+	s.events = events
+	return s.Delete(userID, eventID, version)
+}
+
+// Transaction выполняет fn в транзакции общего хранилища. Квота проверяется
+// по числу событий на начало транзакции с учетом изменений внутри нее.
+// Транзакции арендатора с квотой выполняются по одной.
+func (r *Repository) Transaction(fn func(tx repository.Tx) error) error {
+	if r.maxEvents <= 0 {
+		return r.store.Transaction(func(tx repository.Tx) error {
+			return fn(&scope{tx: tx, id: r.id})
+		})
+	}
+
+	events, err := r.counter.lock(r.store, r.id)
+	if err != nil {
+		return err
+	}
+	defer r.counter.mu.Unlock()
+
+	n := *events
+	err = r.store.Transaction(func(tx repository.Tx) error {
+		return fn(&scope{tx: tx, id: r.id, maxEvents: r.maxEvents, events: &n})
+	})
+	if err == nil {
+		*events = n
+	}
+	return err
+}
+
+// counter - число событий арендатора с квотой.
+type counter struct {
+	mu     sync.Mutex
+	loaded bool
+	events int
+}
+
+// lock блокирует счетчик и возвращает число событий арендатора id, при первом
+// вызове посчитав их в store. Если вернул ошибку, счетчик не заблокирован.
+func (c *counter) lock(store Store, id models.TenantID) (*int, error) {
+	c.mu.Lock()
+	if !c.loaded {
+		n, err := countEvents(store, id)
+		if err != nil {
+			c.mu.Unlock()
+			return nil, err
+		}
+		c.events, c.loaded = n, true
+	}
+
+	return &c.events, nil
+}
+
+// countEvents возвращает число событий арендатора id в store.
+func countEvents(store Store, id models.TenantID) (int, error) {
+	counts, err := store.EventCounts()
+	if err != nil {
+		return 0, err
+	}
+
+	var n int
+	for key, count := range counts {
+		if tenant, _ := Split(key); tenant == id {
+			n += count
+		}
+	}
+
+	return n, nil
+}
+
+// scope реализует repository.Tx арендатора поверх tx общего хранилища.
+type scope struct {
+	tx        repository.Tx
+	id        models.TenantID
+	maxEvents int
+	// events - число событий арендатора с квотой, nil без квоты.
+	events *int
+}
+
+// checkQuota проверяет, можно ли добавить арендатору еще одно событие.
+func (s *scope) checkQuota() error {
+	if s.events != nil && *s.events >= s.maxEvents {
+		return repository.ErrQuotaExceeded
+	}
+	return nil
+}
+
+// added учитывает добавленные (delta > 0) или удаленные события.
+func (s *scope) added(delta int) {
+	if s.events != nil {
+		*s.events += delta
+	}
+}
+
+func (s *scope) key(userID models.UserID) models.UserID {
+	return UserKey(s.id, userID)
+}
+
+// user возвращает ID пользователя арендатора по ключу хранилища.
+func (s *scope) user(key models.UserID) models.UserID {
+	if id, userID := Split(key); id == s.id {
+		return userID
+	}
+	return key
+}
+
+// eventIn возвращает копию события с ключами пользователей вместо их ID.
+func (s *scope) eventIn(event models.Event) models.Event {
+	return s.mapEvent(event, s.key)
+}
+
+// eventOut возвращает копию события с ID пользователей вместо ключей.
+func (s *scope) eventOut(event models.Event) models.Event {
+	return s.mapEvent(event, s.user)
+}
+
+func (s *scope) eventsOut(events []models.Event) []models.Event {
+	if events == nil {
+		return nil
+	}
+
+	out := make([]models.Event, len(events))
+	for i, event := range events {
+		out[i] = s.eventOut(event)
+	}
+	return out
+}
+
+// mapEvent заменяет f владельца и участников события и его измененных вхождений.
+// Срезы копируются: хранилище может возвращать события, которые хранит само.
+func (s *scope) mapEvent(event models.Event, f func(models.UserID) models.UserID) models.Event {
+	if event.Owner != "" {
+		event.Owner = f(event.Owner)
+	}
+
+	if event.Attendees != nil {
+		attendees := make([]models.Attendee, len(event.Attendees))
+		for i, a := range event.Attendees {
+			a.UserID = f(a.UserID)
+			attendees[i] = a
+		}
+		event.Attendees = attendees
+	}
+
+	if event.Overrides != nil {
+		overrides := make([]models.Event, len(event.Overrides))
+		for i, o := range event.Overrides {
+			overrides[i] = s.mapEvent(o, f)
+		}
+		event.Overrides = overrides
+	}
+
+	return event
+}
+
+// mapEntry заменяет f автора записи истории и пользователей в состояниях события.
+func (s *scope) mapEntry(entry models.HistoryEntry, f func(models.UserID) models.UserID) models.HistoryEntry {
+	entry.Actor = f(entry.Actor)
+	if entry.Before != nil {
+		before := s.mapEvent(*entry.Before, f)
+		entry.Before = &before
+	}
+	if entry.After != nil {
+		after := s.mapEvent(*entry.After, f)
+		entry.After = &after
+	}
+
+	return entry
+}
+
+func (s *scope) Put(userID models.UserID, event models.Event) error {
+	if err := s.checkQuota(); err != nil {
+		return err
+	}
+	if err := s.tx.Put(s.key(userID), s.eventIn(event)); err != nil {
+		return err
+	}
+
+	s.added(1)
+	return nil
+}
+
+func (s *scope) Get(userID models.UserID, eventID models.EventID) (*models.Event, error) {
+	event, err := s.tx.Get(s.key(userID), eventID)
+	if err != nil {
+		return nil, err
+	}
+
+	out := s.eventOut(*event)
+	return &out, nil
+}
+
+func (s *scope) Update(userID models.UserID, event models.Event) error {
+	return s.tx.Update(s.key(userID), s.eventIn(event))
+}
+
+func (s *scope) Replace(userID models.UserID, event models.Event) error {
+	return s.tx.Replace(s.key(userID), s.eventIn(event))
+}
+
+func (s *scope) Delete(userID models.UserID, eventID models.EventID, version int64) error {
+	if err := s.tx.Delete(s.key(userID), eventID, version); err != nil {
+		return err
+	}
+
+	s.added(-1)
+	return nil
+}
+
+func (s *scope) GetEventsByDateRange(userID models.UserID, start, end time.Time) ([]models.Event, error) {
+	events, err := s.tx.GetEventsByDateRange(s.key(userID), start, end)
+	return s.eventsOut(events), err
+}
+
+func (s *scope) GetFilteredEvents(
+	userID models.UserID, start, end time.Time, filter models.EventFilter,
+) ([]models.Event, error) {
+	events, err := s.tx.GetFilteredEvents(s.key(userID), start, end, filter)
+	return s.eventsOut(events), err
+}
+
+func (s *scope) GetRecurringEvents(userID models.UserID) ([]models.Event, error) {
+	events, err := s.tx.GetRecurringEvents(s.key(userID))
+	return s.eventsOut(events), err
+}
+
+// Users возвращает только пользователей арендатора.
+func (s *scope) Users() ([]models.UserID, error) {
+	keys, err := s.tx.Users()
+	if err != nil {
+		return nil, err
+	}
+
+	var users []models.UserID
+	for _, key := range keys {
+		if id, userID := Split(key); id == s.id {
+			users = append(users, userID)
+		}
+	}
+
+	return users, nil
+}
+
+func (s *scope) GetInvitedEvents(userID models.UserID) ([]models.Event, error) {
+	events, err := s.tx.GetInvitedEvents(s.key(userID))
+	return s.eventsOut(events), err
+}
+
+func (s *scope) UpdateAttendee(owner models.UserID, eventID models.EventID, attendee models.Attendee) error {
+	attendee.UserID = s.key(attendee.UserID)
+	return s.tx.UpdateAttendee(s.key(owner), eventID, attendee)
+}
+
+func (s *scope) Search(userID models.UserID, query search.Query) ([]models.Event, error) {
+	events, err := s.tx.Search(s.key(userID), query)
+	return s.eventsOut(events), err
+}
+
+func (s *scope) AddHistory(userID models.UserID, entry models.HistoryEntry) error {
+	return s.tx.AddHistory(s.key(userID), s.mapEntry(entry, s.key))
+}
+
+func (s *scope) GetHistory(userID models.UserID, eventID models.EventID) ([]models.HistoryEntry, error) {
+	entries, err := s.tx.GetHistory(s.key(userID), eventID)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]models.HistoryEntry, len(entries))
+	for i, entry := range entries {
+		out[i] = s.mapEntry(entry, s.user)
+	}
+	return out, nil
+}
+
+// PruneHistory удаляет старые записи истории всех арендаторов: срок хранения у них общий.
+func (s *scope) PruneHistory(before time.Time) error {
+	return s.tx.PruneHistory(before)
+}
@@ -0,0 +1,117 @@
+package tenant
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"l2.18/internal/repository"
+	"l2.18/internal/repository/memory"
+	"l2.18/pkg/models"
+)
+
+func TestRepositoryIsolation(t *testing.T) {
+	day := time.Date(2025, time.February, 15, 10, 0, 0, 0, time.UTC)
+	store := memory.NewEventsRepository()
+
+	acme := NewRepository(store, "acme", models.TenantQuota{})
+	other := NewRepository(store, Default, models.TenantQuota{})
+
+	event := models.Event{
+		ID:        "a",
+		Date:      day,
+		Event:     "meeting",
+		Attendees: []models.Attendee{{UserID: "bob", Status: models.RSVPNeedsAction}},
+	}
+	if err := acme.Put("alice", event); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := acme.Get("alice", "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Attendees[0].UserID != "bob" {
+		t.Errorf("expected attendee bob, got %q", got.Attendees[0].UserID)
+	}
+
+	invited, _ := acme.GetInvitedEvents("bob")
+	if len(invited) != 1 || invited[0].Owner != "alice" {
+		t.Errorf("expected invitation from alice, got %+v", invited)
+	}
+
+	if _, err := other.Get("alice", "a"); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("expected %v for another tenant, got %v", repository.ErrNotFound, err)
+	}
+	if invited, _ := other.GetInvitedEvents("bob"); len(invited) != 0 {
+		t.Errorf("expected no invitations in another tenant, got %+v", invited)
+	}
+	if _, err := other.Get("acme/alice", "a"); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("expected default tenant user acme/alice to be separate, got %v", err)
+	}
+
+	users, _ := acme.Users()
+	if len(users) != 1 || users[0] != "alice" {
+		t.Errorf("expected users [alice], got %v", users)
+	}
+	if users, _ := other.Users(); len(users) != 0 {
+		t.Errorf("expected no users in another tenant, got %v", users)
+	}
+}
+
+func TestRepositoryQuota(t *testing.T) {
+	day := time.Date(2025, time.February, 15, 10, 0, 0, 0, time.UTC)
+	store := memory.NewEventsRepository()
+	repo := NewRepository(store, "acme", models.TenantQuota{MaxEvents: 2})
+
+	_ = repo.Put("alice", models.Event{ID: "a", Date: day, Event: "a"})
+	_ = repo.Put("bob", models.Event{ID: "b", Date: day, Event: "b"})
+
+	if err := repo.Put("alice", models.Event{ID: "c", Date: day, Event: "c"}); !errors.Is(err, repository.ErrQuotaExceeded) {
+		t.Fatalf("expected %v, got %v", repository.ErrQuotaExceeded, err)
+	}
+
+	// Квота считается для каждого арендатора отдельно.
+	if err := NewRepository(store, "other", models.TenantQuota{MaxEvents: 2}).Put("alice", models.Event{ID: "c", Date: day, Event: "c"}); err != nil {
+		t.Errorf("expected another tenant to have its own quota, got %v", err)
+	}
+
+	// В транзакции учитываются удаленные в ней события.
+	err := repo.Transaction(func(tx repository.Tx) error {
+		if err := tx.Delete("alice", "a", 0); err != nil {
+			return err
+		}
+		if err := tx.Put("alice", models.Event{ID: "c", Date: day, Event: "c"}); err != nil {
+			return err
+		}
+		return tx.Put("alice", models.Event{ID: "d", Date: day, Event: "d"})
+	})
+	if !errors.Is(err, repository.ErrQuotaExceeded) {
+		t.Fatalf("expected %v, got %v", repository.ErrQuotaExceeded, err)
+	}
+	if _, err := repo.Get("alice", "a"); err != nil {
+		t.Errorf("expected transaction to be rolled back, got %v", err)
+	}
+}
+
+func TestRepositoryHistory(t *testing.T) {
+	day := time.Date(2025, time.February, 15, 10, 0, 0, 0, time.UTC)
+	store := memory.NewEventsRepository()
+	repo := NewRepository(store, "acme", models.TenantQuota{})
+
+	after := models.Event{ID: "a", Date: day, Event: "meeting", Attendees: []models.Attendee{{UserID: "bob"}}}
+	entry := models.HistoryEntry{EventID: "a", Type: models.ChangeCreated, Actor: "alice", At: day, After: &after}
+	if err := repo.AddHistory("alice", entry); err != nil {
+		t.Fatal(err)
+	}
+
+	stored, _ := store.GetHistory("acme/alice", "a")
+	if len(stored) != 1 || stored[0].Actor != "acme/alice" || stored[0].After.Attendees[0].UserID != "acme/bob" {
+		t.Fatalf("expected history stored under tenant keys, got %+v", stored)
+	}
+
+	entries, _ := repo.GetHistory("alice", "a")
+	if len(entries) != 1 || entries[0].Actor != "alice" || entries[0].After.Attendees[0].UserID != "bob" {
+		t.Errorf("expected history with tenant user ids, got %+v", entries)
+	}
+}
@@ -0,0 +1,56 @@
+// Package tenant разделяет данные и квоты арендаторов - команд, которые
+// пользуются одним сервером календаря. Все арендаторы хранят события в общем
+// хранилище, но ключи их пользователей дополняются ID арендатора, поэтому
+// арендаторы не видят пользователей и события друг друга.
+package tenant
+
+import (
+	"regexp"
+	"strings"
+
+	"l2.18/pkg/models"
+)
+
+// Default - арендатор по умолчанию. Его пользователи хранятся под своими ID,
+// как до появления арендаторов, поэтому существующие данные принадлежат ему.
+const Default models.TenantID = ""
+
+// separator отделяет ID арендатора от ID пользователя в ключе хранилища.
+const separator = "/"
+
+var idPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]{0,62}$`)
+
+// Valid сообщает, может ли id быть ID арендатора: до 63 строчных латинских
+// букв, цифр, точек, дефисов и подчеркиваний. Default допустим всегда.
+func Valid(id models.TenantID) bool {
+	return id == Default || idPattern.MatchString(string(id))
+}
+
+// UserKey возвращает ключ пользователя userID арендатора id в общем хранилище.
+//
+// Для арендатора "acme" ключ - "acme/userID". Пользователи арендатора по
+// умолчанию хранятся под своими ID, а ID с разделителем получают его в начале
+// ("/a/b"), чтобы пользователь "acme/bob" не совпал с bob арендатора acme.
+func UserKey(id models.TenantID, userID models.UserID) models.UserID {
+	switch {
+	case id != Default:
+		return models.UserID(string(id) + separator + string(userID))
+	case strings.Contains(string(userID), separator):
+		return models.UserID(separator + string(userID))
+	default:
+		return userID
+	}
+}
+
+// Split разбирает ключ хранилища на арендатора и ID пользователя. Обратна UserKey.
+func Split(key models.UserID) (models.TenantID, models.UserID) {
+	if rest, ok := strings.CutPrefix(string(key), separator); ok {
+		return Default, models.UserID(rest)
+	}
+
+	id, userID, ok := strings.Cut(string(key), separator)
+	if !ok {
+		return Default, key
+	}
+	return models.TenantID(id), models.UserID(userID)
+}
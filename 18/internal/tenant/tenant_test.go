@@ -0,0 +1,55 @@
+package tenant
+
+import (
+	"testing"
+
+	"l2.18/pkg/models"
+)
+
+func TestUserKey(t *testing.T) {
+	testCases := []struct {
+		tenant models.TenantID
+		user   models.UserID
+		key    models.UserID
+	}{
+		{tenant: Default, user: "alice", key: "alice"},
+		{tenant: Default, user: "acme/bob", key: "/acme/bob"},
+		{tenant: "acme", user: "bob", key: "acme/bob"},
+		{tenant: "acme", user: "a/b", key: "acme/a/b"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(string(tc.key), func(t *testing.T) {
+			key := UserKey(tc.tenant, tc.user)
+			if key != tc.key {
+				t.Fatalf("expected key %q, got %q", tc.key, key)
+			}
+
+			tenant, user := Split(key)
+			if tenant != tc.tenant || user != tc.user {
+				t.Errorf("expected %q, %q, got %q, %q", tc.tenant, tc.user, tenant, user)
+			}
+		})
+	}
+}
+
+func TestValid(t *testing.T) {
+	testCases := []struct {
+		id    models.TenantID
+		valid bool
+	}{
+		{id: Default, valid: true},
+		{id: "acme", valid: true},
+		{id: "team-1.dev_ops", valid: true},
+		{id: "Acme", valid: false},
+		{id: "-acme", valid: false},
+		{id: "acme/ops", valid: false},
+		{id: "a b", valid: false},
+	}
+
+	for _, tc := range testCases {
+		if valid := Valid(tc.id); valid != tc.valid {
+			t.Errorf("%q: expected %v, got %v", tc.id, tc.valid, valid)
+		}
+	}
+}
@@ -0,0 +1,4 @@
+// Package calendarpb содержит описание gRPC API календаря и сгенерированный по нему код.
+package calendarpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative calendar.proto
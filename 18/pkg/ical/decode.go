@@ -0,0 +1,411 @@
+package ical
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"l2.18/pkg/models"
+	"l2.18/pkg/rrule"
+)
+
+// ErrInvalidCalendar возвращается, если документ не является VCALENDAR.
+var ErrInvalidCalendar = errors.New("invalid calendar")
+
+var colorPattern = regexp.MustCompile(`^#[0-9a-f]{6}$`)
+
+// Item - результат разбора одного VEVENT. Если Err не nil, событие не разобрано.
+type Item struct {
+	UID   string
+	Event models.Event
+	Err   error
+}
+
+// component - свойства одного компонента без вложенных компонентов.
+type component struct {
+	name  string
+	props []property
+	// sub - вложенные компоненты (нужны для STANDARD/DAYLIGHT в VTIMEZONE).
+	sub []*component
+}
+
+// Decode разбирает документ iCalendar и возвращает по одному Item на каждый VEVENT,
+// кроме измененных вхождений (с RECURRENCE-ID): они добавляются в Overrides своей серии.
+// Ошибка возвращается, только если документ не удалось прочитать целиком.
+func Decode(r io.Reader) ([]Item, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	calendars, err := parseComponents(lines)
+	if err != nil {
+		return nil, err
+	}
+
+	var vevents []*component
+	tzs := make(map[string]*time.Location)
+
+	for _, calendar := range calendars {
+		if calendar.name != "VCALENDAR" {
+			return nil, fmt.Errorf("%w: unexpected component %s", ErrInvalidCalendar, calendar.name)
+		}
+
+		for _, c := range calendar.sub {
+			switch c.name {
+			case "VEVENT":
+				vevents = append(vevents, c)
+			case "VTIMEZONE":
+				if tzid, loc := parseTimezone(c); loc != nil {
+					tzs[tzid] = loc
+				}
+			}
+		}
+	}
+
+	if len(calendars) == 0 {
+		return nil, fmt.Errorf("%w: VCALENDAR not found", ErrInvalidCalendar)
+	}
+
+	var (
+		items     []Item
+		overrides []Item
+		series    = make(map[string]int)
+	)
+
+	for _, c := range vevents {
+		item := parseEvent(c, tzs)
+
+		if item.Err == nil && item.Event.RecurrenceID != nil {
+			overrides = append(overrides, item)
+			continue
+		}
+
+		if item.Err == nil && item.UID != "" {
+			series[item.UID] = len(items)
+		}
+		items = append(items, item)
+	}
+
+	for _, o := range overrides {
+		i, ok := series[o.UID]
+		if !ok || !items[i].Event.IsRecurring() {
+			o.Err = errors.New("recurrence override without series")
+			items = append(items, o)
+			continue
+		}
+
+		if o.Event.Event == "" {
+			o.Event.Event = items[i].Event.Event
+		}
+		// Метаданные вхождения - метаданные серии, у изменения их не хранят.
+		o.Event.Place, o.Event.Description, o.Event.Category, o.Event.Color = "", "", "", ""
+		o.Event.Tags, o.Event.Attributes = nil, nil
+		items[i].Event.Overrides = append(items[i].Event.Overrides, o.Event)
+	}
+
+	return items, nil
+}
+
+// parseComponents строит дерево компонентов из строк контента.
+func parseComponents(lines []string) ([]*component, error) {
+	var (
+		roots []*component
+		stack []*component
+	)
+
+	for _, line := range lines {
+		prop, ok := parseProperty(line)
+		if !ok {
+			return nil, fmt.Errorf("%w: malformed line %q", ErrInvalidCalendar, line)
+		}
+
+		switch prop.name {
+		case "BEGIN":
+			c := &component{name: strings.ToUpper(prop.value)}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.sub = append(parent.sub, c)
+			} else {
+				roots = append(roots, c)
+			}
+			stack = append(stack, c)
+
+		case "END":
+			if len(stack) == 0 || stack[len(stack)-1].name != strings.ToUpper(prop.value) {
+				return nil, fmt.Errorf("%w: unexpected END:%s", ErrInvalidCalendar, prop.value)
+			}
+			stack = stack[:len(stack)-1]
+
+		default:
+			if len(stack) == 0 {
+				return nil, fmt.Errorf("%w: property outside component", ErrInvalidCalendar)
+			}
+			c := stack[len(stack)-1]
+			c.props = append(c.props, prop)
+		}
+	}
+
+	if len(stack) != 0 {
+		return nil, fmt.Errorf("%w: unterminated %s", ErrInvalidCalendar, stack[len(stack)-1].name)
+	}
+
+	return roots, nil
+}
+
+// parseTimezone возвращает часовой пояс VTIMEZONE. Если TZID - известное имя IANA,
+// используется оно, иначе фиксированное смещение стандартного времени.
+func parseTimezone(c *component) (string, *time.Location) {
+	var tzid string
+	for _, p := range c.props {
+		if p.name == "TZID" {
+			tzid = p.value
+		}
+	}
+	if tzid == "" {
+		return "", nil
+	}
+
+	if loc, err := time.LoadLocation(tzid); err == nil {
+		return tzid, loc
+	}
+
+	for _, sub := range c.sub {
+		if sub.name != "STANDARD" {
+			continue
+		}
+		for _, p := range sub.props {
+			if p.name != "TZOFFSETTO" {
+				continue
+			}
+			if offset, err := parseOffset(p.value); err == nil {
+				return tzid, time.FixedZone(tzid, offset)
+			}
+		}
+	}
+
+	return tzid, nil
+}
+
+// parseEvent разбирает VEVENT в событие.
+func parseEvent(c *component, tzs map[string]*time.Location) Item {
+	var (
+		item     Item
+		start    *property
+		end      *property
+		duration string
+	)
+
+	fail := func(err error) Item {
+		item.Err = err
+		return item
+	}
+
+	for i := range c.props {
+		p := &c.props[i]
+
+		switch p.name {
+		case "UID":
+			item.UID = unescapeText(p.value)
+		case "DTSTART":
+			start = p
+		case "DTEND":
+			end = p
+		case "DURATION":
+			duration = p.value
+		case "SUMMARY":
+			item.Event.Event = unescapeText(p.value)
+		case "LOCATION":
+			item.Event.Place = unescapeText(p.value)
+		case "DESCRIPTION":
+			item.Event.Description = unescapeText(p.value)
+		case "COLOR":
+			// Цвета CSS по имени (RFC 7986) не поддерживаются и пропускаются.
+			if color := strings.ToLower(p.value); colorPattern.MatchString(color) {
+				item.Event.Color = color
+			}
+		case "CATEGORIES":
+			item.Event.Tags = append(item.Event.Tags, splitText(p.value)...)
+		case categoryProp:
+			item.Event.Category = unescapeText(p.value)
+		case attributeProp:
+			if name := p.params["NAME"]; name != "" {
+				if item.Event.Attributes == nil {
+					item.Event.Attributes = make(map[string]string)
+				}
+				item.Event.Attributes[name] = unescapeText(p.value)
+			}
+		case "RRULE":
+			rule, err := rrule.Parse(p.value)
+			if err != nil {
+				return fail(err)
+			}
+			item.Event.RRule = rule.String()
+		case "EXDATE":
+			for _, value := range strings.Split(p.value, ",") {
+				t, err := parseTime(value, p.params, tzs)
+				if err != nil {
+					return fail(fmt.Errorf("EXDATE: %w", err))
+				}
+				item.Event.ExDates = append(item.Event.ExDates, t)
+			}
+		case "RECURRENCE-ID":
+			t, err := parseTime(p.value, p.params, tzs)
+			if err != nil {
+				return fail(fmt.Errorf("RECURRENCE-ID: %w", err))
+			}
+			item.Event.RecurrenceID = &t
+		}
+	}
+
+	if start == nil {
+		return fail(errors.New("DTSTART is required"))
+	}
+
+	var err error
+	if item.Event.Date, err = parseTime(start.value, start.params, tzs); err != nil {
+		return fail(fmt.Errorf("DTSTART: %w", err))
+	}
+
+	if tzid := start.params["TZID"]; tzid != "" {
+		if _, err := time.LoadLocation(tzid); err == nil {
+			item.Event.TimeZone = tzid
+		}
+	}
+
+	switch {
+	case end != nil:
+		if item.Event.End, err = parseTime(end.value, end.params, tzs); err != nil {
+			return fail(fmt.Errorf("DTEND: %w", err))
+		}
+	case duration != "":
+		d, err := parseDuration(duration)
+		if err != nil {
+			return fail(fmt.Errorf("DURATION: %w", err))
+		}
+		item.Event.End = item.Event.Date.Add(d)
+	}
+
+	if !item.Event.End.IsZero() && item.Event.End.Before(item.Event.Date) {
+		return fail(errors.New("DTEND is before DTSTART"))
+	}
+
+	return item
+}
+
+// parseTime разбирает значение DATE или DATE-TIME с учетом параметров VALUE и TZID.
+// Время без TZID и без суффикса Z считается временем UTC.
+func parseTime(value string, params map[string]string, tzs map[string]*time.Location) (time.Time, error) {
+	loc := time.UTC
+	if tzid := params["TZID"]; tzid != "" {
+		var ok bool
+		if loc, ok = tzs[tzid]; !ok {
+			var err error
+			if loc, err = time.LoadLocation(strings.TrimPrefix(tzid, "/")); err != nil {
+				return time.Time{}, fmt.Errorf("unknown time zone %q", tzid)
+			}
+		}
+	}
+
+	switch {
+	case params["VALUE"] == "DATE" || len(value) == len(dateLayout):
+		return time.ParseInLocation(dateLayout, value, loc)
+	case strings.HasSuffix(value, "Z"):
+		return time.Parse(utcLayout, value)
+	default:
+		return time.ParseInLocation(localLayout, value, loc)
+	}
+}
+
+// parseDuration разбирает длительность RFC 5545, например "PT1H30M" или "P1D".
+func parseDuration(s string) (time.Duration, error) {
+	sign := time.Duration(1)
+	switch {
+	case strings.HasPrefix(s, "-"):
+		sign, s = -1, s[1:]
+	case strings.HasPrefix(s, "+"):
+		s = s[1:]
+	}
+
+	if !strings.HasPrefix(s, "P") || len(s) < 3 {
+		return 0, fmt.Errorf("malformed duration %q", s)
+	}
+	s = s[1:]
+
+	var (
+		total  time.Duration
+		inTime bool
+		num    string
+	)
+
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			num += string(r)
+			continue
+		case r == 'T':
+			inTime = true
+			continue
+		}
+
+		n, err := strconv.Atoi(num)
+		if err != nil {
+			return 0, fmt.Errorf("malformed duration %q", s)
+		}
+		num = ""
+
+		var unit time.Duration
+		switch {
+		case r == 'W' && !inTime:
+			unit = 7 * 24 * time.Hour
+		case r == 'D' && !inTime:
+			unit = 24 * time.Hour
+		case r == 'H' && inTime:
+			unit = time.Hour
+		case r == 'M' && inTime:
+			unit = time.Minute
+		case r == 'S' && inTime:
+			unit = time.Second
+		default:
+			return 0, fmt.Errorf("malformed duration %q", s)
+		}
+		total += time.Duration(n) * unit
+	}
+
+	if num != "" {
+		return 0, fmt.Errorf("malformed duration %q", s)
+	}
+
+	return sign * total, nil
+}
+
+// parseOffset разбирает смещение UTC вида "+0300" или "-053000" в секундах.
+func parseOffset(s string) (int, error) {
+	if len(s) != 5 && len(s) != 7 {
+		return 0, fmt.Errorf("malformed offset %q", s)
+	}
+
+	sign := 1
+	switch s[0] {
+	case '-':
+		sign = -1
+	case '+':
+	default:
+		return 0, fmt.Errorf("malformed offset %q", s)
+	}
+
+	var parts [3]int
+	for i := 0; i < (len(s)-1)/2; i++ {
+		n, err := strconv.Atoi(s[1+2*i : 3+2*i])
+		if err != nil {
+			return 0, fmt.Errorf("malformed offset %q", s)
+		}
+		parts[i] = n
+	}
+
+	return sign * (parts[0]*3600 + parts[1]*60 + parts[2]), nil
+}
@@ -0,0 +1,236 @@
+// Package ical кодирует события в документ iCalendar (RFC 5545) и разбирает
+// такие документы обратно в события.
+package ical
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"l2.18/pkg/models"
+	"l2.18/pkg/rrule"
+)
+
+const (
+	prodID = "-//l2.18//Calendar//RU"
+
+	utcLayout   = "20060102T150405Z"
+	localLayout = "20060102T150405"
+	dateLayout  = "20060102"
+
+	// categoryProp и attributeProp - нестандартные свойства категории события
+	// и его атрибута. Метки выгружаются в стандартное свойство CATEGORIES.
+	categoryProp  = "X-L218-CATEGORY"
+	attributeProp = "X-L218-ATTRIBUTE"
+)
+
+// Encode записывает события в w как один VCALENDAR. Серии выгружаются
+// с RRULE и EXDATE, их измененные вхождения - отдельными VEVENT с RECURRENCE-ID.
+// Для каждого использованного часового пояса добавляется VTIMEZONE.
+func Encode(w io.Writer, events []models.Event) error {
+	lw := newLineWriter(w)
+	stamp := time.Now().UTC().Format(utcLayout)
+
+	lw.line("BEGIN:VCALENDAR")
+	lw.line("VERSION:2.0")
+	lw.line("PRODID:" + prodID)
+	lw.line("CALSCALE:GREGORIAN")
+
+	writeTimezones(lw, events)
+
+	for _, event := range events {
+		writeEvent(lw, event, event, stamp)
+		for _, override := range event.Overrides {
+			writeEvent(lw, event, override, stamp)
+		}
+	}
+
+	lw.line("END:VCALENDAR")
+	return lw.flush()
+}
+
+// writeEvent записывает VEVENT с данными data. Для измененного вхождения серии
+// data - элемент master.Overrides. Метаданные вхождения - метаданные серии.
+func writeEvent(lw *lineWriter, master, data models.Event, stamp string) {
+	tz := master.TimeZone
+
+	lw.line("BEGIN:VEVENT")
+	lw.line("UID:" + escapeText(string(master.ID)))
+	lw.line("DTSTAMP:" + stamp)
+
+	if data.RecurrenceID != nil {
+		lw.line(formatTime("RECURRENCE-ID", *data.RecurrenceID, tz))
+	}
+
+	lw.line(formatTime("DTSTART", data.Date, tz))
+	if !data.End.IsZero() {
+		lw.line(formatTime("DTEND", data.End, tz))
+	}
+
+	lw.line("SUMMARY:" + escapeText(data.Event))
+	writeMetadata(lw, master)
+
+	if data.RecurrenceID == nil && master.IsRecurring() {
+		lw.line("RRULE:" + master.RRule)
+		for _, exdate := range master.ExDates {
+			lw.line(formatTime("EXDATE", exdate, tz))
+		}
+	}
+
+	lw.line("END:VEVENT")
+}
+
+// writeMetadata записывает место, описание, цвет, метки, категорию и атрибуты события.
+// Атрибуты с кавычками или переводами строк в имени не выгружаются: имя
+// передается значением параметра, а в нем они недопустимы.
+func writeMetadata(lw *lineWriter, event models.Event) {
+	if event.Place != "" {
+		lw.line("LOCATION:" + escapeText(event.Place))
+	}
+	if event.Description != "" {
+		lw.line("DESCRIPTION:" + escapeText(event.Description))
+	}
+	if event.Color != "" {
+		lw.line("COLOR:" + event.Color)
+	}
+	if len(event.Tags) > 0 {
+		tags := make([]string, len(event.Tags))
+		for i, tag := range event.Tags {
+			tags[i] = escapeText(tag)
+		}
+		lw.line("CATEGORIES:" + strings.Join(tags, ","))
+	}
+	if event.Category != "" {
+		lw.line(categoryProp + ":" + escapeText(event.Category))
+	}
+
+	names := make([]string, 0, len(event.Attributes))
+	for name := range event.Attributes {
+		if !strings.ContainsAny(name, "\"\r\n") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		lw.line(fmt.Sprintf(`%s;NAME="%s":%s`, attributeProp, name, escapeText(event.Attributes[name])))
+	}
+}
+
+// formatTime форматирует свойство с датой-временем: с TZID, если задан часовой
+// пояс события, иначе в UTC.
+func formatTime(name string, t time.Time, tz string) string {
+	if tz != "" {
+		if loc, err := time.LoadLocation(tz); err == nil {
+			return fmt.Sprintf("%s;TZID=%s:%s", name, tz, t.In(loc).Format(localLayout))
+		}
+	}
+
+	return name + ":" + t.UTC().Format(utcLayout)
+}
+
+// writeTimezones записывает VTIMEZONE для всех часовых поясов событий.
+func writeTimezones(lw *lineWriter, events []models.Event) {
+	earliest := make(map[string]time.Time)
+	for _, event := range events {
+		if event.TimeZone == "" {
+			continue
+		}
+		if t, ok := earliest[event.TimeZone]; !ok || event.Date.Before(t) {
+			earliest[event.TimeZone] = event.Date
+		}
+	}
+
+	names := make([]string, 0, len(earliest))
+	for name := range earliest {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		loc, err := time.LoadLocation(name)
+		if err != nil {
+			continue
+		}
+		writeTimezone(lw, name, loc, earliest[name].In(loc).Year())
+	}
+}
+
+// writeTimezone описывает часовой пояс переходами года year. Каждый переход
+// становится STANDARD или DAYLIGHT с ежегодным RRULE, вычисленным по его дате.
+func writeTimezone(lw *lineWriter, name string, loc *time.Location, year int) {
+	start := time.Date(year, time.January, 1, 0, 0, 0, 0, loc)
+	end := start.AddDate(1, 0, 0)
+
+	var transitions []time.Time
+	for t := start; ; {
+		_, zoneEnd := t.ZoneBounds()
+		if zoneEnd.IsZero() || !zoneEnd.Before(end) {
+			break
+		}
+		transitions = append(transitions, zoneEnd)
+		t = zoneEnd
+	}
+
+	lw.line("BEGIN:VTIMEZONE")
+	lw.line("TZID:" + name)
+
+	if len(transitions) == 0 {
+		abbr, offset := start.Zone()
+		lw.line("BEGIN:STANDARD")
+		lw.line("DTSTART:19700101T000000")
+		lw.line("TZOFFSETFROM:" + formatOffset(offset))
+		lw.line("TZOFFSETTO:" + formatOffset(offset))
+		lw.line("TZNAME:" + abbr)
+		lw.line("END:STANDARD")
+	}
+
+	for _, t := range transitions {
+		_, from := t.Add(-time.Second).Zone()
+		abbr, to := t.Zone()
+
+		// DTSTART перехода задается настенным временем до перехода.
+		local := t.In(time.FixedZone("", from))
+
+		kind := "STANDARD"
+		if t.IsDST() {
+			kind = "DAYLIGHT"
+		}
+
+		lw.line("BEGIN:" + kind)
+		lw.line("DTSTART:" + local.Format(localLayout))
+		lw.line(fmt.Sprintf("RRULE:FREQ=YEARLY;BYMONTH=%d;BYDAY=%s",
+			int(local.Month()), nthWeekday(local)))
+		lw.line("TZOFFSETFROM:" + formatOffset(from))
+		lw.line("TZOFFSETTO:" + formatOffset(to))
+		lw.line("TZNAME:" + abbr)
+		lw.line("END:" + kind)
+	}
+
+	lw.line("END:VTIMEZONE")
+}
+
+// nthWeekday возвращает день в формате BYDAY: "-1SU" для последней
+// недели месяца, иначе порядковый номер недели, например "2SU".
+func nthWeekday(t time.Time) string {
+	days := time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+
+	n := (t.Day()-1)/7 + 1
+	if t.Day()+7 > days {
+		n = -1
+	}
+
+	return rrule.Weekday{N: n, Weekday: t.Weekday()}.String()
+}
+
+func formatOffset(seconds int) string {
+	sign := "+"
+	if seconds < 0 {
+		sign = "-"
+		seconds = -seconds
+	}
+
+	return fmt.Sprintf("%s%02d%02d", sign, seconds/3600, seconds%3600/60)
+}
@@ -0,0 +1,251 @@
+package ical
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"l2.18/pkg/models"
+)
+
+func TestRoundTrip(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("tzdata unavailable: %v", err)
+	}
+
+	start := time.Date(2025, time.March, 3, 9, 0, 0, 0, loc)
+	recurrenceID := start.AddDate(0, 0, 7)
+	moved := recurrenceID.Add(2 * time.Hour)
+
+	events := []models.Event{
+		{
+			ID:    "1",
+			Date:  time.Date(2025, time.March, 1, 12, 0, 0, 0, time.UTC),
+			End:   time.Date(2025, time.March, 1, 13, 0, 0, 0, time.UTC),
+			Event: "встреча; с командой, обсуждение\n" + strings.Repeat("длинное описание ", 10),
+		},
+		{
+			ID:       "2",
+			Date:     start,
+			End:      start.Add(30 * time.Minute),
+			TimeZone: "Europe/Berlin",
+			Event:    "standup",
+			RRule:    "FREQ=WEEKLY;BYDAY=MO",
+			ExDates:  []time.Time{start.AddDate(0, 0, 14)},
+			Overrides: []models.Event{
+				{Date: moved, End: moved.Add(time.Hour), Event: "late standup", RecurrenceID: &recurrenceID},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, events); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\r\n"), "\r\n") {
+		if len(line) > maxLineOctets {
+			t.Errorf("line is not folded: %q", line)
+		}
+	}
+
+	items, err := Decode(&buf)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(items) != len(events) {
+		t.Fatalf("expected %d items, got %d", len(events), len(items))
+	}
+
+	for i, item := range items {
+		want := events[i]
+		got := item.Event
+
+		if item.Err != nil {
+			t.Fatalf("item[%d]: unexpected error: %v", i, item.Err)
+		}
+		if item.UID != string(want.ID) {
+			t.Errorf("item[%d].UID: got %q, want %q", i, item.UID, want.ID)
+		}
+		if !got.Date.Equal(want.Date) || !got.End.Equal(want.End) {
+			t.Errorf("item[%d] time mismatch: got %v - %v", i, got.Date, got.End)
+		}
+		if got.Event != want.Event || got.TimeZone != want.TimeZone || got.RRule != want.RRule {
+			t.Errorf("item[%d] mismatch: got %+v", i, got)
+		}
+		if len(got.ExDates) != len(want.ExDates) || len(got.Overrides) != len(want.Overrides) {
+			t.Fatalf("item[%d] series mismatch: got %+v", i, got)
+		}
+	}
+
+	override := items[1].Event.Overrides[0]
+	if !override.Date.Equal(moved) || !override.RecurrenceID.Equal(recurrenceID) {
+		t.Errorf("unexpected override %+v", override)
+	}
+}
+
+func TestRoundTripMetadata(t *testing.T) {
+	start := time.Date(2025, time.March, 3, 9, 0, 0, 0, time.UTC)
+	recurrenceID := start.AddDate(0, 0, 7)
+
+	event := models.Event{
+		ID:          "1",
+		Date:        start,
+		Event:       "standup",
+		Place:       "Room 1, floor 2",
+		Description: "daily sync;\nbring updates",
+		Category:    "work",
+		Color:       "#ff8800",
+		Tags:        []string{"team", "a,b"},
+		Attributes:  map[string]string{"jira": "CAL-1", "room;id": "42"},
+		RRule:       "FREQ=DAILY",
+		Overrides:   []models.Event{{Date: recurrenceID.Add(time.Hour), Event: "late", RecurrenceID: &recurrenceID}},
+	}
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, []models.Event{event}); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+
+	items, err := Decode(&buf)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(items) != 1 || items[0].Err != nil {
+		t.Fatalf("expected 1 item, got %+v", items)
+	}
+
+	got := items[0].Event
+	if got.Place != event.Place || got.Description != event.Description ||
+		got.Category != event.Category || got.Color != event.Color {
+		t.Errorf("metadata mismatch: got %+v", got)
+	}
+	if len(got.Tags) != 2 || got.Tags[0] != "team" || got.Tags[1] != "a,b" {
+		t.Errorf("tags mismatch: got %q", got.Tags)
+	}
+	if len(got.Attributes) != 2 || got.Attributes["jira"] != "CAL-1" || got.Attributes["room;id"] != "42" {
+		t.Errorf("attributes mismatch: got %v", got.Attributes)
+	}
+	if len(got.Overrides) != 1 || got.Overrides[0].Place != "" || got.Overrides[0].Tags != nil {
+		t.Errorf("override must not keep series metadata: got %+v", got.Overrides)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	doc := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"BEGIN:VTIMEZONE",
+		"TZID:Custom Standard Time",
+		"BEGIN:STANDARD",
+		"DTSTART:16010101T000000",
+		"TZOFFSETFROM:+0300",
+		"TZOFFSETTO:+0300",
+		"END:STANDARD",
+		"END:VTIMEZONE",
+		"BEGIN:VEVENT",
+		"UID:all-day",
+		"DTSTART;VALUE=DATE:20250310",
+		"DTEND;VALUE=DATE:20250311",
+		"SUMMARY:holi",
+		" day",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:custom-tz",
+		"DTSTART;TZID=Custom Standard Time:20250310T100000",
+		"DURATION:PT1H30M",
+		"SUMMARY:meeting",
+		"BEGIN:VALARM",
+		"TRIGGER:-PT15M",
+		"END:VALARM",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:no-start",
+		"SUMMARY:broken",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:orphan",
+		"RECURRENCE-ID:20250310T100000Z",
+		"DTSTART:20250310T120000Z",
+		"END:VEVENT",
+		"END:VCALENDAR",
+		"",
+	}, "\r\n")
+
+	items, err := Decode(strings.NewReader(doc))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(items) != 4 {
+		t.Fatalf("expected 4 items, got %d", len(items))
+	}
+
+	allDay := items[0]
+	if allDay.Err != nil || allDay.Event.Event != "holiday" ||
+		!allDay.Event.Date.Equal(time.Date(2025, time.March, 10, 0, 0, 0, 0, time.UTC)) ||
+		allDay.Event.Duration() != 24*time.Hour {
+		t.Errorf("unexpected all-day item %+v", allDay)
+	}
+
+	custom := items[1]
+	if custom.Err != nil ||
+		!custom.Event.Date.Equal(time.Date(2025, time.March, 10, 7, 0, 0, 0, time.UTC)) ||
+		custom.Event.Duration() != 90*time.Minute || custom.Event.TimeZone != "" {
+		t.Errorf("unexpected custom time zone item %+v", custom)
+	}
+
+	for _, item := range items[2:] {
+		if item.Err == nil {
+			t.Errorf("expected error for %q", item.UID)
+		}
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	testCases := []struct {
+		name string
+		doc  string
+	}{
+		{name: "empty", doc: ""},
+		{name: "not a calendar", doc: "BEGIN:VEVENT\r\nEND:VEVENT\r\n"},
+		{name: "unterminated", doc: "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\n"},
+		{name: "malformed line", doc: "BEGIN:VCALENDAR\r\ngarbage\r\nEND:VCALENDAR\r\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := Decode(strings.NewReader(tc.doc)); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected time.Duration
+		ok       bool
+	}{
+		{"PT1H30M", 90 * time.Minute, true},
+		{"P1D", 24 * time.Hour, true},
+		{"P1W", 7 * 24 * time.Hour, true},
+		{"P1DT2H", 26 * time.Hour, true},
+		{"-PT15M", -15 * time.Minute, true},
+		{"PT", 0, false},
+		{"P1H", 0, false},
+		{"1H", 0, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			got, err := parseDuration(tc.input)
+			if (err == nil) != tc.ok || got != tc.expected {
+				t.Errorf("got %v, %v; want %v", got, err, tc.expected)
+			}
+		})
+	}
+}
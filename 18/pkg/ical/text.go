@@ -0,0 +1,193 @@
+package ical
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxLineOctets - максимальная длина строки контента без CRLF (RFC 5545, 3.1).
+const maxLineOctets = 75
+
+// lineWriter пишет строки контента, сворачивая длинные строки
+// и завершая каждую CRLF.
+type lineWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+func newLineWriter(w io.Writer) *lineWriter {
+	return &lineWriter{w: bufio.NewWriter(w)}
+}
+
+func (lw *lineWriter) line(s string) {
+	if lw.err != nil {
+		return
+	}
+
+	for len(s) > maxLineOctets {
+		cut := maxLineOctets
+		// Не разрываем многобайтовый символ UTF-8.
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+
+		_, lw.err = lw.w.WriteString(s[:cut] + "\r\n")
+		if lw.err != nil {
+			return
+		}
+		// Строка продолжения начинается с пробела, который занимает один октет.
+		s = " " + s[cut:]
+	}
+
+	_, lw.err = lw.w.WriteString(s + "\r\n")
+}
+
+func (lw *lineWriter) flush() error {
+	if lw.err != nil {
+		return lw.err
+	}
+	return lw.w.Flush()
+}
+
+// escapeText экранирует значение типа TEXT (RFC 5545, 3.3.11).
+func escapeText(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+	).Replace(s)
+}
+
+// unescapeText снимает экранирование значения типа TEXT.
+func unescapeText(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i == len(s)-1 {
+			b.WriteByte(s[i])
+			continue
+		}
+
+		i++
+		switch s[i] {
+		case 'n', 'N':
+			b.WriteByte('\n')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+
+	return b.String()
+}
+
+// splitText разбивает список значений TEXT по неэкранированным запятым
+// и снимает с каждого значения экранирование.
+func splitText(s string) []string {
+	var parts []string
+
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case ',':
+			parts = append(parts, unescapeText(s[start:i]))
+			start = i + 1
+		}
+	}
+
+	return append(parts, unescapeText(s[start:]))
+}
+
+// unfold читает строки контента, склеивая свернутые строки.
+func unfold(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	var lines []string
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" {
+			continue
+		}
+
+		if (line[0] == ' ' || line[0] == '\t') && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	return lines, scanner.Err()
+}
+
+// property - разобранная строка контента: NAME;PARAM=VALUE:value.
+type property struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// parseProperty разбирает строку контента. Значения параметров
+// могут быть заключены в кавычки и содержать ':' и ';'.
+func parseProperty(line string) (property, bool) {
+	prop := property{params: make(map[string]string)}
+
+	inQuotes := false
+	colon := -1
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '"':
+			inQuotes = !inQuotes
+		case ':':
+			if !inQuotes {
+				colon = i
+			}
+		}
+		if colon != -1 {
+			break
+		}
+	}
+	if colon == -1 {
+		return prop, false
+	}
+
+	head := splitUnquoted(line[:colon], ';')
+	prop.name = strings.ToUpper(head[0])
+	prop.value = line[colon+1:]
+
+	for _, param := range head[1:] {
+		key, value, ok := strings.Cut(param, "=")
+		if !ok {
+			continue
+		}
+		prop.params[strings.ToUpper(key)] = strings.Trim(value, `"`)
+	}
+
+	return prop, true
+}
+
+func splitUnquoted(s string, sep byte) []string {
+	var parts []string
+
+	inQuotes := false
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			inQuotes = !inQuotes
+		case sep:
+			if !inQuotes {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+
+	return append(parts, s[start:])
+}
@@ -0,0 +1,28 @@
+package models
+
+// RSVPStatus - ответ участника на приглашение.
+type RSVPStatus string
+
+// Ответы на приглашение, как PARTSTAT в iCalendar.
+const (
+	RSVPNeedsAction RSVPStatus = "needs-action"
+	RSVPAccepted    RSVPStatus = "accepted"
+	RSVPDeclined    RSVPStatus = "declined"
+	RSVPTentative   RSVPStatus = "tentative"
+)
+
+// Valid сообщает, является ли s известным ответом.
+func (s RSVPStatus) Valid() bool {
+	switch s {
+	case RSVPNeedsAction, RSVPAccepted, RSVPDeclined, RSVPTentative:
+		return true
+	default:
+		return false
+	}
+}
+
+// Attendee - приглашенный в событие пользователь и его ответ.
+type Attendee struct {
+	UserID UserID     `json:"user_id"`
+	Status RSVPStatus `json:"status"`
+}
@@ -0,0 +1,30 @@
+package models
+
+// BatchOp - вид операции пакета.
+type BatchOp string
+
+const (
+	BatchCreate BatchOp = "create"
+	BatchUpdate BatchOp = "update"
+	BatchDelete BatchOp = "delete"
+)
+
+// BatchOperation - операция пакетной обработки событий.
+type BatchOperation struct {
+	Op BatchOp `json:"op"`
+	// ID - изменяемое или удаляемое событие для update и delete.
+	ID EventID `json:"id,omitempty"`
+	// Event - новое событие для create или полная замена события ID для update.
+	// Версия события игнорируется.
+	Event *Event `json:"event,omitempty"`
+	// Version - ожидаемая версия события для update и delete (0 - без проверки).
+	Version int64 `json:"version,omitempty"`
+}
+
+// BatchResult - результат операции пакета.
+type BatchResult struct {
+	// EventID - созданное, измененное или удаленное событие.
+	EventID EventID
+	// Err - ошибка операции, nil если операция применена.
+	Err error
+}
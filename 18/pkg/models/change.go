@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// ChangeType определяет вид изменения события.
+type ChangeType string
+
+// Виды изменений событий.
+const (
+	ChangeCreated ChangeType = "created"
+	ChangeUpdated ChangeType = "updated"
+	ChangeDeleted ChangeType = "deleted"
+)
+
+// Change - уведомление об изменении события пользователя.
+type Change struct {
+	Type    ChangeType `json:"type"`
+	EventID EventID    `json:"event_id"`
+	// Event - состояние события после изменения. Пусто для удаления.
+	Event *Event    `json:"event,omitempty"`
+	At    time.Time `json:"at"`
+}
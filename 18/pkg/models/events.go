@@ -1,13 +1,164 @@
 package models
 
-import "time"
+import (
+	"slices"
+	"time"
+)
 
 // EventID определяет модель айди события
 type EventID string
 
 // Event определяет модель события.
 type Event struct {
-	ID    EventID   `json:"id"`
-	Date  time.Time `json:"date"`
-	Event string    `json:"event"`
+	ID EventID `json:"id"`
+	// Date - начало события.
+	Date time.Time `json:"date"`
+	// End - конец события. Нулевое значение означает событие без длительности.
+	End time.Time `json:"end,omitzero"`
+	// TimeZone - часовой пояс IANA, в котором задано событие.
+	// Определяет настенное время вхождений серии.
+	TimeZone string `json:"timezone,omitempty"`
+	Event    string `json:"event"`
+
+	// Place - место проведения события. В JSON и iCalendar - location.
+	Place string `json:"location,omitempty"`
+	// Description - подробное описание события.
+	Description string `json:"description,omitempty"`
+	// Category - категория события, например "work". Хранится в нижнем регистре.
+	Category string `json:"category,omitempty"`
+	// Color - цвет события в формате #rrggbb.
+	Color string `json:"color,omitempty"`
+	// Tags - метки события в нижнем регистре, без повторов и по возрастанию.
+	Tags []string `json:"tags,omitempty"`
+	// Attributes - произвольные атрибуты события.
+	Attributes map[string]string `json:"attributes,omitempty"`
+
+	// RRule - правило повторения в формате iCalendar (RFC 5545),
+	// например "FREQ=WEEKLY;BYDAY=MO". Пустое для одиночных событий.
+	RRule string `json:"rrule,omitempty"`
+	// ExDates - исходные даты начала удаленных вхождений серии.
+	ExDates []time.Time `json:"exdates,omitempty"`
+	// Overrides - измененные вхождения серии. Каждое определяется RecurrenceID.
+	Overrides []Event `json:"overrides,omitempty"`
+	// RecurrenceID - исходная дата начала вхождения серии. Заполняется
+	// у развернутых вхождений и у элементов Overrides.
+	RecurrenceID *time.Time `json:"recurrence_id,omitempty"`
+
+	// Attendees - приглашенные в событие пользователи. Событие видно им
+	// в их собственных запросах событий.
+	Attendees []Attendee `json:"attendees,omitempty"`
+	// Owner - владелец события. Заполняется только у событий из чужих
+	// календарей, в которые пользователь приглашен.
+	Owner UserID `json:"owner,omitempty"`
+
+	// Version - версия события. Хранилище увеличивает ее при каждом изменении.
+	// В изменении события - ожидаемая текущая версия, 0 - без проверки.
+	Version int64 `json:"version,omitempty"`
+}
+
+// IsRecurring сообщает, является ли событие серией.
+func (e *Event) IsRecurring() bool {
+	return e.RRule != ""
+}
+
+// EndTime возвращает конец события, а для событий без длительности - его начало.
+func (e *Event) EndTime() time.Time {
+	if e.End.IsZero() {
+		return e.Date
+	}
+	return e.End
+}
+
+// Duration возвращает длительность события.
+func (e *Event) Duration() time.Duration {
+	return e.EndTime().Sub(e.Date)
+}
+
+// Overlaps сообщает, пересекается ли событие с диапазоном [start, end).
+// Событие без длительности пересекается, если его начало попадает в диапазон.
+func (e *Event) Overlaps(start, end time.Time) bool {
+	if !e.Date.Before(end) {
+		return false
+	}
+
+	return !e.Date.Before(start) || e.EndTime().After(start)
+}
+
+// Location возвращает часовой пояс события. Если TimeZone не задан
+// или неизвестен, используется часовой пояс Date.
+func (e *Event) Location() *time.Location {
+	if e.TimeZone != "" {
+		if loc, err := time.LoadLocation(e.TimeZone); err == nil {
+			return loc
+		}
+	}
+	return e.Date.Location()
+}
+
+// Merge заменяет поля события непустыми полями patch.
+// Срезы и Attributes заменяются целиком, если в patch они не nil.
+func (e *Event) Merge(patch Event) {
+	if !patch.Date.IsZero() {
+		e.Date = patch.Date
+	}
+	if !patch.End.IsZero() {
+		e.End = patch.End
+	}
+	if patch.TimeZone != "" {
+		e.TimeZone = patch.TimeZone
+	}
+	if patch.Event != "" {
+		e.Event = patch.Event
+	}
+	if patch.Place != "" {
+		e.Place = patch.Place
+	}
+	if patch.Description != "" {
+		e.Description = patch.Description
+	}
+	if patch.Category != "" {
+		e.Category = patch.Category
+	}
+	if patch.Color != "" {
+		e.Color = patch.Color
+	}
+	if patch.Tags != nil {
+		e.Tags = patch.Tags
+	}
+	if patch.Attributes != nil {
+		e.Attributes = patch.Attributes
+	}
+	if patch.RRule != "" {
+		e.RRule = patch.RRule
+	}
+	if patch.ExDates != nil {
+		e.ExDates = patch.ExDates
+	}
+	if patch.Overrides != nil {
+		e.Overrides = patch.Overrides
+	}
+	if patch.Attendees != nil {
+		e.Attendees = patch.Attendees
+	}
+}
+
+// HasTag сообщает, есть ли у события метка tag.
+func (e *Event) HasTag(tag string) bool {
+	return slices.Contains(e.Tags, tag)
+}
+
+// MatchesVersion сообщает, совпадает ли версия события с ожидаемой version.
+// Нулевая ожидаемая версия совпадает с любой.
+func (e *Event) MatchesVersion(version int64) bool {
+	return version == 0 || version == e.Version
+}
+
+// Attendee возвращает участника события userID.
+func (e *Event) Attendee(userID UserID) (*Attendee, bool) {
+	for i := range e.Attendees {
+		if e.Attendees[i].UserID == userID {
+			return &e.Attendees[i], true
+		}
+	}
+	return nil, false
 }
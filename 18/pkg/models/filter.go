@@ -0,0 +1,30 @@
+package models
+
+// EventFilter - условия отбора событий по метаданным. Пустой фильтр
+// подходит любому событию.
+type EventFilter struct {
+	// Tags - метки, которые должны быть у события все.
+	Tags []string
+	// Category - категория события. Пустая - любая.
+	Category string
+}
+
+// IsEmpty сообщает, что фильтр не задает условий.
+func (f EventFilter) IsEmpty() bool {
+	return len(f.Tags) == 0 && f.Category == ""
+}
+
+// Matches сообщает, подходит ли событие фильтру.
+func (f EventFilter) Matches(e *Event) bool {
+	if f.Category != "" && e.Category != f.Category {
+		return false
+	}
+
+	for _, tag := range f.Tags {
+		if !e.HasTag(tag) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// Interval - промежуток времени [Start, End).
+type Interval struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// FreeBusy - занятость нескольких пользователей в заданном окне.
+type FreeBusy struct {
+	// Busy - объединенные промежутки, когда занят хотя бы один пользователь.
+	Busy []Interval `json:"busy"`
+	// Free - предложенные свободные для всех промежутки запрошенной длительности.
+	Free []Interval `json:"free"`
+}
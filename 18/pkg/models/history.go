@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// HistoryEntry - запись истории изменений события. Записи не изменяются
+// после сохранения и удаляются только по истечении срока хранения истории.
+type HistoryEntry struct {
+	// Revision - номер записи в истории события, начиная с 1.
+	Revision int64      `json:"revision"`
+	EventID  EventID    `json:"event_id"`
+	Type     ChangeType `json:"type"`
+	// Actor - пользователь, изменивший событие.
+	Actor UserID    `json:"actor"`
+	At    time.Time `json:"at"`
+	// Before - состояние события до изменения. Пусто для созданного события.
+	Before *Event `json:"before,omitempty"`
+	// After - состояние события после изменения. Пусто для удаленного события.
+	After *Event `json:"after,omitempty"`
+}
+
+// Snapshot возвращает состояние события, к которому можно вернуться по этой записи:
+// для удаления - событие перед удалением, для остальных изменений - после изменения.
+func (h HistoryEntry) Snapshot() *Event {
+	if h.Type == ChangeDeleted {
+		return h.Before
+	}
+	return h.After
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// IdempotencyRecord - ответ на запрос с ключом идемпотентности (заголовок Idempotency-Key),
+// который повторяется при повторах запроса с тем же ключом.
+type IdempotencyRecord struct {
+	// UserID и Key - пользователь, отправивший запрос, и ключ. Ключи разных пользователей не пересекаются.
+	UserID UserID `json:"user_id"`
+	Key    string `json:"key"`
+	// Fingerprint - хеш запроса: повтор ключа с другим запросом отклоняется.
+	Fingerprint string `json:"fingerprint"`
+	// Status, Header и Body - сохраненный ответ.
+	Status    int               `json:"status"`
+	Header    map[string]string `json:"header,omitempty"`
+	Body      []byte            `json:"body"`
+	CreatedAt time.Time         `json:"created_at"`
+}
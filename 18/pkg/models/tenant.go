@@ -0,0 +1,28 @@
+package models
+
+// TenantID определяет модель айди арендатора - команды, которой выделен
+// отдельный календарь на общем сервере. Пустой ID - арендатор по умолчанию.
+type TenantID string
+
+// TenantQuota - квоты арендатора. Нулевое значение поля - без ограничения.
+type TenantQuota struct {
+	// MaxEvents - сколько событий могут хранить все пользователи арендатора вместе.
+	MaxEvents int `json:"max_events"`
+	// RateLimit и RateBurst - сколько запросов в секунду в среднем и подряд
+	// могут выполнять все пользователи арендатора вместе.
+	RateLimit float64 `json:"rate_limit"`
+	RateBurst int     `json:"rate_burst"`
+}
+
+// TenantUsage - использование ресурсов арендатором.
+type TenantUsage struct {
+	Tenant TenantID `json:"tenant"`
+	// Users и Events - сколько пользователей хранят события и сколько их у них всего.
+	Users  int `json:"users"`
+	Events int `json:"events"`
+	// Requests и RejectedRequests - сколько запросов принято и отклонено
+	// ограничением частоты с запуска сервера.
+	Requests         uint64      `json:"requests"`
+	RejectedRequests uint64      `json:"rejected_requests"`
+	Quota            TenantQuota `json:"quota"`
+}
@@ -0,0 +1,153 @@
+package rrule
+
+import "time"
+
+// Between возвращает начала вхождений серии, начинающейся в dtstart,
+// которые попадают в диапазон [start, end). Вхождения отсортированы по возрастанию.
+//
+// Вхождения вычисляются по настенному времени в часовом поясе dtstart,
+// поэтому при переходе на летнее время событие остается в тот же час.
+func (r *Rule) Between(dtstart, start, end time.Time) []time.Time {
+	var result []time.Time
+	r.each(dtstart, start, end, func(occurrence time.Time) bool {
+		result = append(result, occurrence)
+		return true
+	})
+	return result
+}
+
+// Any сообщает, есть ли среди вхождений серии в диапазоне [start, end) такое,
+// для которого match вернет true. В отличие от Between вхождения не собираются,
+// а перебор останавливается на первом подходящем.
+func (r *Rule) Any(dtstart, start, end time.Time, match func(occurrence time.Time) bool) bool {
+	found := false
+	r.each(dtstart, start, end, func(occurrence time.Time) bool {
+		found = match(occurrence)
+		return !found
+	})
+	return found
+}
+
+// each вызывает fn для вхождений серии в диапазоне [start, end) по возрастанию,
+// пока fn возвращает true.
+func (r *Rule) each(dtstart, start, end time.Time, fn func(occurrence time.Time) bool) {
+	count := 0
+	until := r.until(dtstart)
+
+	for period := 0; period < maxPeriods; period++ {
+		for _, occurrence := range r.period(dtstart, period) {
+			if occurrence.Before(dtstart) {
+				continue
+			}
+			if !until.IsZero() && occurrence.After(until) {
+				return
+			}
+			if !occurrence.Before(end) {
+				return
+			}
+
+			count++
+			if r.Count > 0 && count > r.Count {
+				return
+			}
+
+			if !occurrence.Before(start) && !fn(occurrence) {
+				return
+			}
+		}
+	}
+}
+
+// Contains сообщает, является ли t вхождением серии, начинающейся в dtstart.
+func (r *Rule) Contains(dtstart, t time.Time) bool {
+	occurrences := r.Between(dtstart, t, t.Add(time.Nanosecond))
+	return len(occurrences) == 1 && occurrences[0].Equal(t)
+}
+
+// period возвращает отсортированных кандидатов во вхождения в n-м периоде серии.
+func (r *Rule) period(dtstart time.Time, n int) []time.Time {
+	step := n * r.Interval
+	year, month, day := dtstart.Date()
+	at := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, dtstart.Hour(), dtstart.Minute(),
+			dtstart.Second(), dtstart.Nanosecond(), dtstart.Location())
+	}
+
+	switch r.Freq {
+	case Daily:
+		t := at(year, month, day+step)
+		if len(r.ByDay) > 0 && !r.matchesWeekday(t.Weekday()) {
+			return nil
+		}
+		return []time.Time{t}
+
+	case Weekly:
+		monday := day - (int(dtstart.Weekday())+6)%7 + step*7
+		if len(r.ByDay) == 0 {
+			return []time.Time{at(year, month, day+step*7)}
+		}
+
+		var result []time.Time
+		for i := 0; i < 7; i++ {
+			t := at(year, month, monday+i)
+			if r.matchesWeekday(t.Weekday()) {
+				result = append(result, t)
+			}
+		}
+		return result
+
+	case Monthly:
+		first := time.Date(year, month+time.Month(step), 1, 0, 0, 0, 0, dtstart.Location())
+		return r.inMonth(first.Year(), first.Month(), day, at)
+
+	case Yearly:
+		return r.inMonth(year+step, month, day, at)
+	}
+
+	return nil
+}
+
+// inMonth возвращает кандидатов в указанном месяце: все подходящие по BYDAY дни,
+// либо день dtstart, если BYDAY не задан (несуществующие даты пропускаются).
+func (r *Rule) inMonth(
+	year int, month time.Month, day int,
+	at func(int, time.Month, int) time.Time,
+) []time.Time {
+	days := daysIn(year, month)
+
+	if len(r.ByDay) == 0 {
+		if day > days {
+			return nil
+		}
+		return []time.Time{at(year, month, day)}
+	}
+
+	var result []time.Time
+	for d := 1; d <= days; d++ {
+		weekday := time.Date(year, month, d, 0, 0, 0, 0, time.UTC).Weekday()
+		nth := (d-1)/7 + 1
+		nthFromEnd := -((days-d)/7 + 1)
+
+		for _, bd := range r.ByDay {
+			if bd.Weekday == weekday && (bd.N == 0 || bd.N == nth || bd.N == nthFromEnd) {
+				result = append(result, at(year, month, d))
+				break
+			}
+		}
+	}
+
+	return result
+}
+
+func (r *Rule) matchesWeekday(weekday time.Weekday) bool {
+	for _, bd := range r.ByDay {
+		if bd.Weekday == weekday {
+			return true
+		}
+	}
+	return false
+}
+
+func daysIn(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
@@ -0,0 +1,246 @@
+// Package rrule реализует подмножество правил повторения iCalendar (RFC 5545):
+// FREQ=DAILY/WEEKLY/MONTHLY/YEARLY, INTERVAL, BYDAY, COUNT и UNTIL.
+package rrule
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidRule возвращается при разборе некорректного правила.
+var ErrInvalidRule = errors.New("invalid recurrence rule")
+
+// maxPeriods ограничивает количество перебираемых периодов,
+// чтобы правило без COUNT и UNTIL не зациклило разворачивание.
+const maxPeriods = 100000
+
+// Frequency определяет частоту повторения.
+type Frequency int
+
+// Поддерживаемые частоты.
+const (
+	Daily Frequency = iota + 1
+	Weekly
+	Monthly
+	Yearly
+)
+
+var frequencyNames = map[Frequency]string{
+	Daily:   "DAILY",
+	Weekly:  "WEEKLY",
+	Monthly: "MONTHLY",
+	Yearly:  "YEARLY",
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+// Weekday - элемент BYDAY. N - порядковый номер дня недели в месяце
+// (1 - первый, -1 - последний), 0 - каждый такой день.
+// N допускается только для MONTHLY и YEARLY.
+type Weekday struct {
+	N       int
+	Weekday time.Weekday
+}
+
+// Rule - разобранное правило повторения.
+type Rule struct {
+	Freq     Frequency
+	Interval int
+	ByDay    []Weekday
+	Count    int
+	Until    time.Time
+	// FloatingUntil - UNTIL задан без часового пояса (без "Z" или только датой).
+	// Тогда Until хранит его настенное время как UTC, а граница серии
+	// вычисляется в часовом поясе ее начала.
+	FloatingUntil bool
+}
+
+// Parse разбирает правило вида "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE;COUNT=10".
+// Префикс "RRULE:" допускается.
+func Parse(s string) (*Rule, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "RRULE:")
+	if s == "" {
+		return nil, fmt.Errorf("%w: empty rule", ErrInvalidRule)
+	}
+
+	rule := &Rule{Interval: 1}
+
+	for _, part := range strings.Split(s, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("%w: malformed part %q", ErrInvalidRule, part)
+		}
+
+		var err error
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			rule.Freq, err = parseFrequency(value)
+		case "INTERVAL":
+			rule.Interval, err = parsePositive(value)
+		case "COUNT":
+			rule.Count, err = parsePositive(value)
+		case "UNTIL":
+			rule.Until, rule.FloatingUntil, err = parseUntil(value)
+		case "BYDAY":
+			rule.ByDay, err = parseByDay(value)
+		case "WKST":
+			// Неделя всегда начинается с понедельника.
+		default:
+			err = fmt.Errorf("unsupported part %q", key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidRule, err)
+		}
+	}
+
+	if rule.Freq == 0 {
+		return nil, fmt.Errorf("%w: FREQ is required", ErrInvalidRule)
+	}
+	if rule.Count != 0 && !rule.Until.IsZero() {
+		return nil, fmt.Errorf("%w: COUNT and UNTIL are mutually exclusive", ErrInvalidRule)
+	}
+	if rule.Freq == Daily || rule.Freq == Weekly {
+		for _, d := range rule.ByDay {
+			if d.N != 0 {
+				return nil, fmt.Errorf("%w: BYDAY %s requires MONTHLY or YEARLY", ErrInvalidRule, d)
+			}
+		}
+	}
+
+	return rule, nil
+}
+
+// String возвращает правило в формате RFC 5545 (без префикса "RRULE:").
+func (r *Rule) String() string {
+	parts := []string{"FREQ=" + frequencyNames[r.Freq]}
+
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, d := range r.ByDay {
+			days[i] = d.String()
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+
+	switch {
+	case r.Until.IsZero():
+	case !r.FloatingUntil:
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	case r.Until.Equal(endOfDay(r.Until)):
+		parts = append(parts, "UNTIL="+r.Until.Format("20060102"))
+	default:
+		parts = append(parts, "UNTIL="+r.Until.Format("20060102T150405"))
+	}
+
+	return strings.Join(parts, ";")
+}
+
+// String возвращает день в формате BYDAY, например "MO" или "-1FR".
+func (w Weekday) String() string {
+	for name, day := range weekdayNames {
+		if day == w.Weekday {
+			if w.N != 0 {
+				return strconv.Itoa(w.N) + name
+			}
+			return name
+		}
+	}
+	return ""
+}
+
+func parseFrequency(s string) (Frequency, error) {
+	for freq, name := range frequencyNames {
+		if strings.EqualFold(name, s) {
+			return freq, nil
+		}
+	}
+	return 0, fmt.Errorf("unsupported FREQ %q", s)
+}
+
+func parsePositive(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("expected positive integer, got %q", s)
+	}
+	return n, nil
+}
+
+// parseUntil разбирает UNTIL и сообщает, задан ли он без часового пояса.
+func parseUntil(s string) (until time.Time, floating bool, err error) {
+	layouts := []string{"20060102T150405Z", "20060102T150405", "20060102"}
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			if layout == "20060102" {
+				// Дата без времени включает весь день.
+				t = endOfDay(t)
+			}
+			return t, layout != "20060102T150405Z", nil
+		}
+	}
+	return time.Time{}, false, fmt.Errorf("malformed UNTIL %q", s)
+}
+
+// until возвращает границу UNTIL для серии, начинающейся в dtstart.
+func (r *Rule) until(dtstart time.Time) time.Time {
+	if !r.FloatingUntil || r.Until.IsZero() {
+		return r.Until
+	}
+
+	u := r.Until
+	return time.Date(u.Year(), u.Month(), u.Day(), u.Hour(), u.Minute(),
+		u.Second(), u.Nanosecond(), dtstart.Location())
+}
+
+// endOfDay возвращает последний момент дня t.
+func endOfDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day+1, 0, 0, 0, 0, t.Location()).Add(-time.Nanosecond)
+}
+
+func parseByDay(s string) ([]Weekday, error) {
+	var days []Weekday
+
+	for _, item := range strings.Split(s, ",") {
+		item = strings.ToUpper(strings.TrimSpace(item))
+		if len(item) < 2 {
+			return nil, fmt.Errorf("malformed BYDAY %q", item)
+		}
+
+		day, ok := weekdayNames[item[len(item)-2:]]
+		if !ok {
+			return nil, fmt.Errorf("malformed BYDAY %q", item)
+		}
+
+		var n int
+		if prefix := item[:len(item)-2]; prefix != "" {
+			var err error
+			n, err = strconv.Atoi(prefix)
+			if err != nil || n == 0 || n < -5 || n > 5 {
+				return nil, fmt.Errorf("malformed BYDAY %q", item)
+			}
+		}
+
+		days = append(days, Weekday{N: n, Weekday: day})
+	}
+
+	return days, nil
+}
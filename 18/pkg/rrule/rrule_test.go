@@ -0,0 +1,241 @@
+package rrule
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected string
+		err      error
+	}{
+		{
+			name:     "success - weekly with byday",
+			input:    "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE;COUNT=10",
+			expected: "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE;COUNT=10",
+		},
+		{
+			name:     "success - rrule prefix and until",
+			input:    "RRULE:FREQ=DAILY;UNTIL=20250301T000000Z",
+			expected: "FREQ=DAILY;UNTIL=20250301T000000Z",
+		},
+		{
+			name:     "success - ordinal byday",
+			input:    "FREQ=MONTHLY;BYDAY=-1FR",
+			expected: "FREQ=MONTHLY;BYDAY=-1FR",
+		},
+		{
+			name:     "success - floating until",
+			input:    "FREQ=DAILY;UNTIL=20250301T100000",
+			expected: "FREQ=DAILY;UNTIL=20250301T100000",
+		},
+		{
+			name:     "success - until date",
+			input:    "FREQ=YEARLY;UNTIL=20270101",
+			expected: "FREQ=YEARLY;UNTIL=20270101",
+		},
+		{
+			name:  "failure - ordinal byday in weekly rule",
+			input: "FREQ=WEEKLY;BYDAY=1MO",
+			err:   ErrInvalidRule,
+		},
+		{
+			name:  "failure - ordinal byday in daily rule",
+			input: "FREQ=DAILY;BYDAY=-1FR",
+			err:   ErrInvalidRule,
+		},
+		{
+			name:  "failure - no freq",
+			input: "INTERVAL=2",
+			err:   ErrInvalidRule,
+		},
+		{
+			name:  "failure - count and until",
+			input: "FREQ=DAILY;COUNT=2;UNTIL=20250301",
+			err:   ErrInvalidRule,
+		},
+		{
+			name:  "failure - unknown weekday",
+			input: "FREQ=WEEKLY;BYDAY=XX",
+			err:   ErrInvalidRule,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rule, err := Parse(tc.input)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("expected %v, got %v", tc.err, err)
+			}
+			if err == nil && rule.String() != tc.expected {
+				t.Errorf("got %q, want %q", rule.String(), tc.expected)
+			}
+		})
+	}
+}
+
+func TestBetween(t *testing.T) {
+	// 2025-01-06 - понедельник.
+	dtstart := time.Date(2025, time.January, 6, 10, 0, 0, 0, time.UTC)
+	day := func(month time.Month, d int) time.Time {
+		return time.Date(2025, month, d, 10, 0, 0, 0, time.UTC)
+	}
+
+	testCases := []struct {
+		name     string
+		rule     string
+		dtstart  time.Time
+		start    time.Time
+		end      time.Time
+		expected []time.Time
+	}{
+		{
+			name:     "daily with count",
+			rule:     "FREQ=DAILY;COUNT=3",
+			dtstart:  dtstart,
+			start:    day(time.January, 1),
+			end:      day(time.February, 1),
+			expected: []time.Time{day(time.January, 6), day(time.January, 7), day(time.January, 8)},
+		},
+		{
+			name:     "weekly byday inside window",
+			rule:     "FREQ=WEEKLY;BYDAY=MO,TH",
+			dtstart:  dtstart,
+			start:    day(time.January, 13),
+			end:      day(time.January, 20),
+			expected: []time.Time{day(time.January, 13), day(time.January, 16)},
+		},
+		{
+			name:     "biweekly",
+			rule:     "FREQ=WEEKLY;INTERVAL=2",
+			dtstart:  dtstart,
+			start:    day(time.January, 1),
+			end:      day(time.February, 4),
+			expected: []time.Time{day(time.January, 6), day(time.January, 20), day(time.February, 3)},
+		},
+		{
+			name:     "monthly skips missing days",
+			rule:     "FREQ=MONTHLY;COUNT=3",
+			dtstart:  time.Date(2025, time.January, 31, 10, 0, 0, 0, time.UTC),
+			start:    day(time.January, 1),
+			end:      time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC),
+			expected: []time.Time{day(time.January, 31), day(time.March, 31), day(time.May, 31)},
+		},
+		{
+			name:     "monthly last friday",
+			rule:     "FREQ=MONTHLY;BYDAY=-1FR",
+			dtstart:  dtstart,
+			start:    day(time.January, 1),
+			end:      day(time.March, 1),
+			expected: []time.Time{day(time.January, 31), day(time.February, 28)},
+		},
+		{
+			name:     "yearly until",
+			rule:     "FREQ=YEARLY;UNTIL=20270101",
+			dtstart:  dtstart,
+			start:    day(time.January, 1),
+			end:      time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC),
+			expected: []time.Time{dtstart, dtstart.AddDate(1, 0, 0)},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rule, err := Parse(tc.rule)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			got := rule.Between(tc.dtstart, tc.start, tc.end)
+			if len(got) != len(tc.expected) {
+				t.Fatalf("expected %d occurrences, got %d: %v", len(tc.expected), len(got), got)
+			}
+			for i := range tc.expected {
+				if !got[i].Equal(tc.expected[i]) {
+					t.Errorf("occurrence[%d]: got %v, want %v", i, got[i], tc.expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestBetweenFloatingUntil(t *testing.T) {
+	// UNTIL без "Z" задан по времени серии (UTC-5), а не по UTC, где
+	// 22:00 и конец 2 марта наступают раньше вхождения 2 марта в 21:00.
+	loc := time.FixedZone("EST", -5*60*60)
+	dtstart := time.Date(2025, time.March, 1, 21, 0, 0, 0, loc)
+
+	testCases := []struct {
+		name     string
+		rule     string
+		expected int
+	}{
+		{name: "floating", rule: "FREQ=DAILY;UNTIL=20250302T220000", expected: 2},
+		{name: "date", rule: "FREQ=DAILY;UNTIL=20250302", expected: 2},
+		{name: "utc", rule: "FREQ=DAILY;UNTIL=20250303T020000Z", expected: 2},
+		{name: "utc before occurrence", rule: "FREQ=DAILY;UNTIL=20250303T015959Z", expected: 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rule, err := Parse(tc.rule)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			got := rule.Between(dtstart, dtstart, dtstart.AddDate(0, 0, 10))
+			if len(got) != tc.expected {
+				t.Errorf("expected %d occurrences, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestBetweenKeepsWallClockAcrossDST(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("tzdata unavailable: %v", err)
+	}
+
+	rule, _ := Parse("FREQ=DAILY")
+	dtstart := time.Date(2025, time.March, 29, 9, 0, 0, 0, loc)
+
+	got := rule.Between(dtstart, dtstart, dtstart.AddDate(0, 0, 3))
+	if len(got) != 3 {
+		t.Fatalf("expected 3 occurrences, got %d", len(got))
+	}
+	for i, occurrence := range got {
+		if occurrence.Hour() != 9 {
+			t.Errorf("occurrence[%d]: got hour %d, want 9", i, occurrence.Hour())
+		}
+	}
+}
+
+func TestAny(t *testing.T) {
+	dtstart := time.Date(2025, time.February, 17, 10, 0, 0, 0, time.UTC)
+	rule, err := Parse("FREQ=DAILY")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var calls int
+	found := rule.Any(dtstart, time.Date(1900, time.January, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2200, time.January, 1, 0, 0, 0, 0, time.UTC), func(occurrence time.Time) bool {
+			calls++
+			return occurrence.Weekday() == time.Wednesday
+		})
+	if !found || calls != 3 {
+		t.Errorf("expected to stop at the third occurrence, got %v after %d calls", found, calls)
+	}
+
+	found = rule.Any(dtstart, dtstart.AddDate(0, 0, 1), dtstart.AddDate(0, 0, 3), func(occurrence time.Time) bool {
+		return occurrence.Equal(dtstart)
+	})
+	if found {
+		t.Error("expected occurrences outside the range to be skipped")
+	}
+}
@@ -0,0 +1,146 @@
+package search
+
+import (
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Index - инвертированный индекс текстов документов с ключами K. Для каждого
+// слова хранятся позиции его вхождений в каждый документ, что позволяет
+// искать фразы. Index не безопасен для конкурентного использования.
+type Index[K comparable] struct {
+	postings map[string]map[K][]int
+	// docs - различные слова каждого документа, чтобы удалять его из postings.
+	docs map[K][]string
+	// terms - отсортированный словарь для поиска по началу слова.
+	terms []string
+}
+
+// NewIndex создает пустой Index.
+func NewIndex[K comparable]() *Index[K] {
+	return &Index[K]{
+		postings: make(map[string]map[K][]int),
+		docs:     make(map[K][]string),
+	}
+}
+
+// Add индексирует текст документа key, заменяя ранее проиндексированный.
+func (ix *Index[K]) Add(key K, text string) {
+	ix.Remove(key)
+
+	tokens := Tokenize(text)
+	if len(tokens) == 0 {
+		return
+	}
+
+	var terms []string
+	for pos, term := range tokens {
+		docs, ok := ix.postings[term]
+		if !ok {
+			docs = make(map[K][]int)
+			ix.postings[term] = docs
+			i, _ := slices.BinarySearch(ix.terms, term)
+			ix.terms = slices.Insert(ix.terms, i, term)
+		}
+		if _, ok := docs[key]; !ok {
+			terms = append(terms, term)
+		}
+		docs[key] = append(docs[key], pos)
+	}
+
+	ix.docs[key] = terms
+}
+
+// Remove удаляет документ key из индекса.
+func (ix *Index[K]) Remove(key K) {
+	for _, term := range ix.docs[key] {
+		docs := ix.postings[term]
+		delete(docs, key)
+		if len(docs) == 0 {
+			delete(ix.postings, term)
+			if i, ok := slices.BinarySearch(ix.terms, term); ok {
+				ix.terms = slices.Delete(ix.terms, i, i+1)
+			}
+		}
+	}
+	delete(ix.docs, key)
+}
+
+// Search возвращает ключи документов, соответствующих запросу, в произвольном порядке.
+func (ix *Index[K]) Search(q Query) []K {
+	var result map[K]bool
+	for _, c := range q.Clauses {
+		matched := ix.match(c)
+		if result == nil {
+			result = matched
+		} else {
+			for key := range result {
+				if !matched[key] {
+					delete(result, key)
+				}
+			}
+		}
+
+		if len(result) == 0 {
+			return nil
+		}
+	}
+
+	keys := make([]K, 0, len(result))
+	for key := range result {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// match возвращает документы, в которых слова условия идут подряд.
+func (ix *Index[K]) match(c Clause) map[K]bool {
+	// positions[i] - позиции i-го слова условия в каждом документе.
+	positions := make([]map[K][]int, len(c.Terms))
+	for i, term := range c.Terms {
+		if c.Prefix && i == len(c.Terms)-1 {
+			positions[i] = ix.prefixPostings(term)
+		} else {
+			positions[i] = ix.postings[term]
+		}
+		if len(positions[i]) == 0 {
+			return nil
+		}
+	}
+
+	result := make(map[K]bool)
+	for key, starts := range positions[0] {
+		for _, start := range starts {
+			if ix.phraseAt(key, start, positions[1:]) {
+				result[key] = true
+				break
+			}
+		}
+	}
+	return result
+}
+
+// phraseAt сообщает, идут ли в документе key следующие слова фразы сразу за позицией start.
+func (ix *Index[K]) phraseAt(key K, start int, next []map[K][]int) bool {
+	for i, docs := range next {
+		if _, ok := slices.BinarySearch(docs[key], start+i+1); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// prefixPostings объединяет позиции всех слов, начинающихся с prefix.
+func (ix *Index[K]) prefixPostings(prefix string) map[K][]int {
+	result := make(map[K][]int)
+	for i := sort.SearchStrings(ix.terms, prefix); i < len(ix.terms) && strings.HasPrefix(ix.terms[i], prefix); i++ {
+		for key, pos := range ix.postings[ix.terms[i]] {
+			result[key] = append(result[key], pos...)
+		}
+	}
+	for _, pos := range result {
+		slices.Sort(pos)
+	}
+	return result
+}
@@ -0,0 +1,85 @@
+// Package search реализует полнотекстовый поиск по коротким текстам: нормализацию
+// и разбиение текста на слова, разбор запросов и инвертированный индекс.
+package search
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// ErrEmptyQuery возвращается, если в запросе нет ни одного слова.
+var ErrEmptyQuery = errors.New("empty search query")
+
+// Normalize приводит текст к виду, в котором сравниваются слова: убирает
+// диакритические знаки (é -> e, ё -> е, й -> и) и регистр.
+func Normalize(s string) string {
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), cases.Fold(), norm.NFC)
+
+	res, _, err := transform.String(t, s)
+	if err != nil {
+		return strings.ToLower(s)
+	}
+	return res
+}
+
+// Tokenize разбивает текст на нормализованные слова. Словом считается
+// последовательность букв и цифр любого алфавита.
+func Tokenize(s string) []string {
+	return strings.FieldsFunc(Normalize(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// Clause - условие запроса: слова, идущие в тексте подряд.
+type Clause struct {
+	Terms []string
+	// Prefix - последнее слово достаточно найти как начало слова текста.
+	Prefix bool
+}
+
+// Query - разобранный запрос. Текст соответствует запросу, если
+// соответствует всем его условиям.
+type Query struct {
+	Clauses []Clause
+}
+
+// Parse разбирает запрос. Слова через пробел ищутся в любом порядке,
+// фраза в кавычках - подряд, звездочка в конце слова или фразы
+// включает поиск по началу слова: "зуб* врач", "к стомат"*.
+func Parse(s string) (Query, error) {
+	var q Query
+
+	add := func(text string, prefix bool) {
+		prefix = prefix || strings.HasSuffix(strings.TrimSpace(text), "*")
+		if terms := Tokenize(text); len(terms) > 0 {
+			q.Clauses = append(q.Clauses, Clause{Terms: terms, Prefix: prefix})
+		}
+	}
+
+	for s != "" {
+		before, after, quoted := strings.Cut(s, `"`)
+		for _, word := range strings.Fields(before) {
+			add(word, false)
+		}
+		if !quoted {
+			break
+		}
+
+		// Незакрытая кавычка продолжается до конца запроса.
+		phrase, rest, _ := strings.Cut(after, `"`)
+		prefix := strings.HasPrefix(rest, "*")
+		add(phrase, prefix)
+		s = strings.TrimPrefix(rest, "*")
+	}
+
+	if len(q.Clauses) == 0 {
+		return Query{}, ErrEmptyQuery
+	}
+	return q, nil
+}
@@ -0,0 +1,118 @@
+package search
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected []string
+	}{
+		{input: "Запись к СТОМАТОЛОГУ!", expected: []string{"запись", "к", "стоматологу"}},
+		{input: "Café crème, 10:30", expected: []string{"cafe", "creme", "10", "30"}},
+		{input: "Ёлка и ЕЛКА", expected: []string{"елка", "и", "елка"}},
+		{input: "Straße", expected: []string{"strasse"}},
+		{input: " -- ", expected: []string{}},
+	}
+
+	for _, tc := range testCases {
+		if got := Tokenize(tc.input); !reflect.DeepEqual(got, tc.expected) {
+			t.Errorf("Tokenize(%q) = %q, expected %q", tc.input, got, tc.expected)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected []Clause
+		err      error
+	}{
+		{
+			input:    "Dentist",
+			expected: []Clause{{Terms: []string{"dentist"}}},
+		},
+		{
+			input: `зуб* "к стомат"* врач`,
+			expected: []Clause{
+				{Terms: []string{"зуб"}, Prefix: true},
+				{Terms: []string{"к", "стомат"}, Prefix: true},
+				{Terms: []string{"врач"}},
+			},
+		},
+		{
+			input:    `"team sync*`,
+			expected: []Clause{{Terms: []string{"team", "sync"}, Prefix: true}},
+		},
+		{
+			input:    "e-mail",
+			expected: []Clause{{Terms: []string{"e", "mail"}}},
+		},
+		{input: ` * "" `, err: ErrEmptyQuery},
+	}
+
+	for _, tc := range testCases {
+		q, err := Parse(tc.input)
+		if !errors.Is(err, tc.err) {
+			t.Errorf("Parse(%q): expected error %v, got %v", tc.input, tc.err, err)
+			continue
+		}
+		if !reflect.DeepEqual(q.Clauses, tc.expected) {
+			t.Errorf("Parse(%q) = %+v, expected %+v", tc.input, q.Clauses, tc.expected)
+		}
+	}
+}
+
+func TestIndex(t *testing.T) {
+	ix := NewIndex[int]()
+	ix.Add(1, "Запись к стоматологу")
+	ix.Add(2, "Стоматолог: повторный приём")
+	ix.Add(3, "Dentist appointment")
+	ix.Add(4, "к другому стоматологу")
+
+	testCases := []struct {
+		query    string
+		expected []int
+	}{
+		{query: "СТОМАТОЛОГУ", expected: []int{1, 4}},
+		{query: "стоматолог*", expected: []int{1, 2, 4}},
+		{query: `"к стоматологу"`, expected: []int{1}},
+		{query: `"к друг"*`, expected: []int{4}},
+		{query: `"стоматологу к"`, expected: nil},
+		{query: "прием стомат*", expected: []int{2}},
+		{query: "dentist missing", expected: nil},
+		{query: "appoint*", expected: []int{3}},
+	}
+
+	search := func(query string) []int {
+		q, err := Parse(query)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", query, err)
+		}
+		keys := ix.Search(q)
+		slices.Sort(keys)
+		return keys
+	}
+
+	for _, tc := range testCases {
+		if got := search(tc.query); !reflect.DeepEqual(got, tc.expected) {
+			t.Errorf("Search(%q) = %v, expected %v", tc.query, got, tc.expected)
+		}
+	}
+
+	ix.Add(1, "Планерка")
+	ix.Remove(4)
+	if got := search("стоматологу"); got != nil {
+		t.Errorf("expected replaced and removed documents to disappear, got %v", got)
+	}
+	if got := search("план*"); !reflect.DeepEqual(got, []int{1}) {
+		t.Errorf("expected replaced document to be found by new text, got %v", got)
+	}
+	if len(ix.terms) != len(ix.postings) {
+		t.Errorf("vocabulary out of sync: %d terms, %d postings", len(ix.terms), len(ix.postings))
+	}
+}
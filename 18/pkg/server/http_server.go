@@ -2,27 +2,75 @@ package server
 
 import (
 	"context"
+	"log/slog"
 	"net/http"
+	"time"
 )
 
+type logger interface {
+	Info(msg string, args ...any)
+	Error(msg string, args ...any)
+}
+
+// Config - параметры HTTP сервера. Нулевой таймаут означает его отсутствие.
+type Config struct {
+	Port              string
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	// WriteTimeout ограничивает запись ответа. Потоковые обработчики
+	// (SSE, WebSocket) продлевают или снимают его сами.
+	WriteTimeout time.Duration
+	IdleTimeout  time.Duration
+	// TLS включает HTTPS и HTTP/2, если задан CertFile.
+	TLS TLSConfig
+	// Logger получает сообщения о перезагрузке сертификатов, по умолчанию slog.Default().
+	Logger logger
+}
+
 // Server определяет структуру HTTP сервера.
 type Server struct {
 	httpServer *http.Server
+	certs      *certificates
+	log        logger
 }
 
-// New создает новый экземпляр Server.
-func New(port string, handler http.Handler) *Server {
-	return &Server{httpServer: &http.Server{
-		Addr:           ":" + port,
-		Handler:        handler,
-		MaxHeaderBytes: 1 << 20, // 1 MB
-		ReadTimeout:    0,
-		WriteTimeout:   0,
-	}}
+// New создает новый экземпляр Server. С TLS сертификаты читаются сразу,
+// ошибка их загрузки возвращается.
+func New(cfg Config, handler http.Handler) (*Server, error) {
+	s := &Server{
+		httpServer: &http.Server{
+			Addr:              ":" + cfg.Port,
+			Handler:           handler,
+			MaxHeaderBytes:    1 << 20, // 1 MB
+			ReadTimeout:       cfg.ReadTimeout,
+			ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+			WriteTimeout:      cfg.WriteTimeout,
+			IdleTimeout:       cfg.IdleTimeout,
+		},
+		log: cfg.Logger,
+	}
+	if s.log == nil {
+		s.log = slog.Default()
+	}
+
+	if cfg.TLS.CertFile != "" {
+		certs, err := newCertificates(cfg.TLS)
+		if err != nil {
+			return nil, err
+		}
+		s.certs = certs
+		s.httpServer.TLSConfig = certs.tlsConfig()
+	}
+
+	return s, nil
 }
 
 // Run запускает HTTP сервер.
 func (s *Server) Run() error {
+	if s.certs != nil {
+		// Сертификаты берутся из TLSConfig.
+		return s.httpServer.ListenAndServeTLS("", "")
+	}
 	return s.httpServer.ListenAndServe()
 }
 
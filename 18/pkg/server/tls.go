@@ -0,0 +1,174 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// TLSConfig - файлы сертификатов сервера. Пустой CertFile отключает TLS.
+type TLSConfig struct {
+	CertFile string
+	KeyFile  string
+	// ClientCAFile - сертификаты CA в PEM. Если задан, сервер требует у клиентов
+	// сертификат, подписанный одним из них (mTLS).
+	ClientCAFile string
+}
+
+// certificates хранит текущие сертификаты и перечитывает их из файлов.
+// Новые сертификаты применяются к новым соединениям, открытые не разрываются.
+type certificates struct {
+	cfg TLSConfig
+
+	mu       sync.RWMutex
+	cert     *tls.Certificate
+	clientCA *x509.CertPool
+	// modTimes - время изменения файлов на момент последней успешной загрузки.
+	modTimes map[string]time.Time
+}
+
+func newCertificates(cfg TLSConfig) (*certificates, error) {
+	c := &certificates{cfg: cfg}
+	if err := c.reload(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *certificates) files() []string {
+	files := []string{c.cfg.CertFile, c.cfg.KeyFile}
+	if c.cfg.ClientCAFile != "" {
+		files = append(files, c.cfg.ClientCAFile)
+	}
+	return files
+}
+
+// reload читает сертификаты из файлов. При ошибке остаются прежние.
+func (c *certificates) reload() error {
+	modTimes := make(map[string]time.Time)
+	for _, name := range c.files() {
+		info, err := os.Stat(name)
+		if err != nil {
+			return err
+		}
+		modTimes[name] = info.ModTime()
+	}
+
+	cert, err := tls.LoadX509KeyPair(c.cfg.CertFile, c.cfg.KeyFile)
+	if err != nil {
+		return err
+	}
+
+	var clientCA *x509.CertPool
+	if c.cfg.ClientCAFile != "" {
+		data, err := os.ReadFile(c.cfg.ClientCAFile)
+		if err != nil {
+			return err
+		}
+		clientCA = x509.NewCertPool()
+		if !clientCA.AppendCertsFromPEM(data) {
+			return fmt.Errorf("%s: no certificates found", c.cfg.ClientCAFile)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.cert, c.clientCA, c.modTimes = &cert, clientCA, modTimes
+	return nil
+}
+
+// changed сообщает, изменился ли какой-нибудь файл после последней загрузки.
+func (c *certificates) changed() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for name, modTime := range c.modTimes {
+		info, err := os.Stat(name)
+		if err != nil || !info.ModTime().Equal(modTime) {
+			return true
+		}
+	}
+	return false
+}
+
+// tlsConfig возвращает настройки TLS, которые на каждом рукопожатии берут
+// текущие сертификаты. h2 объявляется первым, чтобы клиенты выбирали HTTP/2.
+func (c *certificates) tlsConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		NextProtos: []string{"h2", "http/1.1"},
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			c.mu.RLock()
+			defer c.mu.RUnlock()
+
+			cfg := &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				NextProtos:   []string{"h2", "http/1.1"},
+				Certificates: []tls.Certificate{*c.cert},
+			}
+			if c.clientCA != nil {
+				cfg.ClientCAs = c.clientCA
+				cfg.ClientAuth = tls.RequireAndVerifyClientCert
+			}
+			return cfg, nil
+		},
+	}
+}
+
+// TLSConfig возвращает настройки TLS сервера или nil без TLS. Их можно
+// использовать для других слушателей, чтобы сертификаты перечитывались вместе.
+func (s *Server) TLSConfig() *tls.Config {
+	if s.certs == nil {
+		return nil
+	}
+	return s.certs.tlsConfig()
+}
+
+// ReloadTLS перечитывает сертификаты из файлов. Если загрузить их не удалось,
+// сервер продолжает работать с прежними.
+func (s *Server) ReloadTLS() error {
+	if s.certs == nil {
+		return errors.New("tls is not enabled")
+	}
+	return s.certs.reload()
+}
+
+// WatchTLS перечитывает сертификаты при сигнале из reload (например SIGHUP)
+// и при изменении файлов, которые проверяются раз в interval (0 - не проверяются).
+// Завершается при отмене ctx. Без TLS сразу возвращает nil.
+func (s *Server) WatchTLS(ctx context.Context, interval time.Duration, reload <-chan os.Signal) error {
+	if s.certs == nil {
+		return nil
+	}
+
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-reload:
+		case <-tick:
+			if !s.certs.changed() {
+				continue
+			}
+		}
+
+		if err := s.certs.reload(); err != nil {
+			s.log.Error("tls certificates reload failed", "error", err.Error())
+			continue
+		}
+		s.log.Info("tls certificates reloaded")
+	}
+}
@@ -0,0 +1,266 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// issuer - сертификат с ключом, которым подписываются другие сертификаты.
+type issuer struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+// newCert выпускает сертификат с номером serial, подписанный parent
+// (самоподписанный, если parent nil).
+func newCert(t *testing.T, serial int64, parent *issuer, isCA bool) (issuer, []byte, []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "calendar"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	if isCA {
+		tmpl.IsCA, tmpl.BasicConstraintsValid = true, true
+		tmpl.KeyUsage |= x509.KeyUsageCertSign
+	}
+
+	signer := issuer{cert: tmpl, key: key}
+	if parent != nil {
+		signer = *parent
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer.cert, &key.PublicKey, signer.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return issuer{cert: cert, key: key},
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func writeFile(t *testing.T, name string, data []byte) {
+	t.Helper()
+
+	if err := os.WriteFile(name, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// serve запускает s на случайном порту и возвращает его адрес.
+func serve(t *testing.T, s *Server) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.httpServer.ServeTLS(ln, "", "")
+	t.Cleanup(func() { s.Shutdown(context.Background()) })
+
+	return "https://" + ln.Addr().String()
+}
+
+func newClient(roots *x509.CertPool, certs ...tls.Certificate) *http.Client {
+	return &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{RootCAs: roots, Certificates: certs},
+		ForceAttemptHTTP2: true,
+		DisableKeepAlives: true,
+	}}
+}
+
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+// peerSerial выполняет запрос и возвращает номер сертификата сервера.
+func peerSerial(t *testing.T, client *http.Client, url string) int64 {
+	t.Helper()
+
+	resp, err := client.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.Proto != "HTTP/2.0" {
+		t.Errorf("got protocol %s, want HTTP/2.0", resp.Proto)
+	}
+	return resp.TLS.PeerCertificates[0].SerialNumber.Int64()
+}
+
+func TestReloadTLS(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+
+	ca, _, _ := newCert(t, 1, nil, true)
+	_, certPEM, keyPEM := newCert(t, 2, &ca, false)
+	writeFile(t, certFile, certPEM)
+	writeFile(t, keyFile, keyPEM)
+
+	s, err := New(Config{TLS: TLSConfig{CertFile: certFile, KeyFile: keyFile}}, okHandler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	url := serve(t, s)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	client := newClient(roots)
+
+	if serial := peerSerial(t, client, url); serial != 2 {
+		t.Fatalf("got serial %d, want 2", serial)
+	}
+
+	// Битый файл не должен заменить рабочий сертификат.
+	writeFile(t, certFile, []byte("garbage"))
+	if err := s.ReloadTLS(); err == nil {
+		t.Error("expected reload error")
+	}
+	if serial := peerSerial(t, client, url); serial != 2 {
+		t.Fatalf("got serial %d after failed reload, want 2", serial)
+	}
+
+	_, certPEM, keyPEM = newCert(t, 3, &ca, false)
+	writeFile(t, certFile, certPEM)
+	writeFile(t, keyFile, keyPEM)
+	if err := s.ReloadTLS(); err != nil {
+		t.Fatal(err)
+	}
+	if serial := peerSerial(t, client, url); serial != 3 {
+		t.Fatalf("got serial %d after reload, want 3", serial)
+	}
+}
+
+func TestWatchTLS(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+
+	ca, _, _ := newCert(t, 1, nil, true)
+	_, certPEM, keyPEM := newCert(t, 2, &ca, false)
+	writeFile(t, certFile, certPEM)
+	writeFile(t, keyFile, keyPEM)
+
+	s, err := New(Config{TLS: TLSConfig{CertFile: certFile, KeyFile: keyFile}}, okHandler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	url := serve(t, s)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.WatchTLS(ctx, 10*time.Millisecond, nil)
+
+	_, certPEM, keyPEM = newCert(t, 3, &ca, false)
+	writeFile(t, certFile, certPEM)
+	writeFile(t, keyFile, keyPEM)
+	// Время изменения могло совпасть с прежним при грубом разрешении часов ФС.
+	later := time.Now().Add(time.Second)
+	os.Chtimes(certFile, later, later)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	client := newClient(roots)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for peerSerial(t, client, url) != 3 {
+		if time.Now().After(deadline) {
+			t.Fatal("certificate was not reloaded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestClientCertificates(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	caFile := filepath.Join(dir, "ca.pem")
+
+	ca, caPEM, _ := newCert(t, 1, nil, true)
+	_, certPEM, keyPEM := newCert(t, 2, &ca, false)
+	writeFile(t, certFile, certPEM)
+	writeFile(t, keyFile, keyPEM)
+	writeFile(t, caFile, caPEM)
+
+	s, err := New(Config{TLS: TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientCAFile: caFile}}, okHandler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	url := serve(t, s)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+
+	if _, err := newClient(roots).Get(url); err == nil {
+		t.Error("expected client without certificate to be rejected")
+	}
+
+	other, _, _ := newCert(t, 4, nil, true)
+	_, otherCert, otherKey := newCert(t, 5, &other, false)
+	foreign, _ := tls.X509KeyPair(otherCert, otherKey)
+	if _, err := newClient(roots, foreign).Get(url); err == nil {
+		t.Error("expected client with certificate of another CA to be rejected")
+	}
+
+	_, clientCert, clientKey := newCert(t, 6, &ca, false)
+	valid, _ := tls.X509KeyPair(clientCert, clientKey)
+	if serial := peerSerial(t, newClient(roots, valid), url); serial != 2 {
+		t.Errorf("got serial %d, want 2", serial)
+	}
+}
+
+func TestNewTLSErrors(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	caFile := filepath.Join(dir, "ca.pem")
+
+	ca, _, _ := newCert(t, 1, nil, true)
+	_, certPEM, keyPEM := newCert(t, 2, &ca, false)
+	writeFile(t, certFile, certPEM)
+	writeFile(t, keyFile, keyPEM)
+	writeFile(t, caFile, []byte("no certificates"))
+
+	tests := []struct {
+		name string
+		cfg  TLSConfig
+	}{
+		{name: "missing key", cfg: TLSConfig{CertFile: certFile, KeyFile: filepath.Join(dir, "none.pem")}},
+		{name: "key as cert", cfg: TLSConfig{CertFile: keyFile, KeyFile: keyFile}},
+		{name: "empty client ca", cfg: TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientCAFile: caFile}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(Config{TLS: tt.cfg}, okHandler); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}